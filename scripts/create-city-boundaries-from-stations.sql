@@ -0,0 +1,29 @@
+/*
+ * script to create approximate city boundaries from the weather stations
+ *
+ * the boundary of a city is the convex hull of its weather stations
+ * buffered by 2 km. cities that already have a boundary (for example an
+ * official one loaded by hand) are left untouched.
+ */
+
+
+/*
+ * insert the buffered convex hull of the stations of each city
+ */
+INSERT INTO city_boundaries(
+    city_name,
+    boundary,
+    source
+)
+SELECT
+    city_name,
+    ST_Multi(
+        ST_Buffer(
+            ST_ConvexHull(ST_Collect(location::geometry))::geography,
+            2000
+        )::geometry
+    )::geography,
+    'convex-hull-of-stations-buffered-2km'
+FROM weather_union_stations
+GROUP BY city_name
+ON CONFLICT (city_name) DO NOTHING;
@@ -0,0 +1,10 @@
+// Package api embeds the OpenAPI document of the public API, served by the
+// web server and the reference of the client in pkg/client.
+package api
+
+import _ "embed"
+
+// OpenAPI 3 document of the JSON API
+//
+//go:embed openapi.json
+var OpenAPI []byte
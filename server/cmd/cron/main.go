@@ -2,21 +2,36 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/kelaaditya/zomato-weather-union/server/internal/config"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/datasets"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/fixtures"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/metrics"
 	"github.com/kelaaditya/zomato-weather-union/server/internal/models"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/schema"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/tracing"
+	"github.com/kelaaditya/zomato-weather-union/server/scripts"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sync/errgroup"
 )
 
 // application level configurations and operations
 type application struct {
-	config *config.Config
-	models *models.Models
+	config   *config.Config
+	models   *models.Models
+	exporter *datasets.Exporter
+	metrics  *metrics.Metrics
 }
 
 func main() {
@@ -26,69 +41,366 @@ func main() {
 	// create app level background context
 	var ctx context.Context = context.Background()
 
+	// the errors are returned here so that the cleanups deferred by start
+	// (database, spans, temporary files) run before the exit
+	err := app.start(ctx)
+	if err != nil {
+		app.config.Logger.Error(err.Error())
+		// force exit on error
+		os.Exit(1)
+	}
+}
+
+// set up the cron and carry out a single run or run as a daemon
+func (app *application) start(ctx context.Context) error {
 	//
 	// config
 	//
-	app.config = &config.Config{}
+	app.config = &config.Config{
+		Component: "cron",
+		// the subcommands need only the settings they use
+		Subcommands: map[string]config.Subcommand{
+			"migrate": {Component: "migrate"},
+		},
+	}
 	// initialize the configurations of the logger, environment and
 	// database
+	// the command line flags are parsed here
 	err := app.config.New(ctx)
+	if errors.Is(err, config.ErrConfigPrinted) {
+		return nil
+	}
 	if err != nil {
-		app.config.Logger.Error(err.Error())
-		// force exit on error
-		os.Exit(1)
+		return err
 	}
 	// close the postgresql connection pool on function close
-	defer app.config.DB.Close()
+	defer app.config.CloseDatabase()
+	// flush the spans left on function close
+	defer app.config.ShutdownTracing(ctx)
+
+	//
+	// schema
+	//
+	// migrate subcommand, e.g. `cron migrate up`
+	if flag.Arg(0) == "migrate" {
+		return schema.Run(flag.Args()[1:], app.config.Environment.DatabaseURL, os.Stdout)
+	}
+	// refuse to start on a schema of another version (optional)
+	if app.config.Environment.IsSchemaVersionCheckEnabled {
+		err = schema.CheckVersion(ctx, app.config.DB)
+		if err != nil {
+			return err
+		}
+	}
+
+	//
+	// scripts
+	//
+	// wet bulb temperature script from the config, otherwise write the
+	// embedded script to a temporary file for the python interpreter
+	var pathToScriptWetBulbTemperature string = app.config.Environment.PathToScriptWetBulbTemperature
+	if pathToScriptWetBulbTemperature == "" {
+		var removeScripts func() error
+		pathToScriptWetBulbTemperature, removeScripts, err =
+			scripts.WriteWetBulbTemperature()
+		if err != nil {
+			return err
+		}
+		// remove the temporary file on function close
+		defer removeScripts()
+	}
 
 	//
 	// models
 	//
+	app.initializeModels(pathToScriptWetBulbTemperature)
+	if app.config.Environment.ProviderFixturesMode != fixtures.ModeOff {
+		app.config.Logger.Info(
+			"provider fixtures",
+			"mode",
+			app.config.Environment.ProviderFixturesMode,
+			"path",
+			app.config.Environment.PathToProviderFixtures,
+		)
+	}
+
+	//
+	// metrics
+	//
+	// prometheus metrics, exported in daemon mode
+	app.metrics = metrics.New()
+	app.metrics.RegisterDatabase(
+		app.config.DB,
+		func(ctx context.Context) (time.Time, error) {
+			run, err := app.models.Measurement.GetMeasurementRunLatest(ctx)
+			return run.TimeStamp, err
+		},
+	)
+
+	// a single run, unless a cron interval is configured
+	if app.config.Environment.CronInterval == 0 {
+		return app.run(ctx)
+	}
+
+	//
+	// daemon
+	//
+	return app.runDaemon(ctx)
+}
+
+// set up the models of the cron on the database of the config, with the
+// API clients of the providers and the wet bulb temperature script at its
+// path
+func (app *application) initializeModels(pathToScriptWetBulbTemperature string) {
+	// only the models used by the cron, the others stay nil
 	app.models = &models.Models{
 		WeatherUnion:   &models.WeatherUnionModel{DB: app.config.DB},
 		OpenWeatherMap: &models.OpenWeatherMapModel{DB: app.config.DB},
 		Measurement:    &models.MeasurementModel{DB: app.config.DB},
 		Calculation:    &models.CalculationModel{DB: app.config.DB},
+		Rollup:         &models.RollupModel{DB: app.config.DB},
+		Partition:      &models.PartitionModel{DB: app.config.DB},
+		Notification:   &models.NotificationModel{DB: app.config.DB},
+	}
+	// the calculations run the script from its path
+	app.models.Calculation.PathToScriptWetBulbTemperature =
+		pathToScriptWetBulbTemperature
+	// the API calls give up after the provider request timeout and
+	// create a span each. their responses are recorded to fixtures or
+	// replayed from them in the fixtures modes.
+	app.models.WeatherUnion.Client = &http.Client{
+		Timeout: app.config.Environment.ProviderRequestTimeout,
+		Transport: tracing.Transport{
+			Base: fixtures.Transport{
+				Mode:      app.config.Environment.ProviderFixturesMode,
+				Directory: app.config.Environment.PathToProviderFixtures,
+				Provider:  metrics.ProviderWeatherUnion,
+			},
+			Provider: metrics.ProviderWeatherUnion,
+		},
+	}
+	app.models.OpenWeatherMap.Client = &http.Client{
+		Timeout: app.config.Environment.ProviderRequestTimeout,
+		Transport: tracing.Transport{
+			Base: fixtures.Transport{
+				Mode:      app.config.Environment.ProviderFixturesMode,
+				Directory: app.config.Environment.PathToProviderFixtures,
+				Provider:  metrics.ProviderOpenWeatherMap,
+			},
+			Provider: metrics.ProviderOpenWeatherMap,
+		},
+	}
+}
+
+// run every cron interval until SIGINT or SIGTERM, serving the metrics on
+// the metrics port (if configured)
+// errors of a run are logged and the next run goes ahead
+func (app *application) runDaemon(ctx context.Context) error {
+	// stop on SIGINT or SIGTERM
+	ctxSignal, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// metrics server
+	if app.config.Environment.MetricsPort != "" {
+		// create new HTTP multiplexer
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", app.metrics.Handler())
+
+		// server config
+		server := http.Server{
+			Addr:    ":" + app.config.Environment.MetricsPort,
+			Handler: mux,
+			ErrorLog: slog.NewLogLogger(
+				app.config.Logger.Handler(),
+				slog.LevelError,
+			),
+			IdleTimeout:  app.config.Environment.HTTPIdleTimeout,
+			ReadTimeout:  app.config.Environment.HTTPReadTimeout,
+			WriteTimeout: app.config.Environment.HTTPWriteTimeout,
+		}
+		app.config.Logger.Info(
+			"starting the metrics server",
+			"port",
+			app.config.Environment.MetricsPort,
+		)
+		go func() {
+			err := server.ListenAndServe()
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				app.config.Logger.Error(
+					"error in the metrics server",
+					"error",
+					err.Error(),
+				)
+			}
+		}()
+		// stop the metrics server on function close
+		defer server.Close()
+	}
+
+	app.config.Logger.Info(
+		"running as a daemon",
+		"interval",
+		app.config.Environment.CronInterval.String(),
+	)
+	var ticker *time.Ticker = time.NewTicker(app.config.Environment.CronInterval)
+	defer ticker.Stop()
+
+	for {
+		// a run in progress is not interrupted by a signal, it stops the
+		// daemon once the run has finished
+		err := app.run(ctx)
+		if err != nil {
+			app.config.Logger.Error(err.Error())
+		}
+
+		select {
+		case <-ctxSignal.Done():
+			app.config.Logger.Info("daemon stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// a single run: fetch the measurements, calculate the wet bulb
+// temperatures and maintain the rollups, partitions and datasets
+// the run is traced as a single span
+func (app *application) run(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "cron.run")
+	defer span.End()
+
+	err := app.runSteps(ctx)
+	tracing.RecordError(span, err)
+	return err
+}
+
+// steps of a single run
+func (app *application) runSteps(ctx context.Context) error {
+	// create the partitions the measurements and calculations go into
+	err := app.models.Partition.CreatePartitionsFuture(
+		ctx,
+		app.config.Environment.PartitionNumberOfMonthsAhead,
+	)
+	if err != nil {
+		return err
 	}
 
 	// get the measurements from the APIs
-	err = app.GetAndSaveMeasurementsFromAPISingleRun(ctx)
+	runID, err := app.GetAndSaveMeasurementsFromAPISingleRun(ctx)
 	if err != nil {
-		app.config.Logger.Error(err.Error())
-		// force exit on error
-		os.Exit(1)
+		return err
 	}
 
 	// calculate the wet bulb temperatures
 	err = app.CalculateAndSaveTemperaturesAllUnprocessed(ctx)
 	if err != nil {
-		app.config.Logger.Error(err.Error())
-		// force exit on error
-		os.Exit(1)
+		return err
+	}
+
+	// tell the web servers that the run is ready
+	// the run is saved already, so a failed notification is only logged:
+	// the web servers pick the run up when their caches expire
+	err = app.models.Notification.NotifyMeasurementRun(ctx, runID)
+	if err != nil {
+		app.config.Logger.Error(
+			"error in notifying the web servers of the run",
+			"runID",
+			runID.String(),
+			"error",
+			err.Error(),
+		)
+	}
+
+	// update the hourly and daily rollups
+	err = app.models.Rollup.UpdateRollups(ctx)
+	if err != nil {
+		return err
 	}
+
+	// delete the rolled up raw rows past the retention period (optional)
+	if app.config.Environment.RetentionNumberOfDaysRaw > 0 {
+		numberOfRowsDeleted, err := app.models.Rollup.DeleteRawRowsRolledUp(
+			ctx,
+			app.config.Environment.RetentionNumberOfDaysRaw,
+		)
+		if err != nil {
+			return err
+		}
+		app.config.Logger.Info(
+			"raw rows past the retention period deleted",
+			"total",
+			strconv.FormatInt(numberOfRowsDeleted, 10),
+		)
+	}
+
+	// archive the partitions past the months to keep (optional)
+	if app.config.Environment.PartitionNumberOfMonthsToKeep > 0 {
+		slicePartitionsArchived, err := app.models.Partition.ArchivePartitionsOld(
+			ctx,
+			app.config.Environment.PartitionNumberOfMonthsToKeep,
+		)
+		if err != nil {
+			return err
+		}
+		for _, partition := range slicePartitionsArchived {
+			app.config.Logger.Info("partition archived", "partition", partition)
+		}
+	}
+
+	// export the downloadable datasets (optional)
+	if app.config.Environment.IsExportEnabledInCron {
+		//
+		// dataset exporter
+		//
+		app.exporter = &datasets.Exporter{
+			DB:                  app.config.DB,
+			Logger:              app.config.Logger,
+			Directory:           app.config.Environment.PathToDownloads,
+			NumberOfFilesToKeep: app.config.Environment.ExportNumberOfFilesToKeep,
+		}
+
+		err = app.exporter.ExportAll(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	// return nil if all okay
+	return nil
 }
 
 // carry out a single run of measurements over all the
-// weather stations from weather union
+// weather stations from weather union and return the run ID
 func (app *application) GetAndSaveMeasurementsFromAPISingleRun(
 	ctx context.Context,
-) error {
+) (uuid.UUID, error) {
 	// initialize runID as UUID for this calculation
 	runID, err := uuid.NewRandom()
 	if err != nil {
-		return err
+		return runID, err
 	}
 
 	// log runID when started
 	app.config.Logger.Info("run started.", "runID", runID.String())
 
+	// span of the measurements, the run ID is added to the run span too
+	trace.SpanFromContext(ctx).SetAttributes(
+		tracing.AttributeRunID.String(runID.String()),
+	)
+	ctx, span := tracing.Start(
+		ctx,
+		"measurements.fetch",
+		tracing.AttributeRunID.String(runID.String()),
+	)
+	defer span.End()
+
 	// get all weather station data from weather union
 	sliceStationsWeatherUnion, err :=
 		app.models.WeatherUnion.GetWeatherStationsAllWeatherUnion(
 			ctx,
 		)
 	if err != nil {
-		return err
+		return runID, err
 	}
 
 	// create a slice to append measurements from weather union
@@ -96,16 +408,35 @@ func (app *application) GetAndSaveMeasurementsFromAPISingleRun(
 	// create a slice to append measurements from open weather map
 	var sliceMeasurementsOpenWeatherMap []models.OpenWeatherMapMeasurement
 
+	// number of stations with a measurement from each provider
+	var numberOfStationsWeatherUnion int
+	var numberOfStationsOpenWeatherMap int
+
 	// iterate over all stations
 	for _, station := range sliceStationsWeatherUnion {
+		// span of the station, with the calls to both APIs
+		ctxStation, spanStation := tracing.Start(
+			ctx,
+			"station.fetch",
+			tracing.AttributeRunID.String(runID.String()),
+			tracing.AttributeLocalityID.String(station.LocalityID),
+		)
+		// the fixtures of the station are kept by its locality
+		ctxStation = fixtures.WithLocality(ctxStation, station.LocalityID)
+
 		// carry out API call to weather union
+		var timeStart time.Time = time.Now()
 		measurementWeatherUnion, err :=
 			app.models.WeatherUnion.CallAPIWeatherUnionLocality(
+				ctxStation,
 				app.config.Environment.URLBaseWeatherUnion,
 				app.config.Environment.APIKeyWeatherUnion,
 				&station,
 				runID,
 			)
+		app.metrics.ProviderRequestDuration.WithLabelValues(
+			metrics.ProviderWeatherUnion,
+		).Observe(time.Since(timeStart).Seconds())
 		if err != nil {
 			// log error
 			// do not return
@@ -116,21 +447,33 @@ func (app *application) GetAndSaveMeasurementsFromAPISingleRun(
 				"error",
 				err.Error(),
 			)
+			app.metrics.ProviderRequestErrors.WithLabelValues(
+				metrics.ProviderWeatherUnion,
+			).Inc()
+			tracing.RecordError(spanStation, err)
+		} else {
+			numberOfStationsWeatherUnion++
+			// append new measurements to corresponding slices
+			// a failed call has no measurement to save
+			sliceMeasurementsWeatherUnion = append(
+				sliceMeasurementsWeatherUnion,
+				measurementWeatherUnion,
+			)
 		}
-		// append new measurements to corresponding slices
-		sliceMeasurementsWeatherUnion = append(
-			sliceMeasurementsWeatherUnion,
-			measurementWeatherUnion,
-		)
 
 		// carry out API call to open weather map
+		timeStart = time.Now()
 		measurementOpenWeatherMap, err :=
 			app.models.OpenWeatherMap.CallAPIOpenWeatherMap(
+				ctxStation,
 				app.config.Environment.URLBaseOpenWeatherMap,
 				app.config.Environment.APIKeyOpenWeatherMap,
 				&station,
 				runID,
 			)
+		app.metrics.ProviderRequestDuration.WithLabelValues(
+			metrics.ProviderOpenWeatherMap,
+		).Observe(time.Since(timeStart).Seconds())
 		if err != nil {
 			// log error
 			// do not return
@@ -141,17 +484,34 @@ func (app *application) GetAndSaveMeasurementsFromAPISingleRun(
 				"error",
 				err.Error(),
 			)
+			app.metrics.ProviderRequestErrors.WithLabelValues(
+				metrics.ProviderOpenWeatherMap,
+			).Inc()
+			tracing.RecordError(spanStation, err)
+		} else {
+			numberOfStationsOpenWeatherMap++
+			// append new measurements to corresponding slices
+			// a failed call has no measurement to save
+			sliceMeasurementsOpenWeatherMap = append(
+				sliceMeasurementsOpenWeatherMap,
+				measurementOpenWeatherMap,
+			)
 		}
-		// append new measurements to corresponding slices
-		sliceMeasurementsOpenWeatherMap = append(
-			sliceMeasurementsOpenWeatherMap,
-			measurementOpenWeatherMap,
-		)
+
+		spanStation.End()
 
 		// slow down subsequent requests
-		time.Sleep(10 * time.Millisecond)
+		time.Sleep(app.config.Environment.ProviderRequestInterval)
 	}
 
+	// stations fetched in this run
+	app.metrics.RunStationsFetched.WithLabelValues(
+		metrics.ProviderWeatherUnion,
+	).Set(float64(numberOfStationsWeatherUnion))
+	app.metrics.RunStationsFetched.WithLabelValues(
+		metrics.ProviderOpenWeatherMap,
+	).Set(float64(numberOfStationsOpenWeatherMap))
+
 	// log the count of measurements received from weather union
 	app.config.Logger.Info(
 		"measurements gathered from weather union",
@@ -166,9 +526,16 @@ func (app *application) GetAndSaveMeasurementsFromAPISingleRun(
 	)
 
 	// save run ID
-	err = app.models.Measurement.SaveMeasurementRun(ctx, runID)
+	timeStampRun, err := app.models.Measurement.SaveMeasurementRun(ctx, runID)
 	if err != nil {
-		return err
+		return runID, err
+	}
+	// the measurements are saved with the time stamp of their run
+	for i := range sliceMeasurementsWeatherUnion {
+		sliceMeasurementsWeatherUnion[i].TimeStampRun = timeStampRun
+	}
+	for i := range sliceMeasurementsOpenWeatherMap {
+		sliceMeasurementsOpenWeatherMap[i].TimeStampRun = timeStampRun
 	}
 
 	// save measurements from weather union
@@ -177,7 +544,7 @@ func (app *application) GetAndSaveMeasurementsFromAPISingleRun(
 		sliceMeasurementsWeatherUnion,
 	)
 	if err != nil {
-		return err
+		return runID, err
 	}
 
 	// save measurement from open weather map
@@ -186,11 +553,11 @@ func (app *application) GetAndSaveMeasurementsFromAPISingleRun(
 		sliceMeasurementsOpenWeatherMap,
 	)
 	if err != nil {
-		return err
+		return runID, err
 	}
 
 	// return nil if all okay
-	return nil
+	return runID, nil
 }
 
 // calculate all unprocessed wet bulb temperature values
@@ -198,6 +565,9 @@ func (app *application) GetAndSaveMeasurementsFromAPISingleRun(
 func (app *application) CalculateAndSaveTemperaturesAllUnprocessed(
 	ctx context.Context,
 ) error {
+	ctx, span := tracing.Start(ctx, "calculations")
+	defer span.End()
+
 	// get all unprocessed measurements
 	sliceMeasurementsUnprocessed, err :=
 		app.models.Measurement.GetUnprocessedDataForCalculationsTemperature(
@@ -219,14 +589,29 @@ func (app *application) CalculateAndSaveTemperaturesAllUnprocessed(
 	for _, measurement := range sliceMeasurementsUnprocessed {
 		wgCalculations.Go(func() error {
 			// carry out calculations over a single measurement
+			_, spanCalculation := tracing.Start(
+				ctx,
+				"calculation.wet_bulb",
+				tracing.AttributeRunID.String(measurement.RunID.String()),
+			)
+			defer spanCalculation.End()
+			var timeStart time.Time = time.Now()
 			calculation, err :=
 				app.models.Calculation.CalculateTemperatureFromSingleMeasurement(
 					app.config.Environment.PathToPythonEnvironment,
 					measurement,
 				)
 			if err != nil {
+				app.metrics.CalculationErrors.Inc()
+				tracing.RecordError(spanCalculation, err)
 				return err
 			}
+			spanCalculation.SetAttributes(
+				tracing.AttributeMethod.String(calculation.Method),
+			)
+			app.metrics.CalculationDuration.WithLabelValues(
+				calculation.Method,
+			).Observe(time.Since(timeStart).Seconds())
 
 			// append new successful calculation to slice of all successful
 			// calculations
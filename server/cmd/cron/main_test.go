@@ -0,0 +1,417 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/kelaaditya/zomato-weather-union/server/internal/config"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/fixtures"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/metrics"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/models"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/testdb"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/tracing"
+	"github.com/kelaaditya/zomato-weather-union/server/pkg/fakeweather"
+)
+
+// keys of the fake APIs
+const (
+	APIKeyWeatherUnionTest   string = "key-weather-union"
+	APIKeyOpenWeatherMapTest string = "key-open-weather-map"
+)
+
+// application of the cron on the test database, calling the APIs at a URL
+// base (both providers) in a fixtures mode
+func newApplicationTest(
+	DB *pgxpool.Pool,
+	URLBase string,
+	modeFixtures string,
+	pathToFixtures string,
+) *application {
+	var app *application = &application{
+		config: &config.Config{
+			Component: "cron",
+			DB:        DB,
+			Logger:    slog.New(slog.DiscardHandler),
+			Environment: &config.Environment{
+				URLBaseWeatherUnion:    URLBase,
+				URLBaseOpenWeatherMap:  URLBase,
+				APIKeyWeatherUnion:     APIKeyWeatherUnionTest,
+				APIKeyOpenWeatherMap:   APIKeyOpenWeatherMapTest,
+				ProviderRequestTimeout: 5 * time.Second,
+				ProviderFixturesMode:   modeFixtures,
+				PathToProviderFixtures: pathToFixtures,
+			},
+		},
+		metrics: metrics.New(),
+	}
+	// the calculations are not run, they need the python environment
+	app.initializeModels("")
+	return app
+}
+
+// a station of the tests
+type stationTest struct {
+	LocalityID string
+	Latitude   float64
+	Longitude  float64
+}
+
+// stations of the fixtures in testdata/providers, recorded from the fake
+// weather APIs
+var sliceStationsFixtures []stationTest = []stationTest{
+	{"ZWL005764", 28.531759, 77.293973},
+	{"ZWL008752", 28.460895, 77.304764},
+	{"ZWL005996", 28.565268, 77.274971},
+	{"ZWL005243", 28.574404, 77.334178},
+}
+
+// stations of the localities a few kilometres apart
+func stationsOfLocalities(sliceLocalityIDs ...string) []stationTest {
+	var sliceStations []stationTest
+	for index, localityID := range sliceLocalityIDs {
+		sliceStations = append(sliceStations, stationTest{
+			LocalityID: localityID,
+			Latitude:   28.5 + 0.05*float64(index),
+			Longitude:  77.1 + 0.05*float64(index),
+		})
+	}
+	return sliceStations
+}
+
+// save active stations, and return the stations as read by the cron
+func seedStations(t *testing.T, DB *pgxpool.Pool, sliceStationsTest []stationTest) []models.WeatherUnionStation {
+	t.Helper()
+	var ctx context.Context = context.Background()
+
+	for index, station := range sliceStationsTest {
+		_, err := DB.Exec(
+			ctx,
+			`
+			INSERT INTO weather_union_stations (
+				weather_station_id,
+				city_name,
+				locality_name,
+				locality_id,
+				location,
+				device_type,
+				device_type_integer,
+				is_active
+			)
+			VALUES (
+				@stationID,
+				'Delhi NCR',
+				@localityName,
+				@localityID,
+				ST_SetSRID(ST_MakePoint(@longitude, @latitude), 4326)::geography,
+				'Weather Station',
+				1,
+				TRUE
+			);
+			`,
+			pgx.NamedArgs{
+				"stationID":    uuid.New(),
+				"localityName": fmt.Sprintf("Locality %d", index),
+				"localityID":   station.LocalityID,
+				"longitude":    station.Longitude,
+				"latitude":     station.Latitude,
+			},
+		)
+		if err != nil {
+			t.Fatalf("error in saving station: %v", err)
+		}
+	}
+
+	var model models.WeatherUnionModel = models.WeatherUnionModel{DB: DB}
+	sliceStations, err := model.GetWeatherStationsAllWeatherUnion(ctx)
+	if err != nil {
+		t.Fatalf("error in getting stations: %v", err)
+	}
+	return sliceStations
+}
+
+// measurement of a station in a run as saved, the fields are nil when null
+type measurementSaved struct {
+	LocalityID          string
+	TemperatureWU       *float64
+	HumidityWU          *float64
+	TemperatureOWM      *float64
+	WeatherObjectMain   *string
+	IsWeatherUnion      bool
+	IsOpenWeatherMap    bool
+	IsTimeStampOfTheRun bool
+}
+
+// measurements of a run by locality, with whether they were saved by each
+// provider and at the time stamp of the run
+func measurementsSaved(t *testing.T, DB *pgxpool.Pool, runID uuid.UUID) map[string]measurementSaved {
+	t.Helper()
+
+	rows, err := DB.Query(
+		context.Background(),
+		`
+		SELECT
+			wus.locality_id,
+			mwu.temperature,
+			mwu.humidity,
+			mowm.temperature,
+			mowm.weather_object_main,
+			mwu.measurement_id IS NOT NULL,
+			mowm.measurement_id IS NOT NULL,
+			COALESCE(mwu.time_stamp_run, mr.time_stamp) = mr.time_stamp
+				AND COALESCE(mowm.time_stamp_run, mr.time_stamp) = mr.time_stamp
+		FROM weather_union_stations AS wus
+		CROSS JOIN measurement_runs AS mr
+		LEFT JOIN measurements_weather_union AS mwu
+			ON mwu.weather_station_id = wus.weather_station_id
+			AND mwu.run_id = mr.run_id
+		LEFT JOIN measurements_open_weather_map AS mowm
+			ON mowm.weather_station_id = wus.weather_station_id
+			AND mowm.run_id = mr.run_id
+		WHERE mr.run_id = @runID;
+		`,
+		pgx.NamedArgs{"runID": runID},
+	)
+	if err != nil {
+		t.Fatalf("error in getting measurements: %v", err)
+	}
+	sliceMeasurements, err := pgx.CollectRows(rows, pgx.RowToStructByPos[measurementSaved])
+	if err != nil {
+		t.Fatalf("error in getting measurements: %v", err)
+	}
+	if len(sliceMeasurements) == 0 {
+		t.Fatalf("run %s is not saved", runID)
+	}
+
+	var mapMeasurements map[string]measurementSaved = make(map[string]measurementSaved)
+	for _, measurement := range sliceMeasurements {
+		if !measurement.IsTimeStampOfTheRun {
+			t.Errorf("%s: measurement not at the time stamp of the run", measurement.LocalityID)
+		}
+		mapMeasurements[measurement.LocalityID] = measurement
+	}
+	return mapMeasurements
+}
+
+// a run over the fake APIs saves the measurements of the stations that
+// answered, with the values they sent and nulls for the null fields, and
+// goes on past the 429s and 500s
+func TestGetAndSaveMeasurementsFromAPISingleRun(t *testing.T) {
+	var DB *pgxpool.Pool = testdb.New(t)
+	sliceStations := seedStations(
+		t,
+		DB,
+		stationsOfLocalities("ZWL000001", "ZWL000002", "ZWL000003", "ZWL000004"),
+	)
+	var mapStations map[string]models.WeatherUnionStation = make(map[string]models.WeatherUnionStation)
+	for _, station := range sliceStations {
+		mapStations[station.LocalityID] = station
+	}
+
+	// responses of the stations by locality, open weather map is matched
+	// by the coordinates the cron sends
+	scenarioStation := func(
+		localityID string,
+		weatherUnion *fakeweather.Response,
+		openWeatherMap *fakeweather.Response,
+	) fakeweather.ScenarioStation {
+		return fakeweather.ScenarioStation{
+			LocalityID:     localityID,
+			Latitude:       mapStations[localityID].Latitude,
+			Longitude:      mapStations[localityID].Longitude,
+			WeatherUnion:   weatherUnion,
+			OpenWeatherMap: openWeatherMap,
+		}
+	}
+	server, serverTest := fakeweather.NewTestServer(fakeweather.Options{
+		APIKeyWeatherUnion:   APIKeyWeatherUnionTest,
+		APIKeyOpenWeatherMap: APIKeyOpenWeatherMapTest,
+		Seed:                 1,
+		Scenario: &fakeweather.Scenario{
+			Stations: []fakeweather.ScenarioStation{
+				scenarioStation(
+					"ZWL000001",
+					&fakeweather.Response{Values: map[string]float64{"temperature": 34.5, "humidity": 70}},
+					&fakeweather.Response{Values: map[string]float64{"temp": 307.65}},
+				),
+				scenarioStation(
+					"ZWL000002",
+					&fakeweather.Response{StatusCode: http.StatusTooManyRequests},
+					nil,
+				),
+				scenarioStation(
+					"ZWL000003",
+					&fakeweather.Response{StatusCode: http.StatusInternalServerError},
+					&fakeweather.Response{StatusCode: http.StatusTooManyRequests},
+				),
+				scenarioStation(
+					"ZWL000004",
+					&fakeweather.Response{NullFields: []string{"temperature", "humidity"}},
+					// a response without weather objects is saved too
+					&fakeweather.Response{NullFields: []string{"temp", "weather"}},
+				),
+			},
+		},
+	})
+	defer serverTest.Close()
+
+	var app *application = newApplicationTest(DB, serverTest.URL, fixtures.ModeOff, "")
+	runID, err := app.GetAndSaveMeasurementsFromAPISingleRun(context.Background())
+	if err != nil {
+		t.Fatalf("error in the run: %v", err)
+	}
+
+	// every station is asked once by each API
+	for _, endpoint := range []string{fakeweather.EndpointWeatherUnion, fakeweather.EndpointOpenWeatherMap} {
+		if server.NumberOfRequests(endpoint) != len(sliceStations) {
+			t.Errorf(
+				"%s: %d requests, expected %d",
+				endpoint,
+				server.NumberOfRequests(endpoint),
+				len(sliceStations),
+			)
+		}
+	}
+
+	var mapMeasurements map[string]measurementSaved = measurementsSaved(t, DB, runID)
+
+	// both APIs answered with the values of the scenario
+	var measurement measurementSaved = mapMeasurements["ZWL000001"]
+	if !measurement.IsWeatherUnion || !measurement.IsOpenWeatherMap {
+		t.Fatalf("ZWL000001: measurements not saved: %+v", measurement)
+	}
+	if measurement.TemperatureWU == nil || *measurement.TemperatureWU != 34.5 ||
+		measurement.HumidityWU == nil || *measurement.HumidityWU != 70 {
+		t.Errorf("ZWL000001: weather union measurement not as sent: %+v", measurement)
+	}
+	if measurement.TemperatureOWM == nil || *measurement.TemperatureOWM != 307.65 ||
+		measurement.WeatherObjectMain == nil || *measurement.WeatherObjectMain != "Clear" {
+		t.Errorf("ZWL000001: open weather map measurement not as sent: %+v", measurement)
+	}
+
+	// a 429 of weather union, open weather map is saved
+	measurement = mapMeasurements["ZWL000002"]
+	if measurement.IsWeatherUnion || !measurement.IsOpenWeatherMap {
+		t.Errorf("ZWL000002: expected the open weather map measurement only: %+v", measurement)
+	}
+
+	// a 500 and a 429, nothing is saved
+	measurement = mapMeasurements["ZWL000003"]
+	if measurement.IsWeatherUnion || measurement.IsOpenWeatherMap {
+		t.Errorf("ZWL000003: expected no measurements: %+v", measurement)
+	}
+
+	// null fields are saved as nulls
+	measurement = mapMeasurements["ZWL000004"]
+	if !measurement.IsWeatherUnion || !measurement.IsOpenWeatherMap {
+		t.Fatalf("ZWL000004: measurements not saved: %+v", measurement)
+	}
+	if measurement.TemperatureWU != nil || measurement.HumidityWU != nil ||
+		measurement.TemperatureOWM != nil || measurement.WeatherObjectMain != nil {
+		t.Errorf("ZWL000004: null fields not saved as null: %+v", measurement)
+	}
+}
+
+// a run where every call fails is saved without measurements
+func TestGetAndSaveMeasurementsFromAPISingleRunAllFailed(t *testing.T) {
+	var DB *pgxpool.Pool = testdb.New(t)
+	seedStations(t, DB, stationsOfLocalities("ZWL000001", "ZWL000002"))
+
+	_, serverTest := fakeweather.NewTestServer(fakeweather.Options{
+		APIKeyWeatherUnion:   APIKeyWeatherUnionTest,
+		APIKeyOpenWeatherMap: APIKeyOpenWeatherMapTest,
+		Faults:               fakeweather.Faults{RateTooManyRequests: 0.5, RateErrors: 0.5},
+	})
+	defer serverTest.Close()
+
+	var app *application = newApplicationTest(DB, serverTest.URL, fixtures.ModeOff, "")
+	runID, err := app.GetAndSaveMeasurementsFromAPISingleRun(context.Background())
+	if err != nil {
+		t.Fatalf("error in the run: %v", err)
+	}
+
+	for localityID, measurement := range measurementsSaved(t, DB, runID) {
+		if measurement.IsWeatherUnion || measurement.IsOpenWeatherMap {
+			t.Errorf("%s: expected no measurements: %+v", localityID, measurement)
+		}
+	}
+}
+
+// transport of the requests that must not reach the network
+type transportForbidden struct {
+	t *testing.T
+}
+
+func (transport transportForbidden) RoundTrip(request *http.Request) (*http.Response, error) {
+	transport.t.Errorf("request sent to the network: %s", request.URL)
+	return nil, errors.New("network forbidden")
+}
+
+// a run replayed from the fixtures in testdata/providers, without provider
+// URLs or API keys and without the network, saves the recorded
+// measurements
+func TestGetAndSaveMeasurementsFromAPISingleRunReplay(t *testing.T) {
+	var DB *pgxpool.Pool = testdb.New(t)
+	seedStations(t, DB, sliceStationsFixtures)
+
+	var app *application = newApplicationTest(DB, "", fixtures.ModeReplay, "../../testdata/providers")
+	app.config.Environment.APIKeyWeatherUnion = ""
+	app.config.Environment.APIKeyOpenWeatherMap = ""
+	// the fixtures transports fail the test if they call their base
+	for _, client := range []*http.Client{
+		app.models.WeatherUnion.Client,
+		app.models.OpenWeatherMap.Client,
+	} {
+		var transport tracing.Transport = client.Transport.(tracing.Transport)
+		var transportFixtures fixtures.Transport = transport.Base.(fixtures.Transport)
+		transportFixtures.Base = transportForbidden{t: t}
+		transport.Base = transportFixtures
+		client.Transport = transport
+	}
+
+	runID, err := app.GetAndSaveMeasurementsFromAPISingleRun(context.Background())
+	if err != nil {
+		t.Fatalf("error in the run: %v", err)
+	}
+
+	var mapMeasurements map[string]measurementSaved = measurementsSaved(t, DB, runID)
+
+	// the values recorded
+	var measurement measurementSaved = mapMeasurements["ZWL005764"]
+	if !measurement.IsWeatherUnion || !measurement.IsOpenWeatherMap {
+		t.Fatalf("ZWL005764: measurements not saved: %+v", measurement)
+	}
+	if measurement.TemperatureWU == nil || *measurement.TemperatureWU != 34.5 ||
+		measurement.HumidityWU == nil || *measurement.HumidityWU != 70 {
+		t.Errorf("ZWL005764: weather union measurement not as recorded: %+v", measurement)
+	}
+	if measurement.TemperatureOWM == nil || *measurement.TemperatureOWM != 307.26 {
+		t.Errorf("ZWL005764: open weather map measurement not as recorded: %+v", measurement)
+	}
+
+	// a 429 of weather union is recorded
+	measurement = mapMeasurements["ZWL008752"]
+	if measurement.IsWeatherUnion || !measurement.IsOpenWeatherMap {
+		t.Errorf("ZWL008752: expected the open weather map measurement only: %+v", measurement)
+	}
+
+	// a 500 of open weather map is recorded
+	measurement = mapMeasurements["ZWL005996"]
+	if !measurement.IsWeatherUnion || measurement.IsOpenWeatherMap {
+		t.Errorf("ZWL005996: expected the weather union measurement only: %+v", measurement)
+	}
+
+	// a null humidity is recorded
+	measurement = mapMeasurements["ZWL005243"]
+	if !measurement.IsWeatherUnion || measurement.HumidityWU != nil || measurement.TemperatureWU == nil {
+		t.Errorf("ZWL005243: expected a null humidity only: %+v", measurement)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/kelaaditya/zomato-weather-union/server/internal/config"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/datasets"
+)
+
+// application level configurations and operations
+type application struct {
+	config   *config.Config
+	exporter *datasets.Exporter
+}
+
+func main() {
+	//
+	var app application
+
+	// create app level background context
+	var ctx context.Context = context.Background()
+
+	// the errors are returned here so that the cleanups deferred by start
+	// (database, spans) run before the exit
+	err := app.start(ctx)
+	if err != nil {
+		app.config.Logger.Error(err.Error())
+		// force exit on error
+		os.Exit(1)
+	}
+}
+
+// export all datasets to the downloads directory
+func (app *application) start(ctx context.Context) error {
+	//
+	// config
+	//
+	app.config = &config.Config{Component: "export"}
+	// initialize the configurations of the logger, environment and
+	// database
+	err := app.config.New(ctx)
+	if errors.Is(err, config.ErrConfigPrinted) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// close the postgresql connection pool on function close
+	defer app.config.CloseDatabase()
+	// flush the spans left on function close
+	defer app.config.ShutdownTracing(ctx)
+
+	//
+	// dataset exporter
+	//
+	app.exporter = &datasets.Exporter{
+		DB:                  app.config.DB,
+		Logger:              app.config.Logger,
+		Directory:           app.config.Environment.PathToDownloads,
+		NumberOfFilesToKeep: app.config.Environment.ExportNumberOfFilesToKeep,
+	}
+
+	// export all datasets
+	return app.exporter.ExportAll(ctx)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/kelaaditya/zomato-weather-union/server/pkg/fakeweather"
+)
+
+// local stand-in for the weather union and open weather map APIs
+// point both URL bases of the cron at it, for example:
+//
+//	URL_BASE_WEATHER_UNION=http://localhost:8090/gw/weather/external/v0
+//	URL_BASE_OPEN_WEATHER_MAP=http://localhost:8090/data/3.0
+func main() {
+	var logger *slog.Logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	var port *string = flag.String("port", "8090", "port of the server")
+	var seed *uint64 = flag.Uint64("seed", 1, "seed of the generated values")
+	var pathToScenario *string = flag.String(
+		"scenario",
+		"",
+		"JSON file of fixed responses of given stations (default: none)",
+	)
+	var APIKeyWeatherUnion *string = flag.String(
+		"api-key-weather-union",
+		"",
+		"expected X-Zomato-Api-Key, any key if empty",
+	)
+	var APIKeyOpenWeatherMap *string = flag.String(
+		"api-key-open-weather-map",
+		"",
+		"expected appid, any key if empty",
+	)
+	var rateErrors *float64 = flag.Float64("error-rate", 0, "share of requests answered with a 500")
+	var rateTooManyRequests *float64 = flag.Float64(
+		"too-many-requests-rate",
+		0,
+		"share of requests answered with a 429",
+	)
+	var rateNullFields *float64 = flag.Float64(
+		"null-rate",
+		0,
+		"share of requests answered with null measurements",
+	)
+	var latency *time.Duration = flag.Duration("latency", 0, "delay of every response")
+	var latencyJitter *time.Duration = flag.Duration(
+		"latency-jitter",
+		0,
+		"random delay of up to this added to the latency",
+	)
+	flag.Parse()
+
+	// the shares must be between 0 and 1
+	for _, rate := range []float64{*rateErrors, *rateTooManyRequests, *rateNullFields} {
+		if !(rate >= 0 && rate <= 1) {
+			logger.Error("the rates must be between 0 and 1")
+			// force exit on error
+			os.Exit(1)
+		}
+	}
+	if *latency < 0 || *latencyJitter < 0 {
+		logger.Error("the latencies must not be negative")
+		// force exit on error
+		os.Exit(1)
+	}
+
+	var scenario *fakeweather.Scenario
+	if *pathToScenario != "" {
+		var err error
+		scenario, err = fakeweather.LoadScenario(*pathToScenario)
+		if err != nil {
+			logger.Error(err.Error())
+			// force exit on error
+			os.Exit(1)
+		}
+	}
+
+	var server *http.Server = &http.Server{
+		Addr: net.JoinHostPort("", *port),
+		Handler: fakeweather.New(fakeweather.Options{
+			APIKeyWeatherUnion:   *APIKeyWeatherUnion,
+			APIKeyOpenWeatherMap: *APIKeyOpenWeatherMap,
+			Seed:                 *seed,
+			Scenario:             scenario,
+			Faults: fakeweather.Faults{
+				RateErrors:          *rateErrors,
+				RateTooManyRequests: *rateTooManyRequests,
+				RateNullFields:      *rateNullFields,
+				Latency:             *latency,
+				LatencyJitter:       *latencyJitter,
+			},
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelError),
+	}
+
+	logger.Info("starting fake weather APIs", "addr", server.Addr, "seed", *seed)
+	err := server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error(err.Error())
+		// force exit on error
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// usage of the admin subcommand
+const usageAdmin string = `usage: admin <command>
+
+commands:
+  hash-password NAME
+               read a password from stdin and print the admin_users
+               entry of the administrator`
+
+// create the entries of the administrators of the admin pages
+func runAdmin(args []string, r io.Reader, w io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(usageAdmin)
+	}
+
+	switch args[0] {
+	case "hash-password":
+		if len(args) < 2 || args[1] == "" || strings.Contains(args[1], ":") {
+			return fmt.Errorf("missing name or name with a colon\n%s", usageAdmin)
+		}
+
+		// the first line of the input, without the line ending
+		password, err := bufio.NewReader(r).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("error in reading password: %w", err)
+		}
+		password = strings.TrimRight(password, "\r\n")
+		if password == "" {
+			return errors.New("empty password")
+		}
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return fmt.Errorf("error in hashing password: %w", err)
+		}
+
+		fmt.Fprintf(w, "%s:%s\n", args[1], hash)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], usageAdmin)
+	}
+}
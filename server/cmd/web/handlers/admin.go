@@ -0,0 +1,457 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kelaaditya/zomato-weather-union/server/cmd/web/middlewares"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/models"
+)
+
+// rows per page of the admin tables
+const (
+	numberOfStationsPerPageAdmin int = 50
+	numberOfRunsPerPageAdmin     int = 50
+)
+
+// data for the admin stations page template
+type templateDataAdminStations struct {
+	Stations         []models.StationDetails
+	NumberOfStations int
+	// filters of the list: search and "true", "false" or "" for the
+	// active, inactive or all stations
+	Search   string
+	IsActive string
+	Page     int
+	// query strings of the previous and next pages, empty if there are none
+	QueryPagePrevious string
+	QueryPageNext     string
+	// query string of this page, to come back to after a change
+	Query string
+}
+
+// data for the admin station page template
+type templateDataAdminStation struct {
+	Station models.StationDetails
+	// values of the form as sent, shown again with the errors by field
+	Form      formStation
+	Errors    map[string]string
+	IsUpdated bool
+}
+
+// fields of the station form
+type formStation struct {
+	CityName     string
+	LocalityName string
+	Latitude     string
+	Longitude    string
+	Elevation    string
+	IsActive     bool
+}
+
+// data for the admin runs page template
+type templateDataAdminRuns struct {
+	Runs         []templateDataAdminRun
+	Page         int
+	PagePrevious int
+	PageNext     int
+	// run queued for recalculation and the number of its measurements
+	RunIDRecalculated                string
+	NumberOfMeasurementsRecalculated string
+}
+
+// a run on the admin runs page
+type templateDataAdminRun struct {
+	models.MeasurementRunSummary
+	Time string
+}
+
+// the admin pages start at the stations
+func (handler *Handler) Admin() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/admin/stations", http.StatusSeeOther)
+	}
+}
+
+// list and search of the stations
+// query parameters:
+//
+//	q       part of the city name, locality name or locality ID
+//	active  true or false for the active or inactive stations only
+//	page    page of the list, from 1
+func (handler *Handler) AdminStations() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var query url.Values = r.URL.Query()
+
+		page, ok := handler.pageFromQuery(w, r)
+		if !ok {
+			return
+		}
+
+		var data templateDataAdminStations = templateDataAdminStations{
+			Search:   strings.TrimSpace(query.Get("q")),
+			IsActive: query.Get("active"),
+			Page:     page,
+		}
+		var filter models.StationFilter = models.StationFilter{Search: data.Search}
+		switch data.IsActive {
+		case "":
+		case "true", "false":
+			var isActive bool = data.IsActive == "true"
+			filter.IsActive = &isActive
+		default:
+			handler.clientError(w, http.StatusBadRequest, "invalid active, must be true or false")
+			return
+		}
+
+		var err error
+		data.Stations, data.NumberOfStations, err = handler.Models.Station.GetStations(
+			r.Context(),
+			filter,
+			numberOfStationsPerPageAdmin,
+			(page-1)*numberOfStationsPerPageAdmin,
+		)
+		if err != nil {
+			handler.serverError(w, r, "error in fetching stations", err)
+			return
+		}
+
+		// query strings of this and the adjacent pages, with the filters
+		var queryPage = func(page int) string {
+			var values url.Values = url.Values{}
+			if data.Search != "" {
+				values.Set("q", data.Search)
+			}
+			if data.IsActive != "" {
+				values.Set("active", data.IsActive)
+			}
+			if page > 1 {
+				values.Set("page", strconv.Itoa(page))
+			}
+			return values.Encode()
+		}
+		data.Query = queryPage(page)
+		if page > 1 {
+			data.QueryPagePrevious = "?" + queryPage(page-1)
+		}
+		if page*numberOfStationsPerPageAdmin < data.NumberOfStations {
+			data.QueryPageNext = "?" + queryPage(page+1)
+		}
+
+		handler.render(w, r, http.StatusOK, "adminStations", data)
+	}
+}
+
+// activate or deactivate a station
+// form fields:
+//
+//	is_active  true or false
+//	query      query string of the stations page to go back to
+func (handler *Handler) AdminStationActive() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var localityID string = r.PathValue("locality_id")
+
+		isActive, err := strconv.ParseBool(r.PostFormValue("is_active"))
+		if err != nil {
+			handler.clientError(w, http.StatusBadRequest, "invalid is_active, must be true or false")
+			return
+		}
+
+		err = handler.Models.Station.SetStationActive(r.Context(), localityID, isActive)
+		if errors.Is(err, pgx.ErrNoRows) {
+			handler.clientError(w, http.StatusNotFound, "no station with locality ID "+localityID)
+			return
+		}
+		if err != nil {
+			handler.serverError(w, r, "error in updating station activity", err)
+			return
+		}
+		handler.logger(r).Info(
+			"station activity changed",
+			"admin",
+			middlewares.Admin(r),
+			"locality_id",
+			localityID,
+			"is_active",
+			isActive,
+		)
+
+		// back to the same page of the list, only with its own parameters
+		// so that the redirect cannot lead elsewhere
+		queryReturn, _ := url.ParseQuery(r.PostFormValue("query"))
+		var values url.Values = url.Values{}
+		for _, key := range []string{"q", "active", "page"} {
+			if queryReturn.Get(key) != "" {
+				values.Set(key, queryReturn.Get(key))
+			}
+		}
+		var URL string = "/admin/stations"
+		if len(values) > 0 {
+			URL += "?" + values.Encode()
+		}
+		http.Redirect(w, r, URL, http.StatusSeeOther)
+	}
+}
+
+// form with the metadata of a station
+func (handler *Handler) AdminStation() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		station, ok := handler.stationFromPath(w, r)
+		if !ok {
+			return
+		}
+
+		var form formStation = formStation{
+			CityName:     station.CityName,
+			LocalityName: station.LocalityName,
+			Latitude:     strconv.FormatFloat(station.Latitude, 'f', -1, 64),
+			Longitude:    strconv.FormatFloat(station.Longitude, 'f', -1, 64),
+			IsActive:     station.IsActive,
+		}
+		if station.Elevation != nil {
+			form.Elevation = strconv.FormatFloat(*station.Elevation, 'f', -1, 64)
+		}
+
+		handler.render(w, r, http.StatusOK, "adminStation", templateDataAdminStation{
+			Station:   station,
+			Form:      form,
+			IsUpdated: r.URL.Query().Get("updated") == "true",
+		})
+	}
+}
+
+// update the metadata of a station
+// form fields: city_name, locality_name, latitude, longitude, elevation
+// (empty if unknown) and is_active (checkbox)
+// the form is shown again with the errors if a field is invalid
+func (handler *Handler) AdminStationUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		station, ok := handler.stationFromPath(w, r)
+		if !ok {
+			return
+		}
+
+		var form formStation = formStation{
+			CityName:     strings.TrimSpace(r.PostFormValue("city_name")),
+			LocalityName: strings.TrimSpace(r.PostFormValue("locality_name")),
+			Latitude:     strings.TrimSpace(r.PostFormValue("latitude")),
+			Longitude:    strings.TrimSpace(r.PostFormValue("longitude")),
+			Elevation:    strings.TrimSpace(r.PostFormValue("elevation")),
+			IsActive:     r.PostFormValue("is_active") == "true",
+		}
+
+		// check the fields, the comparisons also refuse NaN
+		var mapErrors map[string]string = make(map[string]string)
+		if form.CityName == "" {
+			mapErrors["city_name"] = "required"
+		}
+		if form.LocalityName == "" {
+			mapErrors["locality_name"] = "required"
+		}
+		latitude, err := strconv.ParseFloat(form.Latitude, 64)
+		if err != nil || !(latitude >= -90 && latitude <= 90) {
+			mapErrors["latitude"] = "a number from -90 to 90"
+		}
+		longitude, err := strconv.ParseFloat(form.Longitude, 64)
+		if err != nil || !(longitude >= -180 && longitude <= 180) {
+			mapErrors["longitude"] = "a number from -180 to 180"
+		}
+		var elevation *float64
+		if form.Elevation != "" {
+			value, err := strconv.ParseFloat(form.Elevation, 64)
+			if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+				mapErrors["elevation"] = "a number of metres, or empty if unknown"
+			}
+			elevation = &value
+		}
+		if len(mapErrors) > 0 {
+			handler.render(w, r, http.StatusUnprocessableEntity, "adminStation", templateDataAdminStation{
+				Station: station,
+				Form:    form,
+				Errors:  mapErrors,
+			})
+			return
+		}
+
+		station.CityName = form.CityName
+		station.LocalityName = form.LocalityName
+		station.Latitude = latitude
+		station.Longitude = longitude
+		station.Elevation = elevation
+		station.IsActive = form.IsActive
+		err = handler.Models.Station.UpdateStation(r.Context(), station)
+		if errors.Is(err, pgx.ErrNoRows) {
+			handler.clientError(w, http.StatusNotFound, "no station with locality ID "+station.LocalityID)
+			return
+		}
+		if err != nil {
+			handler.serverError(w, r, "error in updating station", err)
+			return
+		}
+		handler.logger(r).Info(
+			"station updated",
+			"admin",
+			middlewares.Admin(r),
+			"locality_id",
+			station.LocalityID,
+		)
+
+		http.Redirect(
+			w,
+			r,
+			"/admin/stations/"+url.PathEscape(station.LocalityID)+"?updated=true",
+			http.StatusSeeOther,
+		)
+	}
+}
+
+// history of the runs with the outcomes of the providers
+// query parameters:
+//
+//	page  page of the history, from 1
+func (handler *Handler) AdminRuns() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var query url.Values = r.URL.Query()
+
+		page, ok := handler.pageFromQuery(w, r)
+		if !ok {
+			return
+		}
+
+		// one run more than the page, to know whether there is a next page
+		sliceRuns, err := handler.Models.Measurement.GetMeasurementRunSummaries(
+			r.Context(),
+			numberOfRunsPerPageAdmin+1,
+			(page-1)*numberOfRunsPerPageAdmin,
+		)
+		if err != nil {
+			handler.serverError(w, r, "error in fetching measurement runs", err)
+			return
+		}
+
+		var data templateDataAdminRuns = templateDataAdminRuns{
+			Page:                             page,
+			RunIDRecalculated:                query.Get("recalculated"),
+			NumberOfMeasurementsRecalculated: query.Get("measurements"),
+		}
+		if page > 1 {
+			data.PagePrevious = page - 1
+		}
+		if len(sliceRuns) > numberOfRunsPerPageAdmin {
+			sliceRuns = sliceRuns[:numberOfRunsPerPageAdmin]
+			data.PageNext = page + 1
+		}
+		for _, run := range sliceRuns {
+			data.Runs = append(data.Runs, templateDataAdminRun{
+				MeasurementRunSummary: run,
+				Time:                  formatTimeIST(run.TimeStamp),
+			})
+		}
+
+		handler.render(w, r, http.StatusOK, "adminRuns", data)
+	}
+}
+
+// queue the measurements of a run for calculation by the next cron run
+// form fields:
+//
+//	page  page of the history to go back to
+func (handler *Handler) AdminRunRecalculate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		runID, err := uuid.Parse(r.PathValue("run_id"))
+		if err != nil {
+			handler.clientError(w, http.StatusBadRequest, "invalid run ID")
+			return
+		}
+
+		numberOfMeasurements, err := handler.Models.Calculation.ResetCalculationsRun(
+			r.Context(),
+			runID,
+		)
+		if errors.Is(err, pgx.ErrNoRows) {
+			handler.clientError(w, http.StatusNotFound, "no run with ID "+runID.String())
+			return
+		}
+		if err != nil {
+			handler.serverError(w, r, "error in resetting calculations of run", err)
+			return
+		}
+		handler.logger(r).Info(
+			"run queued for recalculation",
+			"admin",
+			middlewares.Admin(r),
+			"run_id",
+			runID.String(),
+			"measurements",
+			numberOfMeasurements,
+		)
+
+		// the calculations of the run are gone until the cron calculates
+		// them again, the web servers clear their caches
+		err = handler.Models.Notification.NotifyMeasurementRun(r.Context(), runID)
+		if err != nil {
+			handler.logger(r).Error(
+				"error in notifying measurement run",
+				"run_id",
+				runID.String(),
+				"error",
+				err.Error(),
+			)
+		}
+
+		var values url.Values = url.Values{}
+		if page, err := strconv.Atoi(r.PostFormValue("page")); err == nil && page > 1 {
+			values.Set("page", strconv.Itoa(page))
+		}
+		values.Set("recalculated", runID.String())
+		values.Set("measurements", strconv.FormatInt(numberOfMeasurements, 10))
+		http.Redirect(w, r, "/admin/runs?"+values.Encode(), http.StatusSeeOther)
+	}
+}
+
+// station of the locality ID in the path
+// on failure an error response is written and false is returned
+func (handler *Handler) stationFromPath(
+	w http.ResponseWriter,
+	r *http.Request,
+) (models.StationDetails, bool) {
+	var localityID string = r.PathValue("locality_id")
+
+	station, err := handler.Models.Station.GetStationDetails(r.Context(), localityID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		handler.clientError(w, http.StatusNotFound, "no station with locality ID "+localityID)
+		return station, false
+	}
+	if err != nil {
+		handler.serverError(w, r, "error in fetching station details", err)
+		return station, false
+	}
+
+	return station, true
+}
+
+// page of a paginated admin table, 1 if there is no page parameter
+// on failure an error response is written and false is returned
+func (handler *Handler) pageFromQuery(
+	w http.ResponseWriter,
+	r *http.Request,
+) (int, bool) {
+	var pageQuery string = r.URL.Query().Get("page")
+	if pageQuery == "" {
+		return 1, true
+	}
+
+	page, err := strconv.Atoi(pageQuery)
+	if err != nil || page < 1 {
+		handler.clientError(w, http.StatusBadRequest, "invalid page")
+		return 0, false
+	}
+
+	return page, true
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/kelaaditya/zomato-weather-union/server/internal/models"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/testdb"
+)
+
+// response of a recalculation of the run in the path
+func serveRecalculate(handler *Handler, runID string) *httptest.ResponseRecorder {
+	var mux *http.ServeMux = http.NewServeMux()
+	mux.HandleFunc("POST /admin/runs/{run_id}/recalculate", handler.AdminRunRecalculate())
+
+	var request *http.Request = httptest.NewRequest(
+		http.MethodPost,
+		"/admin/runs/"+runID+"/recalculate",
+		strings.NewReader(url.Values{"page": {"2"}}.Encode()),
+	)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var recorder *httptest.ResponseRecorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// an invalid run ID is refused before the database
+func TestAdminRunRecalculateInvalidID(t *testing.T) {
+	var handler *Handler = &Handler{Logger: slog.New(slog.DiscardHandler)}
+	var recorder *httptest.ResponseRecorder = serveRecalculate(handler, "run")
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("status is %d, expected %d", recorder.Code, http.StatusBadRequest)
+	}
+}
+
+// a recalculation deletes the calculations of the run, flags its
+// measurements as unprocessed and then notifies the web servers of the run
+func TestAdminRunRecalculate(t *testing.T) {
+	var DB *pgxpool.Pool = testdb.New(t)
+	var ctx context.Context = context.Background()
+	runID, runIDPrevious := seedRuns(t, DB)
+
+	var handler *Handler = &Handler{
+		Logger: slog.New(slog.DiscardHandler),
+		Models: &models.Models{
+			Calculation:  &models.CalculationModel{DB: DB},
+			Notification: &models.NotificationModel{DB: DB},
+		},
+	}
+
+	// number of calculations of a run
+	var countCalculations = func(runID string) int {
+		var numberOfCalculations int
+		err := DB.QueryRow(
+			ctx,
+			`
+			SELECT COUNT(*)
+			FROM calculations_temperature ct
+			JOIN measurements_weather_union mwu
+			ON ct.measurement_id_weather_union = mwu.measurement_id
+			WHERE mwu.run_id = @runID;
+			`,
+			pgx.NamedArgs{"runID": runID},
+		).Scan(&numberOfCalculations)
+		if err != nil {
+			t.Errorf("error in counting calculations: %v", err)
+		}
+		return numberOfCalculations
+	}
+
+	// notifications of the runs, with the number of calculations the run
+	// has when it is notified
+	type notificationRun struct {
+		runID                string
+		numberOfCalculations int
+	}
+	var chanRunIDs chan notificationRun = make(chan notificationRun, 1)
+	var chanListening chan struct{} = make(chan struct{})
+	ctxListen, cancel := context.WithCancel(ctx)
+	var chanDone chan error = make(chan error, 1)
+	go func() {
+		chanDone <- handler.Models.Notification.ListenMeasurementRuns(
+			ctxListen,
+			func() { close(chanListening) },
+			func(runID string) { chanRunIDs <- notificationRun{runID, countCalculations(runID)} },
+		)
+	}()
+	defer func() {
+		cancel()
+		<-chanDone
+	}()
+	select {
+	case <-chanListening:
+	case err := <-chanDone:
+		t.Fatalf("error in listening to the runs: %v", err)
+	}
+
+	var recorder *httptest.ResponseRecorder = serveRecalculate(handler, runIDPrevious.String())
+	if recorder.Code != http.StatusSeeOther {
+		t.Fatalf("status is %d, expected %d: %s", recorder.Code, http.StatusSeeOther, recorder.Body)
+	}
+	location, err := url.Parse(recorder.Header().Get("Location"))
+	if err != nil || location.Path != "/admin/runs" {
+		t.Fatalf("redirect to %q, expected /admin/runs", recorder.Header().Get("Location"))
+	}
+	for key, value := range map[string]string{
+		"page":         "2",
+		"recalculated": runIDPrevious.String(),
+		"measurements": "9",
+	} {
+		if location.Query().Get(key) != value {
+			t.Errorf("%s of the redirect is %q, expected %q", key, location.Query().Get(key), value)
+		}
+	}
+
+	// the run is reset before it is notified
+	select {
+	case notification := <-chanRunIDs:
+		if notification.runID != runIDPrevious.String() {
+			t.Errorf("notified run is %s, expected %s", notification.runID, runIDPrevious)
+		}
+		if notification.numberOfCalculations != 0 {
+			t.Errorf("notified run has %d calculations, expected none", notification.numberOfCalculations)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run is not notified")
+	}
+
+	// calculations and unprocessed measurements of the runs
+	for _, testCase := range []struct {
+		runID                uuid.UUID
+		numberOfCalculations int
+		numberOfProcessed    int
+	}{
+		{runIDPrevious, 0, 0},
+		{runID, 9, 9},
+	} {
+		var numberOfCalculations, numberOfProcessed int
+		err = DB.QueryRow(
+			ctx,
+			`
+			SELECT
+				(
+					SELECT COUNT(*)
+					FROM calculations_temperature ct
+					JOIN measurements_weather_union mwu
+					ON ct.measurement_id_weather_union = mwu.measurement_id
+					WHERE mwu.run_id = @runID
+				),
+				(
+					SELECT COUNT(*)
+					FROM measurements_weather_union
+					WHERE run_id = @runID AND is_processed_for_calculation_temperature
+				);
+			`,
+			pgx.NamedArgs{"runID": testCase.runID},
+		).Scan(&numberOfCalculations, &numberOfProcessed)
+		if err != nil {
+			t.Fatalf("error in counting calculations: %v", err)
+		}
+		if numberOfCalculations != testCase.numberOfCalculations ||
+			numberOfProcessed != testCase.numberOfProcessed {
+			t.Errorf(
+				"run %s has %d calculations and %d processed measurements, expected %d and %d",
+				testCase.runID,
+				numberOfCalculations,
+				numberOfProcessed,
+				testCase.numberOfCalculations,
+				testCase.numberOfProcessed,
+			)
+		}
+	}
+
+	// an unknown run is not found and not notified
+	recorder = serveRecalculate(handler, uuid.New().String())
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("status of an unknown run is %d, expected %d", recorder.Code, http.StatusNotFound)
+	}
+	select {
+	case notification := <-chanRunIDs:
+		t.Errorf("unknown run notified as %s", notification.runID)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/cache"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/models"
+)
+
+// key of the latest run in the cache of the runs
+const keyRunLatest string = "latest"
+
+// number of runs whose calculations are kept in the cache, the latest
+// runs and the runs stepped through on the history page
+const numberOfRunsCached int = 32
+
+// calculations of a run with their JSON, marshalled once per run
+type calculationsRun struct {
+	Calculations []models.CalculationTemperatureWithStationDetails
+	JSON         []byte
+}
+
+// create the caches of the latest run and of the calculations of the runs
+// the entries expire after the TTL, in case a notification of a new run
+// is missed
+func (handler *Handler) InitializeCache(TTL time.Duration) {
+	handler.cacheRuns = cache.New[models.MeasurementRun](TTL, 1)
+	handler.cacheCalculations = cache.New[calculationsRun](TTL, numberOfRunsCached)
+}
+
+// empty the caches, when a run is saved
+func (handler *Handler) ClearCache() {
+	handler.cacheRuns.Clear()
+	handler.cacheCalculations.Clear()
+}
+
+// latest run with calculations, from the cache
+// the loads of the caches are shared by the requests, so they are not
+// canceled with the request that started them
+func (handler *Handler) runLatest(ctx context.Context) (models.MeasurementRun, error) {
+	return handler.cacheRuns.Get(
+		keyRunLatest,
+		func() (models.MeasurementRun, error) {
+			return handler.Models.Measurement.GetMeasurementRunLatest(
+				context.WithoutCancel(ctx),
+			)
+		},
+	)
+}
+
+// calculations of a run with station details, from the cache
+func (handler *Handler) calculationsOfRun(
+	ctx context.Context,
+	runID uuid.UUID,
+) (calculationsRun, error) {
+	return handler.cacheCalculations.Get(
+		runID.String(),
+		func() (calculationsRun, error) {
+			sliceCalculations, err :=
+				handler.
+					Models.
+					Calculation.GetCalculationsTemperatureWithStationDetailsFromRun(
+					context.WithoutCancel(ctx),
+					runID,
+				)
+			if err != nil {
+				return calculationsRun{}, err
+			}
+
+			// convert data to JSON
+			JSONBytes, err := json.Marshal(sliceCalculations)
+			if err != nil {
+				return calculationsRun{}, err
+			}
+
+			return calculationsRun{
+				Calculations: sliceCalculations,
+				JSON:         JSONBytes,
+			}, nil
+		},
+	)
+}
+
+// set the validators of a response derived from a run and write a 304 if
+// the client's copy is current
+// the ETag is the run ID with a hash of the path and query, which also
+// pick the data of the run, and the last modified time is the time of the
+// run. clients revalidate every time, as the latest run changes.
+func (handler *Handler) isNotModified(
+	w http.ResponseWriter,
+	r *http.Request,
+	run models.MeasurementRun,
+) bool {
+	var hashRequest hash.Hash32 = fnv.New32a()
+	hashRequest.Write([]byte(r.URL.Path + "?" + r.URL.RawQuery))
+	var ETag string = fmt.Sprintf(`"%s-%08x"`, run.RunID, hashRequest.Sum32())
+	var timeModified time.Time = run.TimeStamp.UTC().Truncate(time.Second)
+
+	w.Header().Set("ETag", ETag)
+	w.Header().Set("Last-Modified", timeModified.Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", "no-cache")
+
+	var isCurrent bool
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		// If-Modified-Since is ignored when If-None-Match is sent
+		for _, ETagClient := range strings.Split(ifNoneMatch, ",") {
+			ETagClient = strings.TrimPrefix(strings.TrimSpace(ETagClient), "W/")
+			if ETagClient == ETag || ETagClient == "*" {
+				isCurrent = true
+				break
+			}
+		}
+	} else if ifModifiedSince := r.Header.Get("If-Modified-Since"); ifModifiedSince != "" {
+		timeClient, err := http.ParseTime(ifModifiedSince)
+		isCurrent = err == nil && !timeModified.After(timeClient)
+	}
+	if !isCurrent {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dimensions of the server rendered SVG charts (in SVG user units)
+const (
+	chartWidth        float64 = 720
+	chartHeight       float64 = 220
+	chartMarginLeft   float64 = 44
+	chartMarginRight  float64 = 10
+	chartMarginTop    float64 = 10
+	chartMarginBottom float64 = 26
+)
+
+// indian standard time, used for the labels of the charts
+var locationIST *time.Location = time.FixedZone("IST", 5*60*60+30*60)
+
+// a single value of a chart series. nil values break the line.
+type chartPoint struct {
+	Time  time.Time
+	Value *float64
+}
+
+// a series of a chart, drawn as one polyline per unbroken segment
+type chartSeries struct {
+	Name      string
+	ClassName string
+	Points    []chartPoint
+}
+
+// data for rendering a chart as SVG in a template
+type templateDataChart struct {
+	Title      string
+	Width      float64
+	Height     float64
+	PlotLeft   float64
+	PlotRight  float64
+	PlotTop    float64
+	PlotBottom float64
+	Lines      []templateDataChartLine
+	TicksX     []templateDataChartTick
+	TicksY     []templateDataChartTick
+	IsEmpty    bool
+}
+
+// a polyline of a chart
+type templateDataChartLine struct {
+	Name      string
+	ClassName string
+	Points    string
+}
+
+// a tick and its label on an axis of a chart
+type templateDataChartTick struct {
+	Position float64
+	Label    string
+}
+
+// build a line chart of the series between two times.
+// a line is broken where a value is missing or where two consecutive
+// points are further apart than gapMaximum.
+func newChart(
+	title string,
+	timeFrom time.Time,
+	timeTo time.Time,
+	gapMaximum time.Duration,
+	tickEveryDays int,
+	series ...chartSeries,
+) templateDataChart {
+	var chart templateDataChart = templateDataChart{
+		Title:      title,
+		Width:      chartWidth,
+		Height:     chartHeight,
+		PlotLeft:   chartMarginLeft,
+		PlotRight:  chartWidth - chartMarginRight,
+		PlotTop:    chartMarginTop,
+		PlotBottom: chartHeight - chartMarginBottom,
+	}
+
+	// range of the values over all series
+	var valueMin float64 = math.Inf(1)
+	var valueMax float64 = math.Inf(-1)
+	for _, s := range series {
+		for _, point := range s.Points {
+			if point.Value == nil {
+				continue
+			}
+			valueMin = math.Min(valueMin, *point.Value)
+			valueMax = math.Max(valueMax, *point.Value)
+		}
+	}
+	if math.IsInf(valueMin, 1) {
+		chart.IsEmpty = true
+		return chart
+	}
+	// round the range outwards to whole degrees with at least one degree
+	valueMin = math.Floor(valueMin)
+	valueMax = math.Ceil(valueMax)
+	if valueMax-valueMin < 1 {
+		valueMax = valueMin + 1
+	}
+
+	// scales from time and value to SVG coordinates
+	var durationTotal float64 = float64(timeTo.Sub(timeFrom))
+	var scaleX = func(t time.Time) float64 {
+		return chart.PlotLeft +
+			float64(t.Sub(timeFrom))/durationTotal*(chart.PlotRight-chart.PlotLeft)
+	}
+	var scaleY = func(v float64) float64 {
+		return chart.PlotBottom -
+			(v-valueMin)/(valueMax-valueMin)*(chart.PlotBottom-chart.PlotTop)
+	}
+
+	// polylines
+	for _, s := range series {
+		var builder strings.Builder
+		var timePrevious time.Time
+		var flush = func() {
+			if builder.Len() > 0 {
+				chart.Lines = append(chart.Lines, templateDataChartLine{
+					Name:      s.Name,
+					ClassName: s.ClassName,
+					Points:    builder.String(),
+				})
+				builder.Reset()
+			}
+		}
+		for _, point := range s.Points {
+			if point.Value == nil {
+				flush()
+				continue
+			}
+			if builder.Len() > 0 && point.Time.Sub(timePrevious) > gapMaximum {
+				flush()
+			}
+			if builder.Len() > 0 {
+				builder.WriteByte(' ')
+			}
+			builder.WriteString(strconv.FormatFloat(scaleX(point.Time), 'f', 1, 64))
+			builder.WriteByte(',')
+			builder.WriteString(strconv.FormatFloat(scaleY(*point.Value), 'f', 1, 64))
+			timePrevious = point.Time
+		}
+		flush()
+	}
+
+	// ticks on the value axis, about four of them
+	var stepY float64 = math.Max(1, math.Ceil((valueMax-valueMin)/4))
+	for v := valueMin; v <= valueMax; v += stepY {
+		chart.TicksY = append(chart.TicksY, templateDataChartTick{
+			Position: roundToTenth(scaleY(v)),
+			Label:    strconv.FormatFloat(v, 'f', 0, 64) + "°C",
+		})
+	}
+
+	// ticks on the time axis at midnight (IST) every few days
+	var timeTick time.Time = timeFrom.In(locationIST)
+	timeTick = time.Date(
+		timeTick.Year(), timeTick.Month(), timeTick.Day()+1,
+		0, 0, 0, 0, locationIST,
+	)
+	for ; timeTick.Before(timeTo); timeTick = timeTick.AddDate(0, 0, tickEveryDays) {
+		chart.TicksX = append(chart.TicksX, templateDataChartTick{
+			Position: roundToTenth(scaleX(timeTick)),
+			Label:    timeTick.Format("02 Jan"),
+		})
+	}
+
+	return chart
+}
+
+// round a coordinate to a tenth of a unit to keep the SVG short
+func roundToTenth(value float64) float64 {
+	return math.Round(value*10) / 10
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/models"
+)
+
+// number of hottest localities per city on the overview and on the
+// page of a single city
+const (
+	numberOfLocalitiesHottestOverview int = 3
+	numberOfLocalitiesHottestCity     int = 10
+)
+
+// largest difference between the time of the comparison run and the
+// same time yesterday for the trend to be shown
+const maximumOffsetRunPrevious time.Duration = time.Hour
+
+// response of the cities APIs and data for the cities page templates
+type responseCities struct {
+	Run         models.MeasurementRun  `json:"run"`
+	RunPrevious *models.MeasurementRun `json:"run_previous"`
+	Thresholds  []float64              `json:"thresholds"`
+	Cities      []responseCity         `json:"cities"`
+}
+
+// statistics of a single city with its hottest localities
+type responseCity struct {
+	models.CityStatistics
+	TrendMedian       *float64              `json:"trend_median"`
+	LocalitiesHottest []models.CityLocality `json:"localities_hottest"`
+}
+
+// data for the cities and city page templates
+type templateDataCities struct {
+	responseCities
+	RunTime         string
+	RunPreviousTime string
+}
+
+// statistics of all cities in the requested run
+// query parameters:
+//
+//	run_id  ID of the run
+//	time    time (RFC 3339 or YYYY-MM-DD), the latest run at or before it
+//
+// the latest run is used if neither is given
+func (handler *Handler) CitiesAPI() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// get the requested run
+		run, ok := handler.runFromQuery(w, r)
+		if !ok {
+			return
+		}
+		// the client's copy is current
+		if handler.isNotModified(w, r, run) {
+			return
+		}
+
+		response, err := handler.cities(r.Context(), run, nil, numberOfLocalitiesHottestOverview)
+		if err != nil {
+			handler.serverError(w, r, "error in fetching city statistics", err)
+			return
+		}
+
+		handler.writeJSON(w, r, http.StatusOK, "application/json", response)
+	}
+}
+
+// statistics of a single city in the requested run
+// query parameters as for CitiesAPI
+func (handler *Handler) CityAPI() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var cityName string = r.PathValue("name")
+
+		// get the requested run
+		run, ok := handler.runFromQuery(w, r)
+		if !ok {
+			return
+		}
+		// the client's copy is current
+		if handler.isNotModified(w, r, run) {
+			return
+		}
+
+		response, err := handler.cities(r.Context(), run, &cityName, numberOfLocalitiesHottestCity)
+		if err != nil {
+			handler.serverError(w, r, "error in fetching city statistics", err)
+			return
+		}
+		if len(response.Cities) == 0 {
+			handler.clientError(w, http.StatusNotFound, "no calculations for city "+cityName)
+			return
+		}
+
+		handler.writeJSON(w, r, http.StatusOK, "application/json", response)
+	}
+}
+
+// overview page of all cities
+func (handler *Handler) Cities() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// get the requested run
+		run, ok := handler.runFromQuery(w, r)
+		if !ok {
+			return
+		}
+
+		response, err := handler.cities(r.Context(), run, nil, numberOfLocalitiesHottestOverview)
+		if err != nil {
+			handler.serverError(w, r, "error in fetching city statistics", err)
+			return
+		}
+
+		handler.render(w, r, http.StatusOK, "cities", newTemplateDataCities(response))
+	}
+}
+
+// page of a single city
+func (handler *Handler) City() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var cityName string = r.PathValue("name")
+
+		// get the requested run
+		run, ok := handler.runFromQuery(w, r)
+		if !ok {
+			return
+		}
+
+		response, err := handler.cities(r.Context(), run, &cityName, numberOfLocalitiesHottestCity)
+		if err != nil {
+			handler.serverError(w, r, "error in fetching city statistics", err)
+			return
+		}
+		if len(response.Cities) == 0 {
+			handler.clientError(w, http.StatusNotFound, "no calculations for city "+cityName)
+			return
+		}
+
+		handler.render(w, r, http.StatusOK, "city", newTemplateDataCities(response))
+	}
+}
+
+// statistics and hottest localities of the cities in a run, compared
+// with the run at the same time yesterday
+func (handler *Handler) cities(
+	ctx context.Context,
+	run models.MeasurementRun,
+	cityName *string,
+	limitPerCity int,
+) (responseCities, error) {
+	var response responseCities = responseCities{
+		Run:        run,
+		Thresholds: handler.ThresholdsWetBulb,
+		Cities:     []responseCity{},
+	}
+
+	// run at the same time yesterday, if there is one close enough
+	var timeYesterday time.Time = run.TimeStamp.Add(-24 * time.Hour)
+	runPrevious, err := handler.Models.Measurement.GetMeasurementRunAtTime(
+		ctx,
+		timeYesterday,
+	)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		// no runs that old
+	case err != nil:
+		return response, err
+	case timeYesterday.Sub(runPrevious.TimeStamp) <= maximumOffsetRunPrevious:
+		response.RunPrevious = &runPrevious
+	}
+	var runIDPrevious *uuid.UUID
+	if response.RunPrevious != nil {
+		runIDPrevious = &response.RunPrevious.RunID
+	}
+
+	// aggregate statistics
+	sliceStatistics, err := handler.Models.City.GetCityStatistics(
+		ctx,
+		run.RunID,
+		runIDPrevious,
+		handler.ThresholdsWetBulb,
+		cityName,
+	)
+	if err != nil {
+		return response, err
+	}
+
+	// hottest localities
+	sliceLocalities, err := handler.Models.City.GetCityLocalitiesHottest(
+		ctx,
+		run.RunID,
+		limitPerCity,
+		cityName,
+	)
+	if err != nil {
+		return response, err
+	}
+	var mapLocalities map[string][]models.CityLocality = make(map[string][]models.CityLocality)
+	for _, locality := range sliceLocalities {
+		mapLocalities[locality.CityName] = append(mapLocalities[locality.CityName], locality)
+	}
+
+	for _, statistics := range sliceStatistics {
+		var city responseCity = responseCity{
+			CityStatistics:    statistics,
+			LocalitiesHottest: mapLocalities[statistics.CityName],
+		}
+		// trend of the median against the run yesterday
+		if statistics.TemperatureWetBulbMedianPrevious != nil {
+			var trend float64 = statistics.TemperatureWetBulbMedian -
+				*statistics.TemperatureWetBulbMedianPrevious
+			city.TrendMedian = &trend
+		}
+		response.Cities = append(response.Cities, city)
+	}
+
+	return response, nil
+}
+
+// template data with the run times in indian standard time
+func newTemplateDataCities(response responseCities) templateDataCities {
+	var data templateDataCities = templateDataCities{
+		responseCities: response,
+		RunTime:        formatTimeIST(response.Run.TimeStamp),
+	}
+	if response.RunPrevious != nil {
+		data.RunPreviousTime = formatTimeIST(response.RunPrevious.TimeStamp)
+	}
+	return data
+}
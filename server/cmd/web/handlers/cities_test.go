@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/kelaaditya/zomato-weather-union/server/internal/models"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/testdb"
+)
+
+// the runs of the cities are checked before the database
+func TestCitiesInvalidQuery(t *testing.T) {
+	var handler *Handler = &Handler{Logger: slog.New(slog.DiscardHandler)}
+
+	var mux *http.ServeMux = http.NewServeMux()
+	mux.HandleFunc("GET /cities", handler.Cities())
+	mux.HandleFunc("GET /cities/{name}", handler.City())
+	mux.HandleFunc("GET /api/v1/cities", handler.CitiesAPI())
+	mux.HandleFunc("GET /api/v1/cities/{name}", handler.CityAPI())
+
+	for _, path := range []string{
+		"/cities",
+		"/cities/" + url.PathEscape(nameCityTest),
+		"/api/v1/cities",
+		"/api/v1/cities/" + url.PathEscape(nameCityTest),
+	} {
+		for _, query := range []string{"run_id=run", "time=yesterday", "time=2025-02-30"} {
+			t.Run(path+"?"+query, func(t *testing.T) {
+				var recorder *httptest.ResponseRecorder = httptest.NewRecorder()
+				mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path+"?"+query, nil))
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("status is %d, expected %d: %s", recorder.Code, http.StatusBadRequest, recorder.Body)
+				}
+			})
+		}
+	}
+}
+
+// save a run at the time with copies of the measurements and calculations
+// of another run, and return its ID
+func seedRunCopy(t *testing.T, DB *pgxpool.Pool, runIDSource uuid.UUID, timeStamp time.Time) uuid.UUID {
+	t.Helper()
+
+	var runID uuid.UUID = uuid.New()
+	var queryArguments pgx.NamedArgs = pgx.NamedArgs{
+		"runID":       runID,
+		"runIDSource": runIDSource,
+		"timeStamp":   timeStamp,
+	}
+	// the copies of the measurements get IDs derived from the new run, by
+	// which their calculations find them
+	for _, queryString := range []string{
+		"INSERT INTO measurement_runs (run_id, time_stamp) VALUES (@runID, @timeStamp);",
+		`
+		INSERT INTO measurements_weather_union (
+			measurement_id,
+			weather_station_id,
+			run_id,
+			temperature,
+			humidity,
+			is_processed_for_calculation_temperature,
+			is_successful_for_calculation_temperature,
+			time_stamp,
+			time_stamp_run
+		)
+		SELECT
+			md5(measurement_id::TEXT || @runID::TEXT)::UUID,
+			weather_station_id,
+			@runID,
+			temperature,
+			humidity,
+			is_processed_for_calculation_temperature,
+			is_successful_for_calculation_temperature,
+			@timeStamp,
+			@timeStamp
+		FROM measurements_weather_union
+		WHERE run_id = @runIDSource;
+		`,
+		`
+		INSERT INTO calculations_temperature (
+			calculation_id,
+			measurement_id_weather_union,
+			method,
+			temperature_dew_point,
+			temperature_wet_bulb,
+			time_stamp,
+			time_stamp_run
+		)
+		SELECT
+			gen_random_uuid(),
+			md5(ct.measurement_id_weather_union::TEXT || @runID::TEXT)::UUID,
+			ct.method,
+			ct.temperature_dew_point,
+			ct.temperature_wet_bulb,
+			@timeStamp,
+			@timeStamp
+		FROM calculations_temperature ct
+		JOIN measurements_weather_union mwu
+		ON ct.measurement_id_weather_union = mwu.measurement_id
+		WHERE mwu.run_id = @runIDSource;
+		`,
+	} {
+		_, err := DB.Exec(context.Background(), queryString, queryArguments)
+		if err != nil {
+			t.Fatalf("error in copying run: %v", err)
+		}
+	}
+	return runID
+}
+
+// the trend of a city is against the run at the same time yesterday, and
+// only if that run is at most maximumOffsetRunPrevious earlier
+func TestCitiesTrend(t *testing.T) {
+	var DB *pgxpool.Pool = testdb.New(t)
+	var ctx context.Context = context.Background()
+	// the wet bulb temperatures of the latest run are 1° above those of the
+	// run a day before
+	runID, runIDPrevious := seedRuns(t, DB)
+
+	var timeRun time.Time
+	err := DB.QueryRow(
+		ctx,
+		"SELECT time_stamp FROM measurement_runs WHERE run_id = @runID;",
+		pgx.NamedArgs{"runID": runID},
+	).Scan(&timeRun)
+	if err != nil {
+		t.Fatalf("error in reading the time of the run: %v", err)
+	}
+
+	// copies of the latest run whose previous run is the run a day before
+	// the latest, at the largest offset and just past it
+	var runIDOffsetMaximum uuid.UUID = seedRunCopy(t, DB, runID, timeRun.Add(maximumOffsetRunPrevious))
+	var runIDOffsetPast uuid.UUID = seedRunCopy(
+		t,
+		DB,
+		runID,
+		timeRun.Add(maximumOffsetRunPrevious+time.Minute),
+	)
+
+	var handler *Handler = &Handler{
+		Logger:            slog.New(slog.DiscardHandler),
+		ThresholdsWetBulb: []float64{28, 31, 35},
+		Models: &models.Models{
+			Measurement: &models.MeasurementModel{DB: DB},
+			City:        &models.CityModel{DB: DB},
+		},
+	}
+	var mux *http.ServeMux = http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/cities/{name}", handler.CityAPI())
+
+	var sliceTests = []struct {
+		name          string
+		runID         uuid.UUID
+		isTrendShown  bool
+		trendExpected float64
+	}{
+		{"a day after the previous run", runID, true, 1},
+		{"at the largest offset", runIDOffsetMaximum, true, 1},
+		{"past the largest offset", runIDOffsetPast, false, 0},
+		{"no earlier run", runIDPrevious, false, 0},
+	}
+	for _, test := range sliceTests {
+		t.Run(test.name, func(t *testing.T) {
+			var recorder *httptest.ResponseRecorder = httptest.NewRecorder()
+			mux.ServeHTTP(recorder, httptest.NewRequest(
+				http.MethodGet,
+				"/api/v1/cities/"+url.PathEscape(nameCityTest)+"?run_id="+test.runID.String(),
+				nil,
+			))
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("status is %d, expected %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+			}
+
+			var response responseCities
+			err := json.Unmarshal(recorder.Body.Bytes(), &response)
+			if err != nil {
+				t.Fatalf("error in decoding the response: %v", err)
+			}
+			if len(response.Cities) != 1 {
+				t.Fatalf("%d cities, expected 1", len(response.Cities))
+			}
+			var trend *float64 = response.Cities[0].TrendMedian
+
+			if !test.isTrendShown {
+				if response.RunPrevious != nil || trend != nil {
+					t.Errorf("trend against %v is shown", response.RunPrevious)
+				}
+				return
+			}
+			if response.RunPrevious == nil || response.RunPrevious.RunID != runIDPrevious {
+				t.Errorf("previous run is %v, expected %s", response.RunPrevious, runIDPrevious)
+			}
+			if trend == nil || math.Abs(*trend-test.trendExpected) > 1e-6 {
+				t.Errorf("trend is %v, expected %v", trend, test.trendExpected)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kelaaditya/zomato-weather-union/server/internal/geo"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/models"
+)
+
+// GeoJSON contours (zones above wet bulb temperature levels) of a run
+// query parameters:
+//
+//	run_id        run to contour, latest run if empty
+//	time          contour the latest run at or before this time instead
+//	levels        comma separated wet bulb levels in celsius
+//	cell_size_km  size of the interpolation grid cell (0.1 to 5), the grid
+//	              of a city is refused with a 400 if it is too large
+func (handler *Handler) ContoursWetBulb() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var query = r.URL.Query()
+
+		// contour options with defaults, the levels default to the
+		// configured risk thresholds
+		var options models.ContourOptions = models.ContourOptions{
+			Levels:                handler.ThresholdsWetBulb,
+			CellSizeKilometres:    0.5,
+			MaxDistanceKilometres: 5,
+		}
+
+		// wet bulb levels
+		if levelsString := query.Get("levels"); levelsString != "" {
+			var sliceLevels []float64
+			for _, levelString := range strings.Split(levelsString, ",") {
+				level, err := strconv.ParseFloat(strings.TrimSpace(levelString), 64)
+				if err != nil {
+					handler.clientError(w, http.StatusBadRequest, "invalid level: "+levelString)
+					return
+				}
+				sliceLevels = append(sliceLevels, level)
+			}
+			if len(sliceLevels) > 10 {
+				handler.clientError(w, http.StatusBadRequest, "at most 10 levels are allowed")
+				return
+			}
+			options.Levels = sliceLevels
+		}
+
+		// grid cell size
+		if cellSizeString := query.Get("cell_size_km"); cellSizeString != "" {
+			cellSize, err := strconv.ParseFloat(cellSizeString, 64)
+			if err != nil || cellSize < 0.1 || cellSize > 5 {
+				handler.clientError(w, http.StatusBadRequest, "cell_size_km must be between 0.1 and 5")
+				return
+			}
+			options.CellSizeKilometres = cellSize
+		}
+
+		// get the calculations of the requested or latest run
+		run, ok := handler.runFromQuery(w, r)
+		if !ok {
+			return
+		}
+		// the client's copy is current
+		if handler.isNotModified(w, r, run) {
+			return
+		}
+		calculations, err := handler.calculationsOfRun(r.Context(), run.RunID)
+		if err != nil {
+			handler.serverError(w, r, "error in fetching calculations with station data", err)
+			return
+		}
+		// if no data fetched
+		if len(calculations.Calculations) == 0 {
+			handler.clientError(w, http.StatusNotFound, "no calculations found for the run")
+			return
+		}
+
+		// interpolate, contour and clip
+		featureCollection, err := handler.Models.Contour.CreateContoursWetBulb(
+			r.Context(),
+			calculations.Calculations,
+			options,
+		)
+		switch {
+		case errors.Is(err, geo.ErrGridTooLarge):
+			handler.clientError(w, http.StatusBadRequest, "the grid is too large, increase cell_size_km")
+			return
+		case r.Context().Err() != nil:
+			// the client is gone, nobody reads the response
+			return
+		case err != nil:
+			handler.serverError(w, r, "error in creating wet bulb contours", err)
+			return
+		}
+
+		handler.writeJSON(
+			w,
+			r,
+			http.StatusOK,
+			"application/geo+json",
+			featureCollection,
+		)
+	}
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kelaaditya/zomato-weather-union/server/internal/datasets"
+	"github.com/yuin/goldmark"
+)
+
+// name of the dataset description file in the downloads directory
+const fileNameDownloadsReadme string = "README.md"
+
+// order, titles and descriptions of the datasets on the downloads page
+var sliceDownloadsDatasets = []struct {
+	Name        string
+	Title       string
+	Description string
+}{
+	{
+		Name:        datasets.DatasetTemperatureWetBulb,
+		Title:       "Wet Bulb Temperature",
+		Description: "All wet bulb temperature calculations from the start of recording until the time stamp in the file name.",
+	},
+	{
+		Name:        datasets.DatasetTemperatureWetBulbDaily,
+		Title:       "Wet Bulb Temperature (Daily)",
+		Description: "The wet bulb temperature calculations of a single day (UTC), for incremental downloads.",
+	},
+	{
+		Name:        datasets.DatasetMeasurementHistory,
+		Title:       "Measurement History",
+		Description: "All measurements from Weather Union and OpenWeatherMap with their calculations, one Parquet file per month (UTC).",
+	},
+}
+
+// data for the downloads page template
+type templateDataDownloads struct {
+	Datasets    []templateDataDataset
+	SchemaHTML  template.HTML
+	TimeUpdated string
+}
+
+// a dataset and its files on the downloads page
+type templateDataDataset struct {
+	Name        string
+	Title       string
+	Description string
+	LatestURL   string
+	Files       []templateDataFile
+}
+
+// a single file on the downloads page
+type templateDataFile struct {
+	Name        string
+	URL         string
+	ChecksumURL string
+	Format      string
+	Size        string
+	RowCount    string
+	DateRange   string
+	SHA256      string
+}
+
+// catalogue of the downloadable datasets
+func (handler *Handler) Downloads() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// files available for download
+		manifest, err := datasets.ReadManifest(handler.Exporter.Directory)
+		if err != nil {
+			handler.serverError(w, r, "error in reading the downloads manifest", err)
+			return
+		}
+
+		var data templateDataDownloads
+		if !manifest.TimeUpdated.IsZero() {
+			data.TimeUpdated = manifest.TimeUpdated.Format("2006-01-02 15:04 MST")
+		}
+
+		// group the files by dataset, newest first
+		for _, datasetDetails := range sliceDownloadsDatasets {
+			dataset := templateDataDataset{
+				Name:        datasetDetails.Name,
+				Title:       datasetDetails.Title,
+				Description: datasetDetails.Description,
+				LatestURL:   "/downloads/latest/" + datasetDetails.Name,
+			}
+			for i := len(manifest.Files) - 1; i >= 0; i-- {
+				var file datasets.ManifestFile = manifest.Files[i]
+				if file.Dataset != datasetDetails.Name {
+					continue
+				}
+				dataset.Files = append(dataset.Files, templateDataFile{
+					Name:        file.Name,
+					URL:         "/downloads/" + file.Name,
+					ChecksumURL: "/downloads/" + file.Name + ".sha256",
+					Format:      file.Format,
+					Size:        formatSize(file.SizeBytes),
+					RowCount:    strconv.FormatInt(file.RowCount, 10),
+					DateRange:   formatDateRange(file.TimeFrom, file.TimeTo),
+					SHA256:      file.SHA256,
+				})
+			}
+			data.Datasets = append(data.Datasets, dataset)
+		}
+
+		// render the dataset descriptions (schema) from the README
+		readmeBytes, err := os.ReadFile(
+			filepath.Join(handler.Exporter.Directory, fileNameDownloadsReadme),
+		)
+		if err != nil && !os.IsNotExist(err) {
+			handler.serverError(w, r, "error in reading the downloads readme", err)
+			return
+		}
+		if len(readmeBytes) > 0 {
+			schemaBuffer := new(bytes.Buffer)
+			err = goldmark.Convert(readmeBytes, schemaBuffer)
+			if err != nil {
+				handler.serverError(w, r, "error in converting the downloads readme", err)
+				return
+			}
+			// the readme is part of the repository and trusted
+			data.SchemaHTML = template.HTML(schemaBuffer.String())
+		}
+
+		handler.render(w, r, http.StatusOK, "downloads", data)
+	}
+}
+
+// serve a single file from the downloads directory
+// only files in the manifest, their checksums, the manifest itself and the
+// readme are served. http.ServeContent handles range requests and
+// conditional requests.
+func (handler *Handler) DownloadsFile() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var name string = r.PathValue("file")
+
+		manifest, err := datasets.ReadManifest(handler.Exporter.Directory)
+		if err != nil {
+			handler.serverError(w, r, "error in reading the downloads manifest", err)
+			return
+		}
+
+		// cache policy of the file
+		switch {
+		case name == datasets.FileNameManifest || name == fileNameDownloadsReadme:
+			// always revalidate
+			w.Header().Set("Cache-Control", "no-cache")
+		default:
+			file, ok := manifest.Find(strings.TrimSuffix(name, ".sha256"))
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			if file.IsImmutable() {
+				w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+			} else {
+				// files of the current day or month are still rewritten
+				w.Header().Set("Cache-Control", "public, max-age=300")
+			}
+			if !strings.HasSuffix(name, ".sha256") {
+				w.Header().Set("ETag", `"`+file.SHA256+`"`)
+			}
+			if file.Format == "parquet" && !strings.HasSuffix(name, ".sha256") {
+				w.Header().Set("Content-Type", "application/vnd.apache.parquet")
+			}
+		}
+
+		fileOnDisk, err := os.Open(filepath.Join(handler.Exporter.Directory, name))
+		if os.IsNotExist(err) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			handler.serverError(w, r, "error in opening the download file", err)
+			return
+		}
+		defer fileOnDisk.Close()
+
+		fileInfo, err := fileOnDisk.Stat()
+		if err != nil {
+			handler.serverError(w, r, "error in reading the download file", err)
+			return
+		}
+
+		// large files take longer than the server write timeout
+		responseController := http.NewResponseController(w)
+		err = responseController.SetWriteDeadline(time.Now().Add(10 * time.Minute))
+		// writers without deadline support keep the server timeout
+		if err != nil && !errors.Is(err, http.ErrNotSupported) {
+			handler.serverError(w, r, "error in extending the write deadline", err)
+			return
+		}
+
+		http.ServeContent(w, r, name, fileInfo.ModTime(), fileOnDisk)
+	}
+}
+
+// stable URL redirecting to the newest file of a dataset
+func (handler *Handler) DownloadsLatest() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		manifest, err := datasets.ReadManifest(handler.Exporter.Directory)
+		if err != nil {
+			handler.serverError(w, r, "error in reading the downloads manifest", err)
+			return
+		}
+
+		file, ok := manifest.Latest(r.PathValue("dataset"))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		// the target changes with every export
+		w.Header().Set("Cache-Control", "no-cache")
+		http.Redirect(w, r, "/downloads/"+file.Name, http.StatusFound)
+	}
+}
+
+// human readable file size
+func formatSize(sizeBytes int64) string {
+	var units []string = []string{"B", "KB", "MB", "GB", "TB"}
+	var size float64 = float64(sizeBytes)
+	var i int
+	for size >= 1024 && i < len(units)-1 {
+		size /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d %s", sizeBytes, units[i])
+	}
+	return fmt.Sprintf("%.1f %s", size, units[i])
+}
+
+// human readable time range of a file (UTC)
+func formatDateRange(timeFrom *time.Time, timeTo time.Time) string {
+	const layout string = "2006-01-02 15:04"
+	if timeFrom == nil {
+		return "until " + timeTo.UTC().Format(layout) + " UTC"
+	}
+	return timeFrom.UTC().Format(layout) + " to " + timeTo.UTC().Format(layout) + " UTC"
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kelaaditya/zomato-weather-union/server/internal/datasets"
+	"github.com/kelaaditya/zomato-weather-union/server/ui"
+)
+
+// name of the file of the manifest of the tests
+const nameFileDownloadTest string = "temperature-wet-bulb-20240502T000000Z.csv.gz"
+
+// handler of the downloads over a directory with a file in the manifest
+// and a stray file that is not
+func newHandlerDownloads(t *testing.T) *Handler {
+	t.Helper()
+
+	var directory string = t.TempDir()
+	var mapFiles map[string]string = map[string]string{
+		nameFileDownloadTest:             "0123456789",
+		nameFileDownloadTest + ".sha256": "checksum\n",
+		"stray.csv":                      "stray",
+		fileNameDownloadsReadme:          "# Datasets\n",
+	}
+	for name, content := range mapFiles {
+		err := os.WriteFile(filepath.Join(directory, name), []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var timeTo time.Time = time.Date(2024, time.May, 2, 0, 0, 0, 0, time.UTC)
+	data, err := json.Marshal(datasets.Manifest{
+		TimeUpdated: timeTo,
+		Files: []datasets.ManifestFile{{
+			Name:        nameFileDownloadTest,
+			Dataset:     datasets.DatasetTemperatureWetBulb,
+			Format:      "csv",
+			SizeBytes:   10,
+			RowCount:    2,
+			SHA256:      "checksum",
+			TimeTo:      timeTo,
+			TimeCreated: timeTo,
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(directory, datasets.FileNameManifest), data, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	templateCache, err := ui.CreateHTMLTemplateCache(ui.Files)
+	if err != nil {
+		t.Fatalf("error in parsing the templates: %v", err)
+	}
+	return &Handler{
+		Logger:        slog.New(slog.DiscardHandler),
+		TemplateCache: templateCache,
+		Exporter:      &datasets.Exporter{Directory: directory},
+	}
+}
+
+// only the files of the manifest, their checksums, the manifest and the
+// readme are served, and the latest links redirect to the newest file
+func TestDownloads(t *testing.T) {
+	var handler *Handler = newHandlerDownloads(t)
+
+	var mux *http.ServeMux = http.NewServeMux()
+	mux.HandleFunc("GET /downloads/{$}", handler.Downloads())
+	mux.HandleFunc("GET /downloads/{file}", handler.DownloadsFile())
+	mux.HandleFunc("GET /downloads/latest/{dataset}", handler.DownloadsLatest())
+
+	var sliceTests = []struct {
+		name   string
+		target string
+		// range of the request, none if empty
+		headerRange string
+		status      int
+		// part of the body or the location of the redirect
+		part string
+	}{
+		{"catalogue", "/downloads/", "", http.StatusOK, nameFileDownloadTest},
+		{"file", "/downloads/" + nameFileDownloadTest, "", http.StatusOK, "0123456789"},
+		{"range of a file", "/downloads/" + nameFileDownloadTest, "bytes=2-4", http.StatusPartialContent, "234"},
+		{"checksum", "/downloads/" + nameFileDownloadTest + ".sha256", "", http.StatusOK, "checksum"},
+		{"manifest", "/downloads/" + datasets.FileNameManifest, "", http.StatusOK, nameFileDownloadTest},
+		{"readme", "/downloads/" + fileNameDownloadsReadme, "", http.StatusOK, "# Datasets"},
+		{"stray file", "/downloads/stray.csv", "", http.StatusNotFound, ""},
+		{"unknown file", "/downloads/temperature-wet-bulb-20240503T000000Z.csv.gz", "", http.StatusNotFound, ""},
+		{"latest", "/downloads/latest/" + datasets.DatasetTemperatureWetBulb, "", http.StatusFound, "/downloads/" + nameFileDownloadTest},
+		{"latest of a dataset without files", "/downloads/latest/" + datasets.DatasetMeasurementHistory, "", http.StatusNotFound, ""},
+		{"latest of an unknown dataset", "/downloads/latest/weather", "", http.StatusNotFound, ""},
+	}
+	for _, test := range sliceTests {
+		t.Run(test.name, func(t *testing.T) {
+			var request *http.Request = httptest.NewRequest(http.MethodGet, test.target, nil)
+			if test.headerRange != "" {
+				request.Header.Set("Range", test.headerRange)
+			}
+			var recorder *httptest.ResponseRecorder = httptest.NewRecorder()
+			mux.ServeHTTP(recorder, request)
+
+			if recorder.Code != test.status {
+				t.Fatalf("status is %d, expected %d: %s", recorder.Code, test.status, recorder.Body)
+			}
+			switch {
+			case test.status == http.StatusFound:
+				if recorder.Header().Get("Location") != test.part {
+					t.Errorf("redirect to %q, expected %q", recorder.Header().Get("Location"), test.part)
+				}
+			case !strings.Contains(recorder.Body.String(), test.part):
+				t.Errorf("body does not contain %q: %s", test.part, recorder.Body)
+			}
+		})
+	}
+}
+
+// the files of the manifest are cached by their ETag, and the files that
+// change are revalidated
+func TestDownloadsFileCache(t *testing.T) {
+	var handler *Handler = newHandlerDownloads(t)
+
+	var mux *http.ServeMux = http.NewServeMux()
+	mux.HandleFunc("GET /downloads/{file}", handler.DownloadsFile())
+
+	var recorder *httptest.ResponseRecorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/downloads/"+nameFileDownloadTest, nil))
+	if recorder.Header().Get("Cache-Control") != "public, max-age=31536000, immutable" {
+		t.Errorf("Cache-Control is %q, expected an immutable file", recorder.Header().Get("Cache-Control"))
+	}
+	var etag string = recorder.Header().Get("ETag")
+	if etag != `"checksum"` {
+		t.Errorf("ETag is %q, expected the checksum", etag)
+	}
+
+	var request *http.Request = httptest.NewRequest(http.MethodGet, "/downloads/"+nameFileDownloadTest, nil)
+	request.Header.Set("If-None-Match", etag)
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusNotModified {
+		t.Errorf("status with the ETag is %d, expected %d", recorder.Code, http.StatusNotModified)
+	}
+
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/downloads/"+datasets.FileNameManifest, nil))
+	if recorder.Header().Get("Cache-Control") != "no-cache" {
+		t.Errorf("Cache-Control of the manifest is %q, expected no-cache", recorder.Header().Get("Cache-Control"))
+	}
+}
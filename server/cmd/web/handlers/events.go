@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kelaaditya/zomato-weather-union/server/cmd/web/middlewares"
+)
+
+// name of the event sent when the calculations of a run are saved
+const nameEventRun string = "run"
+
+// time the browsers wait before reconnecting, sent to them at the start
+// of every stream
+const intervalRetryEvents time.Duration = 5 * time.Second
+
+// number of events a slow client may fall behind before events are
+// dropped for it
+const sizeBufferEvents int = 4
+
+// errors of a refused subscription
+var (
+	errEventsConnectionsMax      = errors.New("too many event stream connections")
+	errEventsConnectionsPerIPMax = errors.New("too many event stream connections from the client")
+)
+
+// server-sent event
+type event struct {
+	// the run ID, sent back by the browser in Last-Event-ID
+	ID   string
+	Name string
+	Data []byte
+}
+
+// subscribers of the server-sent events
+type brokerEvents struct {
+	NumberOfConnectionsMax      int
+	NumberOfConnectionsPerIPMax int
+	HeartbeatInterval           time.Duration
+
+	mutex       sync.Mutex
+	subscribers map[chan event]struct{}
+	// open connections by client IP
+	mapConnectionsIP map[string]int
+	// closed when the server starts to shut down, which ends the streams
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+// create the broker of the server-sent events of /events
+// connections above the total or per client IP maximum are refused
+func (handler *Handler) InitializeEvents(
+	numberOfConnectionsMax int,
+	numberOfConnectionsPerIPMax int,
+	heartbeatInterval time.Duration,
+) {
+	handler.events = &brokerEvents{
+		NumberOfConnectionsMax:      numberOfConnectionsMax,
+		NumberOfConnectionsPerIPMax: numberOfConnectionsPerIPMax,
+		HeartbeatInterval:           heartbeatInterval,
+		subscribers:                 make(map[chan event]struct{}),
+		mapConnectionsIP:            make(map[string]int),
+		done:                        make(chan struct{}),
+	}
+}
+
+// send a run event with the summary of the latest run to every client
+// called when the cron notifies that a run is saved, after the cache is
+// cleared
+func (handler *Handler) PublishRunLatest(ctx context.Context) error {
+	eventRun, err := handler.eventRunLatest(ctx)
+	if err != nil {
+		return err
+	}
+
+	handler.events.mutex.Lock()
+	defer handler.events.mutex.Unlock()
+
+	for channel := range handler.events.subscribers {
+		select {
+		case channel <- eventRun:
+		default:
+			// the client is too slow, it catches up with the next run
+		}
+	}
+	return nil
+}
+
+// stream of server-sent events
+// a "run" event with the summary of the run is sent every time the cron
+// saves the calculations of a run, and a comment every heartbeat interval
+// to keep the connection open through proxies. a client that reconnects
+// with a Last-Event-ID header (or last_event_id query parameter) of an
+// older run is sent the latest run at once.
+func (handler *Handler) Events() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var controller *http.ResponseController = http.NewResponseController(w)
+
+		// the stream outlives the write timeout of the server
+		err := controller.SetWriteDeadline(time.Time{})
+		if err != nil {
+			handler.serverError(w, r, "error in removing the write deadline of events", err)
+			return
+		}
+
+		// client IP, behind the trusted proxies
+		var IP string = middlewares.ClientIP(r)
+
+		// subscribe to the events, within the connection limits
+		// the client is over its own limit, or the server over the total
+		channel, err := handler.events.subscribe(IP)
+		if err != nil {
+			var status int = http.StatusServiceUnavailable
+			if errors.Is(err, errEventsConnectionsPerIPMax) {
+				status = http.StatusTooManyRequests
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(60))
+			handler.clientError(w, status, err.Error())
+			return
+		}
+		defer handler.events.unsubscribe(channel, IP)
+
+		// the latest run, if the client has not seen it
+		eventRun, err := handler.eventRunLatest(r.Context())
+		if err != nil {
+			handler.serverError(w, r, "error in fetching the latest measurement run", err)
+			return
+		}
+		var lastEventID string = r.Header.Get("Last-Event-ID")
+		if lastEventID == "" {
+			// EventSource cannot set the header on the first connection
+			lastEventID = r.URL.Query().Get("last_event_id")
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-store")
+		// nginx buffers the responses otherwise
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		_, err = fmt.Fprintf(w, "retry: %d\n\n", intervalRetryEvents.Milliseconds())
+		if err == nil && lastEventID != eventRun.ID {
+			err = writeEvent(w, eventRun)
+		}
+		if err == nil {
+			err = controller.Flush()
+		}
+
+		var ticker *time.Ticker = time.NewTicker(handler.events.HeartbeatInterval)
+		defer ticker.Stop()
+
+		for err == nil {
+			select {
+			case <-r.Context().Done():
+				// the client left
+				return
+			case <-handler.events.done:
+				// the server is shutting down, the client reconnects to
+				// another instance
+				return
+			case eventReceived := <-channel:
+				err = writeEvent(w, eventReceived)
+			case <-ticker.C:
+				_, err = fmt.Fprint(w, ": heartbeat\n\n")
+			}
+			if err == nil {
+				err = controller.Flush()
+			}
+		}
+
+		// headers are already sent, only log
+		handler.logger(r).Debug(
+			"error in writing event stream",
+			"method",
+			r.Method,
+			"uri",
+			r.RequestURI,
+			"error",
+			err.Error(),
+		)
+	}
+}
+
+// end the event streams, when the server starts to shut down
+func (handler *Handler) closeEvents() {
+	if handler.events == nil {
+		return
+	}
+	handler.events.doneOnce.Do(func() {
+		close(handler.events.done)
+	})
+}
+
+// run event with the summary of the latest run (the number of
+// calculations and the highest wet bulb temperature)
+func (handler *Handler) eventRunLatest(ctx context.Context) (event, error) {
+	run, err := handler.runLatest(ctx)
+	if err != nil {
+		return event{}, err
+	}
+
+	// convert data to JSON
+	JSONBytes, err := json.Marshal(run)
+	if err != nil {
+		return event{}, fmt.Errorf("error in converting run to JSON: %w", err)
+	}
+
+	return event{
+		ID:   run.RunID.String(),
+		Name: nameEventRun,
+		Data: JSONBytes,
+	}, nil
+}
+
+// register a subscriber from the IP, an error if there are too many
+// connections
+func (broker *brokerEvents) subscribe(IP string) (chan event, error) {
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+
+	if len(broker.subscribers) >= broker.NumberOfConnectionsMax {
+		return nil, errEventsConnectionsMax
+	}
+	if broker.mapConnectionsIP[IP] >= broker.NumberOfConnectionsPerIPMax {
+		return nil, errEventsConnectionsPerIPMax
+	}
+
+	var channel chan event = make(chan event, sizeBufferEvents)
+	broker.subscribers[channel] = struct{}{}
+	broker.mapConnectionsIP[IP]++
+	return channel, nil
+}
+
+// remove a subscriber
+func (broker *brokerEvents) unsubscribe(channel chan event, IP string) {
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+
+	delete(broker.subscribers, channel)
+	broker.mapConnectionsIP[IP]--
+	if broker.mapConnectionsIP[IP] <= 0 {
+		delete(broker.mapConnectionsIP, IP)
+	}
+}
+
+// write an event in the text/event-stream format
+// the data is JSON on a single line
+func writeEvent(w http.ResponseWriter, eventToWrite event) error {
+	_, err := fmt.Fprintf(
+		w,
+		"id: %s\nevent: %s\ndata: %s\n\n",
+		eventToWrite.ID,
+		eventToWrite.Name,
+		eventToWrite.Data,
+	)
+	return err
+}
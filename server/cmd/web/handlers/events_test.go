@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/kelaaditya/zomato-weather-union/server/internal/models"
+)
+
+// handler of the events with the latest run in the cache, so that the
+// database is not needed
+func newHandlerEvents(
+	run models.MeasurementRun,
+	numberOfConnectionsPerIPMax int,
+) *Handler {
+	var handler *Handler = &Handler{Logger: slog.New(slog.DiscardHandler)}
+	handler.InitializeCache(time.Hour)
+	handler.InitializeEvents(10, numberOfConnectionsPerIPMax, time.Hour)
+	handler.cacheRuns.Set(keyRunLatest, run)
+	return handler
+}
+
+// stream of events of a response
+type streamEvents struct {
+	reader *bufio.Reader
+}
+
+// next frame of the stream (an event, a comment or the retry interval) as
+// its lines
+func (stream streamEvents) next(t *testing.T) []string {
+	t.Helper()
+
+	var sliceLines []string
+	for {
+		line, err := stream.reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("error in reading the event stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return sliceLines
+		}
+		sliceLines = append(sliceLines, line)
+	}
+}
+
+// open the event stream with the last event ID, if any, and read up to the
+// retry interval, after which the client is subscribed
+func openEvents(
+	t *testing.T,
+	server *httptest.Server,
+	path string,
+	lastEventID string,
+) streamEvents {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventID != "" {
+		request.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("error in opening the event stream: %v", err)
+	}
+	t.Cleanup(func() { response.Body.Close() })
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("status is %d, expected %d", response.StatusCode, http.StatusOK)
+	}
+	if contentType := response.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("content type is %q, expected text/event-stream", contentType)
+	}
+
+	var stream streamEvents = streamEvents{reader: bufio.NewReader(response.Body)}
+	var sliceLines []string = stream.next(t)
+	if len(sliceLines) != 1 || !strings.HasPrefix(sliceLines[0], "retry: ") {
+		t.Fatalf("first frame is %q, expected the retry interval", sliceLines)
+	}
+	return stream
+}
+
+// ID of an event frame, empty if it is not a run event
+func idEventRun(sliceLines []string) string {
+	if len(sliceLines) != 3 || sliceLines[1] != "event: "+nameEventRun {
+		return ""
+	}
+	return strings.TrimPrefix(sliceLines[0], "id: ")
+}
+
+// a client is sent the latest run unless its last event ID is that run,
+// and every run published after it
+func TestEventsLastEventID(t *testing.T) {
+	var runOld models.MeasurementRun = models.MeasurementRun{RunID: uuid.New()}
+	var runLatest models.MeasurementRun = models.MeasurementRun{RunID: uuid.New()}
+	var runNew models.MeasurementRun = models.MeasurementRun{RunID: uuid.New()}
+
+	var sliceTests = []struct {
+		name        string
+		path        string
+		lastEventID string
+		// whether the latest run is sent on connection
+		isReplayed bool
+	}{
+		{"first connection", "/events", "", true},
+		{"older run", "/events", runOld.RunID.String(), true},
+		{"latest run", "/events", runLatest.RunID.String(), false},
+		{"latest run in the query", "/events?last_event_id=" + runLatest.RunID.String(), "", false},
+	}
+	for _, test := range sliceTests {
+		t.Run(test.name, func(t *testing.T) {
+			var handler *Handler = newHandlerEvents(runLatest, 10)
+			var server *httptest.Server = httptest.NewServer(handler.Events())
+			defer server.Close()
+			// the server waits for the streams to end before it closes
+			defer handler.closeEvents()
+
+			var stream streamEvents = openEvents(t, server, test.path, test.lastEventID)
+			if test.isReplayed {
+				var ID string = idEventRun(stream.next(t))
+				if ID != runLatest.RunID.String() {
+					t.Fatalf("first event is of run %q, expected the latest run", ID)
+				}
+			}
+
+			// a new run is published to the subscribed client
+			handler.cacheRuns.Set(keyRunLatest, runNew)
+			err := handler.PublishRunLatest(context.Background())
+			if err != nil {
+				t.Fatalf("error in publishing the run: %v", err)
+			}
+			var ID string = idEventRun(stream.next(t))
+			if ID != runNew.RunID.String() {
+				t.Errorf("event is of run %q, expected the new run", ID)
+			}
+		})
+	}
+}
+
+// the connections of a client IP above the maximum are refused, and the
+// connections are released once the streams end
+func TestEventsConnectionsPerIPMax(t *testing.T) {
+	var handler *Handler = newHandlerEvents(models.MeasurementRun{RunID: uuid.New()}, 1)
+	var server *httptest.Server = httptest.NewServer(handler.Events())
+	defer server.Close()
+	defer handler.closeEvents()
+
+	openEvents(t, server, "/events", "")
+
+	response, err := http.Get(server.URL + "/events")
+	if err != nil {
+		t.Fatalf("error in opening the event stream: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("status is %d, expected %d", response.StatusCode, http.StatusTooManyRequests)
+	}
+	if response.Header.Get("Retry-After") == "" {
+		t.Error("Retry-After is not set")
+	}
+
+	// the streams end when the server shuts down
+	handler.closeEvents()
+	var timeDeadline time.Time = time.Now().Add(5 * time.Second)
+	for time.Now().Before(timeDeadline) {
+		handler.events.mutex.Lock()
+		var numberOfSubscribers int = len(handler.events.subscribers)
+		var numberOfConnectionsIP int = len(handler.events.mapConnectionsIP)
+		handler.events.mutex.Unlock()
+		if numberOfSubscribers == 0 && numberOfConnectionsIP == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("connections are not released after the streams end")
+}
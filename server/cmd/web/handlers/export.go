@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/kelaaditya/zomato-weather-union/server/internal/datasets"
+)
+
+// content types and file extensions of the export formats
+var mapExportFormats map[string][2]string = map[string][2]string{
+	datasets.FormatCSV:     {"text/csv; charset=utf-8", ".csv"},
+	datasets.FormatJSON:    {"application/json", ".json"},
+	datasets.FormatParquet: {"application/vnd.apache.parquet", ".parquet"},
+}
+
+// on-demand export of the wet bulb temperature dataset
+// the rows are streamed to the client as they are read from the database
+// query parameters:
+//
+//	city    city name, all cities if empty
+//	from    start of the time range (RFC 3339 or YYYY-MM-DD), inclusive
+//	to      end of the time range (RFC 3339 or YYYY-MM-DD), exclusive
+//	format  csv (default), json or parquet
+func (handler *Handler) Export() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var query = r.URL.Query()
+
+		// export format
+		var format string = query.Get("format")
+		if format == "" {
+			format = datasets.FormatCSV
+		}
+		formatDetails, ok := mapExportFormats[format]
+		if !ok {
+			handler.clientError(w, http.StatusBadRequest, "format must be csv, json or parquet")
+			return
+		}
+
+		// filters
+		var filter datasets.Filter
+		if cityName := query.Get("city"); cityName != "" {
+			filter.CityName = &cityName
+		}
+		if fromString := query.Get("from"); fromString != "" {
+			timeFrom, err := parseTimeQuery(fromString)
+			if err != nil {
+				handler.clientError(w, http.StatusBadRequest, "invalid from: "+fromString)
+				return
+			}
+			filter.TimeFrom = &timeFrom
+		}
+		if toString := query.Get("to"); toString != "" {
+			timeTo, err := parseTimeQuery(toString)
+			if err != nil {
+				handler.clientError(w, http.StatusBadRequest, "invalid to: "+toString)
+				return
+			}
+			filter.TimeTo = &timeTo
+		}
+		if filter.TimeFrom != nil &&
+			filter.TimeTo != nil &&
+			!filter.TimeFrom.Before(*filter.TimeTo) {
+			handler.clientError(w, http.StatusBadRequest, "from must be before to")
+			return
+		}
+
+		// large exports take longer than the server write timeout
+		// a minute longer than the query, so that its timeout ends the
+		// stream first
+		responseController := http.NewResponseController(w)
+		err := responseController.SetWriteDeadline(
+			time.Now().Add(datasets.TimeoutStream + time.Minute),
+		)
+		// writers without deadline support keep the server timeout
+		if err != nil && !errors.Is(err, http.ErrNotSupported) {
+			handler.serverError(w, r, "error in extending the write deadline", err)
+			return
+		}
+
+		// the headers of the download are sent with the first bytes, so
+		// that an error of the query is still sent as a 500
+		var writer *exportWriter = &exportWriter{
+			w:           w,
+			contentType: formatDetails[0],
+			fileName:    "temperature-wet-bulb-export" + formatDetails[1],
+		}
+
+		// stream the rows
+		// once streaming has started the response is aborted, so that the
+		// client sees a broken transfer instead of a truncated file
+		rowCount, err := handler.Exporter.StreamTemperatureWetBulb(
+			r.Context(),
+			writer,
+			filter,
+			format,
+		)
+		if err != nil && !writer.isWritten {
+			handler.serverError(w, r, "error in querying the export", err)
+			return
+		}
+		if err != nil {
+			handler.logger(r).Error(
+				"error in streaming the export",
+				"method",
+				r.Method,
+				"uri",
+				r.RequestURI,
+				"rows",
+				rowCount,
+				"error",
+				err.Error(),
+			)
+			panic(http.ErrAbortHandler)
+		}
+	}
+}
+
+// writer of an export that sets the headers of the download on the first
+// write
+type exportWriter struct {
+	w           http.ResponseWriter
+	contentType string
+	fileName    string
+	// whether anything was written, after which the status is sent
+	isWritten bool
+}
+
+func (writer *exportWriter) Write(p []byte) (int, error) {
+	if !writer.isWritten {
+		writer.isWritten = true
+		writer.w.Header().Set("Content-Type", writer.contentType)
+		writer.w.Header().Set(
+			"Content-Disposition",
+			`attachment; filename="`+writer.fileName+`"`,
+		)
+	}
+	return writer.w.Write(p)
+}
+
+// parse a time from a query parameter
+// accepts RFC 3339 time stamps and dates (midnight UTC)
+func parseTimeQuery(value string) (time.Time, error) {
+	timeParsed, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return timeParsed, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
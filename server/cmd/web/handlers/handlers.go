@@ -2,13 +2,60 @@ package handlers
 
 import (
 	"html/template"
+	"io/fs"
 	"log/slog"
+	"sync/atomic"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/kelaaditya/zomato-weather-union/server/internal/cache"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/datasets"
 	"github.com/kelaaditya/zomato-weather-union/server/internal/models"
+	"github.com/kelaaditya/zomato-weather-union/server/ui"
 )
 
 type Handler struct {
 	TemplateCache map[string]*template.Template
 	Logger        *slog.Logger
 	Models        *models.Models
+	Exporter      *datasets.Exporter
+
+	// file system of the templates, re-parsed on every request if
+	// IsTemplateReloadEnabled is set (development)
+	TemplateFS              fs.FS
+	IsTemplateReloadEnabled bool
+
+	// wet bulb temperatures of the risk levels, at which the stations of
+	// a city are counted and the default contour levels
+	// 31°C is the threshold used by emergency responders and 35°C is
+	// the theoretical limit of human survivability
+	ThresholdsWetBulb []float64
+
+	// database pool, pinged by the readiness check
+	DB *pgxpool.Pool
+	// the server is not ready once the latest run is older than this
+	RunMaxAge time.Duration
+	// the server is not ready while the schema is not at the version of
+	// the embedded migrations
+	IsSchemaVersionChecked bool
+	// set when the server starts to shut down
+	isShuttingDown atomic.Bool
+
+	// latest run and calculations of the runs, see InitializeCache
+	cacheRuns         *cache.Cache[models.MeasurementRun]
+	cacheCalculations *cache.Cache[calculationsRun]
+
+	// subscribers of the server-sent events, see InitializeEvents
+	events *brokerEvents
+}
+
+// cache of the HTML templates
+// in development the templates are parsed again so that changes on disk
+// show up without a restart
+func (handler *Handler) templates() (map[string]*template.Template, error) {
+	if handler.IsTemplateReloadEnabled {
+		return ui.CreateHTMLTemplateCache(handler.TemplateFS)
+	}
+	return handler.TemplateCache, nil
 }
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/kelaaditya/zomato-weather-union/server/internal/schema"
+)
+
+// result of a readiness check, "ok" or the reason it failed
+type responseReadiness struct {
+	Status        string            `json:"status"`
+	Checks        map[string]string `json:"checks"`
+	RunTime       *time.Time        `json:"latest_run_time,omitempty"`
+	RunAgeSeconds *float64          `json:"latest_run_age_seconds,omitempty"`
+}
+
+// mark the server as shutting down, after which it is no longer ready
+// so that the load balancer stops sending traffic while the open
+// requests drain. the event streams, which never end on their own, are
+// closed.
+func (handler *Handler) StartShutdown() {
+	handler.isShuttingDown.Store(true)
+	handler.closeEvents()
+}
+
+// liveness of the process, does not touch the database
+func (handler *Handler) Healthz() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		_, _ = w.Write([]byte("ok\n"))
+	}
+}
+
+// readiness to serve traffic
+// the database has to answer a ping, its schema has to be at the version
+// of the embedded migrations (if checked) and the latest run with
+// calculations must not be older than the configured maximum age (stale
+// data), 503 otherwise
+func (handler *Handler) Readyz() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var response responseReadiness = responseReadiness{
+			Status: "ok",
+			Checks: map[string]string{},
+		}
+
+		// create a 2 second timeout context, shorter than the probe
+		ctxWT, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		// defer cancellation of the timeout
+		defer cancel()
+
+		// database
+		err := handler.DB.Ping(ctxWT)
+		if err != nil {
+			response.Checks["database"] = err.Error()
+		} else {
+			response.Checks["database"] = "ok"
+		}
+
+		// version of the schema
+		if err == nil && handler.IsSchemaVersionChecked {
+			errSchema := schema.CheckVersion(ctxWT, handler.DB)
+			if errSchema != nil {
+				response.Checks["schema"] = errSchema.Error()
+			} else {
+				response.Checks["schema"] = "ok"
+			}
+		}
+
+		// freshness of the latest run
+		if err == nil {
+			run, err := handler.Models.Measurement.GetMeasurementRunLatest(ctxWT)
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				response.Checks["latest_run"] = "no run with calculations"
+			case err != nil:
+				response.Checks["latest_run"] = err.Error()
+			default:
+				var age time.Duration = time.Since(run.TimeStamp)
+				var ageSeconds float64 = age.Round(time.Second).Seconds()
+				response.RunTime = &run.TimeStamp
+				response.RunAgeSeconds = &ageSeconds
+				if age > handler.RunMaxAge {
+					response.Checks["latest_run"] = "stale, older than " +
+						handler.RunMaxAge.String()
+				} else {
+					response.Checks["latest_run"] = "ok"
+				}
+			}
+		}
+
+		// shutting down
+		if handler.isShuttingDown.Load() {
+			response.Checks["server"] = "shutting down"
+		}
+
+		var status int = http.StatusOK
+		for _, check := range response.Checks {
+			if check != "ok" {
+				response.Status = "unavailable"
+				status = http.StatusServiceUnavailable
+			}
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		handler.writeJSON(w, r, status, "application/json", response)
+	}
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/kelaaditya/zomato-weather-union/server/internal/models"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/testdb"
+)
+
+// status and checks of the readiness of the handler
+func serveReadyz(t *testing.T, handler *Handler) (int, responseReadiness) {
+	t.Helper()
+
+	var recorder *httptest.ResponseRecorder = httptest.NewRecorder()
+	handler.Readyz()(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if recorder.Header().Get("Cache-Control") != "no-store" {
+		t.Error("readiness may be cached")
+	}
+
+	var response responseReadiness
+	err := json.Unmarshal(recorder.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("error in decoding the readiness: %v", err)
+	}
+	return recorder.Code, response
+}
+
+// the process is alive without the database
+func TestHealthz(t *testing.T) {
+	var handler *Handler = &Handler{Logger: slog.New(slog.DiscardHandler)}
+	handler.StartShutdown()
+
+	var recorder *httptest.ResponseRecorder = httptest.NewRecorder()
+	handler.Healthz()(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "ok\n" {
+		t.Errorf("liveness is %d %q, expected %d \"ok\\n\"", recorder.Code, recorder.Body, http.StatusOK)
+	}
+	if recorder.Header().Get("Cache-Control") != "no-store" {
+		t.Error("liveness may be cached")
+	}
+}
+
+// the server is not ready when the database does not answer, nor once it
+// shuts down
+func TestReadyzDatabaseDown(t *testing.T) {
+	// nothing listens on the port, the pool connects on the first query
+	DB, err := pgxpool.New(
+		context.Background(),
+		"postgres://weather@127.0.0.1:1/weather?connect_timeout=1",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer DB.Close()
+
+	var handler *Handler = &Handler{
+		Logger:                 slog.New(slog.DiscardHandler),
+		DB:                     DB,
+		RunMaxAge:              time.Hour,
+		IsSchemaVersionChecked: true,
+	}
+	status, response := serveReadyz(t, handler)
+	if status != http.StatusServiceUnavailable || response.Status != "unavailable" {
+		t.Errorf("readiness is %d %q, expected %d \"unavailable\"", status, response.Status, http.StatusServiceUnavailable)
+	}
+	if response.Checks["database"] == "ok" {
+		t.Error("database check is ok")
+	}
+	// the checks of the database are not run
+	for _, check := range []string{"schema", "latest_run"} {
+		if _, ok := response.Checks[check]; ok {
+			t.Errorf("%s is checked without the database", check)
+		}
+	}
+
+	handler.StartShutdown()
+	_, response = serveReadyz(t, handler)
+	if response.Checks["server"] != "shutting down" {
+		t.Errorf("server check is %q, expected \"shutting down\"", response.Checks["server"])
+	}
+}
+
+// the server is ready with the database at the schema version and a
+// fresh run, and not once the schema is dirty, the run is stale or it
+// shuts down
+func TestReadyz(t *testing.T) {
+	var DB *pgxpool.Pool = testdb.New(t)
+	var ctx context.Context = context.Background()
+	// the latest run is an hour old
+	seedRuns(t, DB)
+
+	var handler *Handler = &Handler{
+		Logger:                 slog.New(slog.DiscardHandler),
+		DB:                     DB,
+		RunMaxAge:              2 * time.Hour,
+		IsSchemaVersionChecked: true,
+		Models: &models.Models{
+			Measurement: &models.MeasurementModel{DB: DB},
+		},
+	}
+	status, response := serveReadyz(t, handler)
+	if status != http.StatusOK {
+		t.Fatalf("readiness is %d, expected %d: %v", status, http.StatusOK, response.Checks)
+	}
+	for _, check := range []string{"database", "schema", "latest_run"} {
+		if response.Checks[check] != "ok" {
+			t.Errorf("%s check is %q, expected \"ok\"", check, response.Checks[check])
+		}
+	}
+	if response.RunTime == nil || response.RunAgeSeconds == nil {
+		t.Error("latest run is not reported")
+	}
+
+	// a stale run
+	handler.RunMaxAge = 30 * time.Minute
+	status, response = serveReadyz(t, handler)
+	if status != http.StatusServiceUnavailable || response.Checks["latest_run"] == "ok" {
+		t.Errorf("readiness of a stale run is %d, %v", status, response.Checks)
+	}
+	handler.RunMaxAge = 2 * time.Hour
+
+	// a dirty schema, set back before the database is unlocked
+	_, err := DB.Exec(ctx, "UPDATE schema_migrations SET dirty = TRUE;")
+	if err != nil {
+		t.Fatalf("error in marking the schema dirty: %v", err)
+	}
+	t.Cleanup(func() {
+		_, err := DB.Exec(ctx, "UPDATE schema_migrations SET dirty = FALSE;")
+		if err != nil {
+			t.Errorf("error in marking the schema clean: %v", err)
+		}
+	})
+	status, response = serveReadyz(t, handler)
+	if status != http.StatusServiceUnavailable || response.Checks["schema"] == "ok" {
+		t.Errorf("readiness of a dirty schema is %d, %v", status, response.Checks)
+	}
+	_, err = DB.Exec(ctx, "UPDATE schema_migrations SET dirty = FALSE;")
+	if err != nil {
+		t.Fatalf("error in marking the schema clean: %v", err)
+	}
+
+	// shutting down
+	handler.StartShutdown()
+	status, response = serveReadyz(t, handler)
+	if status != http.StatusServiceUnavailable || response.Checks["server"] != "shutting down" {
+		t.Errorf("readiness while shutting down is %d, %v", status, response.Checks)
+	}
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kelaaditya/zomato-weather-union/server/cmd/web/middlewares"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/models"
+)
+
+// logger of the request, with the request ID
+func (handler *Handler) logger(r *http.Request) *slog.Logger {
+	return middlewares.Logger(r, handler.Logger)
+}
+
+// log a server side error and send a 500 response
+func (handler *Handler) serverError(
+	w http.ResponseWriter,
+	r *http.Request,
+	message string,
+	err error,
+) {
+	// log error
+	handler.logger(r).Error(
+		message,
+		"method",
+		r.Method,
+		"uri",
+		r.RequestURI,
+		"error",
+		err.Error(),
+	)
+	// error with built-in status
+	http.Error(
+		w,
+		http.StatusText(http.StatusInternalServerError),
+		http.StatusInternalServerError,
+	)
+}
+
+// send a client error response with a message
+func (handler *Handler) clientError(
+	w http.ResponseWriter,
+	status int,
+	message string,
+) {
+	http.Error(w, message, status)
+}
+
+// marshal data to JSON and write it with the given content type
+func (handler *Handler) writeJSON(
+	w http.ResponseWriter,
+	r *http.Request,
+	status int,
+	contentType string,
+	data any,
+) {
+	// convert data to JSON
+	JSONBytes, err := json.Marshal(data)
+	if err != nil {
+		handler.serverError(w, r, "error in converting data to JSON", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+
+	_, err = w.Write(JSONBytes)
+	if err != nil {
+		// headers are already sent, only log
+		handler.logger(r).Error(
+			"error in writing JSON response",
+			"method",
+			r.Method,
+			"uri",
+			r.RequestURI,
+			"error",
+			err.Error(),
+		)
+	}
+}
+
+// data of every page template, the data of the page itself is in Page
+type templateData struct {
+	// CSP nonce of the request, for inline scripts
+	Nonce string
+	// CSRF token of the request, for the admin forms
+	CSRFToken string
+	Page      any
+}
+
+// execute a page template from the cache into a buffer and write it
+// nothing is sent if the template fails, so that a half-rendered page is
+// never shown
+func (handler *Handler) render(
+	w http.ResponseWriter,
+	r *http.Request,
+	status int,
+	page string,
+	data any,
+) {
+	// get the HTML template cache (re-parsed in development)
+	templateCache, err := handler.templates()
+	if err != nil {
+		handler.serverError(w, r, "error in parsing page templates", err)
+		return
+	}
+
+	// get page HTML template from cache
+	HTMLTemplate, ok := templateCache[page]
+	if !ok {
+		handler.serverError(
+			w,
+			r,
+			"page template file not found in html cache",
+			fmt.Errorf("template %s not found", page),
+		)
+		return
+	}
+
+	// initialize new buffer
+	HTMLTemplateBuffer := new(bytes.Buffer)
+
+	// execute the HTML template
+	err = HTMLTemplate.ExecuteTemplate(
+		HTMLTemplateBuffer,
+		"base",
+		templateData{
+			Nonce:     middlewares.Nonce(r),
+			CSRFToken: middlewares.CSRFToken(r),
+			Page:      data,
+		},
+	)
+	if err != nil {
+		handler.serverError(w, r, "error in executing page template", err)
+		return
+	}
+
+	// write the buffer to w
+	w.WriteHeader(status)
+	_, err = w.Write(HTMLTemplateBuffer.Bytes())
+	if err != nil {
+		// headers are already sent, only log
+		handler.logger(r).Error(
+			"error in writing bytes to the writer in page template",
+			"method",
+			r.Method,
+			"uri",
+			r.RequestURI,
+			"error",
+			err.Error(),
+		)
+	}
+}
+
+// find the run requested by the query parameters
+//
+//	run_id  ID of the run
+//	time    time (RFC 3339 or YYYY-MM-DD), the latest run at or before it
+//
+// the latest run is used if neither is given. on failure an error
+// response is written and false is returned.
+func (handler *Handler) runFromQuery(
+	w http.ResponseWriter,
+	r *http.Request,
+) (models.MeasurementRun, bool) {
+	var query = r.URL.Query()
+
+	var run models.MeasurementRun
+	var err error
+	switch {
+	case query.Get("run_id") != "":
+		runID, errParse := uuid.Parse(query.Get("run_id"))
+		if errParse != nil {
+			handler.clientError(w, http.StatusBadRequest, "invalid run_id")
+			return run, false
+		}
+		run, err = handler.Models.Measurement.GetMeasurementRun(
+			r.Context(),
+			runID,
+		)
+	case query.Get("time") != "":
+		timeAt, errParse := parseTimeQuery(query.Get("time"))
+		if errParse != nil {
+			handler.clientError(w, http.StatusBadRequest, "invalid time")
+			return run, false
+		}
+		run, err = handler.Models.Measurement.GetMeasurementRunAtTime(
+			r.Context(),
+			timeAt,
+		)
+	default:
+		run, err = handler.runLatest(r.Context())
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		handler.clientError(w, http.StatusNotFound, "no run with calculations found")
+		return run, false
+	}
+	if err != nil {
+		handler.serverError(w, r, "error in fetching the measurement run", err)
+		return run, false
+	}
+
+	return run, true
+}
@@ -1,160 +1,42 @@
 package handlers
 
 import (
-	"bytes"
-	"context"
-	"encoding/json"
+	"errors"
+	"html/template"
 	"net/http"
 )
 
 func (handler *Handler) Home() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// http method type
-		var HTTPMethod string = r.Method
-		// request URL
-		var requestURI string = r.RequestURI
-
-		// get calculations for display on the map
-		calculations, err :=
-			handler.
-				Models.
-				Calculation.GetCalculationsTemperatureWithStationDetails(
-				context.Background(),
-			)
+		// get the latest run with calculations
+		run, err := handler.runLatest(r.Context())
 		if err != nil {
-			// log error
-			handler.Logger.Error(
-				"error in fetching calculations with station data",
-				"method",
-				HTTPMethod,
-				"uri",
-				requestURI,
-				"error",
-				err.Error(),
-			)
-			// error with built-in status
-			http.Error(
-				w,
-				http.StatusText(http.StatusInternalServerError),
-				http.StatusInternalServerError,
-			)
-			return
-		}
-		// if no data fetched
-		if len(calculations) == 0 {
-			// log error
-			handler.Logger.Error(
-				"no data found when fetching for calculations",
-				"method",
-				HTTPMethod,
-				"uri",
-				requestURI,
-			)
-			// error with built-in status
-			http.Error(
-				w,
-				http.StatusText(http.StatusInternalServerError),
-				http.StatusInternalServerError,
-			)
+			handler.serverError(w, r, "error in fetching the latest measurement run", err)
 			return
 		}
 
-		// convert data to JSON
-		// as the template JS does not have object transfer from the backend
-		calculationJSONBytes, err := json.Marshal(calculations)
+		// get calculations for display on the map, already in JSON
+		// the page itself is not cached as it carries the CSP nonce
+		calculations, err := handler.calculationsOfRun(r.Context(), run.RunID)
 		if err != nil {
-			// log error
-			handler.Logger.Error(
-				"error in converting data to JSON",
-				"method",
-				HTTPMethod,
-				"uri",
-				requestURI,
-				"error",
-				err.Error(),
-			)
-			// error with built-in status
-			http.Error(
-				w,
-				http.StatusText(http.StatusInternalServerError),
-				http.StatusInternalServerError,
-			)
-			return
-		}
-
-		// convert slice of bytes JSON to string JSON (utf-8)
-		dataForTemplate := string(calculationJSONBytes)
-
-		// get home page HTML template from cache
-		HTMLTemplate, ok := handler.TemplateCache["home"]
-		if !ok {
-			// log error
-			handler.Logger.Error(
-				"home page template file not found in html cache",
-				"method",
-				HTTPMethod,
-				"uri",
-				requestURI,
-			)
-			// error with built-in status
-			http.Error(
-				w,
-				http.StatusText(http.StatusInternalServerError),
-				http.StatusInternalServerError,
-			)
+			handler.serverError(w, r, "error in fetching calculations with station data", err)
 			return
 		}
-
-		// initialize new buffer
-		HTMLTemplateBuffer := new(bytes.Buffer)
-
-		// execute the HTML template
-		err = HTMLTemplate.ExecuteTemplate(
-			HTMLTemplateBuffer,
-			"base",
-			dataForTemplate,
-		)
-		if err != nil {
-			// log error
-			handler.Logger.Error(
-				"error in executing home page template",
-				"method",
-				HTTPMethod,
-				"uri",
-				requestURI,
-				"error",
-				err.Error(),
-			)
-			// error with built-in status
-			http.Error(
+		// if no data fetched
+		if len(calculations.Calculations) == 0 {
+			handler.serverError(
 				w,
-				http.StatusText(http.StatusInternalServerError),
-				http.StatusInternalServerError,
+				r,
+				"no data found when fetching for calculations",
+				errors.New("no calculations in the latest run"),
 			)
 			return
 		}
 
-		// if no error in the HTML template execution
-		// write the buffer to w
-		_, err = w.Write(HTMLTemplateBuffer.Bytes())
-		if err != nil {
-			// log error
-			handler.Logger.Error(
-				"error in writing bytes to the writer in home page template",
-				"method",
-				HTTPMethod,
-				"uri",
-				requestURI,
-				"error",
-				err.Error(),
-			)
-			// error with built-in status
-			http.Error(
-				w,
-				http.StatusText(http.StatusInternalServerError),
-				http.StatusInternalServerError,
-			)
-			return
-		}
+		// the calculations are written into a JSON script block and read
+		// by the map script
+		// json.Marshal escapes <, > and &, so the JSON cannot close the
+		// script block
+		handler.render(w, r, http.StatusOK, "home", template.JS(calculations.JSON))
 	}
 }
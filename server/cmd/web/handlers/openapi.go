@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/kelaaditya/zomato-weather-union/server/api"
+)
+
+// OpenAPI document of the JSON API
+func (handler *Handler) OpenAPI() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		// the document only changes with a new binary
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+
+		_, err := w.Write(api.OpenAPI)
+		if err != nil {
+			// headers are already sent, only log
+			handler.logger(r).Error(
+				"error in writing the openapi document",
+				"method",
+				r.Method,
+				"uri",
+				r.RequestURI,
+				"error",
+				err.Error(),
+			)
+		}
+	}
+}
+
+// reference page of the JSON API, rendered from the OpenAPI document
+func (handler *Handler) Docs() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler.render(w, r, http.StatusOK, "docs", nil)
+	}
+}
@@ -0,0 +1,453 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/kelaaditya/zomato-weather-union/server/api"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/datasets"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/models"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/testdb"
+)
+
+// city of the stations of the test runs
+const nameCityTest string = "Delhi NCR"
+
+// the responses of the JSON API, read from a test database, have the
+// status, content type and schema the OpenAPI document gives them
+func TestAPIMatchesOpenAPI(t *testing.T) {
+	var DB *pgxpool.Pool = testdb.New(t)
+	runID, runIDPrevious := seedRuns(t, DB)
+
+	var document map[string]any
+	err := json.Unmarshal(api.OpenAPI, &document)
+	if err != nil {
+		t.Fatalf("error in decoding the openapi document: %v", err)
+	}
+
+	var handler *Handler = &Handler{
+		Logger:            slog.New(slog.DiscardHandler),
+		ThresholdsWetBulb: []float64{28, 31, 35},
+		DB:                DB,
+		Models: &models.Models{
+			Measurement: &models.MeasurementModel{DB: DB},
+			Calculation: &models.CalculationModel{DB: DB},
+			Contour:     &models.ContourModel{DB: DB},
+			City:        &models.CityModel{DB: DB},
+		},
+		Exporter: &datasets.Exporter{
+			DB:     DB,
+			Logger: slog.New(slog.DiscardHandler),
+		},
+	}
+	handler.InitializeCache(time.Minute)
+
+	// the routes of the API as in the web server, without the api keys
+	var mux *http.ServeMux = http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/runs", handler.Runs())
+	mux.HandleFunc("GET /api/v1/calculations", handler.Calculations())
+	mux.HandleFunc("GET /api/v1/cities", handler.CitiesAPI())
+	mux.HandleFunc("GET /api/v1/cities/{name}", handler.CityAPI())
+	mux.HandleFunc("GET /api/v1/contours", handler.ContoursWetBulb())
+	mux.HandleFunc("GET /api/v1/export", handler.Export())
+
+	var timeNow time.Time = time.Now().UTC()
+	for _, testCase := range []struct {
+		name string
+		// path of the operation in the document
+		path   string
+		target string
+		status int
+	}{
+		{
+			"runs",
+			"/api/v1/runs",
+			"/api/v1/runs?from=" + timeNow.Add(-48*time.Hour).Format(time.RFC3339) +
+				"&to=" + timeNow.Format(time.RFC3339),
+			http.StatusOK,
+		},
+		{"runs from after to", "/api/v1/runs", "/api/v1/runs?from=2025-01-02&to=2025-01-01", http.StatusBadRequest},
+		{"calculations of the latest run", "/api/v1/calculations", "/api/v1/calculations", http.StatusOK},
+		{
+			"calculations of a run",
+			"/api/v1/calculations",
+			"/api/v1/calculations?run_id=" + runIDPrevious.String(),
+			http.StatusOK,
+		},
+		{"calculations of an invalid run", "/api/v1/calculations", "/api/v1/calculations?run_id=run", http.StatusBadRequest},
+		{"calculations before the runs", "/api/v1/calculations", "/api/v1/calculations?time=2000-01-01", http.StatusNotFound},
+		{"cities", "/api/v1/cities", "/api/v1/cities?run_id=" + runID.String(), http.StatusOK},
+		{"cities without a previous run", "/api/v1/cities", "/api/v1/cities?run_id=" + runIDPrevious.String(), http.StatusOK},
+		{"city", "/api/v1/cities/{name}", "/api/v1/cities/" + url.PathEscape(nameCityTest), http.StatusOK},
+		{"unknown city", "/api/v1/cities/{name}", "/api/v1/cities/Atlantis", http.StatusNotFound},
+		{"contours", "/api/v1/contours", "/api/v1/contours?levels=28,30", http.StatusOK},
+		{"contours with an invalid level", "/api/v1/contours", "/api/v1/contours?levels=hot", http.StatusBadRequest},
+		{"export as json", "/api/v1/export", "/api/v1/export?format=json", http.StatusOK},
+		{"export as csv", "/api/v1/export", "/api/v1/export?city=" + url.QueryEscape(nameCityTest), http.StatusOK},
+		{"export in an unknown format", "/api/v1/export", "/api/v1/export?format=xml", http.StatusBadRequest},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			var recorder *httptest.ResponseRecorder = httptest.NewRecorder()
+			mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, testCase.target, nil))
+
+			if recorder.Code != testCase.status {
+				t.Fatalf("status %d, expected %d: %s", recorder.Code, testCase.status, recorder.Body)
+			}
+
+			// the response of the status in the document
+			response, err := resolveRef(
+				document,
+				walk(document, "paths", testCase.path, "get", "responses", fmt.Sprint(recorder.Code)),
+			)
+			if err != nil || response == nil {
+				t.Fatalf("status %d of %s is not in the document", recorder.Code, testCase.path)
+			}
+
+			// the content type is one of the response
+			mediaType, _, err := mime.ParseMediaType(recorder.Header().Get("Content-Type"))
+			if err != nil {
+				t.Fatalf("invalid content type %q", recorder.Header().Get("Content-Type"))
+			}
+			content, ok := walk(response, "content", mediaType).(map[string]any)
+			if !ok {
+				t.Fatalf("content type %s is not in the document", mediaType)
+			}
+
+			// JSON bodies match their schema, numbers are kept as text to
+			// tell integers apart
+			if !strings.HasSuffix(mediaType, "json") {
+				return
+			}
+			var decoder *json.Decoder = json.NewDecoder(bytes.NewReader(recorder.Body.Bytes()))
+			decoder.UseNumber()
+			var body any
+			err = decoder.Decode(&body)
+			if err != nil {
+				t.Fatalf("error in decoding the body: %v", err)
+			}
+			for _, problem := range validate(document, content["schema"], body, "body") {
+				t.Error(problem)
+			}
+		})
+	}
+}
+
+// value at the keys of a decoded JSON document, nil if there is none
+func walk(value any, keys ...string) any {
+	for _, key := range keys {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+// the schema or response a $ref points to, or the value itself
+func resolveRef(document map[string]any, value any) (map[string]any, error) {
+	object, _ := value.(map[string]any)
+	ref, ok := object["$ref"].(string)
+	if !ok {
+		return object, nil
+	}
+	resolved, ok := walk(document, strings.Split(strings.TrimPrefix(ref, "#/"), "/")...).(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("$ref %s is not in the document", ref)
+	}
+	return resolveRef(document, resolved)
+}
+
+// problems of a decoded JSON value against a schema of the document
+// only the keywords used by the document are known, another keyword is a
+// problem so that this is extended with the document. objects must not
+// have properties their schema does not list.
+func validate(document map[string]any, schemaValue any, value any, path string) []string {
+	schema, err := resolveRef(document, schemaValue)
+	if err != nil {
+		return []string{path + ": " + err.Error()}
+	}
+
+	var sliceProblems []string
+	for keyword := range schema {
+		switch keyword {
+		case "type", "properties", "required", "items", "const", "oneOf",
+			"format", "description", "contentEncoding":
+		default:
+			sliceProblems = append(sliceProblems, path+": unknown keyword "+keyword)
+		}
+	}
+
+	// exactly one of the schemas
+	if sliceOneOf, ok := schema["oneOf"].([]any); ok {
+		var numberOfMatches int
+		for _, schemaOne := range sliceOneOf {
+			if len(validate(document, schemaOne, value, path)) == 0 {
+				numberOfMatches++
+			}
+		}
+		if numberOfMatches != 1 {
+			sliceProblems = append(
+				sliceProblems,
+				fmt.Sprintf("%s: %v matches %d schemas of oneOf", path, value, numberOfMatches),
+			)
+		}
+	}
+
+	if constant, ok := schema["const"]; ok && value != constant {
+		sliceProblems = append(sliceProblems, fmt.Sprintf("%s: %v is not %v", path, value, constant))
+	}
+
+	// one of the types
+	if typeSchema, ok := schema["type"]; ok {
+		var sliceTypes []any = []any{typeSchema}
+		if sliceTypesSchema, ok := typeSchema.([]any); ok {
+			sliceTypes = sliceTypesSchema
+		}
+		if !slices.ContainsFunc(sliceTypes, func(typeOne any) bool {
+			return isOfType(value, typeOne.(string))
+		}) {
+			sliceProblems = append(
+				sliceProblems,
+				fmt.Sprintf("%s: %v is not of type %v", path, value, typeSchema),
+			)
+			return sliceProblems
+		}
+	}
+
+	switch value := value.(type) {
+	case string:
+		switch schema["format"] {
+		case "uuid":
+			if _, err := uuid.Parse(value); err != nil {
+				sliceProblems = append(sliceProblems, path+": "+value+" is not a uuid")
+			}
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339Nano, value); err != nil {
+				sliceProblems = append(sliceProblems, path+": "+value+" is not a date-time")
+			}
+		}
+	case []any:
+		if schemaItems, ok := schema["items"]; ok {
+			for index, item := range value {
+				sliceProblems = append(
+					sliceProblems,
+					validate(document, schemaItems, item, fmt.Sprintf("%s[%d]", path, index))...,
+				)
+			}
+		}
+	case map[string]any:
+		sliceRequired, _ := schema["required"].([]any)
+		for _, name := range sliceRequired {
+			if _, ok := value[name.(string)]; !ok {
+				sliceProblems = append(sliceProblems, fmt.Sprintf("%s: %s is required", path, name))
+			}
+		}
+		properties, ok := schema["properties"].(map[string]any)
+		if !ok {
+			break
+		}
+		for name, property := range value {
+			schemaProperty, ok := properties[name]
+			if !ok {
+				sliceProblems = append(sliceProblems, fmt.Sprintf("%s: %s is not in the schema", path, name))
+				continue
+			}
+			sliceProblems = append(
+				sliceProblems,
+				validate(document, schemaProperty, property, path+"."+name)...,
+			)
+		}
+	}
+
+	return sliceProblems
+}
+
+// whether a decoded JSON value (numbers as json.Number) is of a JSON
+// schema type
+func isOfType(value any, typeSchema string) bool {
+	switch typeSchema {
+	case "null":
+		return value == nil
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		_, err := number.Int64()
+		return err == nil
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	}
+	return false
+}
+
+// save two runs a day apart with measurements and calculations of 9
+// stations on a grid in a city, and return the IDs of the latest run and
+// of the one before it
+func seedRuns(t *testing.T, DB *pgxpool.Pool) (uuid.UUID, uuid.UUID) {
+	t.Helper()
+	var ctx context.Context = context.Background()
+
+	var sliceStationIDs []uuid.UUID
+	for index := range 9 {
+		var stationID uuid.UUID = uuid.New()
+		_, err := DB.Exec(
+			ctx,
+			`
+			INSERT INTO weather_union_stations (
+				weather_station_id,
+				city_name,
+				locality_name,
+				locality_id,
+				location,
+				device_type,
+				device_type_integer,
+				is_active
+			)
+			VALUES (
+				@stationID,
+				@cityName,
+				@localityName,
+				@localityID,
+				ST_SetSRID(ST_MakePoint(@longitude, @latitude), 4326)::geography,
+				'Weather Station',
+				1,
+				TRUE
+			);
+			`,
+			pgx.NamedArgs{
+				"stationID":    stationID,
+				"cityName":     nameCityTest,
+				"localityName": fmt.Sprintf("Locality %d", index),
+				"localityID":   fmt.Sprintf("ZWL%06d", index),
+				"longitude":    77.15 + 0.03*float64(index%3),
+				"latitude":     28.58 + 0.03*float64(index/3),
+			},
+		)
+		if err != nil {
+			t.Fatalf("error in saving station: %v", err)
+		}
+		sliceStationIDs = append(sliceStationIDs, stationID)
+	}
+
+	var timeRun time.Time = time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	var sliceRunIDs []uuid.UUID
+	for _, timeStampRun := range []time.Time{timeRun.Add(-24 * time.Hour), timeRun} {
+		var runID uuid.UUID = uuid.New()
+		_, err := DB.Exec(
+			ctx,
+			"INSERT INTO measurement_runs (run_id, time_stamp) VALUES (@runID, @timeStamp);",
+			pgx.NamedArgs{"runID": runID, "timeStamp": timeStampRun},
+		)
+		if err != nil {
+			t.Fatalf("error in saving run: %v", err)
+		}
+
+		for index, stationID := range sliceStationIDs {
+			var measurementID uuid.UUID = uuid.New()
+			var temperatureWetBulb float64 = 26.5 + 0.75*float64(index) + float64(len(sliceRunIDs))
+			_, err := DB.Exec(
+				ctx,
+				`
+				INSERT INTO measurements_weather_union (
+					measurement_id,
+					weather_station_id,
+					run_id,
+					temperature,
+					humidity,
+					is_processed_for_calculation_temperature,
+					is_successful_for_calculation_temperature,
+					time_stamp,
+					time_stamp_run
+				)
+				VALUES (
+					@measurementID,
+					@stationID,
+					@runID,
+					@temperature,
+					70,
+					TRUE,
+					TRUE,
+					@timeStamp,
+					@timeStamp
+				);
+				`,
+				pgx.NamedArgs{
+					"measurementID": measurementID,
+					"stationID":     stationID,
+					"runID":         runID,
+					"temperature":   temperatureWetBulb + 4,
+					"timeStamp":     timeStampRun,
+				},
+			)
+			if err != nil {
+				t.Fatalf("error in saving measurement: %v", err)
+			}
+			_, err = DB.Exec(
+				ctx,
+				`
+				INSERT INTO calculations_temperature (
+					calculation_id,
+					measurement_id_weather_union,
+					method,
+					temperature_dew_point,
+					temperature_wet_bulb,
+					time_stamp,
+					time_stamp_run
+				)
+				VALUES (
+					@calculationID,
+					@measurementID,
+					'metpy-with-open-weather-map',
+					@temperatureDewPoint,
+					@temperatureWetBulb,
+					@timeStamp,
+					@timeStamp
+				);
+				`,
+				pgx.NamedArgs{
+					"calculationID":       uuid.New(),
+					"measurementID":       measurementID,
+					"temperatureDewPoint": temperatureWetBulb - 1,
+					"temperatureWetBulb":  temperatureWetBulb,
+					"timeStamp":           timeStampRun,
+				},
+			)
+			if err != nil {
+				t.Fatalf("error in saving calculation: %v", err)
+			}
+		}
+		sliceRunIDs = append(sliceRunIDs, runID)
+	}
+
+	return sliceRunIDs[1], sliceRunIDs[0]
+}
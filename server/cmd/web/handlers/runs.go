@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/kelaaditya/zomato-weather-union/server/internal/models"
+)
+
+// longest time range of the run index
+const maximumRangeRuns time.Duration = 31 * 24 * time.Hour
+
+// response of the calculations API
+type responseCalculations struct {
+	Run          models.MeasurementRun `json:"run"`
+	Calculations json.RawMessage       `json:"calculations"`
+}
+
+// index of the runs with calculations in a time range
+// query parameters:
+//
+//	from  start of the range (RFC 3339 or YYYY-MM-DD), default 24 hours ago
+//	to    end of the range (RFC 3339 or YYYY-MM-DD), default now
+func (handler *Handler) Runs() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var query = r.URL.Query()
+
+		// time range
+		var timeTo time.Time = time.Now()
+		if toString := query.Get("to"); toString != "" {
+			timeParsed, err := parseTimeQuery(toString)
+			if err != nil {
+				handler.clientError(w, http.StatusBadRequest, "invalid to: "+toString)
+				return
+			}
+			timeTo = timeParsed
+		}
+		var timeFrom time.Time = timeTo.Add(-24 * time.Hour)
+		if fromString := query.Get("from"); fromString != "" {
+			timeParsed, err := parseTimeQuery(fromString)
+			if err != nil {
+				handler.clientError(w, http.StatusBadRequest, "invalid from: "+fromString)
+				return
+			}
+			timeFrom = timeParsed
+		}
+		if !timeFrom.Before(timeTo) {
+			handler.clientError(w, http.StatusBadRequest, "from must be before to")
+			return
+		}
+		if timeTo.Sub(timeFrom) > maximumRangeRuns {
+			handler.clientError(w, http.StatusBadRequest, "the time range must be at most 31 days")
+			return
+		}
+
+		// get the runs
+		sliceRuns, err := handler.Models.Measurement.GetMeasurementRuns(
+			r.Context(),
+			timeFrom,
+			timeTo,
+		)
+		if err != nil {
+			handler.serverError(w, r, "error in fetching measurement runs", err)
+			return
+		}
+		if sliceRuns == nil {
+			sliceRuns = []models.MeasurementRun{}
+		}
+
+		handler.writeJSON(w, r, http.StatusOK, "application/json", sliceRuns)
+	}
+}
+
+// calculations with station details of a single run
+// query parameters:
+//
+//	run_id  ID of the run
+//	time    time (RFC 3339 or YYYY-MM-DD), the latest run at or before it
+//
+// the latest run is returned if neither is given
+func (handler *Handler) Calculations() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// get the requested run
+		run, ok := handler.runFromQuery(w, r)
+		if !ok {
+			return
+		}
+
+		// the client's copy is current
+		if handler.isNotModified(w, r, run) {
+			return
+		}
+
+		// get the calculations of the run, already in JSON
+		calculations, err := handler.calculationsOfRun(r.Context(), run.RunID)
+		if err != nil {
+			handler.serverError(w, r, "error in fetching calculations with station data", err)
+			return
+		}
+
+		handler.writeJSON(
+			w,
+			r,
+			http.StatusOK,
+			"application/json",
+			responseCalculations{
+				Run:          run,
+				Calculations: calculations.JSON,
+			},
+		)
+	}
+}
+
+// historical map page stepping through past runs
+func (handler *Handler) History() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler.render(w, r, http.StatusOK, "history", nil)
+	}
+}
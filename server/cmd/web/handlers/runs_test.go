@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// the time ranges and runs of the history are checked before the database
+func TestRunsInvalidQuery(t *testing.T) {
+	var handler *Handler = &Handler{Logger: slog.New(slog.DiscardHandler)}
+
+	var mux *http.ServeMux = http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/runs", handler.Runs())
+	mux.HandleFunc("GET /api/v1/calculations", handler.Calculations())
+
+	var sliceTests = []struct {
+		name   string
+		target string
+	}{
+		{"invalid from", "/api/v1/runs?from=yesterday"},
+		{"invalid to", "/api/v1/runs?to=2025-13-01"},
+		{"from after to", "/api/v1/runs?from=2025-01-02&to=2025-01-01"},
+		{"from at to", "/api/v1/runs?from=2025-01-01&to=2025-01-01"},
+		{"range over 31 days", "/api/v1/runs?from=2025-01-01&to=2025-02-02"},
+		{"from after the default to", "/api/v1/runs?from=2999-01-01"},
+		{"invalid run", "/api/v1/calculations?run_id=run"},
+		{"invalid time", "/api/v1/calculations?time=01/01/2025"},
+	}
+	for _, test := range sliceTests {
+		t.Run(test.name, func(t *testing.T) {
+			var recorder *httptest.ResponseRecorder = httptest.NewRecorder()
+			mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, test.target, nil))
+			if recorder.Code != http.StatusBadRequest {
+				t.Errorf("status is %d, expected %d: %s", recorder.Code, http.StatusBadRequest, recorder.Body)
+			}
+		})
+	}
+}
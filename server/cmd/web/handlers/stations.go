@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/models"
+)
+
+// radius and number of the nearby stations on the station page
+const (
+	stationsNearbyRadiusKilometres float64 = 10
+	stationsNearbyLimit            int     = 8
+)
+
+// data for the station page template
+type templateDataStation struct {
+	Station        models.StationDetails
+	Reading        *models.StationReadingLatest
+	ReadingTime    string
+	Health         models.StationHealth
+	HealthTime     string
+	StationsNearby []models.StationNearby
+	Charts         []templateDataChart
+}
+
+// page with the details of a single station
+func (handler *Handler) Station() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var localityID string = r.PathValue("locality_id")
+
+		// station metadata
+		station, err := handler.Models.Station.GetStationDetails(
+			r.Context(),
+			localityID,
+		)
+		if errors.Is(err, pgx.ErrNoRows) {
+			handler.clientError(w, http.StatusNotFound, "no station with locality ID "+localityID)
+			return
+		}
+		if err != nil {
+			handler.serverError(w, r, "error in fetching station details", err)
+			return
+		}
+
+		var data templateDataStation = templateDataStation{Station: station}
+
+		// latest readings of both providers
+		reading, err := handler.Models.Station.GetStationReadingLatest(
+			r.Context(),
+			station.WeatherStationID,
+		)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			// no measurements yet
+		case err != nil:
+			handler.serverError(w, r, "error in fetching latest station readings", err)
+			return
+		default:
+			data.Reading = &reading
+			data.ReadingTime = formatTimeIST(reading.TimeStamp)
+		}
+
+		// quality control over the last 24 hours
+		data.Health, err = handler.Models.Station.GetStationHealth(
+			r.Context(),
+			station,
+		)
+		if err != nil {
+			handler.serverError(w, r, "error in fetching station health", err)
+			return
+		}
+		if data.Health.TimeLastMeasurement != nil {
+			data.HealthTime = formatTimeIST(*data.Health.TimeLastMeasurement)
+		}
+
+		// nearby stations
+		data.StationsNearby, err = handler.Models.Station.GetStationsNearby(
+			r.Context(),
+			station.WeatherStationID,
+			stationsNearbyRadiusKilometres,
+			stationsNearbyLimit,
+		)
+		if err != nil {
+			handler.serverError(w, r, "error in fetching nearby stations", err)
+			return
+		}
+
+		// hourly series for the last 30 days, the last 7 days are a subset
+		var timeNow time.Time = time.Now()
+		var timeFrom30 time.Time = timeNow.AddDate(0, 0, -30)
+		var timeFrom7 time.Time = timeNow.AddDate(0, 0, -7)
+		sliceSeries, err := handler.Models.Station.GetStationSeriesHourly(
+			r.Context(),
+			station.WeatherStationID,
+			timeFrom30,
+		)
+		if err != nil {
+			handler.serverError(w, r, "error in fetching station time series", err)
+			return
+		}
+		data.Charts = []templateDataChart{
+			newStationChart("Last 7 days", sliceSeries, timeFrom7, timeNow, 1),
+			newStationChart("Last 30 days", sliceSeries, timeFrom30, timeNow, 5),
+		}
+
+		handler.render(w, r, http.StatusOK, "station", data)
+	}
+}
+
+// chart of the hourly mean and maximum wet bulb temperature of a station
+func newStationChart(
+	title string,
+	sliceSeries []models.StationSeriesPoint,
+	timeFrom time.Time,
+	timeTo time.Time,
+	tickEveryDays int,
+) templateDataChart {
+	var seriesMean chartSeries = chartSeries{Name: "Mean", ClassName: "chart-line-mean"}
+	var seriesMax chartSeries = chartSeries{Name: "Maximum", ClassName: "chart-line-max"}
+	for _, point := range sliceSeries {
+		if point.Hour.Before(timeFrom) {
+			continue
+		}
+		seriesMean.Points = append(seriesMean.Points, chartPoint{
+			Time:  point.Hour,
+			Value: point.TemperatureWetBulbMean,
+		})
+		seriesMax.Points = append(seriesMax.Points, chartPoint{
+			Time:  point.Hour,
+			Value: point.TemperatureWetBulbMax,
+		})
+	}
+	return newChart(title, timeFrom, timeTo, 3*time.Hour, tickEveryDays, seriesMean, seriesMax)
+}
+
+// human readable time in indian standard time
+func formatTimeIST(t time.Time) string {
+	return t.In(locationIST).Format("02 Jan 2006, 15:04 IST")
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/kelaaditya/zomato-weather-union/server/internal/models"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/testdb"
+	"github.com/kelaaditya/zomato-weather-union/server/ui"
+)
+
+// the page of a station is found by its locality ID, and unknown IDs are
+// not found
+func TestStation(t *testing.T) {
+	var DB *pgxpool.Pool = testdb.New(t)
+	seedRuns(t, DB)
+
+	templateCache, err := ui.CreateHTMLTemplateCache(ui.Files)
+	if err != nil {
+		t.Fatalf("error in parsing the templates: %v", err)
+	}
+	var handler *Handler = &Handler{
+		Logger:        slog.New(slog.DiscardHandler),
+		TemplateCache: templateCache,
+		Models: &models.Models{
+			Station: &models.StationModel{DB: DB},
+		},
+	}
+
+	var mux *http.ServeMux = http.NewServeMux()
+	mux.HandleFunc("GET /stations/{locality_id}", handler.Station())
+
+	var sliceTests = []struct {
+		name       string
+		localityID string
+		status     int
+		// part of the body, none if empty
+		part string
+	}{
+		{"station", "ZWL000004", http.StatusOK, "Locality 4"},
+		{"unknown station", "ZWL999999", http.StatusNotFound, ""},
+		{"lower case", "zwl000004", http.StatusNotFound, ""},
+		{"not a locality ID", "station", http.StatusNotFound, ""},
+	}
+	for _, test := range sliceTests {
+		t.Run(test.name, func(t *testing.T) {
+			var recorder *httptest.ResponseRecorder = httptest.NewRecorder()
+			mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/stations/"+test.localityID, nil))
+			if recorder.Code != test.status {
+				t.Fatalf("status is %d, expected %d: %s", recorder.Code, test.status, recorder.Body)
+			}
+			if !strings.Contains(recorder.Body.String(), test.part) {
+				t.Errorf("body does not contain %q", test.part)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"html"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/kelaaditya/zomato-weather-union/server/cmd/web/middlewares"
+	"github.com/kelaaditya/zomato-weather-union/server/ui"
+)
+
+// nonce of the script-src directive of a Content-Security-Policy
+var regexpNonce *regexp.Regexp = regexp.MustCompile(`script-src [^;]*'nonce-([^']+)'`)
+
+// every script of the pages carries the CSP nonce of the request
+func TestScriptsCarryTheNonce(t *testing.T) {
+	templateCache, err := ui.CreateHTMLTemplateCache(ui.Files)
+	if err != nil {
+		t.Fatalf("error in parsing the templates: %v", err)
+	}
+	var handler *Handler = &Handler{
+		Logger:        slog.New(slog.DiscardHandler),
+		TemplateCache: templateCache,
+	}
+	var middleware *middlewares.Middleware = &middlewares.Middleware{
+		Logger: slog.New(slog.DiscardHandler),
+	}
+
+	for _, page := range []string{"home", "history", "docs"} {
+		t.Run(page, func(t *testing.T) {
+			var recorder *httptest.ResponseRecorder = httptest.NewRecorder()
+			middleware.CommonHeaders(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					handler.render(w, r, http.StatusOK, page, nil)
+				}),
+			).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("status is %d, expected %d", recorder.Code, http.StatusOK)
+			}
+
+			var sliceMatches []string = regexpNonce.FindStringSubmatch(
+				recorder.Header().Get("Content-Security-Policy"),
+			)
+			if sliceMatches == nil {
+				t.Fatal("no nonce in the Content-Security-Policy")
+			}
+
+			// the attributes are escaped, a + of the nonce is written &#43;
+			var body string = html.UnescapeString(recorder.Body.String())
+			var numberOfScripts int = strings.Count(body, "<script") -
+				strings.Count(body, "<!-- <script")
+			var numberOfNonces int = strings.Count(body, `nonce="`+sliceMatches[1]+`"`)
+			if numberOfScripts == 0 || numberOfNonces != numberOfScripts {
+				t.Errorf("%d of the %d scripts carry the nonce", numberOfNonces, numberOfScripts)
+			}
+		})
+	}
+}
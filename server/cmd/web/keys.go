@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kelaaditya/zomato-weather-union/server/internal/models"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/scopes"
+)
+
+// usage of the keys subcommand
+const usageKeys string = `usage: keys <command>
+
+commands:
+  issue -name NAME [-scopes read,export] [-rate R] [-burst B] [-quota Q]
+               issue a key, printed once
+  list         list the keys, revoked ones included
+  revoke PREFIX
+               revoke the key with the prefix (as printed by list)`
+
+// issue, list and revoke the API keys of the public API
+func runKeys(
+	ctx context.Context,
+	args []string,
+	model *models.APIKeyModel,
+	w io.Writer,
+) error {
+	if len(args) == 0 {
+		return errors.New(usageKeys)
+	}
+
+	switch args[0] {
+	case "issue":
+		var flagSet *flag.FlagSet = flag.NewFlagSet("issue", flag.ContinueOnError)
+		flagSet.SetOutput(w)
+		var name *string = flagSet.String("name", "", "name of the consumer of the key")
+		var scopesKey *string = flagSet.String(
+			"scopes",
+			scopes.Read,
+			"comma separated scopes: "+strings.Join(scopes.SliceAll, ", "),
+		)
+		var requestsPerSecond *float64 = flagSet.Float64("rate", 10, "requests per second")
+		var burst *int = flagSet.Int("burst", 20, "requests in a burst")
+		var quotaDaily *int = flagSet.Int("quota", 0, "requests per day (UTC), 0 for no quota")
+		err := flagSet.Parse(args[1:])
+		if err != nil {
+			return err
+		}
+
+		// check the settings of the key
+		if *name == "" {
+			return fmt.Errorf("missing -name\n%s", usageKeys)
+		}
+		var sliceScopes []string
+		for _, scope := range strings.Split(*scopesKey, ",") {
+			scope = strings.TrimSpace(scope)
+			if !slices.Contains(scopes.SliceAll, scope) {
+				return fmt.Errorf(
+					"unknown scope %q, not one of %s",
+					scope,
+					strings.Join(scopes.SliceAll, ", "),
+				)
+			}
+			sliceScopes = append(sliceScopes, scope)
+		}
+		if *requestsPerSecond <= 0 || *burst < 1 || *quotaDaily < 0 {
+			return errors.New("-rate must be positive, -burst at least 1 and -quota not negative")
+		}
+
+		keySecret, key, err := model.InsertAPIKey(
+			ctx,
+			models.APIKey{
+				Name:              *name,
+				Scopes:            sliceScopes,
+				RequestsPerSecond: *requestsPerSecond,
+				Burst:             *burst,
+				QuotaDaily:        *quotaDaily,
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "issued key %s (%s) for %q\n", key.Prefix, key.APIKeyID, key.Name)
+		fmt.Fprintln(w, "the key is not stored and cannot be shown again:")
+		fmt.Fprintln(w, keySecret)
+		return nil
+	case "list":
+		sliceKeys, err := model.GetAPIKeys(ctx)
+		if err != nil {
+			return err
+		}
+
+		var writer *tabwriter.Writer = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "PREFIX\tNAME\tSCOPES\tRATE\tBURST\tQUOTA\tCREATED\tLAST USED\tREVOKED")
+		for _, key := range sliceKeys {
+			fmt.Fprintf(
+				writer,
+				"%s\t%s\t%s\t%g\t%d\t%d\t%s\t%s\t%s\n",
+				key.Prefix,
+				key.Name,
+				strings.Join(key.Scopes, ","),
+				key.RequestsPerSecond,
+				key.Burst,
+				key.QuotaDaily,
+				key.TimeStampCreated.Format(time.RFC3339),
+				formatTimeOptional(key.TimeStampLastUsed),
+				formatTimeOptional(key.TimeStampRevoked),
+			)
+		}
+		return writer.Flush()
+	case "revoke":
+		if len(args) < 2 {
+			return errors.New(usageKeys)
+		}
+		err := model.RevokeAPIKey(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		// the servers are notified and evict the key from their caches
+		fmt.Fprintf(w, "revoked key %s\n", args[1])
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], usageKeys)
+	}
+}
+
+// time in RFC 3339, - if there is none
+func formatTimeOptional(timeStamp *time.Time) string {
+	if timeStamp == nil {
+		return "-"
+	}
+	return timeStamp.Format(time.RFC3339)
+}
@@ -2,17 +2,27 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"io/fs"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/justinas/alice"
 	"github.com/kelaaditya/zomato-weather-union/server/cmd/web/handlers"
 	"github.com/kelaaditya/zomato-weather-union/server/cmd/web/middlewares"
 	"github.com/kelaaditya/zomato-weather-union/server/internal/config"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/datasets"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/metrics"
 	"github.com/kelaaditya/zomato-weather-union/server/internal/models"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/schema"
 	"github.com/kelaaditya/zomato-weather-union/server/ui"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 // application level configurations and operations
@@ -20,100 +30,252 @@ type application struct {
 	config      *config.Config
 	handlers    *handlers.Handler
 	middlewares *middlewares.Middleware
+	models      *models.Models
 }
 
 func main() {
-	//
-	// application
 	//
 	var app application
 
-	//
-	// context
-	//
 	// create app level background context
 	var ctx context.Context = context.Background()
 
+	// the errors are returned here so that the cleanups deferred by start
+	// (database, spans, metrics server) run before the exit
+	err := app.start(ctx)
+	if err != nil {
+		app.config.Logger.Error(err.Error())
+		// force exit on error
+		os.Exit(1)
+	}
+}
+
+// set up the web server, or run a subcommand, and serve until a signal
+func (app *application) start(ctx context.Context) error {
+	//
+	// flags
+	//
+	// read the templates and static files from ./ui on disk instead of
+	// the embedded copies and parse the templates on every request
+	var isDevelopment *bool = flag.Bool(
+		"dev",
+		false,
+		"read templates and static files from ./ui and reload templates on every request",
+	)
+
 	//
 	// config
 	//
-	app.config = &config.Config{}
+	app.config = &config.Config{
+		Component: "web",
+		// the subcommands need only the settings they use
+		Subcommands: map[string]config.Subcommand{
+			"migrate": {Component: "migrate"},
+			"keys":    {Component: "keys", IsDatabaseUsed: true},
+			"admin":   {Component: "admin"},
+		},
+	}
 	// initialize the configurations of the logger, environment and
 	// database
+	// the command line flags (including -dev) are parsed here
 	err := app.config.New(ctx)
+	if errors.Is(err, config.ErrConfigPrinted) {
+		return nil
+	}
 	if err != nil {
-		app.config.Logger.Error(err.Error())
-		// force exit on error
-		os.Exit(1)
+		return err
 	}
 	// close the postgresql connection pool on function close
-	defer app.config.DB.Close()
+	defer app.config.CloseDatabase()
+	// flush the spans left on function close
+	defer app.config.ShutdownTracing(ctx)
+
+	//
+	// subcommands
+	//
+	// run before the server is set up, with only the settings they use
+	// migrate subcommand, e.g. `web migrate up`
+	if flag.Arg(0) == "migrate" {
+		return schema.Run(flag.Args()[1:], app.config.Environment.DatabaseURL, os.Stdout)
+	}
+	// keys subcommand, e.g. `web keys issue -name consumer`
+	if flag.Arg(0) == "keys" {
+		return runKeys(
+			ctx,
+			flag.Args()[1:],
+			&models.APIKeyModel{DB: app.config.DB},
+			os.Stdout,
+		)
+	}
+
+	// admin subcommand, e.g. `web admin hash-password NAME`
+	if flag.Arg(0) == "admin" {
+		return runAdmin(flag.Args()[1:], os.Stdin, os.Stdout)
+	}
+
+	//
+	// schema
+	//
+	// refuse to start on a schema of another version (optional)
+	if app.config.Environment.IsSchemaVersionCheckEnabled {
+		err = schema.CheckVersion(ctx, app.config.DB)
+		if err != nil {
+			return err
+		}
+	}
 
 	//
 	// models
 	//
-	models := &models.Models{
-		WeatherUnion:   &models.WeatherUnionModel{DB: app.config.DB},
-		OpenWeatherMap: &models.OpenWeatherMapModel{DB: app.config.DB},
-		Measurement:    &models.MeasurementModel{DB: app.config.DB},
-		Calculation:    &models.CalculationModel{DB: app.config.DB},
+	// only the models used by the web server, the others stay nil
+	app.models = &models.Models{
+		Measurement:  &models.MeasurementModel{DB: app.config.DB},
+		Calculation:  &models.CalculationModel{DB: app.config.DB},
+		Contour:      &models.ContourModel{DB: app.config.DB},
+		Station:      &models.StationModel{DB: app.config.DB},
+		City:         &models.CityModel{DB: app.config.DB},
+		Notification: &models.NotificationModel{DB: app.config.DB},
+		APIKey:       &models.APIKeyModel{DB: app.config.DB},
+	}
+
+	//
+	// metrics
+	//
+	// prometheus metrics, including the connection pool and the age of
+	// the latest run
+	var metricsWeb *metrics.Metrics = metrics.New()
+	metricsWeb.RegisterDatabase(
+		app.config.DB,
+		func(ctx context.Context) (time.Time, error) {
+			run, err := app.models.Measurement.GetMeasurementRunLatest(ctx)
+			return run.TimeStamp, err
+		},
+	)
+
+	//
+	// ui files
+	//
+	// embedded templates and static files, or ./ui on disk in development
+	var filesUI fs.FS = ui.Files
+	if *isDevelopment {
+		filesUI = os.DirFS("./ui")
+		app.config.Logger.Info("development mode, reading ui files from ./ui")
+	}
+	// static files are served from the static directory
+	filesStatic, err := fs.Sub(filesUI, "static")
+	if err != nil {
+		return err
 	}
 
 	//
 	// html template cache
 	//
-	HTMLTemplateCache, err := ui.CreateHTMLTemplateCache()
+	HTMLTemplateCache, err := ui.CreateHTMLTemplateCache(filesUI)
 	if err != nil {
-		app.config.Logger.Error(err.Error())
-		// force exit on error
-		os.Exit(1)
+		return err
 	}
 
 	//
 	// handlers
 	//
 	app.handlers = &handlers.Handler{
-		Logger:        app.config.Logger,
-		TemplateCache: HTMLTemplateCache,
-		Models:        models,
+		Logger:                  app.config.Logger,
+		TemplateCache:           HTMLTemplateCache,
+		TemplateFS:              filesUI,
+		IsTemplateReloadEnabled: *isDevelopment,
+		ThresholdsWetBulb:       app.config.Environment.ThresholdsTemperatureWetBulb,
+		DB:                      app.config.DB,
+		RunMaxAge:               app.config.Environment.ReadinessRunMaxAge,
+		IsSchemaVersionChecked:  app.config.Environment.IsSchemaVersionCheckEnabled,
+		Models:                  app.models,
+		Exporter: &datasets.Exporter{
+			DB:                  app.config.DB,
+			Logger:              app.config.Logger,
+			Directory:           app.config.Environment.PathToDownloads,
+			NumberOfFilesToKeep: app.config.Environment.ExportNumberOfFilesToKeep,
+		},
 	}
+	// in-process cache of the latest run and the calculations of the runs
+	app.handlers.InitializeCache(app.config.Environment.CacheTTL)
+	// subscribers of the server-sent events of the runs
+	app.handlers.InitializeEvents(
+		app.config.Environment.EventsConnectionsMax,
+		app.config.Environment.EventsConnectionsPerIPMax,
+		app.config.Environment.EventsHeartbeatInterval,
+	)
 
 	//
 	// middlewares
 	//
 	app.middlewares = &middlewares.Middleware{
-		Logger: app.config.Logger,
+		Logger:  app.config.Logger,
+		Metrics: metricsWeb,
+	}
+	// api keys, scopes and rate limits of the public API
+	app.middlewares.InitializeAPIKeys(
+		app.models.APIKey,
+		app.config.Environment.APIScopesAnonymous,
+		app.config.Environment.RateLimitPerIPRequestsPerSecond,
+		app.config.Environment.RateLimitPerIPBurst,
+	)
+	// proxies in front of the server, for the client IPs
+	err = app.middlewares.InitializeTrustedProxies(app.config.Environment.TrustedProxies)
+	if err != nil {
+		return err
+	}
+	// administrators of the admin pages
+	err = app.middlewares.InitializeAdmin(app.config.Environment.AdminUsers)
+	if err != nil {
+		return err
 	}
 
 	//
 	// http mux
 	//
-	// create new HTTP multiplexer
-	mux := http.NewServeMux()
-
-	// static file server for the local file system
-	var fileServerUI http.Handler = http.FileServer(http.Dir("./ui/static/"))
-	// handle req
-	mux.Handle("GET /static/", http.StripPrefix("/static", fileServerUI))
+	mux, _ := app.routes(filesStatic)
 
-	// static file server for the data files
-	var fileServerDownloads http.Handler = http.FileServer(http.Dir("./downloads"))
-	// handle req
-	mux.Handle("GET /downloads/", http.StripPrefix("/downloads", fileServerDownloads))
-
-	// attaching the home handler to the mux
-	// restrict subtree paths using `${1}`
-	mux.HandleFunc("GET /{$}", app.handlers.Home())
-
-	// compose chain starting with common headers and ending with recover panic
-	// recover panic envelopes the entire system
+	// compose chain starting with the span name and ending with the
+	// request ID
+	// recover panic envelopes the entire system but the request ID and
+	// client IP, so that panics are logged with them
+	// compression comes after the request logs, which then log the size
+	// sent
+	// the metrics and span name come last, after common headers and the
+	// api keys, which replace the request, as they read the route the mux
+	// sets on the request they pass to it
+	// the requests refused by the api keys are logged and traced, but not
+	// counted by route
 	// link the routes handler to the middleware chain
 	muxWithMiddleware := alice.New(
+		app.middlewares.SetRequestID,
+		app.middlewares.SetClientIP,
 		app.middlewares.RecoverPanic,
 		app.middlewares.LogRequests,
 		app.middlewares.CommonHeaders,
+		app.middlewares.Compress,
+		app.middlewares.AuthenticateAPIKey,
+		app.middlewares.RecordMetrics,
+		app.middlewares.NameSpan,
 	).Then(mux)
 
+	// a span for every request, around the whole chain
+	// the probes, static files and the long-lived event streams are not
+	// traced
+	var handlerTraced http.Handler = otelhttp.NewHandler(
+		muxWithMiddleware,
+		"http",
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			switch {
+			case r.URL.Path == "/healthz",
+				r.URL.Path == "/readyz",
+				r.URL.Path == "/events",
+				strings.HasPrefix(r.URL.Path, "/static/"):
+				return false
+			}
+			return true
+		}),
+	)
+
 	//
 	// http server
 	//
@@ -122,14 +284,14 @@ func main() {
 	// server config
 	server := http.Server{
 		Addr:    ":" + HTTPPort,
-		Handler: muxWithMiddleware,
+		Handler: handlerTraced,
 		ErrorLog: slog.NewLogLogger(
 			app.config.Logger.Handler(),
 			slog.LevelError,
 		),
-		IdleTimeout:  time.Minute,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		IdleTimeout:  app.config.Environment.HTTPIdleTimeout,
+		ReadTimeout:  app.config.Environment.HTTPReadTimeout,
+		WriteTimeout: app.config.Environment.HTTPWriteTimeout,
 	}
 	// print if port variable is fetched
 	app.config.Logger.Info(
@@ -138,11 +300,181 @@ func main() {
 		HTTPPort,
 	)
 
+	// stop on SIGINT or SIGTERM
+	ctxSignal, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// clear the cache and tell the event stream clients when the cron
+	// finishes a run
+	go app.listen(
+		ctxSignal,
+		"measurement runs",
+		func(ctx context.Context) error {
+			return app.models.Notification.ListenMeasurementRuns(
+				ctx,
+				// runs may have finished while not listening
+				app.handlers.ClearCache,
+				func(runID string) {
+					app.config.Logger.Info(
+						"measurement run finished, clearing the cache",
+						"run_id",
+						runID,
+					)
+					app.handlers.ClearCache()
+
+					err := app.handlers.PublishRunLatest(ctx)
+					if err != nil {
+						app.config.Logger.Error(
+							"error in publishing measurement run event",
+							"error",
+							err.Error(),
+						)
+					}
+				},
+			)
+		},
+	)
+
+	// evict the revoked keys from the cache
+	go app.listen(
+		ctxSignal,
+		"api key revocations",
+		func(ctx context.Context) error {
+			return app.models.Notification.ListenAPIKeysRevoked(
+				ctx,
+				// keys may have been revoked while not listening
+				app.middlewares.ClearAPIKeys,
+				func(hashKeyHex string) {
+					app.config.Logger.Info("api key revoked, evicting it from the cache")
+					app.middlewares.EvictAPIKey(hashKeyHex)
+				},
+			)
+		},
+	)
+
+	// metrics server, on a port of its own so that the metrics are not
+	// public
+	if app.config.Environment.MetricsPort != "" {
+		// create new HTTP multiplexer
+		muxMetrics := http.NewServeMux()
+		muxMetrics.Handle("GET /metrics", metricsWeb.Handler())
+
+		// server config
+		serverMetrics := http.Server{
+			Addr:    ":" + app.config.Environment.MetricsPort,
+			Handler: muxMetrics,
+			ErrorLog: slog.NewLogLogger(
+				app.config.Logger.Handler(),
+				slog.LevelError,
+			),
+			IdleTimeout:  app.config.Environment.HTTPIdleTimeout,
+			ReadTimeout:  app.config.Environment.HTTPReadTimeout,
+			WriteTimeout: app.config.Environment.HTTPWriteTimeout,
+		}
+		app.config.Logger.Info(
+			"starting the metrics server",
+			"port",
+			app.config.Environment.MetricsPort,
+		)
+		go func() {
+			err := serverMetrics.ListenAndServe()
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				app.config.Logger.Error(
+					"error in the metrics server",
+					"error",
+					err.Error(),
+				)
+			}
+		}()
+		// stop the metrics server on function close, after the http server
+		defer serverMetrics.Close()
+	}
+
+	// write the usage of the api keys every interval, until the signal
+	go app.middlewares.RunAPIKeyUsageFlush(ctxSignal)
+
 	// create a http server
-	err = server.ListenAndServe()
+	var channelErrorServer chan error = make(chan error, 1)
+	go func() {
+		channelErrorServer <- server.ListenAndServe()
+	}()
+
+	// wait for the server to fail or a signal
+	select {
+	case err = <-channelErrorServer:
+		return err
+	case <-ctxSignal.Done():
+		// restore the default behaviour, a second signal kills the process
+		stop()
+	}
+
+	//
+	// shutdown
+	//
+	// fail the readiness probe and keep serving until the load balancers
+	// have seen it, then let the open requests drain
+	app.handlers.StartShutdown()
+	app.config.Logger.Info(
+		"not ready, waiting before the shutdown",
+		"delay",
+		app.config.Environment.ShutdownReadinessDelay.String(),
+	)
+	time.Sleep(app.config.Environment.ShutdownReadinessDelay)
+	app.config.Logger.Info(
+		"shutting down the http server",
+		"timeout",
+		app.config.Environment.HTTPShutdownTimeout.String(),
+	)
+
+	// stop accepting connections and wait for the open requests
+	// until the drain timeout
+	ctxShutdown, cancel := context.WithTimeout(
+		ctx,
+		app.config.Environment.HTTPShutdownTimeout,
+	)
+	defer cancel()
+	err = server.Shutdown(ctxShutdown)
 	if err != nil {
-		app.config.Logger.Error(err.Error())
-		// force exit on error
-		os.Exit(1)
+		// close the remaining connections
+		app.config.Logger.Error("error in shutting down the http server", "error", err.Error())
+		server.Close()
+	}
+
+	// write the usage of the api keys of the last requests
+	err = app.middlewares.FlushAPIKeyUsage(ctx)
+	if err != nil {
+		app.config.Logger.Error("error in writing api key usage", "error", err.Error())
+	}
+
+	// the postgresql connection pool is closed by the deferred close
+	app.config.Logger.Info("http server stopped")
+
+	// return nil if all okay
+	return nil
+}
+
+// listen to postgresql notifications until the context is done
+// the listening connection is opened again after an error
+func (app *application) listen(
+	ctx context.Context,
+	name string,
+	listen func(ctx context.Context) error,
+) {
+	for {
+		err := listen(ctx)
+		if err != nil {
+			app.config.Logger.Error(
+				"error in listening to "+name,
+				"error",
+				err.Error(),
+			)
+		}
+
+		// wait before listening again
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
 	}
 }
@@ -0,0 +1,216 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/time/rate"
+)
+
+// prefix of the paths of the admin pages
+const prefixPathAdmin string = "/admin"
+
+// failed logins of a client IP: a bucket of 10 attempts, refilled with one
+// attempt every 6 seconds
+const (
+	burstAdminFailures int        = 10
+	limitAdminFailures rate.Limit = rate.Limit(1.0 / 6)
+)
+
+// CSRF token of the admin forms, sent both as a cookie and as a form
+// field (double submit)
+const (
+	cookieCSRF string = "admin_csrf"
+	FieldCSRF  string = "csrf_token"
+)
+
+// largest admin form accepted
+const sizeAdminFormMax int64 = 64 << 10
+
+const (
+	contextKeyCSRFToken contextKey = "csrf_token"
+	contextKeyAdmin     contextKey = "admin"
+)
+
+// administrators of the admin pages, see InitializeAdmin
+type administrators struct {
+	// bcrypt hashes of the passwords by name
+	mapHashes map[string][]byte
+	// hash compared for unknown names, so that they take as long as
+	// known names
+	hashUnknown []byte
+	// failed logins by client IP
+	limitersFailures *limiters
+}
+
+// set up the administrators of the admin pages from name:bcrypt-hash
+// entries (as checked by the config), the admin pages are not found if
+// there are none
+func (middleware *Middleware) InitializeAdmin(users []string) error {
+	var mapHashes map[string][]byte = make(map[string][]byte)
+	// cost of the placeholder hash, the highest cost of the hashes
+	var cost int = bcrypt.DefaultCost
+	for _, user := range users {
+		name, hash, _ := strings.Cut(user, ":")
+		mapHashes[name] = []byte(hash)
+		if costHash, err := bcrypt.Cost([]byte(hash)); err == nil {
+			cost = max(cost, costHash)
+		}
+	}
+
+	hashUnknown, err := bcrypt.GenerateFromPassword([]byte(rand.Text()), cost)
+	if err != nil {
+		return fmt.Errorf("error in hashing admin placeholder password: %w", err)
+	}
+
+	middleware.administrators = &administrators{
+		mapHashes:        mapHashes,
+		hashUnknown:      hashUnknown,
+		limitersFailures: newLimiters(),
+	}
+
+	return nil
+}
+
+// CSRF token of the request, for the admin forms
+func CSRFToken(r *http.Request) string {
+	token, _ := r.Context().Value(contextKeyCSRFToken).(string)
+	return token
+}
+
+// name of the administrator of the request, empty outside the admin pages
+func Admin(r *http.Request) string {
+	name, _ := r.Context().Value(contextKeyAdmin).(string)
+	return name
+}
+
+// protect the admin pages with basic authentication and CSRF tokens
+// the passwords are checked against their bcrypt hashes and the failed
+// logins of a client IP are limited. the forms must send the CSRF token
+// of the cookie back and cross-origin form posts are refused.
+func (middleware *Middleware) RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the admin pages do not exist without administrators
+		if middleware.administrators == nil || len(middleware.administrators.mapHashes) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		// never keep the admin pages in caches
+		w.Header().Set("Cache-Control", "no-store")
+
+		// client IP, behind the trusted proxies
+		var IP string = ClientIP(r)
+
+		// the passwords are not checked once the failed logins are used up
+		var limitersFailures *limiters = middleware.administrators.limitersFailures
+		if limitersFailures.tokens(IP, limitAdminFailures, burstAdminFailures) < 1 {
+			limitersFailures.allow(w, IP, limitAdminFailures, burstAdminFailures)
+			return
+		}
+
+		name, password, ok := r.BasicAuth()
+		if !ok || !middleware.administrators.verify(name, password) {
+			if ok {
+				Logger(r, middleware.Logger).Warn(
+					"failed admin login",
+					"name",
+					name,
+					"ip",
+					IP,
+				)
+				// the failed login takes a token, a 429 is written if
+				// there was none left
+				if !limitersFailures.allow(w, IP, limitAdminFailures, burstAdminFailures) {
+					return
+				}
+			}
+			w.Header().Set("WWW-Authenticate", `Basic realm="admin", charset="UTF-8"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		// CSRF token of the cookie, a new one if there is none
+		var token string
+		cookie, err := r.Cookie(cookieCSRF)
+		if err == nil && len(cookie.Value) == base64.RawURLEncoding.EncodedLen(32) {
+			token = cookie.Value
+		}
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			if token == "" {
+				var tokenBytes []byte = make([]byte, 32)
+				_, err = rand.Read(tokenBytes)
+				if err != nil {
+					Logger(r, middleware.Logger).Error("error in creating CSRF token", "error", err.Error())
+					http.Error(
+						w,
+						http.StatusText(http.StatusInternalServerError),
+						http.StatusInternalServerError,
+					)
+					return
+				}
+				token = base64.RawURLEncoding.EncodeToString(tokenBytes)
+				http.SetCookie(w, &http.Cookie{
+					Name:     cookieCSRF,
+					Value:    token,
+					Path:     prefixPathAdmin,
+					HttpOnly: true,
+					Secure:   r.TLS != nil,
+					SameSite: http.SameSiteStrictMode,
+				})
+			}
+		default:
+			// forms of other sites are refused by the browser headers,
+			// forms without the token of the cookie by the token
+			r.Body = http.MaxBytesReader(w, r.Body, sizeAdminFormMax)
+			if !isSameOrigin(r) ||
+				token == "" ||
+				subtle.ConstantTimeCompare([]byte(token), []byte(r.PostFormValue(FieldCSRF))) != 1 {
+				http.Error(w, "invalid or missing CSRF token, reload the page", http.StatusForbidden)
+				return
+			}
+		}
+
+		// call the next-in-line with the token and the administrator
+		var ctx context.Context = context.WithValue(r.Context(), contextKeyCSRFToken, token)
+		ctx = context.WithValue(ctx, contextKeyAdmin, name)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// check the password of an administrator
+// unknown names are checked against a placeholder hash, so that they
+// cannot be told apart by the response time
+func (administrators *administrators) verify(name string, password string) bool {
+	hash, ok := administrators.mapHashes[name]
+	if !ok {
+		hash = administrators.hashUnknown
+	}
+	err := bcrypt.CompareHashAndPassword(hash, []byte(password))
+	return ok && err == nil
+}
+
+// whether a form post comes from the same site, as told by the browser
+// requests without the headers (not from a browser) are allowed
+func isSameOrigin(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "", "same-origin", "none":
+	default:
+		return false
+	}
+
+	var origin string = r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	URL, err := url.Parse(origin)
+	return err == nil && URL.Host == r.Host
+}
@@ -0,0 +1,259 @@
+package middlewares
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// password of the administrator of the tests
+const passwordAdminTest string = "correct horse"
+
+// middleware with a single administrator, admin
+func newMiddlewareAdmin(t *testing.T) *Middleware {
+	t.Helper()
+
+	// the lowest cost keeps the tests fast
+	hash, err := bcrypt.GenerateFromPassword([]byte(passwordAdminTest), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var middleware *Middleware = &Middleware{Logger: slog.New(slog.DiscardHandler)}
+	err = middleware.InitializeAdmin([]string{"admin:" + string(hash)})
+	if err != nil {
+		t.Fatalf("error in initializing the administrators: %v", err)
+	}
+	return middleware
+}
+
+// a request to the admin pages
+type requestAdmin struct {
+	method   string
+	name     string
+	password string
+	// CSRF token of the cookie and of the form, none if empty
+	tokenCookie string
+	tokenForm   string
+	header      map[string]string
+	// address of the client, the default of httptest if empty
+	remoteAddr string
+}
+
+// response of the request through the admin protection
+func serveAdmin(middleware *Middleware, request requestAdmin) *httptest.ResponseRecorder {
+	var handler http.Handler = middleware.RequireAdmin(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Admin", Admin(r))
+			w.Header().Set("X-CSRF-Token", CSRFToken(r))
+		}),
+	)
+
+	var form url.Values = url.Values{}
+	if request.tokenForm != "" {
+		form.Set(FieldCSRF, request.tokenForm)
+	}
+	var r *http.Request = httptest.NewRequest(
+		request.method,
+		"http://example.com/admin/runs",
+		strings.NewReader(form.Encode()),
+	)
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if request.name != "" {
+		r.SetBasicAuth(request.name, request.password)
+	}
+	if request.tokenCookie != "" {
+		r.AddCookie(&http.Cookie{Name: cookieCSRF, Value: request.tokenCookie})
+	}
+	for key, value := range request.header {
+		r.Header.Set(key, value)
+	}
+	if request.remoteAddr != "" {
+		r.RemoteAddr = request.remoteAddr
+	}
+
+	var recorder *httptest.ResponseRecorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, r)
+	return recorder
+}
+
+// CSRF token of the cookie set by a page of the admin
+func tokenCSRF(t *testing.T, middleware *Middleware) string {
+	t.Helper()
+
+	var recorder *httptest.ResponseRecorder = serveAdmin(middleware, requestAdmin{
+		method:   http.MethodGet,
+		name:     "admin",
+		password: passwordAdminTest,
+	})
+	for _, cookie := range recorder.Result().Cookies() {
+		if cookie.Name == cookieCSRF {
+			if !cookie.HttpOnly || cookie.SameSite != http.SameSiteStrictMode {
+				t.Errorf("CSRF cookie is not HttpOnly and SameSite strict")
+			}
+			if token := recorder.Header().Get("X-CSRF-Token"); token != cookie.Value {
+				t.Errorf("token of the page is %q, expected the cookie %q", token, cookie.Value)
+			}
+			return cookie.Value
+		}
+	}
+	t.Fatal("no CSRF cookie is set")
+	return ""
+}
+
+// the administrators are let in with their password only
+func TestRequireAdminLogin(t *testing.T) {
+	var middleware *Middleware = newMiddlewareAdmin(t)
+
+	var sliceTests = []struct {
+		name     string
+		user     string
+		password string
+		status   int
+	}{
+		{"administrator", "admin", passwordAdminTest, http.StatusOK},
+		{"wrong password", "admin", "battery staple", http.StatusUnauthorized},
+		{"unknown name", "root", passwordAdminTest, http.StatusUnauthorized},
+		{"no credentials", "", "", http.StatusUnauthorized},
+	}
+	for _, test := range sliceTests {
+		t.Run(test.name, func(t *testing.T) {
+			var recorder *httptest.ResponseRecorder = serveAdmin(middleware, requestAdmin{
+				method:   http.MethodGet,
+				name:     test.user,
+				password: test.password,
+			})
+			if recorder.Code != test.status {
+				t.Fatalf("status is %d, expected %d", recorder.Code, test.status)
+			}
+			if recorder.Header().Get("Cache-Control") != "no-store" {
+				t.Error("admin response may be cached")
+			}
+			if test.status == http.StatusUnauthorized &&
+				!strings.HasPrefix(recorder.Header().Get("WWW-Authenticate"), "Basic ") {
+				t.Error("WWW-Authenticate does not ask for basic authentication")
+			}
+			if test.status == http.StatusOK && recorder.Header().Get("X-Admin") != test.user {
+				t.Errorf("administrator is %q, expected %q", recorder.Header().Get("X-Admin"), test.user)
+			}
+		})
+	}
+
+	// the admin pages are not found without administrators
+	var recorder *httptest.ResponseRecorder = serveAdmin(
+		&Middleware{Logger: slog.New(slog.DiscardHandler)},
+		requestAdmin{method: http.MethodGet, name: "admin", password: passwordAdminTest},
+	)
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("status without administrators is %d, expected %d", recorder.Code, http.StatusNotFound)
+	}
+}
+
+// unknown names are checked against the placeholder hash, of the cost of
+// the hashes of the administrators, and are refused even if the password
+// matches it
+func TestRequireAdminUnknownName(t *testing.T) {
+	var middleware *Middleware = newMiddlewareAdmin(t)
+
+	cost, err := bcrypt.Cost(middleware.administrators.hashUnknown)
+	if err != nil {
+		t.Fatalf("placeholder is not a bcrypt hash: %v", err)
+	}
+	if cost < bcrypt.DefaultCost {
+		t.Errorf("placeholder cost is %d, expected at least %d", cost, bcrypt.DefaultCost)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("placeholder"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	middleware.administrators.hashUnknown = hash
+	if middleware.administrators.verify("root", "placeholder") {
+		t.Error("unknown name is let in with the password of the placeholder")
+	}
+	if !middleware.administrators.verify("admin", passwordAdminTest) {
+		t.Error("administrator is refused")
+	}
+}
+
+// the forms must send the token of the cookie back, from the same site
+func TestRequireAdminCSRF(t *testing.T) {
+	var middleware *Middleware = newMiddlewareAdmin(t)
+	var token string = tokenCSRF(t, middleware)
+
+	var sliceTests = []struct {
+		name        string
+		tokenCookie string
+		tokenForm   string
+		header      map[string]string
+		status      int
+	}{
+		{"token of the cookie", token, token, nil, http.StatusOK},
+		{"same origin", token, token, map[string]string{"Sec-Fetch-Site": "same-origin", "Origin": "http://example.com"}, http.StatusOK},
+		{"no form token", token, "", nil, http.StatusForbidden},
+		{"no cookie", "", token, nil, http.StatusForbidden},
+		{"other token", token, strings.Repeat("A", len(token)), nil, http.StatusForbidden},
+		{"cross site", token, token, map[string]string{"Sec-Fetch-Site": "cross-site"}, http.StatusForbidden},
+		{"same site", token, token, map[string]string{"Sec-Fetch-Site": "same-site"}, http.StatusForbidden},
+		{"other origin", token, token, map[string]string{"Origin": "https://attacker.example"}, http.StatusForbidden},
+	}
+	for _, test := range sliceTests {
+		t.Run(test.name, func(t *testing.T) {
+			var recorder *httptest.ResponseRecorder = serveAdmin(middleware, requestAdmin{
+				method:      http.MethodPost,
+				name:        "admin",
+				password:    passwordAdminTest,
+				tokenCookie: test.tokenCookie,
+				tokenForm:   test.tokenForm,
+				header:      test.header,
+			})
+			if recorder.Code != test.status {
+				t.Errorf("status is %d, expected %d", recorder.Code, test.status)
+			}
+		})
+	}
+}
+
+// the passwords are not checked once the failed logins of a client IP are
+// used up, and the other clients are not affected
+func TestRequireAdminFailuresLimited(t *testing.T) {
+	var middleware *Middleware = newMiddlewareAdmin(t)
+
+	for i := range burstAdminFailures {
+		var recorder *httptest.ResponseRecorder = serveAdmin(middleware, requestAdmin{
+			method:   http.MethodGet,
+			name:     "admin",
+			password: "guess",
+		})
+		if recorder.Code != http.StatusUnauthorized {
+			t.Fatalf("status of failed login %d is %d, expected %d", i+1, recorder.Code, http.StatusUnauthorized)
+		}
+	}
+
+	// the right password does not help once the failures are used up
+	var recorder *httptest.ResponseRecorder = serveAdmin(middleware, requestAdmin{
+		method:   http.MethodGet,
+		name:     "admin",
+		password: passwordAdminTest,
+	})
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("status after the failed logins is %d, expected %d", recorder.Code, http.StatusTooManyRequests)
+	}
+	if recorder.Header().Get("Retry-After") == "" {
+		t.Error("Retry-After is not set")
+	}
+
+	recorder = serveAdmin(middleware, requestAdmin{
+		method:     http.MethodGet,
+		name:       "admin",
+		password:   passwordAdminTest,
+		remoteAddr: "198.51.100.7:4321",
+	})
+	if recorder.Code != http.StatusOK {
+		t.Errorf("status of another client is %d, expected %d", recorder.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,296 @@
+package middlewares
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/time/rate"
+
+	"github.com/kelaaditya/zomato-weather-union/server/internal/cache"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/models"
+)
+
+// prefix of the paths of the public API
+const prefixPathAPI string = "/api/"
+
+// keys are looked up again after this long
+// revoked keys are evicted at once when the server is notified, so this is
+// only how long a revoked key keeps working while the server is not
+// listening to the revocations
+const durationAPIKeyCache time.Duration = time.Minute
+
+// number of keys kept in the cache of the known keys, and in the cache of
+// the unknown keys
+const numberOfAPIKeysCached int = 1024
+
+const contextKeyAPIKey contextKey = "api_key"
+
+// a key is not in the database or is revoked, kept out of the cache of
+// the known keys
+var errAPIKeyUnknown = errors.New("unknown api key")
+
+// API keys and rate limits of the public API, see InitializeAPIKeys
+type authenticator struct {
+	model *models.APIKeyModel
+	// known keys by the hex of their hash, and unknown or revoked ones
+	// apart, so that guessed keys do not evict the known keys
+	cacheKeys        *cache.Cache[models.APIKey]
+	cacheKeysUnknown *cache.Cache[struct{}]
+	// requests of the keys, written to the database every interval
+	usage *usage
+	// scopes of the requests without a key
+	scopesAnonymous []string
+
+	// token buckets of the keys (by key ID) and of the requests without
+	// a key (by client IP)
+	limitersKeys *limiters
+	limitersIPs  *limiters
+	limitIP      rate.Limit
+	burstIP      int
+}
+
+// set up the authentication with API keys and the rate limits
+// requests without a key get the anonymous scopes and a token bucket of
+// the rate (requests per second) and burst by client IP
+func (middleware *Middleware) InitializeAPIKeys(
+	model *models.APIKeyModel,
+	scopesAnonymous []string,
+	requestsPerSecondIP float64,
+	burstIP int,
+) {
+	middleware.authenticator = &authenticator{
+		model:            model,
+		cacheKeys:        cache.New[models.APIKey](durationAPIKeyCache, numberOfAPIKeysCached),
+		cacheKeysUnknown: cache.New[struct{}](durationAPIKeyCache, numberOfAPIKeysCached),
+		usage:            newUsage(),
+		scopesAnonymous:  scopesAnonymous,
+		limitersKeys:     newLimiters(),
+		limitersIPs:      newLimiters(),
+		limitIP:          rate.Limit(requestsPerSecondIP),
+		burstIP:          burstIP,
+	}
+}
+
+// evict a revoked key from the cache of the known keys, by the hex of its
+// hash
+func (middleware *Middleware) EvictAPIKey(hashKeyHex string) {
+	middleware.authenticator.cacheKeys.Delete(hashKeyHex)
+}
+
+// evict all the known keys, when revocations may have been missed
+func (middleware *Middleware) ClearAPIKeys() {
+	middleware.authenticator.cacheKeys.Clear()
+}
+
+// API key of the request, false if the request has none
+func APIKey(r *http.Request) (models.APIKey, bool) {
+	key, ok := r.Context().Value(contextKeyAPIKey).(models.APIKey)
+	return key, ok
+}
+
+// identify and throttle the requests to the public API
+// the key is read from the Authorization header (Bearer) or the X-API-Key
+// header. requests with a key are limited by the token bucket and the
+// daily quota of the key, requests without one by the token bucket of
+// their IP. an unknown or revoked key is refused with a 401.
+func (middleware *Middleware) AuthenticateAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, prefixPathAPI) {
+			// call the next-in-line
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// client IP, behind the trusted proxies
+		var IP string = ClientIP(r)
+
+		var keySecret string = keyFromRequest(r)
+		if keySecret == "" {
+			if !middleware.authenticator.limitersIPs.allow(
+				w,
+				IP,
+				middleware.authenticator.limitIP,
+				middleware.authenticator.burstIP,
+			) {
+				return
+			}
+
+			// call the next-in-line
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var hashKey []byte = models.HashAPIKey(keySecret)
+		key, isCached := middleware.authenticator.cached(hashKey)
+		// a key that is not cached is looked up in the database, which
+		// takes a token of the IP first so that made-up keys cannot reach
+		// the database at the rate of the server
+		var isIPLimited bool
+		var err error
+		if !isCached {
+			if !middleware.authenticator.limitersIPs.allow(
+				w,
+				IP,
+				middleware.authenticator.limitIP,
+				middleware.authenticator.burstIP,
+			) {
+				return
+			}
+			isIPLimited = true
+
+			key, err = middleware.authenticator.lookUp(r.Context(), hashKey)
+		}
+		if err != nil {
+			// log error
+			Logger(r, middleware.Logger).Error(
+				"error in looking up api key",
+				"method",
+				r.Method,
+				"uri",
+				r.RequestURI,
+				"error",
+				err.Error(),
+			)
+			// error with built-in status
+			http.Error(
+				w,
+				http.StatusText(http.StatusInternalServerError),
+				http.StatusInternalServerError,
+			)
+			return
+		}
+		if key.APIKeyID == uuid.Nil {
+			// unknown or revoked key, counted against the IP so that keys
+			// cannot be guessed at the rate of the server
+			if !isIPLimited && !middleware.authenticator.limitersIPs.allow(
+				w,
+				IP,
+				middleware.authenticator.limitIP,
+				middleware.authenticator.burstIP,
+			) {
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			http.Error(w, "invalid or revoked api key", http.StatusUnauthorized)
+			return
+		}
+
+		// token bucket of the key
+		if !middleware.authenticator.limitersKeys.allow(
+			w,
+			key.APIKeyID.String(),
+			rate.Limit(key.RequestsPerSecond),
+			key.Burst,
+		) {
+			return
+		}
+
+		// daily quota of the key, the usage is counted for every key and
+		// written to the database every interval
+		var numberOfRequests int = middleware.authenticator.usage.add(key.APIKeyID)
+		if key.QuotaDaily > 0 {
+			w.Header().Set("X-Quota-Limit", strconv.Itoa(key.QuotaDaily))
+			w.Header().Set(
+				"X-Quota-Remaining",
+				strconv.Itoa(max(0, key.QuotaDaily-numberOfRequests)),
+			)
+			if numberOfRequests > key.QuotaDaily {
+				// the quota starts again at midnight UTC
+				var timeNow time.Time = time.Now().UTC()
+				var timeReset time.Time = timeNow.Truncate(24 * time.Hour).Add(24 * time.Hour)
+				w.Header().Set(
+					"Retry-After",
+					strconv.Itoa(int(timeReset.Sub(timeNow).Seconds())+1),
+				)
+				http.Error(w, "daily quota exceeded", http.StatusTooManyRequests)
+				return
+			}
+		}
+
+		// call the next-in-line with the key
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKeyAPIKey, key)))
+	})
+}
+
+// refuse the requests without the scope
+// requests without a key have the anonymous scopes, they get a 401 so
+// that the client sends a key, and requests with a key a 403
+func (middleware *Middleware) RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, ok := APIKey(r)
+			switch {
+			case ok && key.HasScope(scope):
+			case ok:
+				http.Error(w, "api key without the "+scope+" scope", http.StatusForbidden)
+				return
+			case !slices.Contains(middleware.authenticator.scopesAnonymous, scope):
+				w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+				http.Error(w, "api key with the "+scope+" scope required", http.StatusUnauthorized)
+				return
+			}
+
+			// call the next-in-line
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// key of the request, empty if there is none
+// keys are not read from the query, which ends up in the logs
+func keyFromRequest(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+
+// key with the hash from the caches, an unknown or revoked key has no ID
+// false if the key is in neither cache
+func (authenticator *authenticator) cached(hashKey []byte) (models.APIKey, bool) {
+	var keyCache string = hex.EncodeToString(hashKey)
+	if key, ok := authenticator.cacheKeys.Peek(keyCache); ok {
+		return key, true
+	}
+	if _, ok := authenticator.cacheKeysUnknown.Peek(keyCache); ok {
+		return models.APIKey{}, true
+	}
+	return models.APIKey{}, false
+}
+
+// key with the hash from the database, stored in the cache of the known
+// or of the unknown keys
+// an unknown or revoked key has no ID
+func (authenticator *authenticator) lookUp(
+	ctx context.Context,
+	hashKey []byte,
+) (models.APIKey, error) {
+	var keyCache string = hex.EncodeToString(hashKey)
+	key, err := authenticator.cacheKeys.Get(
+		keyCache,
+		func() (models.APIKey, error) {
+			key, err := authenticator.model.GetAPIKeyByHash(
+				context.WithoutCancel(ctx),
+				hashKey,
+			)
+			if errors.Is(err, pgx.ErrNoRows) {
+				// errors are not cached
+				return models.APIKey{}, errAPIKeyUnknown
+			}
+			return key, err
+		},
+	)
+	if errors.Is(err, errAPIKeyUnknown) {
+		authenticator.cacheKeysUnknown.Set(keyCache, struct{}{})
+		return models.APIKey{}, nil
+	}
+	return key, err
+}
@@ -0,0 +1,165 @@
+package middlewares
+
+import (
+	"context"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/justinas/alice"
+
+	"github.com/kelaaditya/zomato-weather-union/server/internal/models"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/scopes"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/testdb"
+)
+
+// middleware with the api keys of the model and the anonymous scopes
+func newMiddlewareAPIKeys(model *models.APIKeyModel, scopesAnonymous []string) *Middleware {
+	var middleware *Middleware = &Middleware{Logger: slog.New(slog.DiscardHandler)}
+	middleware.InitializeAPIKeys(model, scopesAnonymous, 100, 100)
+	return middleware
+}
+
+// status of a request with the key (none if empty) through the
+// authentication and the scope of the route
+func statusAPIKey(middleware *Middleware, scope string, keySecret string) int {
+	var handler http.Handler = alice.New(
+		middleware.AuthenticateAPIKey,
+		middleware.RequireScope(scope),
+	).ThenFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	var request *http.Request = httptest.NewRequest(http.MethodGet, "/api/v1/runs", nil)
+	if keySecret != "" {
+		request.Header.Set("Authorization", "Bearer "+keySecret)
+	}
+	var recorder *httptest.ResponseRecorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder.Code
+}
+
+// the requests without a key only get the anonymous scopes, and the keys
+// only their own
+func TestRequireScope(t *testing.T) {
+	var middleware *Middleware = newMiddlewareAPIKeys(nil, []string{scopes.Read})
+
+	// a key in the cache, so that the database is not needed
+	var keySecret string = "wu_test"
+	middleware.authenticator.cacheKeys.Set(
+		hex.EncodeToString(models.HashAPIKey(keySecret)),
+		models.APIKey{
+			APIKeyID:          uuid.New(),
+			Scopes:            []string{scopes.Read},
+			RequestsPerSecond: 100,
+			Burst:             100,
+		},
+	)
+
+	var sliceTests = []struct {
+		name      string
+		scope     string
+		keySecret string
+		status    int
+	}{
+		{"anonymous read", scopes.Read, "", http.StatusOK},
+		{"anonymous export", scopes.Export, "", http.StatusUnauthorized},
+		{"key with the scope", scopes.Read, keySecret, http.StatusOK},
+		{"key without the scope", scopes.Export, keySecret, http.StatusForbidden},
+	}
+	for _, test := range sliceTests {
+		t.Run(test.name, func(t *testing.T) {
+			var status int = statusAPIKey(middleware, test.scope, test.keySecret)
+			if status != test.status {
+				t.Errorf("status is %d, expected %d", status, test.status)
+			}
+		})
+	}
+}
+
+// an evicted key is not served from the cache any more
+func TestEvictAPIKey(t *testing.T) {
+	var middleware *Middleware = newMiddlewareAPIKeys(nil, nil)
+
+	var hashKey []byte = models.HashAPIKey("wu_test")
+	middleware.authenticator.cacheKeys.Set(
+		hex.EncodeToString(hashKey),
+		models.APIKey{APIKeyID: uuid.New()},
+	)
+	if _, ok := middleware.authenticator.cached(hashKey); !ok {
+		t.Fatal("key is not cached")
+	}
+
+	middleware.EvictAPIKey(hex.EncodeToString(hashKey))
+	if _, ok := middleware.authenticator.cached(hashKey); ok {
+		t.Error("key is still cached after the eviction")
+	}
+}
+
+// a key revoked in the database is refused at once by a server listening
+// to the revocations, although it is in the cache
+func TestRevokedAPIKeyIsRefused(t *testing.T) {
+	var DB *pgxpool.Pool = testdb.New(t)
+	var model *models.APIKeyModel = &models.APIKeyModel{DB: DB}
+	var middleware *Middleware = newMiddlewareAPIKeys(model, nil)
+
+	var ctx context.Context = context.Background()
+	keySecret, key, err := model.InsertAPIKey(
+		ctx,
+		models.APIKey{
+			Name:              "test",
+			Scopes:            []string{scopes.Read},
+			RequestsPerSecond: 100,
+			Burst:             100,
+		},
+	)
+	if err != nil {
+		t.Fatalf("error in inserting the api key: %v", err)
+	}
+
+	// looked up once, then cached
+	if status := statusAPIKey(middleware, scopes.Read, keySecret); status != http.StatusOK {
+		t.Fatalf("status before the revocation is %d, expected %d", status, http.StatusOK)
+	}
+
+	// listen to the revocations as the web server does
+	ctxListen, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var chanListening chan struct{} = make(chan struct{})
+	var chanEvicted chan struct{} = make(chan struct{}, 1)
+	go func() {
+		err := (&models.NotificationModel{DB: DB}).ListenAPIKeysRevoked(
+			ctxListen,
+			func() { close(chanListening) },
+			func(hashKeyHex string) {
+				middleware.EvictAPIKey(hashKeyHex)
+				chanEvicted <- struct{}{}
+			},
+		)
+		if err != nil {
+			t.Errorf("error in listening to the revocations: %v", err)
+		}
+	}()
+	select {
+	case <-chanListening:
+	case <-time.After(5 * time.Second):
+		t.Fatal("not listening to the revocations")
+	}
+
+	err = model.RevokeAPIKey(ctx, key.Prefix)
+	if err != nil {
+		t.Fatalf("error in revoking the api key: %v", err)
+	}
+	select {
+	case <-chanEvicted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the revocation was not notified")
+	}
+
+	if status := statusAPIKey(middleware, scopes.Read, keySecret); status != http.StatusUnauthorized {
+		t.Errorf("status after the revocation is %d, expected %d", status, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,118 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// header of the client and proxy addresses, appended to by every proxy
+const headerForwardedFor string = "X-Forwarded-For"
+
+const contextKeyClientIP contextKey = "client_ip"
+
+// set up the proxies in front of the server from IPs and CIDRs (as
+// checked by the config), the X-Forwarded-For header is only read from
+// these
+func (middleware *Middleware) InitializeTrustedProxies(proxies []string) error {
+	var slicePrefixes []netip.Prefix
+	for _, proxy := range proxies {
+		prefix, err := parsePrefix(proxy)
+		if err != nil {
+			return fmt.Errorf("error in parsing trusted proxy %q: %w", proxy, err)
+		}
+		slicePrefixes = append(slicePrefixes, prefix)
+	}
+	middleware.trustedProxies = slicePrefixes
+
+	return nil
+}
+
+// IP of the client of the request, the peer address if SetClientIP did
+// not run
+func ClientIP(r *http.Request) string {
+	IP, ok := r.Context().Value(contextKeyClientIP).(string)
+	if !ok {
+		return hostOfAddress(r.RemoteAddr)
+	}
+	return IP
+}
+
+// find the IP of the client, for the rate limits and the logs
+// the peer address is the client unless it is a trusted proxy. behind
+// trusted proxies, the X-Forwarded-For addresses are read from the last
+// one (added by the nearest proxy) and the first address that is not a
+// trusted proxy is the client. the addresses before it were sent by the
+// client and are never trusted.
+func (middleware *Middleware) SetClientIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var IP string = hostOfAddress(r.RemoteAddr)
+		// IPv4 peers of an IPv6 listener, as in X-Forwarded-For
+		if address, err := netip.ParseAddr(IP); err == nil {
+			IP = address.Unmap().String()
+		}
+
+		if middleware.isTrustedProxy(IP) {
+			// all the headers, in the order they were received
+			var sliceAddresses []string = strings.Split(
+				strings.Join(r.Header.Values(headerForwardedFor), ","),
+				",",
+			)
+			for index := len(sliceAddresses) - 1; index >= 0; index-- {
+				address, err := netip.ParseAddr(strings.TrimSpace(sliceAddresses[index]))
+				if err != nil {
+					// the proxy before an invalid address is the client
+					break
+				}
+				IP = address.Unmap().String()
+				if !middleware.isTrustedProxy(IP) {
+					break
+				}
+			}
+		}
+
+		// call the next-in-line with the client IP in the request context
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKeyClientIP, IP)))
+	})
+}
+
+// whether an IP is one of the trusted proxies
+func (middleware *Middleware) isTrustedProxy(IP string) bool {
+	address, err := netip.ParseAddr(IP)
+	if err != nil {
+		return false
+	}
+	address = address.Unmap()
+	for _, prefix := range middleware.trustedProxies {
+		if prefix.Contains(address) {
+			return true
+		}
+	}
+	return false
+}
+
+// a CIDR or a single IP
+func parsePrefix(text string) (netip.Prefix, error) {
+	if strings.Contains(text, "/") {
+		prefix, err := netip.ParsePrefix(text)
+		return prefix.Masked(), err
+	}
+	address, err := netip.ParseAddr(text)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	address = address.Unmap()
+	return netip.PrefixFrom(address, address.BitLen()), nil
+}
+
+// address without the port
+func hostOfAddress(address string) string {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	return host
+}
@@ -0,0 +1,173 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSetClientIP(t *testing.T) {
+	var middleware *Middleware = &Middleware{}
+	err := middleware.InitializeTrustedProxies([]string{"10.0.0.0/8", "2001:db8::/32", "192.0.2.1"})
+	if err != nil {
+		t.Fatalf("error in initializing the trusted proxies: %v", err)
+	}
+
+	var sliceTests = []struct {
+		name       string
+		remoteAddr string
+		// X-Forwarded-For headers, in the order they are received
+		sliceForwardedFor []string
+		IP                string
+	}{
+		{
+			name:       "direct client",
+			remoteAddr: "203.0.113.7:52000",
+			IP:         "203.0.113.7",
+		},
+		{
+			// the header of a client that is not a proxy is not read
+			name:              "untrusted peer with a header",
+			remoteAddr:        "203.0.113.7:52000",
+			sliceForwardedFor: []string{"198.51.100.1"},
+			IP:                "203.0.113.7",
+		},
+		{
+			name:              "behind a proxy",
+			remoteAddr:        "10.0.0.1:52000",
+			sliceForwardedFor: []string{"203.0.113.7"},
+			IP:                "203.0.113.7",
+		},
+		{
+			name:       "proxy without a header",
+			remoteAddr: "10.0.0.1:52000",
+			IP:         "10.0.0.1",
+		},
+		{
+			// the client sends made-up addresses in front of its own
+			name:              "spoofed left-most entries",
+			remoteAddr:        "10.0.0.1:52000",
+			sliceForwardedFor: []string{"1.1.1.1, 10.0.0.9, 203.0.113.7"},
+			IP:                "203.0.113.7",
+		},
+		{
+			name:              "spoofed header before the header of the proxy",
+			remoteAddr:        "10.0.0.1:52000",
+			sliceForwardedFor: []string{"1.1.1.1", "203.0.113.7"},
+			IP:                "203.0.113.7",
+		},
+		{
+			name:              "chain of proxies",
+			remoteAddr:        "10.0.0.1:52000",
+			sliceForwardedFor: []string{"203.0.113.7, 192.0.2.1, 10.0.0.2"},
+			IP:                "203.0.113.7",
+		},
+		{
+			// nothing but proxies, the furthest one is the client
+			name:              "all trusted",
+			remoteAddr:        "10.0.0.1:52000",
+			sliceForwardedFor: []string{"10.0.0.3, 10.0.0.2"},
+			IP:                "10.0.0.3",
+		},
+		{
+			// the proxy that added the malformed entry is the client
+			name:              "malformed entry",
+			remoteAddr:        "10.0.0.1:52000",
+			sliceForwardedFor: []string{"203.0.113.7, not-an-ip"},
+			IP:                "10.0.0.1",
+		},
+		{
+			name:              "malformed entry before a proxy",
+			remoteAddr:        "10.0.0.1:52000",
+			sliceForwardedFor: []string{"not-an-ip, 10.0.0.2"},
+			IP:                "10.0.0.2",
+		},
+		{
+			name:              "address with a port",
+			remoteAddr:        "10.0.0.1:52000",
+			sliceForwardedFor: []string{"203.0.113.7:443"},
+			IP:                "10.0.0.1",
+		},
+		{
+			name:              "empty entry",
+			remoteAddr:        "10.0.0.1:52000",
+			sliceForwardedFor: []string{"203.0.113.7,,10.0.0.2"},
+			IP:                "10.0.0.2",
+		},
+		{
+			name:       "ipv6 client",
+			remoteAddr: "[2001:db9::7]:52000",
+			IP:         "2001:db9::7",
+		},
+		{
+			name:              "ipv6 proxy",
+			remoteAddr:        "[2001:db8::1]:52000",
+			sliceForwardedFor: []string{"2001:db9::7"},
+			IP:                "2001:db9::7",
+		},
+		{
+			name:              "ipv4-mapped proxy",
+			remoteAddr:        "[::ffff:10.0.0.1]:52000",
+			sliceForwardedFor: []string{"203.0.113.7"},
+			IP:                "203.0.113.7",
+		},
+		{
+			name:              "ipv4-mapped client",
+			remoteAddr:        "10.0.0.1:52000",
+			sliceForwardedFor: []string{"::ffff:203.0.113.7"},
+			IP:                "203.0.113.7",
+		},
+		{
+			name:       "ipv4-mapped peer",
+			remoteAddr: "[::ffff:203.0.113.7]:52000",
+			IP:         "203.0.113.7",
+		},
+		{
+			name:              "ipv4-mapped proxy in the header",
+			remoteAddr:        "10.0.0.1:52000",
+			sliceForwardedFor: []string{"203.0.113.7, ::ffff:192.0.2.1"},
+			IP:                "203.0.113.7",
+		},
+	}
+	for _, test := range sliceTests {
+		t.Run(test.name, func(t *testing.T) {
+			var request *http.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			request.RemoteAddr = test.remoteAddr
+			for _, forwardedFor := range test.sliceForwardedFor {
+				request.Header.Add(headerForwardedFor, forwardedFor)
+			}
+
+			var IP string
+			middleware.SetClientIP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				IP = ClientIP(r)
+			})).ServeHTTP(httptest.NewRecorder(), request)
+			if IP != test.IP {
+				t.Errorf("client IP is %s, expected %s", IP, test.IP)
+			}
+		})
+	}
+}
+
+// the trusted proxies are IPs or CIDRs
+func TestInitializeTrustedProxies(t *testing.T) {
+	var sliceTests = []struct {
+		name    string
+		proxies []string
+		isValid bool
+	}{
+		{"ipv4", []string{"192.0.2.1"}, true},
+		{"ipv4 cidr", []string{"10.0.0.0/8"}, true},
+		{"ipv6 cidr", []string{"2001:db8::/32"}, true},
+		{"ipv4-mapped", []string{"::ffff:192.0.2.1"}, true},
+		{"hostname", []string{"proxy.internal"}, false},
+		{"invalid cidr", []string{"10.0.0.0/33"}, false},
+	}
+	for _, test := range sliceTests {
+		t.Run(test.name, func(t *testing.T) {
+			err := (&Middleware{}).InitializeTrustedProxies(test.proxies)
+			if (err == nil) != test.isValid {
+				t.Errorf("error is %v, expected valid %v", err, test.isValid)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package middlewares
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// content encodings, in order of preference
+const (
+	encodingBrotli string = "br"
+	encodingGzip   string = "gzip"
+)
+
+// responses smaller than this (if their length is known) are sent as is
+const sizeCompressMin int = 1024
+
+// content types worth compressing, by prefix
+// event streams are left out, so that every event is sent at once
+var sliceContentTypesCompressible []string = []string{
+	"text/html",
+	"text/css",
+	"text/plain",
+	"text/csv",
+	"text/javascript",
+	"application/javascript",
+	"application/json",
+	"application/geo+json",
+	"image/svg+xml",
+}
+
+// compress the responses with brotli or gzip when the client accepts it
+// only text responses are compressed, not the downloads (which are
+// compressed already), partial responses or responses without a body
+func (middleware *Middleware) Compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the response depends on the accepted encodings
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		var encoding string = negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Header.Get("Range") != "" {
+			// call the next-in-line
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var writer *responseWriterCompress = &responseWriterCompress{
+			ResponseWriter: w,
+			encoding:       encoding,
+		}
+		// call the next-in-line
+		next.ServeHTTP(writer, r)
+
+		// flush the end of the compressed stream
+		// not deferred, so that an aborted response is not finished
+		writer.Close()
+	})
+}
+
+// preferred encoding of the Accept-Encoding header, empty if neither
+// brotli nor gzip is accepted
+func negotiateEncoding(acceptEncoding string) string {
+	var isBrotliAccepted, isGzipAccepted bool
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, parameters, _ := strings.Cut(strings.TrimSpace(part), ";")
+		// an encoding with q=0 is refused
+		if quality, ok := strings.CutPrefix(strings.TrimSpace(parameters), "q="); ok {
+			value, err := strconv.ParseFloat(quality, 64)
+			if err != nil || value == 0 {
+				continue
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(coding)) {
+		case encodingBrotli:
+			isBrotliAccepted = true
+		case encodingGzip:
+			isGzipAccepted = true
+		}
+	}
+
+	switch {
+	case isBrotliAccepted:
+		return encodingBrotli
+	case isGzipAccepted:
+		return encodingGzip
+	default:
+		return ""
+	}
+}
+
+// response writer that compresses the body if its headers allow it
+// the decision is taken when the headers are written
+type responseWriterCompress struct {
+	http.ResponseWriter
+	encoding string
+
+	isHeaderWritten bool
+	// nil if the response is sent as is
+	encoder io.WriteCloser
+}
+
+func (w *responseWriterCompress) WriteHeader(status int) {
+	if w.isHeaderWritten {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	// informational responses are followed by the real one
+	if status >= 100 && status < 200 {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.isHeaderWritten = true
+
+	if w.isCompressible(status) {
+		var header http.Header = w.Header()
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		// the ETag of the uncompressed body does not fit the compressed one
+		if ETag := header.Get("ETag"); ETag != "" && !strings.HasPrefix(ETag, "W/") {
+			header.Set("ETag", "W/"+ETag)
+		}
+
+		switch w.encoding {
+		case encodingBrotli:
+			w.encoder = brotli.NewWriterLevel(w.ResponseWriter, 5)
+		case encodingGzip:
+			w.encoder, _ = gzip.NewWriterLevel(w.ResponseWriter, gzip.DefaultCompression)
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriterCompress) Write(b []byte) (int, error) {
+	if !w.isHeaderWritten {
+		// the content type is needed to decide, detect it as net/http would
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.encoder == nil {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.encoder.Write(b)
+}
+
+// send what has been compressed so far
+func (w *responseWriterCompress) Flush() {
+	if w.encoder != nil {
+		if flusher, ok := w.encoder.(interface{ Flush() error }); ok {
+			_ = flusher.Flush()
+		}
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// underlying response writer, so that http.ResponseController can still
+// set the read and write deadlines
+func (w *responseWriterCompress) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// finish the compressed stream
+func (w *responseWriterCompress) Close() error {
+	if w.encoder == nil {
+		return nil
+	}
+	return w.encoder.Close()
+}
+
+// whether a response with the status and the headers written so far is
+// compressed
+func (w *responseWriterCompress) isCompressible(status int) bool {
+	var header http.Header = w.Header()
+
+	switch {
+	case status == http.StatusNoContent,
+		status == http.StatusNotModified,
+		status == http.StatusPartialContent:
+		return false
+	case header.Get("Content-Encoding") != "":
+		return false
+	}
+
+	// too small to be worth it
+	if length, err := strconv.Atoi(header.Get("Content-Length")); err == nil &&
+		length < sizeCompressMin {
+		return false
+	}
+
+	var contentType string = header.Get("Content-Type")
+	for _, prefix := range sliceContentTypesCompressible {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,217 @@
+package middlewares
+
+import (
+	"compress/gzip"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+// body larger than the minimum size that is compressed
+var bodyCompressible string = strings.Repeat(`{"temperature_wet_bulb":26.3}`, 100)
+
+// response of the handler through the compression, to a request with the
+// headers
+func serveCompress(
+	handler http.HandlerFunc,
+	header map[string]string,
+) *httptest.ResponseRecorder {
+	var middleware *Middleware = &Middleware{Logger: slog.New(slog.DiscardHandler)}
+
+	var request *http.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	for key, value := range header {
+		request.Header.Set(key, value)
+	}
+	var recorder *httptest.ResponseRecorder = httptest.NewRecorder()
+	middleware.Compress(handler).ServeHTTP(recorder, request)
+	return recorder
+}
+
+// handler writing the body with the content type and headers
+func handlerBody(contentType string, status int, header map[string]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		for key, value := range header {
+			w.Header().Set(key, value)
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, bodyCompressible)
+	}
+}
+
+// text responses are compressed with the preferred accepted encoding and
+// decode to the body
+func TestCompress(t *testing.T) {
+	var sliceTests = []struct {
+		name           string
+		acceptEncoding string
+		encoding       string
+	}{
+		{"brotli preferred", "gzip, deflate, br", encodingBrotli},
+		{"gzip", "gzip", encodingGzip},
+		{"brotli refused", "br;q=0, gzip;q=0.5", encodingGzip},
+		{"none accepted", "deflate", ""},
+	}
+	for _, test := range sliceTests {
+		t.Run(test.name, func(t *testing.T) {
+			var recorder *httptest.ResponseRecorder = serveCompress(
+				handlerBody("application/json", http.StatusOK, map[string]string{"ETag": `"abc"`}),
+				map[string]string{"Accept-Encoding": test.acceptEncoding},
+			)
+
+			if !strings.Contains(recorder.Header().Get("Vary"), "Accept-Encoding") {
+				t.Error("Vary does not name Accept-Encoding")
+			}
+			var encoding string = recorder.Header().Get("Content-Encoding")
+			if encoding != test.encoding {
+				t.Fatalf("encoding is %q, expected %q", encoding, test.encoding)
+			}
+
+			var reader io.Reader = recorder.Body
+			switch encoding {
+			case encodingBrotli:
+				reader = brotli.NewReader(recorder.Body)
+			case encodingGzip:
+				readerGzip, err := gzip.NewReader(recorder.Body)
+				if err != nil {
+					t.Fatalf("error in reading the gzip stream: %v", err)
+				}
+				reader = readerGzip
+			}
+			body, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("error in decoding the body: %v", err)
+			}
+			if string(body) != bodyCompressible {
+				t.Error("decoded body is not the body written")
+			}
+
+			// the ETag of a compressed body is weak
+			var ETagExpected string = `"abc"`
+			if encoding != "" {
+				ETagExpected = `W/"abc"`
+			}
+			if ETag := recorder.Header().Get("ETag"); ETag != ETagExpected {
+				t.Errorf("ETag is %s, expected %s", ETag, ETagExpected)
+			}
+		})
+	}
+}
+
+// ranged requests, partial responses, event streams, downloads and small
+// bodies are sent as they are
+func TestCompressSkipped(t *testing.T) {
+	var sliceTests = []struct {
+		name          string
+		handler       http.HandlerFunc
+		headerRequest map[string]string
+		// encoding set by the handler, if any
+		encoding string
+	}{
+		{
+			"ranged request",
+			handlerBody("text/csv", http.StatusOK, nil),
+			map[string]string{"Range": "bytes=0-99"},
+			"",
+		},
+		{
+			"partial response",
+			handlerBody("text/csv", http.StatusPartialContent, nil),
+			nil,
+			"",
+		},
+		{
+			"event stream",
+			handlerBody("text/event-stream", http.StatusOK, nil),
+			nil,
+			"",
+		},
+		{
+			"download",
+			handlerBody("application/gzip", http.StatusOK, nil),
+			nil,
+			"",
+		},
+		{
+			"already encoded",
+			handlerBody("text/csv", http.StatusOK, map[string]string{"Content-Encoding": "gzip"}),
+			nil,
+			encodingGzip,
+		},
+		{
+			"small body",
+			handlerBody(
+				"application/json",
+				http.StatusOK,
+				map[string]string{"Content-Length": strconv.Itoa(sizeCompressMin - 1)},
+			),
+			nil,
+			"",
+		},
+	}
+	for _, test := range sliceTests {
+		t.Run(test.name, func(t *testing.T) {
+			var header map[string]string = map[string]string{"Accept-Encoding": "br, gzip"}
+			for key, value := range test.headerRequest {
+				header[key] = value
+			}
+			var recorder *httptest.ResponseRecorder = serveCompress(test.handler, header)
+
+			var encoding string = recorder.Header().Get("Content-Encoding")
+			if encoding != test.encoding {
+				t.Errorf("encoding is %q, expected %q", encoding, test.encoding)
+			}
+			if recorder.Body.String() != bodyCompressible {
+				t.Error("body is not sent as it is")
+			}
+		})
+	}
+}
+
+// events are sent at once through the compression, so that every flush
+// reaches the client
+func TestCompressEventStreamFlush(t *testing.T) {
+	var middleware *Middleware = &Middleware{Logger: slog.New(slog.DiscardHandler)}
+	var isFlushed chan struct{} = make(chan struct{})
+	var isRead chan struct{} = make(chan struct{})
+
+	var server *httptest.Server = httptest.NewServer(middleware.Compress(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			io.WriteString(w, "event: run\ndata: {}\n\n")
+			http.NewResponseController(w).Flush()
+			close(isFlushed)
+			// the stream stays open until the event is read
+			<-isRead
+		}),
+	))
+	defer server.Close()
+	defer close(isRead)
+
+	request, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set("Accept-Encoding", "gzip")
+	response, err := http.DefaultTransport.RoundTrip(request)
+	if err != nil {
+		t.Fatalf("error in sending the request: %v", err)
+	}
+	defer response.Body.Close()
+	<-isFlushed
+
+	if encoding := response.Header.Get("Content-Encoding"); encoding != "" {
+		t.Fatalf("encoding is %q, expected none", encoding)
+	}
+	var buffer []byte = make([]byte, len("event: run\ndata: {}\n\n"))
+	_, err = io.ReadFull(response.Body, buffer)
+	if err != nil || string(buffer) != "event: run\ndata: {}\n\n" {
+		t.Errorf("event read is %q (error %v), expected the event flushed", buffer, err)
+	}
+}
@@ -1,22 +1,53 @@
 package middlewares
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"net/http"
+	"strings"
 )
 
+// key of the CSP nonce in the request context
+type contextKey string
+
+const contextKeyNonce contextKey = "nonce"
+
+// CSP nonce of the request, empty if CommonHeaders did not run
+func Nonce(r *http.Request) string {
+	nonce, _ := r.Context().Value(contextKeyNonce).(string)
+	return nonce
+}
+
 // set common headers
+// a new random CSP nonce is created for every request and added to the
+// request context for the templates
 func (middleware *Middleware) CommonHeaders(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 128 bit random nonce
+		var nonceBytes []byte = make([]byte, 16)
+		_, err := rand.Read(nonceBytes)
+		if err != nil {
+			Logger(r, middleware.Logger).Error("error in creating CSP nonce", "error", err.Error())
+			http.Error(
+				w,
+				http.StatusText(http.StatusInternalServerError),
+				http.StatusInternalServerError,
+			)
+			return
+		}
+		var nonce string = base64.StdEncoding.EncodeToString(nonceBytes)
+
 		// set CSP
 		w.Header().Set(
 			"Content-Security-Policy",
-			`
-				default-src 'self';
-				style-src 'self' fonts.googleapis.com https://unpkg.com;
-				font-src fonts.gstatic.com;
-				script-src 'self' 'nonce-J2B9AHS41HA8' https://unpkg.com;
-				img-src 'self' https://tile.openstreetmap.org https://openstreetmap.org data:;
-			`,
+			strings.Join([]string{
+				"default-src 'self'",
+				"style-src 'self' fonts.googleapis.com https://unpkg.com",
+				"font-src fonts.gstatic.com",
+				"script-src 'self' 'nonce-" + nonce + "' https://unpkg.com",
+				"img-src 'self' https://tile.openstreetmap.org https://openstreetmap.org data:",
+			}, "; "),
 		)
 
 		w.Header().Set("Referrer-Policy", "origin-when-cross-origin")
@@ -26,7 +57,7 @@ func (middleware *Middleware) CommonHeaders(next http.Handler) http.Handler {
 
 		w.Header().Set("Server", "Go")
 
-		// call the next-in-line
-		next.ServeHTTP(w, r)
+		// call the next-in-line with the nonce in the request context
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKeyNonce, nonce)))
 	})
 }
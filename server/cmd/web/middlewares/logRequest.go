@@ -1,32 +1,52 @@
 package middlewares
 
 import (
+	"log/slog"
 	"net/http"
+	"time"
 )
 
-// log all incoming requests to the server
+// log every request once the response is written, with its status, size
+// and latency
+// a panic is logged as a 500 and passed on to RecoverPanic
 func (middleware *Middleware) LogRequests(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// request parameters for logging
-		var ip string = r.RemoteAddr
-		var proto string = r.Proto
-		var method string = r.Method
-		var uri string = r.URL.RequestURI()
+		var timeStart time.Time = time.Now()
+		var writer *responseWriterRecorder = &responseWriterRecorder{ResponseWriter: w}
 
-		// log each request via the application logger
-		middleware.Logger.Info(
-			"received request",
-			"ip",
-			ip,
-			"proto",
-			proto,
-			"method",
-			method,
-			"uri",
-			uri,
-		)
+		defer func() {
+			var status int = writer.Status()
+			var errPanic any = recover()
+			if errPanic != nil {
+				status = http.StatusInternalServerError
+			}
+
+			// server errors are logged as errors
+			var level slog.Level = slog.LevelInfo
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+
+			// log each request via the logger of the request
+			Logger(r, middleware.Logger).LogAttrs(
+				r.Context(),
+				level,
+				"request",
+				slog.String("ip", ClientIP(r)),
+				slog.String("proto", r.Proto),
+				slog.String("method", r.Method),
+				slog.String("uri", r.URL.RequestURI()),
+				slog.Int("status", status),
+				slog.Int("size", writer.size),
+				slog.Duration("duration", time.Since(timeStart)),
+			)
+
+			if errPanic != nil {
+				panic(errPanic)
+			}
+		}()
 
 		// call the next-in-line
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(writer, r)
 	})
 }
@@ -0,0 +1,96 @@
+package middlewares
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/justinas/alice"
+)
+
+// the requests are logged once their response is written, with the
+// request ID, status, size and duration, and server errors as errors
+func TestLogRequests(t *testing.T) {
+	var sliceTests = []struct {
+		name   string
+		handle func(w http.ResponseWriter, r *http.Request)
+		status float64
+		size   float64
+		level  string
+	}{
+		{
+			"ok",
+			func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("hello")) },
+			http.StatusOK,
+			5,
+			"INFO",
+		},
+		{
+			"not found",
+			func(w http.ResponseWriter, r *http.Request) { http.Error(w, "not found", http.StatusNotFound) },
+			http.StatusNotFound,
+			10,
+			"INFO",
+		},
+		{
+			"server error",
+			func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusBadGateway) },
+			http.StatusBadGateway,
+			0,
+			"ERROR",
+		},
+		{
+			"panic",
+			func(w http.ResponseWriter, r *http.Request) { panic("handler") },
+			http.StatusInternalServerError,
+			0,
+			"ERROR",
+		},
+	}
+	for _, test := range sliceTests {
+		t.Run(test.name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			var middleware *Middleware = &Middleware{
+				Logger: slog.New(slog.NewJSONHandler(&buffer, nil)),
+			}
+			var handler http.Handler = alice.New(
+				middleware.SetRequestID,
+				middleware.RecoverPanic,
+				middleware.LogRequests,
+			).ThenFunc(test.handle)
+
+			var request *http.Request = httptest.NewRequest(http.MethodGet, "/cities?run_id=1", nil)
+			request.Header.Set(headerRequestID, "request-1")
+			handler.ServeHTTP(httptest.NewRecorder(), request)
+
+			// the line of the request, after the one of the panic if any
+			var sliceLines []map[string]any = linesLog(t, &buffer)
+			var mapLine map[string]any
+			for _, line := range sliceLines {
+				if line["msg"] == "request" {
+					mapLine = line
+				}
+			}
+			if mapLine == nil {
+				t.Fatalf("request is not logged: %v", sliceLines)
+			}
+			for key, value := range map[string]any{
+				"request_id": "request-1",
+				"method":     http.MethodGet,
+				"uri":        "/cities?run_id=1",
+				"status":     test.status,
+				"size":       test.size,
+				"level":      test.level,
+			} {
+				if mapLine[key] != value {
+					t.Errorf("%s is %v, expected %v", key, mapLine[key], value)
+				}
+			}
+			if _, ok := mapLine["duration"].(float64); !ok {
+				t.Errorf("duration is %v, expected a number", mapLine["duration"])
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// count the requests and record their latency by route pattern, method and
+// status
+// the route is the pattern the mux sets on the request, so this has to
+// come after the middlewares that replace the request. a panic is recorded
+// as a 500 and passed on to RecoverPanic.
+func (middleware *Middleware) RecordMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var timeStart time.Time = time.Now()
+		var writer *responseWriterRecorder = &responseWriterRecorder{ResponseWriter: w}
+
+		defer func() {
+			var status int = writer.Status()
+			var errPanic any = recover()
+			if errPanic != nil {
+				status = http.StatusInternalServerError
+			}
+
+			// requests that match no route are grouped together so that
+			// unknown paths do not create new series
+			var route string = r.Pattern
+			if route == "" {
+				route = "unmatched"
+			}
+
+			var labels []string = []string{route, r.Method, strconv.Itoa(status)}
+			middleware.Metrics.HTTPRequests.WithLabelValues(labels...).Inc()
+			middleware.Metrics.HTTPRequestDuration.WithLabelValues(labels...).Observe(
+				time.Since(timeStart).Seconds(),
+			)
+
+			if errPanic != nil {
+				panic(errPanic)
+			}
+		}()
+
+		// call the next-in-line
+		next.ServeHTTP(writer, r)
+	})
+}
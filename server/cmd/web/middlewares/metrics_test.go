@@ -0,0 +1,112 @@
+package middlewares
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/justinas/alice"
+
+	"github.com/kelaaditya/zomato-weather-union/server/internal/metrics"
+)
+
+// number of requests counted with the labels, by route, method and status
+func countRequests(t *testing.T, metricsWeb *metrics.Metrics, route, method, status string) float64 {
+	t.Helper()
+
+	sliceFamilies, err := metricsWeb.Registry.Gather()
+	if err != nil {
+		t.Fatalf("error in gathering the metrics: %v", err)
+	}
+	for _, family := range sliceFamilies {
+		if family.GetName() != "weather_union_http_requests_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			var mapLabels map[string]string = map[string]string{}
+			for _, label := range metric.GetLabel() {
+				mapLabels[label.GetName()] = label.GetValue()
+			}
+			if mapLabels["route"] == route &&
+				mapLabels["method"] == method &&
+				mapLabels["status"] == status {
+				return metric.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+// the requests are counted by the route pattern of the mux, not by their
+// path, and the unknown paths are grouped together
+func TestRecordMetricsRoute(t *testing.T) {
+	var metricsWeb *metrics.Metrics = metrics.New()
+	var middleware *Middleware = &Middleware{
+		Logger:  slog.New(slog.DiscardHandler),
+		Metrics: metricsWeb,
+	}
+
+	var mux *http.ServeMux = http.NewServeMux()
+	mux.HandleFunc("GET /stations/{locality_id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("locality_id") == "ZWL000002" {
+			http.NotFound(w, r)
+		}
+	})
+	var handler http.Handler = alice.New(middleware.RecordMetrics).Then(mux)
+
+	for _, path := range []string{
+		"/stations/ZWL000001",
+		"/stations/ZWL000003",
+		"/stations/ZWL000002",
+		"/unknown/ZWL000001",
+		"/unknown/ZWL000002",
+	} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	var sliceTests = []struct {
+		route  string
+		status string
+		count  float64
+	}{
+		{"GET /stations/{locality_id}", "200", 2},
+		{"GET /stations/{locality_id}", "404", 1},
+		{"unmatched", "404", 2},
+		{"/stations/ZWL000001", "200", 0},
+		{"/unknown/ZWL000001", "404", 0},
+	}
+	for _, test := range sliceTests {
+		var count float64 = countRequests(t, metricsWeb, test.route, http.MethodGet, test.status)
+		if count != test.count {
+			t.Errorf("requests of %s with %s are %v, expected %v", test.route, test.status, count, test.count)
+		}
+	}
+}
+
+// a panic is counted as a 500 and passed on
+func TestRecordMetricsPanic(t *testing.T) {
+	var metricsWeb *metrics.Metrics = metrics.New()
+	var middleware *Middleware = &Middleware{
+		Logger:  slog.New(slog.DiscardHandler),
+		Metrics: metricsWeb,
+	}
+
+	var mux *http.ServeMux = http.NewServeMux()
+	mux.HandleFunc("GET /cities", func(w http.ResponseWriter, r *http.Request) {
+		panic("cities")
+	})
+	var handler http.Handler = alice.New(middleware.RecordMetrics).Then(mux)
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("panic is not passed on")
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/cities", nil))
+	}()
+	if count := countRequests(t, metricsWeb, "GET /cities", http.MethodGet, "500"); count != 1 {
+		t.Errorf("panics counted are %v, expected 1", count)
+	}
+}
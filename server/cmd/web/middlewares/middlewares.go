@@ -1,7 +1,20 @@
 package middlewares
 
-import "log/slog"
+import (
+	"log/slog"
+	"net/netip"
+
+	"github.com/kelaaditya/zomato-weather-union/server/internal/metrics"
+)
 
 type Middleware struct {
-	Logger *slog.Logger
+	Logger  *slog.Logger
+	Metrics *metrics.Metrics
+
+	// API keys and rate limits, see InitializeAPIKeys
+	authenticator *authenticator
+	// administrators of /admin, see InitializeAdmin
+	administrators *administrators
+	// proxies whose X-Forwarded-For is read, see InitializeTrustedProxies
+	trustedProxies []netip.Prefix
 }
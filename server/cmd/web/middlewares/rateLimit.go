@@ -0,0 +1,124 @@
+package middlewares
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// limiters not used for this long are removed
+const durationLimiterIdle time.Duration = 10 * time.Minute
+
+// token bucket limiter of a client, with the time it was last used
+type limiterClient struct {
+	limiter      *rate.Limiter
+	timeLastSeen time.Time
+}
+
+// token bucket limiters by client (API key or IP)
+type limiters struct {
+	mutex      sync.Mutex
+	mapClients map[string]*limiterClient
+	// idle limiters are removed at most once per idle duration
+	timeLastSweep time.Time
+}
+
+// create an empty set of limiters
+func newLimiters() *limiters {
+	return &limiters{
+		mapClients:    make(map[string]*limiterClient),
+		timeLastSweep: time.Now(),
+	}
+}
+
+// take a token from the bucket of the client, created with the rate
+// (tokens per second) and the burst (size of the bucket) on first use
+// the rate limit headers are set on the response and a 429 is written if
+// the bucket is empty, in which case false is returned
+func (limitersClients *limiters) allow(
+	w http.ResponseWriter,
+	client string,
+	limit rate.Limit,
+	burst int,
+) bool {
+	var timeNow time.Time = time.Now()
+	var limiter *rate.Limiter = limitersClients.get(client, limit, burst, timeNow)
+
+	var isAllowed bool = limiter.AllowN(timeNow, 1)
+	var tokens float64 = limiter.TokensAt(timeNow)
+
+	// size of the bucket, requests left in it and seconds until it is full
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(burst))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(max(0, int(tokens))))
+	w.Header().Set(
+		"X-RateLimit-Reset",
+		strconv.Itoa(secondsUntil(float64(burst)-tokens, limit)),
+	)
+	if isAllowed {
+		return true
+	}
+
+	// seconds until the next token
+	w.Header().Set("Retry-After", strconv.Itoa(max(1, secondsUntil(1-tokens, limit))))
+	http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+	return false
+}
+
+// tokens left in the bucket of the client, without taking one
+func (limitersClients *limiters) tokens(
+	client string,
+	limit rate.Limit,
+	burst int,
+) float64 {
+	var timeNow time.Time = time.Now()
+	return limitersClients.get(client, limit, burst, timeNow).TokensAt(timeNow)
+}
+
+// limiter of the client, the rate and burst of an existing limiter are
+// updated in case they changed
+func (limitersClients *limiters) get(
+	client string,
+	limit rate.Limit,
+	burst int,
+	timeNow time.Time,
+) *rate.Limiter {
+	limitersClients.mutex.Lock()
+	defer limitersClients.mutex.Unlock()
+
+	// remove the idle limiters, their buckets are full again by now
+	if timeNow.Sub(limitersClients.timeLastSweep) > durationLimiterIdle {
+		for key, limiterIdle := range limitersClients.mapClients {
+			if timeNow.Sub(limiterIdle.timeLastSeen) > durationLimiterIdle {
+				delete(limitersClients.mapClients, key)
+			}
+		}
+		limitersClients.timeLastSweep = timeNow
+	}
+
+	limiterOfClient, ok := limitersClients.mapClients[client]
+	if !ok {
+		limiterOfClient = &limiterClient{limiter: rate.NewLimiter(limit, burst)}
+		limitersClients.mapClients[client] = limiterOfClient
+	}
+	if limiterOfClient.limiter.Limit() != limit {
+		limiterOfClient.limiter.SetLimitAt(timeNow, limit)
+	}
+	if limiterOfClient.limiter.Burst() != burst {
+		limiterOfClient.limiter.SetBurstAt(timeNow, burst)
+	}
+	limiterOfClient.timeLastSeen = timeNow
+
+	return limiterOfClient.limiter
+}
+
+// whole seconds it takes to refill the tokens at the rate
+func secondsUntil(tokens float64, limit rate.Limit) int {
+	if tokens <= 0 || limit <= 0 {
+		return 0
+	}
+	return int(math.Ceil(tokens / float64(limit)))
+}
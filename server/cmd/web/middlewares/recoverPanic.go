@@ -6,10 +6,15 @@ import (
 )
 
 // recover panic, log panic message, and continue middleware chain
+// http.ErrAbortHandler is passed on, so that net/http aborts the response
 func (middleware *Middleware) RecoverPanic(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+
 				// set HTTP connection close
 				w.Header().Set("Connection", "close")
 
@@ -19,7 +24,7 @@ func (middleware *Middleware) RecoverPanic(next http.Handler) http.Handler {
 				var requestURI string = r.RequestURI
 
 				// log request parameters that generated error
-				middleware.Logger.Error(
+				Logger(r, middleware.Logger).Error(
 					fmt.Sprintf("error: %s", err),
 					"method",
 					HTTPMethod,
@@ -0,0 +1,74 @@
+package middlewares
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/justinas/alice"
+)
+
+// a response aborted after its body started reaches the client as a
+// broken transfer, compressed or not, instead of a short body
+func TestRecoverPanicAbort(t *testing.T) {
+	var middleware *Middleware = &Middleware{Logger: slog.New(slog.DiscardHandler)}
+
+	var handler http.Handler = alice.New(
+		middleware.RecoverPanic,
+		middleware.Compress,
+	).ThenFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		io.WriteString(w, strings.Repeat("a,b,c\n", 1000))
+		http.NewResponseController(w).Flush()
+		panic(http.ErrAbortHandler)
+	})
+	var server *httptest.Server = httptest.NewServer(handler)
+	defer server.Close()
+
+	for _, acceptEncoding := range []string{"identity", "gzip"} {
+		t.Run(acceptEncoding, func(t *testing.T) {
+			request, err := http.NewRequest(http.MethodGet, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			request.Header.Set("Accept-Encoding", acceptEncoding)
+
+			response, err := http.DefaultClient.Do(request)
+			if err != nil {
+				t.Fatalf("error in sending the request: %v", err)
+			}
+			defer response.Body.Close()
+			if response.StatusCode != http.StatusOK {
+				t.Fatalf("status is %d, expected %d", response.StatusCode, http.StatusOK)
+			}
+
+			_, err = io.ReadAll(response.Body)
+			if err == nil {
+				t.Error("aborted response was read without error")
+			}
+		})
+	}
+}
+
+// other panics are recovered as a 500
+func TestRecoverPanic(t *testing.T) {
+	var middleware *Middleware = &Middleware{Logger: slog.New(slog.DiscardHandler)}
+
+	var handler http.Handler = middleware.RecoverPanic(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("failure")
+		}),
+	)
+	var recorder *httptest.ResponseRecorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("status is %d, expected %d", recorder.Code, http.StatusInternalServerError)
+	}
+	if recorder.Header().Get("Connection") != "close" {
+		t.Error("connection is not closed")
+	}
+}
@@ -0,0 +1,97 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// header of the request ID, read from the client or the proxy in front and
+// sent back in the response
+const headerRequestID string = "X-Request-ID"
+
+// longest request ID accepted from a client
+const lengthRequestIDMax int = 128
+
+const (
+	contextKeyRequestID contextKey = "request_id"
+	contextKeyLogger    contextKey = "logger"
+)
+
+// ID of the request, empty if SetRequestID did not run
+func RequestID(r *http.Request) string {
+	requestID, _ := r.Context().Value(contextKeyRequestID).(string)
+	return requestID
+}
+
+// logger of the request, with its ID
+// the fallback logger is returned if SetRequestID did not run
+func Logger(r *http.Request, fallback *slog.Logger) *slog.Logger {
+	logger, ok := r.Context().Value(contextKeyLogger).(*slog.Logger)
+	if !ok {
+		return fallback
+	}
+	return logger
+}
+
+// give every request an ID, the X-Request-ID header if the client sent a
+// valid one and a new random ID otherwise
+// the ID is sent back in the response, added to the span of the request
+// and to a logger in the request context, so that every log line of the
+// request carries it
+func (middleware *Middleware) SetRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var requestID string = r.Header.Get(headerRequestID)
+		if !isRequestIDValid(requestID) {
+			// 128 bit random ID
+			var requestIDBytes []byte = make([]byte, 16)
+			// never returns an error
+			_, _ = rand.Read(requestIDBytes)
+			requestID = hex.EncodeToString(requestIDBytes)
+		}
+
+		w.Header().Set(headerRequestID, requestID)
+		trace.SpanFromContext(r.Context()).SetAttributes(
+			attribute.String("http.request.id", requestID),
+		)
+
+		// logger with the request ID
+		var logger *slog.Logger = middleware.Logger.With("request_id", requestID)
+
+		var ctx context.Context = context.WithValue(
+			r.Context(),
+			contextKeyRequestID,
+			requestID,
+		)
+		ctx = context.WithValue(ctx, contextKeyLogger, logger)
+
+		// call the next-in-line with the ID and logger in the request
+		// context
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// a request ID from a client is used if it is not too long and only has
+// letters, digits and the characters - _ . : so that it cannot break the
+// log lines
+func isRequestIDValid(requestID string) bool {
+	if requestID == "" || len(requestID) > lengthRequestIDMax {
+		return false
+	}
+	for _, character := range requestID {
+		switch {
+		case character >= 'a' && character <= 'z',
+			character >= 'A' && character <= 'Z',
+			character >= '0' && character <= '9',
+			character == '-', character == '_', character == '.', character == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
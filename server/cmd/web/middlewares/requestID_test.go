@@ -0,0 +1,118 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/justinas/alice"
+)
+
+// log lines written to the buffer, decoded from JSON
+func linesLog(t *testing.T, buffer *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var sliceLines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var mapLine map[string]any
+		err := json.Unmarshal([]byte(line), &mapLine)
+		if err != nil {
+			t.Fatalf("error in decoding the log line %q: %v", line, err)
+		}
+		sliceLines = append(sliceLines, mapLine)
+	}
+	return sliceLines
+}
+
+// the request ID of the client is kept if it is valid and replaced by a
+// random one otherwise, sent back and given to the logger of the request
+func TestSetRequestID(t *testing.T) {
+	var sliceTests = []struct {
+		name      string
+		requestID string
+		isKept    bool
+	}{
+		{"valid", "f3a1-req_42.retry:1", true},
+		{"longest", strings.Repeat("a", lengthRequestIDMax), true},
+		{"missing", "", false},
+		{"too long", strings.Repeat("a", lengthRequestIDMax+1), false},
+		{"space", "request 1", false},
+		{"new line", "request\nlevel=ERROR", false},
+		{"quote", `request"1`, false},
+	}
+	for _, test := range sliceTests {
+		t.Run(test.name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			var middleware *Middleware = &Middleware{
+				Logger: slog.New(slog.NewJSONHandler(&buffer, nil)),
+			}
+			var requestIDHandler string
+			var handler http.Handler = alice.New(middleware.SetRequestID).ThenFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					requestIDHandler = RequestID(r)
+					Logger(r, nil).Info("handler")
+				},
+			)
+
+			var request *http.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			if test.requestID != "" {
+				request.Header.Set(headerRequestID, test.requestID)
+			}
+			var recorder *httptest.ResponseRecorder = httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			var requestID string = recorder.Header().Get(headerRequestID)
+			if test.isKept && requestID != test.requestID {
+				t.Errorf("request ID is %q, expected %q", requestID, test.requestID)
+			}
+			if !test.isKept {
+				// 128 bit random ID, in hex
+				_, err := hex.DecodeString(requestID)
+				if err != nil || len(requestID) != 32 || requestID == test.requestID {
+					t.Errorf("request ID is %q, expected a new random ID", requestID)
+				}
+			}
+			if requestIDHandler != requestID {
+				t.Errorf("request ID of the handler is %q, expected %q", requestIDHandler, requestID)
+			}
+
+			var sliceLines []map[string]any = linesLog(t, &buffer)
+			if len(sliceLines) != 1 || sliceLines[0]["request_id"] != requestID {
+				t.Errorf("log lines are %v, expected one with the request ID %q", sliceLines, requestID)
+			}
+		})
+	}
+
+	// the IDs of the requests without one differ
+	var middleware *Middleware = &Middleware{Logger: slog.New(slog.DiscardHandler)}
+	var handler http.Handler = middleware.SetRequestID(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	var mapRequestIDs map[string]bool = map[string]bool{}
+	for range 10 {
+		var recorder *httptest.ResponseRecorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		mapRequestIDs[recorder.Header().Get(headerRequestID)] = true
+	}
+	if len(mapRequestIDs) != 10 {
+		t.Errorf("%d distinct request IDs in 10 requests", len(mapRequestIDs))
+	}
+}
+
+// the handlers without SetRequestID get the fallback logger and no ID
+func TestLoggerFallback(t *testing.T) {
+	var logger *slog.Logger = slog.New(slog.DiscardHandler)
+	var request *http.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	if Logger(request, logger) != logger {
+		t.Error("logger is not the fallback")
+	}
+	if RequestID(request) != "" {
+		t.Errorf("request ID is %q, expected none", RequestID(request))
+	}
+}
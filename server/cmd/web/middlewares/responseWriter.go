@@ -0,0 +1,41 @@
+package middlewares
+
+import "net/http"
+
+// response writer that keeps the status code and the number of bytes of
+// the body for the metrics and the access logs
+type responseWriterRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (w *responseWriterRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriterRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	size, err := w.ResponseWriter.Write(b)
+	w.size += size
+	return size, err
+}
+
+// underlying response writer, so that http.ResponseController can still
+// flush and set the read and write deadlines
+func (w *responseWriterRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// status code of the response, 200 if nothing was written
+func (w *responseWriterRecorder) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
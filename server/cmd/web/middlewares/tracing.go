@@ -0,0 +1,25 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// name the span of the request after the route pattern matched by the
+// mux, e.g. "GET /stations/{locality_id}"
+// the pattern is set on the request by the mux, so this has to come after
+// the middlewares that replace the request
+func (middleware *Middleware) NameSpan(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// call the next-in-line
+		next.ServeHTTP(w, r)
+
+		if r.Pattern != "" {
+			var span trace.Span = trace.SpanFromContext(r.Context())
+			span.SetName(r.Pattern)
+			span.SetAttributes(attribute.String("http.route", r.Pattern))
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package middlewares
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/justinas/alice"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// the span of a request is named after the route pattern of the mux and
+// keeps its name when no route matches
+func TestNameSpan(t *testing.T) {
+	var middleware *Middleware = &Middleware{Logger: slog.New(slog.DiscardHandler)}
+
+	var mux *http.ServeMux = http.NewServeMux()
+	mux.HandleFunc("GET /stations/{locality_id}", func(w http.ResponseWriter, r *http.Request) {})
+	var handler http.Handler = alice.New(middleware.SetRequestID, middleware.NameSpan).Then(mux)
+
+	var sliceTests = []struct {
+		name  string
+		path  string
+		span  string
+		route string
+	}{
+		{"route", "/stations/ZWL000001", "GET /stations/{locality_id}", "GET /stations/{locality_id}"},
+		{"no route", "/unknown", "http", ""},
+	}
+	for _, test := range sliceTests {
+		t.Run(test.name, func(t *testing.T) {
+			var recorder *tracetest.SpanRecorder = tracetest.NewSpanRecorder()
+			var provider *sdktrace.TracerProvider = sdktrace.NewTracerProvider(
+				sdktrace.WithSpanProcessor(recorder),
+			)
+
+			// the span of otelhttp, around the middlewares
+			ctx, span := provider.Tracer("test").Start(context.Background(), "http")
+			var request *http.Request = httptest.NewRequest(http.MethodGet, test.path, nil)
+			request.Header.Set(headerRequestID, "request-1")
+			var responseRecorder *httptest.ResponseRecorder = httptest.NewRecorder()
+			handler.ServeHTTP(responseRecorder, request.WithContext(ctx))
+			span.End()
+
+			var sliceSpans []sdktrace.ReadOnlySpan = recorder.Ended()
+			if len(sliceSpans) != 1 {
+				t.Fatalf("%d spans ended, expected 1", len(sliceSpans))
+			}
+			if sliceSpans[0].Name() != test.span {
+				t.Errorf("span is named %q, expected %q", sliceSpans[0].Name(), test.span)
+			}
+
+			var mapAttributes map[attribute.Key]string = map[attribute.Key]string{}
+			for _, keyValue := range sliceSpans[0].Attributes() {
+				mapAttributes[keyValue.Key] = keyValue.Value.Emit()
+			}
+			if mapAttributes["http.route"] != test.route {
+				t.Errorf("route of the span is %q, expected %q", mapAttributes["http.route"], test.route)
+			}
+			if mapAttributes["http.request.id"] != "request-1" {
+				t.Errorf("request ID of the span is %q, expected \"request-1\"", mapAttributes["http.request.id"])
+			}
+		})
+	}
+}
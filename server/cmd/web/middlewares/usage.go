@@ -0,0 +1,148 @@
+package middlewares
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// the usage of the keys is written to the database every interval, in one
+// batch, instead of on every request
+// the daily quotas are checked against the usage of the last write plus
+// the requests served since, so the servers may go over a quota by what
+// the others (or a restarted one) serve in an interval
+const intervalAPIKeyUsageFlush time.Duration = 10 * time.Second
+
+// requests of a key on a UTC day
+type usageKey struct {
+	day time.Time
+	// requests of the day by all the servers, as of the last write
+	numberOfRequestsSaved int
+	// requests of this server not written yet
+	numberOfRequestsPending int
+}
+
+// requests of the keys, see FlushAPIKeyUsage
+type usage struct {
+	mutex   sync.Mutex
+	mapKeys map[uuid.UUID]*usageKey
+}
+
+// create an empty usage
+func newUsage() *usage {
+	return &usage{mapKeys: make(map[uuid.UUID]*usageKey)}
+}
+
+// count a request of the key and return the number of requests of the key
+// today (UTC), this one included
+func (usage *usage) add(APIKeyID uuid.UUID) int {
+	usage.mutex.Lock()
+	defer usage.mutex.Unlock()
+
+	var day time.Time = time.Now().UTC().Truncate(24 * time.Hour)
+	usageOfKey, ok := usage.mapKeys[APIKeyID]
+	if !ok {
+		usageOfKey = &usageKey{day: day}
+		usage.mapKeys[APIKeyID] = usageOfKey
+	}
+	// the requests written are those of the previous day, the pending
+	// ones are written to the new day
+	if !usageOfKey.day.Equal(day) {
+		usageOfKey.day = day
+		usageOfKey.numberOfRequestsSaved = 0
+	}
+
+	usageOfKey.numberOfRequestsPending++
+	return usageOfKey.numberOfRequestsSaved + usageOfKey.numberOfRequestsPending
+}
+
+// take the pending requests of the keys, to be written
+func (usage *usage) takePending() map[uuid.UUID]int {
+	usage.mutex.Lock()
+	defer usage.mutex.Unlock()
+
+	var mapNumberOfRequests map[uuid.UUID]int = make(map[uuid.UUID]int)
+	for APIKeyID, usageOfKey := range usage.mapKeys {
+		if usageOfKey.numberOfRequestsPending > 0 {
+			mapNumberOfRequests[APIKeyID] = usageOfKey.numberOfRequestsPending
+			usageOfKey.numberOfRequestsPending = 0
+		}
+	}
+	return mapNumberOfRequests
+}
+
+// store the requests of the keys after a write, or give the taken
+// requests back if the write failed
+func (usage *usage) saved(
+	mapNumberOfRequestsTaken map[uuid.UUID]int,
+	mapNumberOfRequestsToday map[uuid.UUID]int,
+	isSaved bool,
+) {
+	usage.mutex.Lock()
+	defer usage.mutex.Unlock()
+
+	var day time.Time = time.Now().UTC().Truncate(24 * time.Hour)
+	for APIKeyID, numberOfRequests := range mapNumberOfRequestsTaken {
+		usageOfKey, ok := usage.mapKeys[APIKeyID]
+		if !ok {
+			continue
+		}
+		if !isSaved {
+			usageOfKey.numberOfRequestsPending += numberOfRequests
+			continue
+		}
+		if usageOfKey.day.Equal(day) {
+			usageOfKey.numberOfRequestsSaved = mapNumberOfRequestsToday[APIKeyID]
+		}
+	}
+
+	// keys without requests today are forgotten
+	maps.DeleteFunc(usage.mapKeys, func(_ uuid.UUID, usageOfKey *usageKey) bool {
+		return !usageOfKey.day.Equal(day) && usageOfKey.numberOfRequestsPending == 0
+	})
+}
+
+// write the usage of the keys every interval until the context is done
+// the caller writes the last requests with FlushAPIKeyUsage once the
+// server has stopped
+func (middleware *Middleware) RunAPIKeyUsageFlush(ctx context.Context) {
+	var ticker *time.Ticker = time.NewTicker(intervalAPIKeyUsageFlush)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := middleware.FlushAPIKeyUsage(ctx)
+			if err != nil {
+				middleware.Logger.Error(
+					"error in writing api key usage",
+					"error",
+					err.Error(),
+				)
+			}
+		}
+	}
+}
+
+// write the requests of the keys since the last write to the database
+// the requests are kept for the next write if this one fails
+func (middleware *Middleware) FlushAPIKeyUsage(ctx context.Context) error {
+	var usage *usage = middleware.authenticator.usage
+	var mapNumberOfRequests map[uuid.UUID]int = usage.takePending()
+	if len(mapNumberOfRequests) == 0 {
+		usage.saved(mapNumberOfRequests, nil, true)
+		return nil
+	}
+
+	mapNumberOfRequestsToday, err := middleware.authenticator.model.AddAPIKeyUsage(
+		ctx,
+		mapNumberOfRequests,
+	)
+	usage.saved(mapNumberOfRequests, mapNumberOfRequestsToday, err == nil)
+	return err
+}
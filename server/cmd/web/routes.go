@@ -0,0 +1,88 @@
+package main
+
+import (
+	"io/fs"
+	"net/http"
+
+	"github.com/justinas/alice"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/scopes"
+)
+
+// routes of the web server on a new mux, with the patterns they are
+// registered with
+func (app *application) routes(filesStatic fs.FS) (*http.ServeMux, []string) {
+	// scopes of the API routes
+	var requireRead alice.Chain = alice.New(app.middlewares.RequireScope(scopes.Read))
+	var requireExport alice.Chain = alice.New(app.middlewares.RequireScope(scopes.Export))
+	var requireAdmin alice.Chain = alice.New(app.middlewares.RequireAdmin)
+
+	// create new HTTP multiplexer
+	var mux *http.ServeMux = http.NewServeMux()
+	// patterns of the routes, in their order of registration
+	var slicePatterns []string
+	var handle = func(pattern string, handler http.Handler) {
+		mux.Handle(pattern, handler)
+		slicePatterns = append(slicePatterns, pattern)
+	}
+
+	// liveness and readiness probes
+	handle("GET /healthz", app.handlers.Healthz())
+	handle("GET /readyz", app.handlers.Readyz())
+
+	// static file server for the ui files
+	var fileServerUI http.Handler = http.FileServerFS(filesStatic)
+	// handle req
+	handle("GET /static/", http.StripPrefix("/static", fileServerUI))
+
+	// downloads catalogue, files and stable links to the newest files
+	handle("GET /downloads/{$}", app.handlers.Downloads())
+	handle("GET /downloads/{file}", app.handlers.DownloadsFile())
+	handle("GET /downloads/latest/{dataset}", app.handlers.DownloadsLatest())
+
+	// attaching the home handler to the mux
+	// restrict subtree paths using `${1}`
+	handle("GET /{$}", app.handlers.Home())
+
+	// server-sent events of the new runs, for the live map
+	handle("GET /events", app.handlers.Events())
+
+	// historical map stepping through past runs
+	handle("GET /history", app.handlers.History())
+
+	// details of a single station
+	handle("GET /stations/{locality_id}", app.handlers.Station())
+
+	// statistics of the cities
+	handle("GET /cities", app.handlers.Cities())
+	handle("GET /cities/{name}", app.handlers.City())
+
+	// index of runs and calculations of a run (by ID or time)
+	handle("GET /api/v1/runs", requireRead.Then(app.handlers.Runs()))
+	handle("GET /api/v1/calculations", requireRead.Then(app.handlers.Calculations()))
+
+	// statistics of the cities in a run (by ID or time)
+	handle("GET /api/v1/cities", requireRead.Then(app.handlers.CitiesAPI()))
+	handle("GET /api/v1/cities/{name}", requireRead.Then(app.handlers.CityAPI()))
+
+	// wet bulb temperature contours as GeoJSON
+	handle("GET /api/v1/contours", requireRead.Then(app.handlers.ContoursWetBulb()))
+
+	// OpenAPI document of the JSON API and its reference page
+	handle("GET /api/openapi.json", app.handlers.OpenAPI())
+	handle("GET /api/docs", app.handlers.Docs())
+
+	// on-demand export of the wet bulb temperature dataset
+	handle("GET /api/v1/export", requireExport.Then(app.handlers.Export()))
+
+	// admin pages of the stations and runs, behind basic authentication
+	// and CSRF tokens
+	handle("GET /admin/{$}", requireAdmin.Then(app.handlers.Admin()))
+	handle("GET /admin/stations", requireAdmin.Then(app.handlers.AdminStations()))
+	handle("GET /admin/stations/{locality_id}", requireAdmin.Then(app.handlers.AdminStation()))
+	handle("POST /admin/stations/{locality_id}", requireAdmin.Then(app.handlers.AdminStationUpdate()))
+	handle("POST /admin/stations/{locality_id}/active", requireAdmin.Then(app.handlers.AdminStationActive()))
+	handle("GET /admin/runs", requireAdmin.Then(app.handlers.AdminRuns()))
+	handle("POST /admin/runs/{run_id}/recalculate", requireAdmin.Then(app.handlers.AdminRunRecalculate()))
+
+	return mux, slicePatterns
+}
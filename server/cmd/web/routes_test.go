@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/kelaaditya/zomato-weather-union/server/api"
+	"github.com/kelaaditya/zomato-weather-union/server/cmd/web/handlers"
+	"github.com/kelaaditya/zomato-weather-union/server/cmd/web/middlewares"
+)
+
+// routes of the pages, probes and files, which are not part of the JSON
+// API and so not in the OpenAPI document
+var sliceRoutesUndocumented []string = []string{
+	"GET /healthz",
+	"GET /readyz",
+	"GET /static/",
+	"GET /downloads/{$}",
+	"GET /downloads/{file}",
+	"GET /downloads/latest/{dataset}",
+	"GET /{$}",
+	"GET /history",
+	"GET /stations/{locality_id}",
+	"GET /cities",
+	"GET /cities/{name}",
+	"GET /api/openapi.json",
+	"GET /api/docs",
+	"GET /admin/{$}",
+	"GET /admin/stations",
+	"GET /admin/stations/{locality_id}",
+	"POST /admin/stations/{locality_id}",
+	"POST /admin/stations/{locality_id}/active",
+	"GET /admin/runs",
+	"POST /admin/runs/{run_id}/recalculate",
+}
+
+// every route of the web server but the pages is in the OpenAPI document,
+// and every operation of the document is a route of the web server
+func TestRoutesMatchOpenAPI(t *testing.T) {
+	var app *application = &application{
+		handlers:    &handlers.Handler{Logger: slog.New(slog.DiscardHandler)},
+		middlewares: &middlewares.Middleware{Logger: slog.New(slog.DiscardHandler)},
+	}
+	_, slicePatterns := app.routes(fstest.MapFS{})
+
+	// operations of the document, as route patterns
+	var document struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	err := json.Unmarshal(api.OpenAPI, &document)
+	if err != nil {
+		t.Fatalf("error in decoding the openapi document: %v", err)
+	}
+	var sliceOperations []string
+	for path, mapOperations := range document.Paths {
+		for method := range mapOperations {
+			switch method {
+			case "get", "put", "post", "delete", "options", "head", "patch", "trace":
+				sliceOperations = append(sliceOperations, strings.ToUpper(method)+" "+path)
+			}
+		}
+	}
+
+	for _, pattern := range slicePatterns {
+		if slices.Contains(sliceRoutesUndocumented, pattern) {
+			continue
+		}
+		if !slices.Contains(sliceOperations, pattern) {
+			t.Errorf("route %s is not in the openapi document", pattern)
+		}
+	}
+	for _, operation := range sliceOperations {
+		if !slices.Contains(slicePatterns, operation) {
+			t.Errorf("operation %s of the openapi document is not a route", operation)
+		}
+	}
+	// the undocumented routes are not left behind once removed
+	for _, pattern := range sliceRoutesUndocumented {
+		if !slices.Contains(slicePatterns, pattern) {
+			t.Errorf("undocumented route %s is not a route", pattern)
+		}
+	}
+
+}
@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// entry of the cache with the time it expires
+type entry[V any] struct {
+	value      V
+	timeExpiry time.Time
+}
+
+// in-process cache of values by key
+// concurrent loads of the same key are de-duplicated, so that a missing
+// key is only loaded once however many requests ask for it. entries expire
+// after the TTL and the oldest entry is evicted once there are more than
+// NumberOfEntriesMax.
+type Cache[V any] struct {
+	TTL                time.Duration
+	NumberOfEntriesMax int
+
+	mutex sync.Mutex
+	// entries by key and the keys in the order they were added
+	mapEntries map[string]entry[V]
+	sliceKeys  []string
+	// loads in progress
+	group singleflight.Group
+	// incremented by Clear and Delete, so that loads started before them
+	// are not stored
+	generation uint64
+}
+
+// create a new cache
+func New[V any](TTL time.Duration, numberOfEntriesMax int) *Cache[V] {
+	return &Cache[V]{
+		TTL:                TTL,
+		NumberOfEntriesMax: numberOfEntriesMax,
+		mapEntries:         make(map[string]entry[V]),
+	}
+}
+
+// value of the key, loaded with load if it is not in the cache
+// errors are not cached
+func (cache *Cache[V]) Get(key string, load func() (V, error)) (V, error) {
+	cache.mutex.Lock()
+	cachedEntry, ok := cache.mapEntries[key]
+	var generation uint64 = cache.generation
+	cache.mutex.Unlock()
+	if ok && time.Now().Before(cachedEntry.timeExpiry) {
+		return cachedEntry.value, nil
+	}
+
+	// load once for all the concurrent callers
+	// callers after a clear do not join the loads started before it
+	var keyGroup string = strconv.FormatUint(generation, 10) + ":" + key
+	value, err, _ := cache.group.Do(keyGroup, func() (any, error) {
+		value, err := load()
+		if err != nil {
+			return value, err
+		}
+		cache.set(key, value, generation)
+		return value, nil
+	})
+	return value.(V), err
+}
+
+// value of the key if it is in the cache and has not expired, without
+// loading it
+func (cache *Cache[V]) Peek(key string) (V, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cachedEntry, ok := cache.mapEntries[key]
+	if !ok || !time.Now().Before(cachedEntry.timeExpiry) {
+		var zero V
+		return zero, false
+	}
+	return cachedEntry.value, true
+}
+
+// store the value of the key
+func (cache *Cache[V]) Set(key string, value V) {
+	cache.mutex.Lock()
+	var generation uint64 = cache.generation
+	cache.mutex.Unlock()
+
+	cache.set(key, value, generation)
+}
+
+// remove all the entries
+func (cache *Cache[V]) Clear() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.mapEntries = make(map[string]entry[V])
+	cache.sliceKeys = nil
+	cache.generation++
+}
+
+// remove the entry of the key
+// loads in progress may have read the value before it was removed, so
+// they are not stored either
+func (cache *Cache[V]) Delete(key string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if _, ok := cache.mapEntries[key]; !ok {
+		cache.generation++
+		return
+	}
+	delete(cache.mapEntries, key)
+	var index int = slices.Index(cache.sliceKeys, key)
+	cache.sliceKeys = slices.Delete(cache.sliceKeys, index, index+1)
+	cache.generation++
+}
+
+// store a value loaded in the given generation
+func (cache *Cache[V]) set(key string, value V, generation uint64) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	// cleared while loading, the value may be stale
+	if generation != cache.generation {
+		return
+	}
+
+	if _, ok := cache.mapEntries[key]; !ok {
+		cache.sliceKeys = append(cache.sliceKeys, key)
+	}
+	cache.mapEntries[key] = entry[V]{
+		value:      value,
+		timeExpiry: time.Now().Add(cache.TTL),
+	}
+
+	// evict the oldest entries
+	for len(cache.sliceKeys) > cache.NumberOfEntriesMax {
+		delete(cache.mapEntries, cache.sliceKeys[0])
+		cache.sliceKeys = cache.sliceKeys[1:]
+	}
+}
@@ -2,20 +2,45 @@ package config
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/tracing"
 )
 
+// returned by New when the effective config was printed (-print-config),
+// after which the binary exits
+var ErrConfigPrinted = errors.New("config printed")
+
 type Config struct {
+	// binary the config is for (web, cron or export), decides which
+	// settings are required
+	Component string
+	// subcommands of the binary by name, e.g. `web migrate`
+	Subcommands map[string]Subcommand
+	// subcommand of the command line, empty when the binary itself runs
+	Subcommand string
+
 	DB          *pgxpool.Pool
 	Environment *Environment
 	Logger      *slog.Logger
+	// flush the spans left and stop the tracer provider, before exit
+	ShutdownTracing func(ctx context.Context) error
+}
+
+// settings and resources of a subcommand
+// a subcommand needs only the settings of its component and is not traced
+type Subcommand struct {
+	// component of the required settings, e.g. migrate
+	Component string
+	// open the database connection pool
+	IsDatabaseUsed bool
 }
 
 func (config *Config) New(ctx context.Context) error {
-	// initialize logger
-	config.initializeLogger()
+	// initialize logger with the default settings
+	config.initializeLogger(newEnvironmentDefault())
 
 	// initialize environment
 	err := config.initializeEnvironment()
@@ -23,10 +48,39 @@ func (config *Config) New(ctx context.Context) error {
 		return err
 	}
 
-	// get database URL from environment
+	// initialize logger with the configured format and level
+	config.initializeLogger(*config.Environment)
+
+	// subcommands are not traced and open the database only if they use it
+	if config.Subcommand != "" {
+		config.ShutdownTracing = func(context.Context) error { return nil }
+		if !config.Subcommands[config.Subcommand].IsDatabaseUsed {
+			return nil
+		}
+		return config.initializeDatabase(
+			ctx,
+			config.Environment.DatabaseURL,
+			config.Environment.DatabaseMaxConnections,
+		)
+	}
+
+	// initialize tracing, before the database so that the queries are
+	// traced
+	config.ShutdownTracing, err = tracing.Initialize(
+		ctx,
+		config.Environment.TracingExporter,
+		"zomato-weather-union-"+config.Component,
+		config.Environment.TracingSamplingRatio,
+	)
+	if err != nil {
+		return err
+	}
+
+	// get database URL and pool size from environment
 	var databaseURL string = config.Environment.DatabaseURL
+	var maxConnections int = config.Environment.DatabaseMaxConnections
 	// initialize database
-	err = config.initializeDatabase(ctx, databaseURL)
+	err = config.initializeDatabase(ctx, databaseURL, maxConnections)
 	if err != nil {
 		return err
 	}
@@ -34,3 +88,10 @@ func (config *Config) New(ctx context.Context) error {
 	// return nil if all okay
 	return nil
 }
+
+// close the database connection pool, if it was opened
+func (config *Config) CloseDatabase() {
+	if config.DB != nil {
+		config.DB.Close()
+	}
+}
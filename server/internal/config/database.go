@@ -5,17 +5,28 @@ import (
 	"time"
 
 	// external
+	"github.com/exaring/otelpgx"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-// create a database pool from context and database URL
-// and return a pointer to the pool
+// create a database pool from context, database URL and the maximum
+// number of connections and return a pointer to the pool
 func (config *Config) initializeDatabase(
 	ctx context.Context,
 	databaseURL string,
+	maxConnections int,
 ) error {
-	// create new pool using the database URL
-	DB, err := pgxpool.New(ctx, databaseURL)
+	// parse the database URL into the pool config
+	poolConfig, err := pgxpool.ParseConfig(databaseURL)
+	if err != nil {
+		return err
+	}
+	poolConfig.MaxConns = int32(maxConnections)
+	// spans of the queries, batches and copies
+	poolConfig.ConnConfig.Tracer = otelpgx.NewTracer()
+
+	// create new pool using the pool config
+	DB, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		return err
 	}
@@ -27,6 +38,9 @@ func (config *Config) initializeDatabase(
 	// ping check
 	err = DB.Ping(ctxWT)
 	if err != nil {
+		// close the pool and its connections, the config keeps no
+		// reference to it
+		DB.Close()
 		return err
 	}
 
@@ -2,41 +2,243 @@ package config
 
 import (
 	// external
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/netip"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/fixtures"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/scopes"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
 )
 
+// every setting is read, in increasing order of precedence, from its
+// default, the optional config file (key in the yaml tag), the environment
+// (env tag) and the command line (flag named like the yaml key, with
+// dashes). secrets are redacted when the config is printed and the
+// required tag lists the binaries that refuse to start without the setting,
+// unless the replayed tag marks it as unused in the fixtures replay mode.
 type Environment struct {
-	Port                    string
-	DatabaseURL             string
-	URLBaseWeatherUnion     string
-	URLBaseOpenWeatherMap   string
-	APIKeyWeatherUnion      string
-	APIKeyOpenWeatherMap    string
-	PathToPythonEnvironment string
+	// http server
+	Port             string        `yaml:"port" env:"PORT" required:"web"`
+	HTTPReadTimeout  time.Duration `yaml:"http_read_timeout" env:"HTTP_READ_TIMEOUT"`
+	HTTPWriteTimeout time.Duration `yaml:"http_write_timeout" env:"HTTP_WRITE_TIMEOUT"`
+	HTTPIdleTimeout  time.Duration `yaml:"http_idle_timeout" env:"HTTP_IDLE_TIMEOUT"`
+	// proxies in front of the server as IPs or CIDRs, the client IP is
+	// read from the X-Forwarded-For header of their requests
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+	// time the open requests get to finish on shutdown
+	HTTPShutdownTimeout time.Duration `yaml:"http_shutdown_timeout" env:"HTTP_SHUTDOWN_TIMEOUT"`
+	// time the readiness probe fails on shutdown before the server stops
+	// accepting connections, for the load balancers to notice
+	ShutdownReadinessDelay time.Duration `yaml:"shutdown_readiness_delay" env:"SHUTDOWN_READINESS_DELAY"`
+	// the server is not ready once the latest run is older than this
+	ReadinessRunMaxAge time.Duration `yaml:"readiness_run_max_age" env:"READINESS_RUN_MAX_AGE"`
+	// time the map data of a run is kept in memory, the cache is also
+	// cleared when the cron finishes a run
+	CacheTTL time.Duration `yaml:"cache_ttl" env:"CACHE_TTL"`
+	// server-sent events of /events, connections above the limits are
+	// refused
+	EventsConnectionsMax      int           `yaml:"events_connections_max" env:"EVENTS_CONNECTIONS_MAX"`
+	EventsConnectionsPerIPMax int           `yaml:"events_connections_per_ip_max" env:"EVENTS_CONNECTIONS_PER_IP_MAX"`
+	EventsHeartbeatInterval   time.Duration `yaml:"events_heartbeat_interval" env:"EVENTS_HEARTBEAT_INTERVAL"`
+
+	// public API
+	// scopes of the requests without an API key, empty to require a key
+	APIScopesAnonymous []string `yaml:"api_scopes_anonymous" env:"API_SCOPES_ANONYMOUS"`
+	// token bucket of the requests without an API key, by client IP
+	RateLimitPerIPRequestsPerSecond float64 `yaml:"rate_limit_per_ip_requests_per_second" env:"RATE_LIMIT_PER_IP_REQUESTS_PER_SECOND"`
+	RateLimitPerIPBurst             int     `yaml:"rate_limit_per_ip_burst" env:"RATE_LIMIT_PER_IP_BURST"`
+
+	// admin
+	// administrators of /admin as name:bcrypt-hash, /admin is off if empty
+	AdminUsers []string `yaml:"admin_users" env:"ADMIN_USERS" secret:"true"`
+
+	// database
+	DatabaseURL            string `yaml:"database_url" env:"DATABASE_URL" required:"web,cron,export,migrate,keys" secret:"true"`
+	DatabaseMaxConnections int    `yaml:"database_max_connections" env:"DATABASE_MAX_CONNECTIONS"`
+	// refuse to start when the schema is not at the embedded version
+	IsSchemaVersionCheckEnabled bool `yaml:"schema_version_check" env:"SCHEMA_VERSION_CHECK"`
+
+	// weather providers
+	URLBaseWeatherUnion   string `yaml:"url_base_weather_union" env:"URL_BASE_WEATHER_UNION" required:"cron" replayed:"true"`
+	URLBaseOpenWeatherMap string `yaml:"url_base_open_weather_map" env:"URL_BASE_OPEN_WEATHER_MAP" required:"cron" replayed:"true"`
+	APIKeyWeatherUnion    string `yaml:"api_key_weather_union" env:"API_KEY_WEATHER_UNION" required:"cron" replayed:"true" secret:"true"`
+	APIKeyOpenWeatherMap  string `yaml:"api_key_open_weather_map" env:"API_KEY_OPEN_WEATHER_MAP" required:"cron" replayed:"true" secret:"true"`
+	// timeout of a single request to a provider
+	ProviderRequestTimeout time.Duration `yaml:"provider_request_timeout" env:"PROVIDER_REQUEST_TIMEOUT"`
+	// pause between the requests of two stations
+	ProviderRequestInterval time.Duration `yaml:"provider_request_interval" env:"PROVIDER_REQUEST_INTERVAL"`
+	// record the responses of the providers to fixtures or replay them
+	// instead of calling the providers: off, record or replay
+	ProviderFixturesMode string `yaml:"provider_fixtures_mode" env:"PROVIDER_FIXTURES_MODE"`
+	// directory of the recorded fixtures
+	PathToProviderFixtures string `yaml:"path_to_provider_fixtures" env:"PATH_TO_PROVIDER_FIXTURES"`
+
+	// cron
+	// run the cron as a daemon every interval, a single run if 0
+	CronInterval time.Duration `yaml:"cron_interval" env:"CRON_INTERVAL"`
+	// port of the metrics of the web server and of the cron daemon, no
+	// metrics server if empty
+	MetricsPort string `yaml:"metrics_port" env:"METRICS_PORT"`
+
+	// calculations
+	PathToPythonEnvironment string `yaml:"path_to_python_environment" env:"PATH_TO_PYTHON_ENVIRONMENT" required:"cron"`
+	// wet bulb temperature script on disk, the embedded script if empty
+	PathToScriptWetBulbTemperature string `yaml:"path_to_script_wet_bulb_temperature" env:"PATH_TO_SCRIPT_WET_BULB_TEMPERATURE"`
+	// wet bulb temperatures of the risk levels, used by the city
+	// statistics and as the default contour levels
+	ThresholdsTemperatureWetBulb []float64 `yaml:"thresholds_temperature_wet_bulb" env:"THRESHOLDS_TEMPERATURE_WET_BULB"`
+
+	// logs
+	// format of the logs: text or json
+	LogFormat string `yaml:"log_format" env:"LOG_FORMAT"`
+	// lowest level logged: debug, info, warn or error
+	LogLevel string `yaml:"log_level" env:"LOG_LEVEL"`
+	// add the source file and line to the logs
+	IsLogSourceEnabled bool `yaml:"log_source" env:"LOG_SOURCE"`
+
+	// tracing
+	// exporter of the spans: none, otlp or stdout
+	// the otlp exporter is configured with the standard OTEL_EXPORTER_OTLP_*
+	// environment variables
+	TracingExporter string `yaml:"tracing_exporter" env:"TRACING_EXPORTER"`
+	// share of the new traces that are recorded, from 0 to 1
+	TracingSamplingRatio float64 `yaml:"tracing_sampling_ratio" env:"TRACING_SAMPLING_RATIO"`
+
+	// datasets
+	PathToDownloads string `yaml:"path_to_downloads" env:"PATH_TO_DOWNLOADS"`
+	// number of dataset files to keep in the downloads directory
+	ExportNumberOfFilesToKeep int `yaml:"export_number_of_files_to_keep" env:"EXPORT_NUMBER_OF_FILES_TO_KEEP"`
+	// export the datasets at the end of every cron run
+	IsExportEnabledInCron bool `yaml:"export_enabled_in_cron" env:"EXPORT_ENABLED_IN_CRON"`
+
+	// storage
+	// days of raw measurements and calculations to keep once they are
+	// rolled up, 0 keeps everything
+	RetentionNumberOfDaysRaw int `yaml:"retention_days_raw" env:"RETENTION_DAYS_RAW"`
+	// months of partitions to create ahead of the current month
+	PartitionNumberOfMonthsAhead int `yaml:"partition_months_ahead" env:"PARTITION_MONTHS_AHEAD"`
+	// months of partitions to keep attached before archiving them,
+	// 0 keeps everything
+	PartitionNumberOfMonthsToKeep int `yaml:"partition_months_to_keep" env:"PARTITION_MONTHS_TO_KEEP"`
 }
 
-// load environment variable values
-// from .env to a struct and then return
+// default values of the settings
+func newEnvironmentDefault() Environment {
+	return Environment{
+		HTTPReadTimeout:                 10 * time.Second,
+		HTTPWriteTimeout:                10 * time.Second,
+		HTTPIdleTimeout:                 time.Minute,
+		HTTPShutdownTimeout:             20 * time.Second,
+		ShutdownReadinessDelay:          5 * time.Second,
+		ReadinessRunMaxAge:              2 * time.Hour,
+		CacheTTL:                        5 * time.Minute,
+		EventsConnectionsMax:            1000,
+		EventsConnectionsPerIPMax:       10,
+		EventsHeartbeatInterval:         30 * time.Second,
+		APIScopesAnonymous:              []string{scopes.Read},
+		RateLimitPerIPRequestsPerSecond: 5,
+		RateLimitPerIPBurst:             20,
+		DatabaseMaxConnections:          10,
+		ProviderRequestTimeout:          10 * time.Second,
+		ProviderRequestInterval:         10 * time.Millisecond,
+		ProviderFixturesMode:            fixtures.ModeOff,
+		PathToProviderFixtures:          "./testdata/providers",
+		ThresholdsTemperatureWetBulb:    []float64{28, 31, 35},
+		LogFormat:                       LogFormatText,
+		LogLevel:                        "info",
+		IsLogSourceEnabled:              true,
+		TracingExporter:                 "none",
+		TracingSamplingRatio:            1,
+		PathToDownloads:                 "./downloads",
+		ExportNumberOfFilesToKeep:       10,
+		PartitionNumberOfMonthsAhead:    3,
+		PartitionNumberOfMonthsToKeep:   0,
+	}
+}
+
+// load the settings from the defaults, the config file, the environment
+// (and .env, if present) and the command line into a struct, validate
+// them and then return
 func (config *Config) initializeEnvironment() error {
-	// load environment variables
-	err := godotenv.Load()
+	return config.loadEnvironment(flag.CommandLine, os.Args[1:])
+}
+
+// load the settings with the flags of the flag set, parsed from the
+// arguments of the command line
+func (config *Config) loadEnvironment(flagSet *flag.FlagSet, sliceArguments []string) error {
+	// struct for storing values, starting from the defaults
+	var newEnvironment Environment = newEnvironmentDefault()
+
+	// command line flags
+	// the values are only collected here and applied after the environment
+	var pathToConfigFile *string = flagSet.String(
+		"config",
+		"",
+		"path to a YAML config file (default $CONFIG_FILE)",
+	)
+	var isPrintConfig *bool = flagSet.Bool(
+		"print-config",
+		false,
+		"print the effective config with secrets redacted and exit",
+	)
+	var mapFlagValues map[string]string = registerFlags(
+		flagSet,
+		&newEnvironment,
+	)
+	err := flagSet.Parse(sliceArguments)
 	if err != nil {
 		return err
 	}
 
-	// struct for storing values
-	var newEnvironment Environment
-	// get environment variables and
-	// add them to the struct
-	newEnvironment.Port = os.Getenv("PORT")
-	newEnvironment.DatabaseURL = os.Getenv("DATABASE_URL")
-	newEnvironment.URLBaseWeatherUnion = os.Getenv("URL_BASE_WEATHER_UNION")
-	newEnvironment.URLBaseOpenWeatherMap = os.Getenv("URL_BASE_OPEN_WEATHER_MAP")
-	newEnvironment.APIKeyWeatherUnion = os.Getenv("API_KEY_WEATHER_UNION")
-	newEnvironment.APIKeyOpenWeatherMap = os.Getenv("API_KEY_OPEN_WEATHER_MAP")
-	newEnvironment.PathToPythonEnvironment = os.Getenv("PATH_TO_PYTHON_ENVIRONMENT")
+	// subcommand of the command line, if the binary has it
+	if _, ok := config.Subcommands[flagSet.Arg(0)]; ok {
+		config.Subcommand = flagSet.Arg(0)
+	}
+
+	// load environment variables from .env, which is optional
+	err = godotenv.Load()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error in loading .env: %w", err)
+	}
+
+	// config file
+	if *pathToConfigFile == "" {
+		*pathToConfigFile = os.Getenv("CONFIG_FILE")
+	}
+	if *pathToConfigFile != "" {
+		err = readConfigFile(*pathToConfigFile, &newEnvironment)
+		if err != nil {
+			return err
+		}
+	}
+
+	// environment variables and then flags
+	// all the problems are collected and reported at once
+	var errs []error
+	errs = append(errs, applyEnvironmentVariables(&newEnvironment)...)
+	errs = append(errs, applyFlags(&newEnvironment, mapFlagValues)...)
+	errs = append(errs, newEnvironment.validate(config.componentRequired())...)
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config\n%w", errors.Join(errs...))
+	}
+
+	// print the effective config and let the binary exit
+	if *isPrintConfig {
+		fmt.Print(newEnvironment.String())
+		return ErrConfigPrinted
+	}
 
 	// configured environment variables struct
 	config.Environment = &newEnvironment
@@ -44,3 +246,280 @@ func (config *Config) initializeEnvironment() error {
 	// return nil if all okay
 	return nil
 }
+
+// component of the settings required by the command line, the binary or
+// its subcommand
+func (config *Config) componentRequired() string {
+	if config.Subcommand != "" {
+		return config.Subcommands[config.Subcommand].Component
+	}
+	return config.Component
+}
+
+// read the settings of a YAML config file over the current ones
+func readConfigFile(path string, environment *Environment) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error in opening config file: %w", err)
+	}
+	defer file.Close()
+
+	// unknown keys are errors so that typos do not go unnoticed
+	var decoder *yaml.Decoder = yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	err = decoder.Decode(environment)
+	// an empty file is fine
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("error in reading config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// check the settings and return all the problems found
+func (environment Environment) validate(component string) []error {
+	// settings required by the binary
+	var errs []error = checkRequired(environment, component)
+
+	// ports
+	for _, port := range []struct {
+		key   string
+		value string
+	}{
+		{"port", environment.Port},
+		{"metrics_port", environment.MetricsPort},
+	} {
+		if port.value == "" {
+			continue
+		}
+		number, err := strconv.Atoi(port.value)
+		if err != nil || number < 1 || number > 65535 {
+			errs = append(
+				errs,
+				fmt.Errorf("%s: %q is not a port number", port.key, port.value),
+			)
+		}
+	}
+
+	// the metrics of the web server are not served on its public port
+	if component == "web" &&
+		environment.MetricsPort != "" &&
+		environment.MetricsPort == environment.Port {
+		errs = append(
+			errs,
+			fmt.Errorf("metrics_port: %q is the port of the web server", environment.MetricsPort),
+		)
+	}
+
+	// timeouts must be positive
+	for _, timeout := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"http_read_timeout", environment.HTTPReadTimeout},
+		{"http_write_timeout", environment.HTTPWriteTimeout},
+		{"http_idle_timeout", environment.HTTPIdleTimeout},
+		{"http_shutdown_timeout", environment.HTTPShutdownTimeout},
+		{"readiness_run_max_age", environment.ReadinessRunMaxAge},
+		{"cache_ttl", environment.CacheTTL},
+		{"events_heartbeat_interval", environment.EventsHeartbeatInterval},
+		{"provider_request_timeout", environment.ProviderRequestTimeout},
+	} {
+		if timeout.value <= 0 {
+			errs = append(
+				errs,
+				fmt.Errorf("%s: %s is not positive", timeout.key, timeout.value),
+			)
+		}
+	}
+	if environment.ShutdownReadinessDelay < 0 {
+		errs = append(
+			errs,
+			fmt.Errorf(
+				"shutdown_readiness_delay: %s is negative",
+				environment.ShutdownReadinessDelay,
+			),
+		)
+	}
+	if environment.CronInterval < 0 {
+		errs = append(
+			errs,
+			fmt.Errorf("cron_interval: %s is negative", environment.CronInterval),
+		)
+	}
+	if environment.ProviderRequestInterval < 0 {
+		errs = append(
+			errs,
+			fmt.Errorf(
+				"provider_request_interval: %s is negative",
+				environment.ProviderRequestInterval,
+			),
+		)
+	}
+
+	if !slices.Contains(fixtures.SliceModes, environment.ProviderFixturesMode) {
+		errs = append(
+			errs,
+			fmt.Errorf(
+				"provider_fixtures_mode: %q is not one of %s",
+				environment.ProviderFixturesMode,
+				strings.Join(fixtures.SliceModes, ", "),
+			),
+		)
+	}
+	if environment.ProviderFixturesMode != fixtures.ModeOff &&
+		environment.PathToProviderFixtures == "" {
+		errs = append(errs, errors.New("path_to_provider_fixtures: empty"))
+	}
+
+	if environment.DatabaseMaxConnections < 1 {
+		errs = append(
+			errs,
+			fmt.Errorf(
+				"database_max_connections: %d is less than 1",
+				environment.DatabaseMaxConnections,
+			),
+		)
+	}
+
+	// thresholds must be given in increasing order
+	if len(environment.ThresholdsTemperatureWetBulb) == 0 {
+		errs = append(errs, errors.New("thresholds_temperature_wet_bulb: empty"))
+	}
+	for i := 1; i < len(environment.ThresholdsTemperatureWetBulb); i++ {
+		if environment.ThresholdsTemperatureWetBulb[i] <=
+			environment.ThresholdsTemperatureWetBulb[i-1] {
+			errs = append(
+				errs,
+				errors.New("thresholds_temperature_wet_bulb: not increasing"),
+			)
+			break
+		}
+	}
+
+	// logs
+	switch environment.LogFormat {
+	case LogFormatText, LogFormatJSON:
+	default:
+		errs = append(
+			errs,
+			fmt.Errorf(
+				"log_format: %q is not one of text or json",
+				environment.LogFormat,
+			),
+		)
+	}
+	var level slog.Level
+	if level.UnmarshalText([]byte(environment.LogLevel)) != nil {
+		errs = append(
+			errs,
+			fmt.Errorf(
+				"log_level: %q is not one of debug, info, warn or error",
+				environment.LogLevel,
+			),
+		)
+	}
+
+	// tracing
+	switch environment.TracingExporter {
+	case "none", "otlp", "stdout":
+	default:
+		errs = append(
+			errs,
+			fmt.Errorf(
+				"tracing_exporter: %q is not one of none, otlp or stdout",
+				environment.TracingExporter,
+			),
+		)
+	}
+	if environment.TracingSamplingRatio < 0 || environment.TracingSamplingRatio > 1 {
+		errs = append(
+			errs,
+			fmt.Errorf(
+				"tracing_sampling_ratio: %v is not between 0 and 1",
+				environment.TracingSamplingRatio,
+			),
+		)
+	}
+
+	// scopes must exist and be open to the requests without a key
+	for _, scope := range environment.APIScopesAnonymous {
+		if !slices.Contains(scopes.SliceAnonymous, scope) {
+			errs = append(
+				errs,
+				fmt.Errorf(
+					"api_scopes_anonymous: %q is not one of %s",
+					scope,
+					strings.Join(scopes.SliceAnonymous, ", "),
+				),
+			)
+		}
+	}
+
+	// rate limits must allow requests
+	if environment.RateLimitPerIPRequestsPerSecond <= 0 {
+		errs = append(
+			errs,
+			fmt.Errorf(
+				"rate_limit_per_ip_requests_per_second: %g is not positive",
+				environment.RateLimitPerIPRequestsPerSecond,
+			),
+		)
+	}
+	if environment.RateLimitPerIPBurst < 1 {
+		errs = append(
+			errs,
+			fmt.Errorf(
+				"rate_limit_per_ip_burst: %d is less than 1",
+				environment.RateLimitPerIPBurst,
+			),
+		)
+	}
+
+	// trusted proxies must be IPs or CIDRs
+	for _, proxy := range environment.TrustedProxies {
+		_, errPrefix := netip.ParsePrefix(proxy)
+		_, errAddr := netip.ParseAddr(proxy)
+		if errPrefix != nil && errAddr != nil {
+			errs = append(
+				errs,
+				fmt.Errorf("trusted_proxies: %q is not an IP or a CIDR", proxy),
+			)
+		}
+	}
+
+	// administrators must have a name and a bcrypt hash
+	for _, user := range environment.AdminUsers {
+		name, hash, _ := strings.Cut(user, ":")
+		_, err := bcrypt.Cost([]byte(hash))
+		if name == "" || err != nil {
+			errs = append(
+				errs,
+				fmt.Errorf("admin_users: %q is not name:bcrypt-hash", name),
+			)
+		}
+	}
+
+	// counts must not be negative
+	// 0 event connections turns the event streams off
+	for _, count := range []struct {
+		key   string
+		value int
+	}{
+		{"events_connections_max", environment.EventsConnectionsMax},
+		{"events_connections_per_ip_max", environment.EventsConnectionsPerIPMax},
+		{"export_number_of_files_to_keep", environment.ExportNumberOfFilesToKeep},
+		{"retention_days_raw", environment.RetentionNumberOfDaysRaw},
+		{"partition_months_ahead", environment.PartitionNumberOfMonthsAhead},
+		{"partition_months_to_keep", environment.PartitionNumberOfMonthsToKeep},
+	} {
+		if count.value < 0 {
+			errs = append(
+				errs,
+				fmt.Errorf("%s: %d is negative", count.key, count.value),
+			)
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,216 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kelaaditya/zomato-weather-union/server/internal/fixtures"
+)
+
+// a setting of the Environment struct
+type field struct {
+	// key in the config file
+	key string
+	// environment variable
+	env      string
+	required []string
+	// not required when the responses of the providers are replayed
+	isReplayed bool
+	isSecret   bool
+	value      reflect.Value
+}
+
+// settings of an Environment struct, in their order in the struct
+func fields(environment *Environment) []field {
+	var valueStruct reflect.Value = reflect.ValueOf(environment).Elem()
+	var typeStruct reflect.Type = valueStruct.Type()
+
+	var sliceFields []field
+	for i := 0; i < typeStruct.NumField(); i++ {
+		var structField reflect.StructField = typeStruct.Field(i)
+		var key string = structField.Tag.Get("yaml")
+		if key == "" || key == "-" {
+			continue
+		}
+		var required []string
+		if tag := structField.Tag.Get("required"); tag != "" {
+			required = strings.Split(tag, ",")
+		}
+		sliceFields = append(sliceFields, field{
+			key:        key,
+			env:        structField.Tag.Get("env"),
+			required:   required,
+			isReplayed: structField.Tag.Get("replayed") == "true",
+			isSecret:   structField.Tag.Get("secret") == "true",
+			value:      valueStruct.Field(i),
+		})
+	}
+	return sliceFields
+}
+
+// name of the command line flag of a setting
+func (f field) flagName() string {
+	return strings.ReplaceAll(f.key, "_", "-")
+}
+
+// set a setting from its text form
+// durations are like 10s, lists are comma separated
+func (f field) set(text string) error {
+	switch f.value.Interface().(type) {
+	case string:
+		f.value.SetString(text)
+	case bool:
+		value, err := strconv.ParseBool(text)
+		if err != nil {
+			return err
+		}
+		f.value.SetBool(value)
+	case int:
+		value, err := strconv.Atoi(text)
+		if err != nil {
+			return err
+		}
+		f.value.SetInt(int64(value))
+	case float64:
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return err
+		}
+		f.value.SetFloat(value)
+	case time.Duration:
+		value, err := time.ParseDuration(text)
+		if err != nil {
+			return err
+		}
+		f.value.SetInt(int64(value))
+	case []float64:
+		var values []float64
+		for _, part := range strings.Split(text, ",") {
+			value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				return err
+			}
+			values = append(values, value)
+		}
+		f.value.Set(reflect.ValueOf(values))
+	case []string:
+		// an empty text is an empty list
+		var values []string = []string{}
+		for _, part := range strings.Split(text, ",") {
+			if strings.TrimSpace(part) != "" {
+				values = append(values, strings.TrimSpace(part))
+			}
+		}
+		f.value.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("unsupported type %s", f.value.Type())
+	}
+	return nil
+}
+
+// text form of a setting
+func (f field) String() string {
+	switch value := f.value.Interface().(type) {
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case []float64:
+		var parts []string
+		for _, number := range value {
+			parts = append(parts, strconv.FormatFloat(number, 'f', -1, 64))
+		}
+		return strings.Join(parts, ",")
+	case []string:
+		return strings.Join(value, ",")
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// register a flag for every setting on the flag set
+// the values given on the command line are collected in the returned map
+// (by key) and applied after the environment
+func registerFlags(flagSet *flag.FlagSet, environment *Environment) map[string]string {
+	var mapFlagValues map[string]string = make(map[string]string)
+	for _, f := range fields(environment) {
+		var usage string = "overrides $" + f.env + " (default: " + f.String() + ")"
+		if f.isSecret {
+			usage = "overrides $" + f.env
+		}
+		flagSet.Func(f.flagName(), usage, func(text string) error {
+			mapFlagValues[f.key] = text
+			return nil
+		})
+	}
+	return mapFlagValues
+}
+
+// apply the environment variables that are set
+func applyEnvironmentVariables(environment *Environment) []error {
+	var errs []error
+	for _, f := range fields(environment) {
+		text, ok := os.LookupEnv(f.env)
+		if !ok || text == "" {
+			continue
+		}
+		err := f.set(text)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid value: %w", f.env, err))
+		}
+	}
+	return errs
+}
+
+// apply the flags given on the command line
+func applyFlags(environment *Environment, mapFlagValues map[string]string) []error {
+	var errs []error
+	for _, f := range fields(environment) {
+		text, ok := mapFlagValues[f.key]
+		if !ok {
+			continue
+		}
+		err := f.set(text)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("-%s: invalid value: %w", f.flagName(), err))
+		}
+	}
+	return errs
+}
+
+// check that the settings required by a binary are set
+func checkRequired(environment Environment, component string) []error {
+	var errs []error
+	for _, f := range fields(&environment) {
+		if f.isReplayed && environment.ProviderFixturesMode == fixtures.ModeReplay {
+			continue
+		}
+		if slices.Contains(f.required, component) && f.value.IsZero() {
+			errs = append(errs, fmt.Errorf("%s: required (set %s or -%s)", f.key, f.env, f.flagName()))
+		}
+	}
+	return errs
+}
+
+// effective settings in the format of the config file, with the secrets
+// redacted
+func (environment Environment) String() string {
+	var builder strings.Builder
+	for _, f := range fields(&environment) {
+		var text string = f.String()
+		switch {
+		case f.isSecret && text != "":
+			text = strconv.Quote("[redacted]")
+		case f.value.Kind() == reflect.String:
+			text = strconv.Quote(text)
+		case f.value.Kind() == reflect.Slice:
+			text = "[" + strings.ReplaceAll(text, ",", ", ") + "]"
+		}
+		fmt.Fprintf(&builder, "%s: %s\n", f.key, text)
+	}
+	return builder.String()
+}
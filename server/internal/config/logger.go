@@ -1,23 +1,43 @@
 package config
 
 import (
+	"io"
 	"log/slog"
 	"os"
 )
 
+// formats of the logs
+const (
+	LogFormatText string = "text"
+	LogFormatJSON string = "json"
+)
+
 // create logger at the app level
-func (config *Config) initializeLogger() {
+// the logger is created with the defaults first, so that problems with
+// the settings can be logged, and again with the settings
+func (config *Config) initializeLogger(environment Environment) {
+	// level of the logs, already validated
+	var level slog.Level
+	_ = level.UnmarshalText([]byte(environment.LogLevel))
+
 	// logger configuration options
 	loggerOptions := slog.HandlerOptions{
-		Level:     slog.LevelInfo,
-		AddSource: true,
+		Level:     level,
+		AddSource: environment.IsLogSourceEnabled,
 	}
 
-	// logger handler
-	newTextHandler := slog.NewTextHandler(os.Stdout, &loggerOptions)
+	// logger handler, text or JSON lines
+	var writer io.Writer = os.Stdout
+	var handler slog.Handler
+	switch environment.LogFormat {
+	case LogFormatJSON:
+		handler = slog.NewJSONHandler(writer, &loggerOptions)
+	default:
+		handler = slog.NewTextHandler(writer, &loggerOptions)
+	}
 
 	// create new logger from handler
-	newLogger := slog.New(newTextHandler)
+	newLogger := slog.New(handler)
 
 	// set newly created logger on the config struct
 	config.Logger = newLogger
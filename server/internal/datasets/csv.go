@@ -0,0 +1,281 @@
+package datasets
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// prefix of the file names of the daily wet bulb temperature dataset
+const prefixTemperatureWetBulbDaily string = "temperature-wet-bulb-daily"
+
+// extension of the gzipped CSV dataset files
+const extensionCSVGzip string = ".csv.gz"
+
+// layout of the day in the daily file names
+const layoutDayFileName string = "2006-01-02"
+
+// format of the CSV datasets in the manifest
+const formatCSVGzip string = "csv.gz"
+
+// columns of the wet bulb temperature dataset
+// see downloads/README.md for the description of each column
+var querySelectTemperatureWetBulb string = `
+SELECT
+	ct.calculation_id,
+	ct.method                       AS calculation_method,
+	ct.temperature_dew_point        AS calculated_temperature_dew_point,
+	ct.temperature_wet_bulb         AS calculated_temperature_wet_bulb,
+	mwu.measurement_id,
+	mwu.run_id                      AS measurement_run_id,
+	wus.city_name                   AS weather_union_station_city_name,
+	wus.locality_name               AS weather_union_station_locality_name,
+	wus.locality_id                 AS weather_union_station_locality_id,
+	ST_X(wus.location::geometry)    AS weather_union_station_longitude,
+	ST_Y(wus.location::geometry)    AS weather_union_station_latitude,
+	mwu.temperature                 AS weather_union_station_temperature,
+	mwu.humidity                    AS weather_union_station_humidity,
+	mwu.wind_speed                  AS weather_union_station_wind_speed,
+	mwu.wind_direction              AS weather_union_station_wind_direction,
+	mwu.rain_intensity              AS weather_union_station_rain_intensity,
+	mwu.rain_accumulation           AS weather_union_station_rain_accumulation,
+	mwu.time_stamp                  AS measurement_time_stamp
+FROM calculations_temperature ct
+JOIN measurements_weather_union mwu
+	ON mwu.measurement_id = ct.measurement_id_weather_union
+JOIN weather_union_stations wus
+	ON wus.weather_station_id = mwu.weather_station_id
+`
+
+// stream the wet bulb temperature dataset from postgresql into a new
+// gzipped CSV file, write its checksum sidecar and remove old files.
+// returns the path of the new file.
+func (exporter Exporter) ExportTemperatureWetBulbCSV(
+	ctx context.Context,
+) (string, error) {
+	// file name with the time stamp of the export
+	var timeNow time.Time = time.Now().UTC()
+	var fileName string = fmt.Sprintf(
+		"%s-%s%s",
+		prefixTemperatureWetBulb,
+		timeNow.Format(layoutTimeStampFileName),
+		extensionCSVGzip,
+	)
+	var path string = filepath.Join(exporter.Directory, fileName)
+
+	// all measurements up to now
+	rowCount, checksum, err := exporter.writeTemperatureWetBulbCSV(
+		ctx,
+		path,
+		"ORDER BY mwu.time_stamp DESC",
+	)
+	if err != nil {
+		return "", err
+	}
+
+	exporter.Logger.Info("dataset exported", "path", path, "rows", rowCount)
+
+	// keep only the newest files
+	sliceRemoved, err := removeOldFiles(
+		exporter.Directory,
+		prefixTemperatureWetBulb,
+		extensionCSVGzip,
+		exporter.NumberOfFilesToKeep,
+	)
+	if err != nil {
+		return path, err
+	}
+	for _, name := range sliceRemoved {
+		exporter.Logger.Info("old dataset removed", "file", name)
+	}
+
+	// add the new file to the manifest
+	manifestFile, err := newManifestFile(
+		path,
+		prefixTemperatureWetBulb,
+		formatCSVGzip,
+		rowCount,
+		checksum,
+		nil,
+		timeNow,
+	)
+	if err != nil {
+		return path, err
+	}
+	err = exporter.updateManifest(manifestFile)
+	if err != nil {
+		return path, err
+	}
+
+	// return path of the new file if all okay
+	return path, nil
+}
+
+// write the wet bulb temperatures of each day (UTC) into daily delta
+// files so that consumers only download the days they are missing.
+// days with a complete file in the manifest are skipped.
+// returns the paths of the written files.
+func (exporter Exporter) ExportTemperatureWetBulbDailyCSV(
+	ctx context.Context,
+) ([]string, error) {
+	// postgresql query string
+	// all days (UTC) with calculations
+	var queryStringDays string = `
+	SELECT DISTINCT
+		date_trunc('day', mwu.time_stamp AT TIME ZONE 'UTC') AS day
+	FROM calculations_temperature ct
+	JOIN measurements_weather_union mwu
+		ON mwu.measurement_id = ct.measurement_id_weather_union
+	ORDER BY day;
+	`
+
+	// create a 5 second timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, 5*time.Second)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// prepare the query
+	rows, err := exporter.DB.Query(ctxWT, queryStringDays)
+	if err != nil {
+		return nil, err
+	}
+	// run the query and collect rows
+	sliceDays, err := pgx.CollectRows(rows, pgx.RowTo[time.Time])
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := ReadManifest(exporter.Directory)
+	if err != nil {
+		return nil, err
+	}
+
+	var slicePaths []string
+	for _, day := range sliceDays {
+		// time range of the day in UTC
+		var timeFrom time.Time = time.Date(
+			day.Year(),
+			day.Month(),
+			day.Day(),
+			0,
+			0,
+			0,
+			0,
+			time.UTC,
+		)
+		var timeTo time.Time = timeFrom.AddDate(0, 0, 1)
+
+		var fileName string = fmt.Sprintf(
+			"%s-%s%s",
+			prefixTemperatureWetBulbDaily,
+			timeFrom.Format(layoutDayFileName),
+			extensionCSVGzip,
+		)
+		var path string = filepath.Join(exporter.Directory, fileName)
+
+		// finished days do not change once written
+		if manifest.Has(fileName) && isPeriodFileComplete(path, timeTo) {
+			continue
+		}
+
+		// time stamps are formatted by the exporter and not taken from
+		// user input, so they are safe to inline in the COPY statement
+		rowCount, checksum, err := exporter.writeTemperatureWetBulbCSV(
+			ctx,
+			path,
+			fmt.Sprintf(
+				"WHERE mwu.time_stamp >= '%s' AND mwu.time_stamp < '%s'\n"+
+					"ORDER BY mwu.time_stamp DESC",
+				timeFrom.Format(time.RFC3339),
+				timeTo.Format(time.RFC3339),
+			),
+		)
+		if err != nil {
+			return slicePaths, err
+		}
+
+		exporter.Logger.Info("dataset exported", "path", path, "rows", rowCount)
+		slicePaths = append(slicePaths, path)
+
+		// add the file to the manifest
+		manifestFile, err := newManifestFile(
+			path,
+			prefixTemperatureWetBulbDaily,
+			formatCSVGzip,
+			rowCount,
+			checksum,
+			&timeFrom,
+			timeTo,
+		)
+		if err != nil {
+			return slicePaths, err
+		}
+		err = exporter.updateManifest(manifestFile)
+		if err != nil {
+			return slicePaths, err
+		}
+	}
+
+	// return paths of the new files if all okay
+	return slicePaths, nil
+}
+
+// stream the wet bulb temperature rows matching the clause (WHERE and
+// ORDER BY) from postgresql into a gzipped CSV file.
+// returns the number of rows and the checksum of the file.
+func (exporter Exporter) writeTemperatureWetBulbCSV(
+	ctx context.Context,
+	path string,
+	clause string,
+) (int64, string, error) {
+	// create a 5 minute timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// acquire a single connection for the copy
+	connection, err := exporter.DB.Acquire(ctxWT)
+	if err != nil {
+		return 0, "", err
+	}
+	defer connection.Release()
+
+	// temporary file renamed on commit
+	file, err := createAtomicFile(path)
+	if err != nil {
+		return 0, "", err
+	}
+	// gzip everything written to the file
+	gzipWriter := gzip.NewWriter(file)
+
+	// stream the rows straight from postgresql into the file
+	commandTag, err := connection.Conn().PgConn().CopyTo(
+		ctxWT,
+		gzipWriter,
+		"COPY ("+querySelectTemperatureWetBulb+clause+") TO STDOUT WITH (FORMAT CSV, HEADER)",
+	)
+	if err != nil {
+		file.Abort()
+		return 0, "", fmt.Errorf(
+			"error in copying the wet bulb temperature dataset from postgresql: %w",
+			err,
+		)
+	}
+
+	// flush the gzip footer before committing the file
+	err = gzipWriter.Close()
+	if err != nil {
+		file.Abort()
+		return 0, "", err
+	}
+	checksum, err := file.Commit()
+	if err != nil {
+		return 0, "", err
+	}
+
+	return commandTag.RowsAffected(), checksum, nil
+}
@@ -0,0 +1,56 @@
+package datasets
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// prefix of the file names of the wet bulb temperature dataset
+const prefixTemperatureWetBulb string = "temperature-wet-bulb"
+
+// names of the datasets in the manifest
+const (
+	DatasetTemperatureWetBulb      string = prefixTemperatureWetBulb
+	DatasetTemperatureWetBulbDaily string = prefixTemperatureWetBulbDaily
+	DatasetMeasurementHistory      string = prefixMeasurementHistory
+)
+
+// layout of the time stamp in the file names
+// sorts lexicographically in time order
+const layoutTimeStampFileName string = "20060102T150405Z"
+
+// exporter of the downloadable datasets
+type Exporter struct {
+	DB     *pgxpool.Pool
+	Logger *slog.Logger
+	// directory the dataset files are written to
+	Directory string
+	// number of files of each dataset to keep (0 keeps all)
+	NumberOfFilesToKeep int
+}
+
+// export all downloadable datasets
+func (exporter Exporter) ExportAll(ctx context.Context) error {
+	// wet bulb temperature dataset (CSV)
+	_, err := exporter.ExportTemperatureWetBulbCSV(ctx)
+	if err != nil {
+		return err
+	}
+
+	// daily wet bulb temperature delta files (CSV)
+	_, err = exporter.ExportTemperatureWetBulbDailyCSV(ctx)
+	if err != nil {
+		return err
+	}
+
+	// measurement history dataset (parquet, one file per month)
+	_, err = exporter.ExportMeasurementHistoryParquet(ctx)
+	if err != nil {
+		return err
+	}
+
+	// return nil if all okay
+	return nil
+}
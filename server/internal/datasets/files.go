@@ -0,0 +1,215 @@
+package datasets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// extension of the SHA-256 checksum sidecar files
+const extensionChecksum string = ".sha256"
+
+// time after the end of a period (day, month) before its file is
+// considered complete. covers measurements calculated late.
+const gracePeriodFile time.Duration = time.Hour
+
+// file being written atomically
+// data goes to a temporary file in the same directory which is only
+// renamed to the final name on commit, so readers never see a
+// partially written file
+type atomicFile struct {
+	file     *os.File
+	path     string
+	checksum hash.Hash
+	writer   io.Writer
+}
+
+// create a temporary file for the final path
+func createAtomicFile(path string) (*atomicFile, error) {
+	file, err := os.CreateTemp(filepath.Dir(path), ".tmp-"+filepath.Base(path)+"-*")
+	if err != nil {
+		return nil, err
+	}
+
+	// hash all bytes written to the file
+	var checksum hash.Hash = sha256.New()
+
+	return &atomicFile{
+		file:     file,
+		path:     path,
+		checksum: checksum,
+		writer:   io.MultiWriter(file, checksum),
+	}, nil
+}
+
+// write to the temporary file
+func (fileAtomic *atomicFile) Write(p []byte) (int, error) {
+	return fileAtomic.writer.Write(p)
+}
+
+// flush the temporary file to disk, move it to its final path and write
+// the checksum sidecar next to it. returns the hex encoded checksum.
+func (fileAtomic *atomicFile) Commit() (string, error) {
+	err := fileAtomic.file.Sync()
+	if err != nil {
+		fileAtomic.Abort()
+		return "", err
+	}
+	err = fileAtomic.file.Close()
+	if err != nil {
+		os.Remove(fileAtomic.file.Name())
+		return "", err
+	}
+	err = os.Chmod(fileAtomic.file.Name(), 0o644)
+	if err != nil {
+		os.Remove(fileAtomic.file.Name())
+		return "", err
+	}
+
+	err = os.Rename(fileAtomic.file.Name(), fileAtomic.path)
+	if err != nil {
+		os.Remove(fileAtomic.file.Name())
+		return "", err
+	}
+
+	// write the sidecar once the dataset is in place, so that a failed
+	// rename never leaves a checksum of a missing or older file. a sidecar
+	// that cannot be written is removed rather than left stale.
+	var checksum string = hex.EncodeToString(fileAtomic.checksum.Sum(nil))
+	err = writeChecksumFile(fileAtomic.path, checksum)
+	if err != nil {
+		os.Remove(fileAtomic.path + extensionChecksum)
+		return "", err
+	}
+
+	return checksum, nil
+}
+
+// remove the temporary file
+func (fileAtomic *atomicFile) Abort() {
+	fileAtomic.file.Close()
+	os.Remove(fileAtomic.file.Name())
+}
+
+// write a small file through a temporary file and a rename
+func writeFileAtomically(path string, data []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(path), ".tmp-"+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	err = file.Close()
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	err = os.Chmod(file.Name(), 0o644)
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+// write a checksum sidecar in the format of sha256sum
+// so that it can be verified with `sha256sum --check`
+func writeChecksumFile(path string, checksum string) error {
+	return writeFileAtomically(
+		path+extensionChecksum,
+		[]byte(fmt.Sprintf("%s  %s\n", checksum, filepath.Base(path))),
+	)
+}
+
+// read the checksum from the sidecar of a file
+func readChecksumFile(path string) (string, error) {
+	data, err := os.ReadFile(path + extensionChecksum)
+	if err != nil {
+		return "", err
+	}
+	checksum, _, _ := strings.Cut(string(data), " ")
+	return checksum, nil
+}
+
+// check if the file of a period (day, month) was written after the
+// period ended, in which case it will not change anymore
+func isPeriodFileComplete(path string, timeTo time.Time) bool {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return fileInfo.ModTime().After(timeTo.Add(gracePeriodFile))
+}
+
+// check if a file name is prefix-<time stamp>suffix
+func isTimeStampedFileName(name string, prefix string, suffix string) bool {
+	if !strings.HasPrefix(name, prefix+"-") || !strings.HasSuffix(name, suffix) {
+		return false
+	}
+	var timeStamp string = strings.TrimSuffix(
+		strings.TrimPrefix(name, prefix+"-"),
+		suffix,
+	)
+	_, err := time.Parse(layoutTimeStampFileName, timeStamp)
+	return err == nil
+}
+
+// remove all but the newest time stamped files with the prefix and suffix
+// along with their checksum sidecars. the time stamps in the file names
+// sort lexicographically so the newest files are the last ones.
+func removeOldFiles(
+	directory string,
+	prefix string,
+	suffix string,
+	numberOfFilesToKeep int,
+) ([]string, error) {
+	if numberOfFilesToKeep <= 0 {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return nil, err
+	}
+
+	// collect the matching dataset files
+	var sliceFileNames []string
+	for _, entry := range entries {
+		if entry.IsDir() || !isTimeStampedFileName(entry.Name(), prefix, suffix) {
+			continue
+		}
+		sliceFileNames = append(sliceFileNames, entry.Name())
+	}
+	if len(sliceFileNames) <= numberOfFilesToKeep {
+		return nil, nil
+	}
+	sort.Strings(sliceFileNames)
+
+	// remove the oldest files
+	var sliceRemoved []string = sliceFileNames[:len(sliceFileNames)-numberOfFilesToKeep]
+	for _, name := range sliceRemoved {
+		var path string = filepath.Join(directory, name)
+		err = os.Remove(path)
+		if err != nil {
+			return nil, err
+		}
+		err = os.Remove(path + extensionChecksum)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	return sliceRemoved, nil
+}
@@ -0,0 +1,176 @@
+package datasets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// names of the files in a directory
+func readDirNames(t *testing.T, directory string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sliceNames []string
+	for _, entry := range entries {
+		sliceNames = append(sliceNames, entry.Name())
+	}
+	return sliceNames
+}
+
+// a committed file replaces the previous one and gets a sidecar with its
+// checksum in the format of sha256sum
+func TestAtomicFileCommit(t *testing.T) {
+	var directory string = t.TempDir()
+	var path string = filepath.Join(directory, "dataset.csv.gz")
+
+	err := os.WriteFile(path, []byte("previous"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := createAtomicFile(path)
+	if err != nil {
+		t.Fatalf("error in creating the file: %v", err)
+	}
+	_, err = file.Write([]byte("dataset"))
+	if err != nil {
+		t.Fatalf("error in writing the file: %v", err)
+	}
+
+	// nothing is visible under the final name before the commit
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "previous" {
+		t.Errorf("file before the commit is %q, expected the previous one", data)
+	}
+
+	checksum, err := file.Commit()
+	if err != nil {
+		t.Fatalf("error in committing the file: %v", err)
+	}
+
+	var sum [32]byte = sha256.Sum256([]byte("dataset"))
+	var checksumExpected string = hex.EncodeToString(sum[:])
+	if checksum != checksumExpected {
+		t.Errorf("checksum is %s, expected %s", checksum, checksumExpected)
+	}
+
+	data, err = os.ReadFile(path)
+	if err != nil || string(data) != "dataset" {
+		t.Errorf("file is %q, expected %q", data, "dataset")
+	}
+	fileInfo, err := os.Stat(path)
+	if err != nil || fileInfo.Mode().Perm() != 0o644 {
+		t.Errorf("file mode is %v, expected %v", fileInfo.Mode().Perm(), os.FileMode(0o644))
+	}
+
+	data, err = os.ReadFile(path + extensionChecksum)
+	if err != nil {
+		t.Fatalf("error in reading the sidecar: %v", err)
+	}
+	if string(data) != checksumExpected+"  dataset.csv.gz\n" {
+		t.Errorf("sidecar is %q, expected the format of sha256sum", data)
+	}
+	checksumRead, err := readChecksumFile(path)
+	if err != nil || checksumRead != checksumExpected {
+		t.Errorf("checksum read is %s, expected %s", checksumRead, checksumExpected)
+	}
+
+	// no temporary files are left behind
+	var sliceNames []string = readDirNames(t, directory)
+	if !slices.Equal(sliceNames, []string{"dataset.csv.gz", "dataset.csv.gz.sha256"}) {
+		t.Errorf("files are %v, expected the dataset and its sidecar", sliceNames)
+	}
+}
+
+// an aborted file leaves the previous one and no temporary file
+func TestAtomicFileAbort(t *testing.T) {
+	var directory string = t.TempDir()
+	var path string = filepath.Join(directory, "dataset.csv.gz")
+
+	err := os.WriteFile(path, []byte("previous"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := createAtomicFile(path)
+	if err != nil {
+		t.Fatalf("error in creating the file: %v", err)
+	}
+	_, err = file.Write([]byte("partial"))
+	if err != nil {
+		t.Fatalf("error in writing the file: %v", err)
+	}
+	file.Abort()
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "previous" {
+		t.Errorf("file is %q, expected the previous one", data)
+	}
+	var sliceNames []string = readDirNames(t, directory)
+	if !slices.Equal(sliceNames, []string{"dataset.csv.gz"}) {
+		t.Errorf("files are %v, expected only the previous dataset", sliceNames)
+	}
+}
+
+// only the newest time stamped files of the dataset are kept, along with
+// their sidecars
+func TestRemoveOldFiles(t *testing.T) {
+	var directory string = t.TempDir()
+
+	var sliceNames []string = []string{
+		"temperature-wet-bulb-20240501T000000Z.csv.gz",
+		"temperature-wet-bulb-20240501T000000Z.csv.gz.sha256",
+		"temperature-wet-bulb-20240503T000000Z.csv.gz",
+		"temperature-wet-bulb-20240503T000000Z.csv.gz.sha256",
+		"temperature-wet-bulb-20240502T000000Z.csv.gz",
+		// other datasets and files are left alone
+		"temperature-wet-bulb-daily-2024-05-01.csv.gz",
+		"temperature-wet-bulb-latest.csv.gz",
+		"manifest.json",
+	}
+	for _, name := range sliceNames {
+		err := os.WriteFile(filepath.Join(directory, name), nil, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sliceRemoved, err := removeOldFiles(
+		directory,
+		prefixTemperatureWetBulb,
+		extensionCSVGzip,
+		2,
+	)
+	if err != nil {
+		t.Fatalf("error in removing the old files: %v", err)
+	}
+	if !slices.Equal(sliceRemoved, []string{"temperature-wet-bulb-20240501T000000Z.csv.gz"}) {
+		t.Errorf("removed files are %v, expected the file of 1 May", sliceRemoved)
+	}
+
+	var sliceExpected []string = []string{
+		"manifest.json",
+		"temperature-wet-bulb-20240502T000000Z.csv.gz",
+		"temperature-wet-bulb-20240503T000000Z.csv.gz",
+		"temperature-wet-bulb-20240503T000000Z.csv.gz.sha256",
+		"temperature-wet-bulb-daily-2024-05-01.csv.gz",
+		"temperature-wet-bulb-latest.csv.gz",
+	}
+	var sliceLeft []string = readDirNames(t, directory)
+	if !slices.Equal(sliceLeft, sliceExpected) {
+		t.Errorf("files left are %v, expected %v", sliceLeft, sliceExpected)
+	}
+
+	// zero keeps all files
+	sliceRemoved, err = removeOldFiles(directory, prefixTemperatureWetBulb, extensionCSVGzip, 0)
+	if err != nil || len(sliceRemoved) != 0 {
+		t.Errorf("removed files are %v (error %v), expected none", sliceRemoved, err)
+	}
+}
@@ -0,0 +1,174 @@
+package datasets
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// name of the manifest file in the downloads directory
+const FileNameManifest string = "manifest.json"
+
+// serializes the read-modify-write of the manifest within a process
+var mutexManifest sync.Mutex
+
+// manifest of the dataset files available for download
+// consumers compare checksums with their local copies to sync
+// incrementally
+type Manifest struct {
+	TimeUpdated time.Time      `json:"time_updated"`
+	Files       []ManifestFile `json:"files"`
+}
+
+// a single dataset file in the manifest
+type ManifestFile struct {
+	Name      string `json:"name"`
+	Dataset   string `json:"dataset"`
+	Format    string `json:"format"`
+	SizeBytes int64  `json:"size_bytes"`
+	RowCount  int64  `json:"row_count"`
+	SHA256    string `json:"sha256"`
+	// time range of the measurements in the file
+	// a nil start means from the start of recording
+	TimeFrom    *time.Time `json:"time_from"`
+	TimeTo      time.Time  `json:"time_to"`
+	TimeCreated time.Time  `json:"time_created"`
+}
+
+// read the manifest of a downloads directory
+// an empty manifest is returned if there is none yet
+func ReadManifest(directory string) (Manifest, error) {
+	var manifest Manifest = Manifest{Files: []ManifestFile{}}
+
+	data, err := os.ReadFile(filepath.Join(directory, FileNameManifest))
+	if errors.Is(err, os.ErrNotExist) {
+		return manifest, nil
+	}
+	if err != nil {
+		return manifest, err
+	}
+
+	err = json.Unmarshal(data, &manifest)
+	if err != nil {
+		return manifest, err
+	}
+
+	return manifest, nil
+}
+
+// create the manifest entry of a newly written dataset file
+func newManifestFile(
+	path string,
+	dataset string,
+	format string,
+	rowCount int64,
+	checksum string,
+	timeFrom *time.Time,
+	timeTo time.Time,
+) (ManifestFile, error) {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return ManifestFile{}, err
+	}
+
+	return ManifestFile{
+		Name:        filepath.Base(path),
+		Dataset:     dataset,
+		Format:      format,
+		SizeBytes:   fileInfo.Size(),
+		RowCount:    rowCount,
+		SHA256:      checksum,
+		TimeFrom:    timeFrom,
+		TimeTo:      timeTo,
+		TimeCreated: fileInfo.ModTime().UTC(),
+	}, nil
+}
+
+// check if the manifest has an entry for a file name
+func (manifest Manifest) Has(name string) bool {
+	_, ok := manifest.Find(name)
+	return ok
+}
+
+// newest file of a dataset
+// file names carry sortable time stamps, so the newest is the last
+func (manifest Manifest) Latest(dataset string) (ManifestFile, bool) {
+	var latest ManifestFile
+	var isFound bool
+	for _, file := range manifest.Files {
+		if file.Dataset == dataset && (!isFound || file.Name > latest.Name) {
+			latest = file
+			isFound = true
+		}
+	}
+	return latest, isFound
+}
+
+// find the entry of a file name
+func (manifest Manifest) Find(name string) (ManifestFile, bool) {
+	for _, file := range manifest.Files {
+		if file.Name == name {
+			return file, true
+		}
+	}
+	return ManifestFile{}, false
+}
+
+// check if a file will never be rewritten
+// exports of the full dataset get a new file name every time and period
+// files (day, month) are final once written after their period ended
+func (file ManifestFile) IsImmutable() bool {
+	if file.Dataset == DatasetTemperatureWetBulb {
+		return true
+	}
+	return file.TimeCreated.After(file.TimeTo.Add(gracePeriodFile))
+}
+
+// add or replace entries in the manifest, drop the entries of files that
+// no longer exist and write the manifest atomically
+func (exporter Exporter) updateManifest(sliceFiles ...ManifestFile) error {
+	mutexManifest.Lock()
+	defer mutexManifest.Unlock()
+
+	manifest, err := ReadManifest(exporter.Directory)
+	if err != nil {
+		return err
+	}
+
+	// entries by file name
+	var mapFiles map[string]ManifestFile = make(map[string]ManifestFile)
+	for _, file := range manifest.Files {
+		mapFiles[file.Name] = file
+	}
+	for _, file := range sliceFiles {
+		mapFiles[file.Name] = file
+	}
+
+	// keep the entries of files still on disk
+	manifest.Files = []ManifestFile{}
+	for name, file := range mapFiles {
+		_, err := os.Stat(filepath.Join(exporter.Directory, name))
+		if err != nil {
+			continue
+		}
+		manifest.Files = append(manifest.Files, file)
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool {
+		return manifest.Files[i].Name < manifest.Files[j].Name
+	})
+	manifest.TimeUpdated = time.Now().UTC()
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomically(
+		filepath.Join(exporter.Directory, FileNameManifest),
+		data,
+	)
+}
@@ -0,0 +1,138 @@
+package datasets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// an empty downloads directory has an empty manifest
+func TestReadManifestMissing(t *testing.T) {
+	manifest, err := ReadManifest(t.TempDir())
+	if err != nil {
+		t.Fatalf("error in reading the manifest: %v", err)
+	}
+	if manifest.Files == nil || len(manifest.Files) != 0 {
+		t.Errorf("files are %v, expected none", manifest.Files)
+	}
+}
+
+// entries are added and replaced by name, sorted, and dropped once their
+// file is gone
+func TestUpdateManifest(t *testing.T) {
+	var directory string = t.TempDir()
+	var exporter Exporter = Exporter{Directory: directory}
+
+	for _, name := range []string{"b.csv.gz", "a.csv.gz", "c.csv.gz"} {
+		err := os.WriteFile(filepath.Join(directory, name), []byte(name), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := exporter.updateManifest(
+		ManifestFile{Name: "b.csv.gz", RowCount: 1},
+		ManifestFile{Name: "c.csv.gz", RowCount: 1},
+	)
+	if err != nil {
+		t.Fatalf("error in updating the manifest: %v", err)
+	}
+
+	// c.csv.gz is removed, b.csv.gz rewritten and a.csv.gz added
+	err = os.Remove(filepath.Join(directory, "c.csv.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = exporter.updateManifest(
+		ManifestFile{Name: "b.csv.gz", RowCount: 2},
+		ManifestFile{Name: "a.csv.gz", RowCount: 3},
+	)
+	if err != nil {
+		t.Fatalf("error in updating the manifest: %v", err)
+	}
+
+	manifest, err := ReadManifest(directory)
+	if err != nil {
+		t.Fatalf("error in reading the manifest: %v", err)
+	}
+	var sliceExpected []ManifestFile = []ManifestFile{
+		{Name: "a.csv.gz", RowCount: 3},
+		{Name: "b.csv.gz", RowCount: 2},
+	}
+	if len(manifest.Files) != len(sliceExpected) {
+		t.Fatalf("files are %v, expected %v", manifest.Files, sliceExpected)
+	}
+	for i, file := range manifest.Files {
+		if file.Name != sliceExpected[i].Name || file.RowCount != sliceExpected[i].RowCount {
+			t.Errorf("file %d is %+v, expected %+v", i, file, sliceExpected[i])
+		}
+	}
+	if manifest.TimeUpdated.IsZero() {
+		t.Error("time updated is not set")
+	}
+
+	// no temporary files are left behind
+	sliceTemporary, _ := filepath.Glob(filepath.Join(directory, ".tmp-*"))
+	if len(sliceTemporary) != 0 {
+		t.Errorf("temporary files are left: %v", sliceTemporary)
+	}
+}
+
+// the newest file of a dataset is the one with the last time stamp
+func TestManifestLatest(t *testing.T) {
+	var manifest Manifest = Manifest{Files: []ManifestFile{
+		{Name: "temperature-wet-bulb-20240502T000000Z.csv.gz", Dataset: DatasetTemperatureWetBulb},
+		{Name: "temperature-wet-bulb-daily-2024-05-03.csv.gz", Dataset: DatasetTemperatureWetBulbDaily},
+		{Name: "temperature-wet-bulb-20240501T000000Z.csv.gz", Dataset: DatasetTemperatureWetBulb},
+	}}
+
+	latest, ok := manifest.Latest(DatasetTemperatureWetBulb)
+	if !ok || latest.Name != "temperature-wet-bulb-20240502T000000Z.csv.gz" {
+		t.Errorf("latest is %q (found %t), expected the file of 2 May", latest.Name, ok)
+	}
+	_, ok = manifest.Latest(DatasetMeasurementHistory)
+	if ok {
+		t.Error("latest of a dataset without files is found")
+	}
+}
+
+// full exports never change, period files only once written after their
+// period ended
+func TestManifestFileIsImmutable(t *testing.T) {
+	var timeTo time.Time = time.Date(2024, time.May, 2, 0, 0, 0, 0, time.UTC)
+
+	var sliceTests = []struct {
+		name        string
+		file        ManifestFile
+		isImmutable bool
+	}{
+		{
+			"full export",
+			ManifestFile{Dataset: DatasetTemperatureWetBulb, TimeTo: timeTo, TimeCreated: timeTo},
+			true,
+		},
+		{
+			"day written during the day",
+			ManifestFile{Dataset: DatasetTemperatureWetBulbDaily, TimeTo: timeTo, TimeCreated: timeTo.Add(-time.Hour)},
+			false,
+		},
+		{
+			"day written within the grace period",
+			ManifestFile{Dataset: DatasetTemperatureWetBulbDaily, TimeTo: timeTo, TimeCreated: timeTo.Add(gracePeriodFile / 2)},
+			false,
+		},
+		{
+			"day written after the grace period",
+			ManifestFile{Dataset: DatasetTemperatureWetBulbDaily, TimeTo: timeTo, TimeCreated: timeTo.Add(2 * gracePeriodFile)},
+			true,
+		},
+	}
+	for _, test := range sliceTests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.file.IsImmutable() != test.isImmutable {
+				t.Errorf("immutable is %t, expected %t", !test.isImmutable, test.isImmutable)
+			}
+		})
+	}
+}
@@ -0,0 +1,340 @@
+package datasets
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/parquet-go/parquet-go"
+)
+
+// prefix of the file names of the measurement history dataset
+const prefixMeasurementHistory string = "measurement-history"
+
+// extension of the parquet dataset files
+const extensionParquet string = ".parquet"
+
+// layout of the month in the partition file names
+const layoutMonthFileName string = "2006-01"
+
+// format of the parquet datasets in the manifest
+const formatParquet string = "parquet"
+
+// number of rows buffered before writing to the parquet file
+const sizeParquetWriteBatch int = 1000
+
+// one row of the measurement history dataset
+// measurements from weather union joined with the measurements from open
+// weather map of the same station and run and the wet bulb calculation.
+// nullable columns are pointers so that nulls are kept as nulls.
+type RowMeasurementHistory struct {
+	MeasurementID                       string     `db:"measurement_id" parquet:"measurement_id"`
+	MeasurementRunID                    string     `db:"measurement_run_id" parquet:"measurement_run_id"`
+	MeasurementTimeStamp                time.Time  `db:"measurement_time_stamp" parquet:"measurement_time_stamp,timestamp(microsecond)"`
+	CalculationID                       *string    `db:"calculation_id" parquet:"calculation_id,optional"`
+	CalculationMethod                   *string    `db:"calculation_method" parquet:"calculation_method,optional"`
+	CalculatedTemperatureDewPoint       *float64   `db:"calculated_temperature_dew_point" parquet:"calculated_temperature_dew_point,optional"`
+	CalculatedTemperatureWetBulb        *float64   `db:"calculated_temperature_wet_bulb" parquet:"calculated_temperature_wet_bulb,optional"`
+	CalculationTimeStamp                *time.Time `db:"calculation_time_stamp" parquet:"calculation_time_stamp,optional,timestamp(microsecond)"`
+	WeatherUnionStationCityName         string     `db:"weather_union_station_city_name" parquet:"weather_union_station_city_name,dict"`
+	WeatherUnionStationLocalityName     string     `db:"weather_union_station_locality_name" parquet:"weather_union_station_locality_name,dict"`
+	WeatherUnionStationLocalityID       string     `db:"weather_union_station_locality_id" parquet:"weather_union_station_locality_id,dict"`
+	WeatherUnionStationLongitude        float64    `db:"weather_union_station_longitude" parquet:"weather_union_station_longitude"`
+	WeatherUnionStationLatitude         float64    `db:"weather_union_station_latitude" parquet:"weather_union_station_latitude"`
+	WeatherUnionStationMessage          *string    `db:"weather_union_station_message" parquet:"weather_union_station_message,optional"`
+	WeatherUnionStationTemperature      *float64   `db:"weather_union_station_temperature" parquet:"weather_union_station_temperature,optional"`
+	WeatherUnionStationHumidity         *float64   `db:"weather_union_station_humidity" parquet:"weather_union_station_humidity,optional"`
+	WeatherUnionStationWindSpeed        *float64   `db:"weather_union_station_wind_speed" parquet:"weather_union_station_wind_speed,optional"`
+	WeatherUnionStationWindDirection    *float64   `db:"weather_union_station_wind_direction" parquet:"weather_union_station_wind_direction,optional"`
+	WeatherUnionStationRainIntensity    *float64   `db:"weather_union_station_rain_intensity" parquet:"weather_union_station_rain_intensity,optional"`
+	WeatherUnionStationRainAccumulation *float64   `db:"weather_union_station_rain_accumulation" parquet:"weather_union_station_rain_accumulation,optional"`
+	OpenWeatherMapMeasurementID         *string    `db:"open_weather_map_measurement_id" parquet:"open_weather_map_measurement_id,optional"`
+	OpenWeatherMapTimeZone              *string    `db:"open_weather_map_time_zone" parquet:"open_weather_map_time_zone,optional"`
+	OpenWeatherMapTimeZoneOffset        *int32     `db:"open_weather_map_time_zone_offset" parquet:"open_weather_map_time_zone_offset,optional"`
+	OpenWeatherMapTimeCurrent           *int64     `db:"open_weather_map_time_current" parquet:"open_weather_map_time_current,optional"`
+	OpenWeatherMapTimeSunrise           *int64     `db:"open_weather_map_time_sunrise" parquet:"open_weather_map_time_sunrise,optional"`
+	OpenWeatherMapTimeSunset            *int64     `db:"open_weather_map_time_sunset" parquet:"open_weather_map_time_sunset,optional"`
+	OpenWeatherMapTemperature           *float64   `db:"open_weather_map_temperature" parquet:"open_weather_map_temperature,optional"`
+	OpenWeatherMapFeelsLike             *float64   `db:"open_weather_map_feels_like" parquet:"open_weather_map_feels_like,optional"`
+	OpenWeatherMapPressure              *float64   `db:"open_weather_map_pressure" parquet:"open_weather_map_pressure,optional"`
+	OpenWeatherMapHumidity              *float64   `db:"open_weather_map_humidity" parquet:"open_weather_map_humidity,optional"`
+	OpenWeatherMapDewPoint              *float64   `db:"open_weather_map_dew_point" parquet:"open_weather_map_dew_point,optional"`
+	OpenWeatherMapUVIndex               *float64   `db:"open_weather_map_uv_index" parquet:"open_weather_map_uv_index,optional"`
+	OpenWeatherMapClouds                *float64   `db:"open_weather_map_clouds" parquet:"open_weather_map_clouds,optional"`
+	OpenWeatherMapVisibility            *int64     `db:"open_weather_map_visibility" parquet:"open_weather_map_visibility,optional"`
+	OpenWeatherMapWindSpeed             *float64   `db:"open_weather_map_wind_speed" parquet:"open_weather_map_wind_speed,optional"`
+	OpenWeatherMapWindDirection         *float64   `db:"open_weather_map_wind_direction" parquet:"open_weather_map_wind_direction,optional"`
+	OpenWeatherMapWindGust              *float64   `db:"open_weather_map_wind_gust" parquet:"open_weather_map_wind_gust,optional"`
+	OpenWeatherMapWeatherID             *int32     `db:"open_weather_map_weather_id" parquet:"open_weather_map_weather_id,optional"`
+	OpenWeatherMapWeatherMain           *string    `db:"open_weather_map_weather_main" parquet:"open_weather_map_weather_main,optional"`
+	OpenWeatherMapWeatherDescription    *string    `db:"open_weather_map_weather_description" parquet:"open_weather_map_weather_description,optional"`
+	OpenWeatherMapWeatherIcon           *string    `db:"open_weather_map_weather_icon" parquet:"open_weather_map_weather_icon,optional"`
+}
+
+// rows of the measurement history dataset in a time range
+// UUIDs are cast to text as parquet has no native UUID column here
+var queryStringMeasurementHistory string = `
+SELECT
+	mwu.measurement_id::TEXT            AS measurement_id,
+	mwu.run_id::TEXT                    AS measurement_run_id,
+	mwu.time_stamp                      AS measurement_time_stamp,
+	ct.calculation_id::TEXT             AS calculation_id,
+	ct.method                           AS calculation_method,
+	ct.temperature_dew_point            AS calculated_temperature_dew_point,
+	ct.temperature_wet_bulb             AS calculated_temperature_wet_bulb,
+	ct.time_stamp                       AS calculation_time_stamp,
+	wus.city_name                       AS weather_union_station_city_name,
+	wus.locality_name                   AS weather_union_station_locality_name,
+	wus.locality_id                     AS weather_union_station_locality_id,
+	ST_X(wus.location::geometry)        AS weather_union_station_longitude,
+	ST_Y(wus.location::geometry)        AS weather_union_station_latitude,
+	mwu.message                         AS weather_union_station_message,
+	mwu.temperature                     AS weather_union_station_temperature,
+	mwu.humidity                        AS weather_union_station_humidity,
+	mwu.wind_speed                      AS weather_union_station_wind_speed,
+	mwu.wind_direction                  AS weather_union_station_wind_direction,
+	mwu.rain_intensity                  AS weather_union_station_rain_intensity,
+	mwu.rain_accumulation               AS weather_union_station_rain_accumulation,
+	mowm.measurement_id::TEXT           AS open_weather_map_measurement_id,
+	mowm.time_zone                      AS open_weather_map_time_zone,
+	mowm.time_zone_offset               AS open_weather_map_time_zone_offset,
+	mowm.time_current                   AS open_weather_map_time_current,
+	mowm.time_sunrise                   AS open_weather_map_time_sunrise,
+	mowm.time_sunset                    AS open_weather_map_time_sunset,
+	mowm.temperature                    AS open_weather_map_temperature,
+	mowm.feels_like                     AS open_weather_map_feels_like,
+	mowm.pressure                       AS open_weather_map_pressure,
+	mowm.humidity                       AS open_weather_map_humidity,
+	mowm.dew_point                      AS open_weather_map_dew_point,
+	mowm.uv_index                       AS open_weather_map_uv_index,
+	mowm.clouds                         AS open_weather_map_clouds,
+	mowm.visibility                     AS open_weather_map_visibility,
+	mowm.wind_speed                     AS open_weather_map_wind_speed,
+	mowm.wind_direction                 AS open_weather_map_wind_direction,
+	mowm.wind_gust                      AS open_weather_map_wind_gust,
+	mowm.weather_object_id              AS open_weather_map_weather_id,
+	mowm.weather_object_main            AS open_weather_map_weather_main,
+	mowm.weather_object_description     AS open_weather_map_weather_description,
+	mowm.weather_object_icon            AS open_weather_map_weather_icon
+FROM measurements_weather_union mwu
+JOIN weather_union_stations wus
+	ON wus.weather_station_id = mwu.weather_station_id
+LEFT JOIN measurements_open_weather_map mowm
+	ON
+		mowm.weather_station_id = mwu.weather_station_id AND
+		mowm.run_id = mwu.run_id
+LEFT JOIN calculations_temperature ct
+	ON ct.measurement_id_weather_union = mwu.measurement_id
+WHERE
+	mwu.time_stamp >= @timeFrom AND
+	mwu.time_stamp < @timeTo
+ORDER BY mwu.time_stamp, mwu.measurement_id
+`
+
+// write the measurement history as parquet files partitioned by month
+// (UTC). months with a complete file in the manifest are skipped, so the
+// file of the current month is rewritten until the month is over.
+// returns the paths of the written files.
+func (exporter Exporter) ExportMeasurementHistoryParquet(
+	ctx context.Context,
+) ([]string, error) {
+	// postgresql query string
+	// all months (UTC) with measurements
+	var queryStringMonths string = `
+	SELECT DISTINCT
+		date_trunc('month', time_stamp AT TIME ZONE 'UTC') AS month
+	FROM measurements_weather_union
+	ORDER BY month;
+	`
+
+	// create a 5 second timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, 5*time.Second)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// prepare the query
+	rows, err := exporter.DB.Query(ctxWT, queryStringMonths)
+	if err != nil {
+		return nil, err
+	}
+	// run the query and collect rows
+	sliceMonths, err := pgx.CollectRows(rows, pgx.RowTo[time.Time])
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := ReadManifest(exporter.Directory)
+	if err != nil {
+		return nil, err
+	}
+
+	var slicePaths []string
+	for _, month := range sliceMonths {
+		// time range of the month in UTC
+		var timeFrom time.Time = time.Date(
+			month.Year(),
+			month.Month(),
+			1,
+			0,
+			0,
+			0,
+			0,
+			time.UTC,
+		)
+		var timeTo time.Time = timeFrom.AddDate(0, 1, 0)
+
+		var fileName string = prefixMeasurementHistory + "-" +
+			timeFrom.Format(layoutMonthFileName) + extensionParquet
+		var path string = filepath.Join(exporter.Directory, fileName)
+
+		// finished months do not change once written
+		if manifest.Has(fileName) && isPeriodFileComplete(path, timeTo) {
+			continue
+		}
+
+		rowCount, checksum, err := exporter.writeMeasurementHistoryParquet(
+			ctx,
+			path,
+			timeFrom,
+			timeTo,
+		)
+		if err != nil {
+			return slicePaths, err
+		}
+
+		exporter.Logger.Info("dataset exported", "path", path, "rows", rowCount)
+		slicePaths = append(slicePaths, path)
+
+		// add the file to the manifest
+		manifestFile, err := newManifestFile(
+			path,
+			prefixMeasurementHistory,
+			formatParquet,
+			rowCount,
+			checksum,
+			&timeFrom,
+			timeTo,
+		)
+		if err != nil {
+			return slicePaths, err
+		}
+		err = exporter.updateManifest(manifestFile)
+		if err != nil {
+			return slicePaths, err
+		}
+	}
+
+	// return paths of the new files if all okay
+	return slicePaths, nil
+}
+
+// stream the measurement history of a time range into a parquet file
+// returns the number of rows and the checksum of the file
+func (exporter Exporter) writeMeasurementHistoryParquet(
+	ctx context.Context,
+	path string,
+	timeFrom time.Time,
+	timeTo time.Time,
+) (int64, string, error) {
+	// named arguments for building the query string
+	var queryArguments pgx.NamedArgs = pgx.NamedArgs{
+		"timeFrom": timeFrom,
+		"timeTo":   timeTo,
+	}
+
+	// create a 5 minute timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// prepare the query
+	rows, err := exporter.DB.Query(
+		ctxWT,
+		queryStringMeasurementHistory,
+		queryArguments,
+	)
+	if err != nil {
+		return 0, "", err
+	}
+	defer rows.Close()
+
+	return writeRowsMeasurementHistory(path, rows)
+}
+
+// write the measurement history rows into a parquet file as they are read
+// the file is committed only once all rows were read without error.
+// returns the number of rows and the checksum of the file.
+func writeRowsMeasurementHistory(
+	path string,
+	rows pgx.Rows,
+) (int64, string, error) {
+	// temporary file renamed on commit
+	file, err := createAtomicFile(path)
+	if err != nil {
+		return 0, "", err
+	}
+
+	// zstd compressed parquet writer
+	parquetWriter := parquet.NewGenericWriter[RowMeasurementHistory](
+		file,
+		parquet.Compression(&parquet.Zstd),
+	)
+
+	// write the rows in batches as they arrive
+	var rowCount int64
+	var sliceBatch []RowMeasurementHistory = make(
+		[]RowMeasurementHistory,
+		0,
+		sizeParquetWriteBatch,
+	)
+	for rows.Next() {
+		row, err := pgx.RowToStructByName[RowMeasurementHistory](rows)
+		if err != nil {
+			file.Abort()
+			return 0, "", err
+		}
+		sliceBatch = append(sliceBatch, row)
+
+		if len(sliceBatch) == sizeParquetWriteBatch {
+			_, err = parquetWriter.Write(sliceBatch)
+			if err != nil {
+				file.Abort()
+				return 0, "", err
+			}
+			rowCount += int64(len(sliceBatch))
+			sliceBatch = sliceBatch[:0]
+		}
+	}
+	if err := rows.Err(); err != nil {
+		file.Abort()
+		return 0, "", fmt.Errorf(
+			"error in reading the measurement history from postgresql: %w",
+			err,
+		)
+	}
+
+	// write the last partial batch
+	if len(sliceBatch) > 0 {
+		_, err = parquetWriter.Write(sliceBatch)
+		if err != nil {
+			file.Abort()
+			return 0, "", err
+		}
+		rowCount += int64(len(sliceBatch))
+	}
+
+	// write the parquet footer before committing the file
+	err = parquetWriter.Close()
+	if err != nil {
+		file.Abort()
+		return 0, "", err
+	}
+	checksum, err := file.Commit()
+	if err != nil {
+		return 0, "", err
+	}
+
+	return rowCount, checksum, nil
+}
@@ -0,0 +1,112 @@
+package datasets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// measurement history rows of a station, one per hour
+// every other row has a calculation, the others are nulls
+func newRowsMeasurementHistory(count int) []RowMeasurementHistory {
+	var method string = "stull"
+	var temperature float64 = 26.3
+	var sliceRows []RowMeasurementHistory
+	for i := range count {
+		var row RowMeasurementHistory = RowMeasurementHistory{
+			MeasurementID:    "measurement",
+			MeasurementRunID: "run",
+			MeasurementTimeStamp: time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC).
+				Add(time.Duration(i) * time.Hour),
+			WeatherUnionStationCityName:     "Mumbai",
+			WeatherUnionStationLocalityName: "Andheri",
+			WeatherUnionStationLocalityID:   "ZWL001",
+		}
+		if i%2 == 0 {
+			row.CalculationMethod = &method
+			row.CalculatedTemperatureWetBulb = &temperature
+		}
+		sliceRows = append(sliceRows, row)
+	}
+	return sliceRows
+}
+
+// the rows are written in batches into a parquet file with its checksum
+// and nulls are kept as nulls
+func TestWriteRowsMeasurementHistory(t *testing.T) {
+	var path string = filepath.Join(t.TempDir(), "measurement-history-2024-05.parquet")
+	// more than two batches, so that a partial batch is left
+	var sliceRows []RowMeasurementHistory = newRowsMeasurementHistory(
+		2*sizeParquetWriteBatch + 1,
+	)
+
+	rowCount, checksum, err := writeRowsMeasurementHistory(
+		path,
+		&rowsFake[RowMeasurementHistory]{sliceRows: sliceRows},
+	)
+	if err != nil {
+		t.Fatalf("error in writing the rows: %v", err)
+	}
+	if rowCount != int64(len(sliceRows)) {
+		t.Errorf("rows written are %d, expected %d", rowCount, len(sliceRows))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("error in reading the file: %v", err)
+	}
+	var sum [32]byte = sha256.Sum256(data)
+	if checksum != hex.EncodeToString(sum[:]) {
+		t.Errorf("checksum is %s, expected the checksum of the file", checksum)
+	}
+
+	sliceRowsRead, err := parquet.ReadFile[RowMeasurementHistory](path)
+	if err != nil {
+		t.Fatalf("error in reading the parquet file: %v", err)
+	}
+	if len(sliceRowsRead) != len(sliceRows) {
+		t.Fatalf("rows read are %d, expected %d", len(sliceRowsRead), len(sliceRows))
+	}
+	for i, row := range sliceRowsRead {
+		if !row.MeasurementTimeStamp.Equal(sliceRows[i].MeasurementTimeStamp) {
+			t.Fatalf(
+				"row %d is at %v, expected %v",
+				i,
+				row.MeasurementTimeStamp,
+				sliceRows[i].MeasurementTimeStamp,
+			)
+		}
+		var isNull bool = row.CalculatedTemperatureWetBulb == nil
+		if isNull != (sliceRows[i].CalculatedTemperatureWetBulb == nil) {
+			t.Fatalf("row %d has a null wet bulb temperature: %t", i, isNull)
+		}
+	}
+}
+
+// a failed read leaves no file, sidecar or temporary file
+func TestWriteRowsMeasurementHistoryError(t *testing.T) {
+	var directory string = t.TempDir()
+	var errRows error = errors.New("connection reset")
+
+	_, _, err := writeRowsMeasurementHistory(
+		filepath.Join(directory, "measurement-history-2024-05.parquet"),
+		&rowsFake[RowMeasurementHistory]{
+			sliceRows: newRowsMeasurementHistory(sizeParquetWriteBatch + 1),
+			err:       errRows,
+		},
+	)
+	if !errors.Is(err, errRows) {
+		t.Fatalf("error is %v, expected %v", err, errRows)
+	}
+
+	var sliceNames []string = readDirNames(t, directory)
+	if len(sliceNames) != 0 {
+		t.Errorf("files are %v, expected none", sliceNames)
+	}
+}
@@ -0,0 +1,63 @@
+package datasets
+
+import (
+	"reflect"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// rows of a query that fail with an error after the given rows, if any
+// the columns are the db tags of the fields of T
+type rowsFake[T any] struct {
+	sliceRows []T
+	err       error
+	index     int
+}
+
+func (rows *rowsFake[T]) Close()                        {}
+func (rows *rowsFake[T]) CommandTag() pgconn.CommandTag { return pgconn.CommandTag{} }
+func (rows *rowsFake[T]) RawValues() [][]byte           { return nil }
+func (rows *rowsFake[T]) Conn() *pgx.Conn               { return nil }
+
+func (rows *rowsFake[T]) Err() error {
+	if rows.index > len(rows.sliceRows) {
+		return rows.err
+	}
+	return nil
+}
+
+func (rows *rowsFake[T]) Next() bool {
+	rows.index++
+	return rows.index <= len(rows.sliceRows)
+}
+
+// columns named by the db tags of the row
+func (rows *rowsFake[T]) FieldDescriptions() []pgconn.FieldDescription {
+	var typeRow reflect.Type = reflect.TypeFor[T]()
+	var sliceFields []pgconn.FieldDescription
+	for i := range typeRow.NumField() {
+		sliceFields = append(
+			sliceFields,
+			pgconn.FieldDescription{Name: typeRow.Field(i).Tag.Get("db")},
+		)
+	}
+	return sliceFields
+}
+
+func (rows *rowsFake[T]) Values() ([]any, error) {
+	var valueRow reflect.Value = reflect.ValueOf(rows.sliceRows[rows.index-1])
+	var sliceValues []any
+	for i := range valueRow.NumField() {
+		sliceValues = append(sliceValues, valueRow.Field(i).Interface())
+	}
+	return sliceValues, nil
+}
+
+func (rows *rowsFake[T]) Scan(dest ...any) error {
+	sliceValues, _ := rows.Values()
+	for i, value := range sliceValues {
+		reflect.ValueOf(dest[i]).Elem().Set(reflect.ValueOf(value))
+	}
+	return nil
+}
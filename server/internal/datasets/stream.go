@@ -0,0 +1,279 @@
+package datasets
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/parquet-go/parquet-go"
+)
+
+// formats of the on-demand export
+const (
+	FormatCSV     string = "csv"
+	FormatJSON    string = "json"
+	FormatParquet string = "parquet"
+)
+
+// time limit of the query of a stream, the write deadline of the on-demand
+// export is derived from it
+const TimeoutStream time.Duration = 5 * time.Minute
+
+// number of rows in a row group of a streamed parquet file
+// bounds the rows held in memory while streaming
+const sizeParquetRowGroupStream int64 = 10_000
+
+// filters of the on-demand export
+// empty (nil) filters are not applied
+type Filter struct {
+	CityName *string
+	TimeFrom *time.Time
+	TimeTo   *time.Time
+}
+
+// one row of the wet bulb temperature dataset
+// same columns as the CSV files in the downloads directory
+type RowTemperatureWetBulb struct {
+	CalculationID                       string    `db:"calculation_id" json:"calculation_id" parquet:"calculation_id"`
+	CalculationMethod                   string    `db:"calculation_method" json:"calculation_method" parquet:"calculation_method,dict"`
+	CalculatedTemperatureDewPoint       float64   `db:"calculated_temperature_dew_point" json:"calculated_temperature_dew_point" parquet:"calculated_temperature_dew_point"`
+	CalculatedTemperatureWetBulb        float64   `db:"calculated_temperature_wet_bulb" json:"calculated_temperature_wet_bulb" parquet:"calculated_temperature_wet_bulb"`
+	MeasurementID                       string    `db:"measurement_id" json:"measurement_id" parquet:"measurement_id"`
+	MeasurementRunID                    string    `db:"measurement_run_id" json:"measurement_run_id" parquet:"measurement_run_id"`
+	WeatherUnionStationCityName         string    `db:"weather_union_station_city_name" json:"weather_union_station_city_name" parquet:"weather_union_station_city_name,dict"`
+	WeatherUnionStationLocalityName     string    `db:"weather_union_station_locality_name" json:"weather_union_station_locality_name" parquet:"weather_union_station_locality_name,dict"`
+	WeatherUnionStationLocalityID       string    `db:"weather_union_station_locality_id" json:"weather_union_station_locality_id" parquet:"weather_union_station_locality_id,dict"`
+	WeatherUnionStationLongitude        float64   `db:"weather_union_station_longitude" json:"weather_union_station_longitude" parquet:"weather_union_station_longitude"`
+	WeatherUnionStationLatitude         float64   `db:"weather_union_station_latitude" json:"weather_union_station_latitude" parquet:"weather_union_station_latitude"`
+	WeatherUnionStationTemperature      *float64  `db:"weather_union_station_temperature" json:"weather_union_station_temperature" parquet:"weather_union_station_temperature,optional"`
+	WeatherUnionStationHumidity         *float64  `db:"weather_union_station_humidity" json:"weather_union_station_humidity" parquet:"weather_union_station_humidity,optional"`
+	WeatherUnionStationWindSpeed        *float64  `db:"weather_union_station_wind_speed" json:"weather_union_station_wind_speed" parquet:"weather_union_station_wind_speed,optional"`
+	WeatherUnionStationWindDirection    *float64  `db:"weather_union_station_wind_direction" json:"weather_union_station_wind_direction" parquet:"weather_union_station_wind_direction,optional"`
+	WeatherUnionStationRainIntensity    *float64  `db:"weather_union_station_rain_intensity" json:"weather_union_station_rain_intensity" parquet:"weather_union_station_rain_intensity,optional"`
+	WeatherUnionStationRainAccumulation *float64  `db:"weather_union_station_rain_accumulation" json:"weather_union_station_rain_accumulation" parquet:"weather_union_station_rain_accumulation,optional"`
+	MeasurementTimeStamp                time.Time `db:"measurement_time_stamp" json:"measurement_time_stamp" parquet:"measurement_time_stamp,timestamp(microsecond)"`
+}
+
+// header of the streamed CSV (same order as the struct fields)
+var headerCSVTemperatureWetBulb []string = []string{
+	"calculation_id",
+	"calculation_method",
+	"calculated_temperature_dew_point",
+	"calculated_temperature_wet_bulb",
+	"measurement_id",
+	"measurement_run_id",
+	"weather_union_station_city_name",
+	"weather_union_station_locality_name",
+	"weather_union_station_locality_id",
+	"weather_union_station_longitude",
+	"weather_union_station_latitude",
+	"weather_union_station_temperature",
+	"weather_union_station_humidity",
+	"weather_union_station_wind_speed",
+	"weather_union_station_wind_direction",
+	"weather_union_station_rain_intensity",
+	"weather_union_station_rain_accumulation",
+	"measurement_time_stamp",
+}
+
+// stream the wet bulb temperature rows matching the filter to the writer
+// in the given format. rows are written as they are read from
+// postgresql, so the result is never held in memory.
+// on error the output is left unfinished (no closing bracket or parquet
+// footer), so that a truncated stream is never a valid file.
+// returns the number of rows written.
+func (exporter Exporter) StreamTemperatureWetBulb(
+	ctx context.Context,
+	w io.Writer,
+	filter Filter,
+	format string,
+) (int64, error) {
+	// postgresql query string
+	// UUIDs are cast to text for the CSV, JSON and parquet output
+	var queryString string = `
+	SELECT
+		ct.calculation_id::TEXT         AS calculation_id,
+		ct.method                       AS calculation_method,
+		ct.temperature_dew_point        AS calculated_temperature_dew_point,
+		ct.temperature_wet_bulb         AS calculated_temperature_wet_bulb,
+		mwu.measurement_id::TEXT        AS measurement_id,
+		mwu.run_id::TEXT                AS measurement_run_id,
+		wus.city_name                   AS weather_union_station_city_name,
+		wus.locality_name               AS weather_union_station_locality_name,
+		wus.locality_id                 AS weather_union_station_locality_id,
+		ST_X(wus.location::geometry)    AS weather_union_station_longitude,
+		ST_Y(wus.location::geometry)    AS weather_union_station_latitude,
+		mwu.temperature                 AS weather_union_station_temperature,
+		mwu.humidity                    AS weather_union_station_humidity,
+		mwu.wind_speed                  AS weather_union_station_wind_speed,
+		mwu.wind_direction              AS weather_union_station_wind_direction,
+		mwu.rain_intensity              AS weather_union_station_rain_intensity,
+		mwu.rain_accumulation           AS weather_union_station_rain_accumulation,
+		mwu.time_stamp                  AS measurement_time_stamp
+	FROM calculations_temperature ct
+	JOIN measurements_weather_union mwu
+		ON mwu.measurement_id = ct.measurement_id_weather_union
+	JOIN weather_union_stations wus
+		ON wus.weather_station_id = mwu.weather_station_id
+	WHERE
+		(@cityName::TEXT IS NULL OR wus.city_name = @cityName) AND
+		(@timeFrom::TIMESTAMPTZ IS NULL OR mwu.time_stamp >= @timeFrom) AND
+		(@timeTo::TIMESTAMPTZ IS NULL OR mwu.time_stamp < @timeTo)
+	ORDER BY mwu.time_stamp DESC;
+	`
+
+	// named arguments for building the query string
+	var queryArguments pgx.NamedArgs = pgx.NamedArgs{
+		"cityName": filter.CityName,
+		"timeFrom": filter.TimeFrom,
+		"timeTo":   filter.TimeTo,
+	}
+
+	// create a timeout context for the whole stream
+	ctxWT, cancel := context.WithTimeout(ctx, TimeoutStream)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// prepare the query
+	rows, err := exporter.DB.Query(ctxWT, queryString, queryArguments)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	return writeRowsTemperatureWetBulb(w, rows, format)
+}
+
+// write the wet bulb temperature rows to the writer in the given format as
+// they are read
+// the output is finished only once all rows were read without error.
+// returns the number of rows written.
+func writeRowsTemperatureWetBulb(
+	w io.Writer,
+	rows pgx.Rows,
+	format string,
+) (int64, error) {
+	// read the first row before writing anything, so that the errors of
+	// the query are returned with nothing written
+	var isRow bool = rows.Next()
+	if !isRow && rows.Err() != nil {
+		return 0, fmt.Errorf(
+			"error in streaming the wet bulb temperatures from postgresql: %w",
+			rows.Err(),
+		)
+	}
+
+	// writer for the requested format
+	var rowWriter func(row RowTemperatureWetBulb) error
+	var closeWriter func() error
+	switch format {
+	case FormatCSV:
+		csvWriter := csv.NewWriter(w)
+		err := csvWriter.Write(headerCSVTemperatureWetBulb)
+		if err != nil {
+			return 0, err
+		}
+		rowWriter = func(row RowTemperatureWetBulb) error {
+			return csvWriter.Write(row.recordCSV())
+		}
+		closeWriter = func() error {
+			csvWriter.Flush()
+			return csvWriter.Error()
+		}
+	case FormatJSON:
+		// JSON array written one element at a time
+		var isFirst bool = true
+		_, err := io.WriteString(w, "[")
+		if err != nil {
+			return 0, err
+		}
+		jsonEncoder := json.NewEncoder(w)
+		rowWriter = func(row RowTemperatureWetBulb) error {
+			if !isFirst {
+				_, err := io.WriteString(w, ",")
+				if err != nil {
+					return err
+				}
+			}
+			isFirst = false
+			return jsonEncoder.Encode(row)
+		}
+		closeWriter = func() error {
+			_, err := io.WriteString(w, "]\n")
+			return err
+		}
+	case FormatParquet:
+		parquetWriter := parquet.NewGenericWriter[RowTemperatureWetBulb](
+			w,
+			parquet.Compression(&parquet.Zstd),
+			parquet.MaxRowsPerRowGroup(sizeParquetRowGroupStream),
+		)
+		rowWriter = func(row RowTemperatureWetBulb) error {
+			_, err := parquetWriter.Write([]RowTemperatureWetBulb{row})
+			return err
+		}
+		closeWriter = parquetWriter.Close
+	default:
+		return 0, fmt.Errorf("unknown export format: %s", format)
+	}
+
+	// write the rows as they arrive
+	var rowCount int64
+	for ; isRow; isRow = rows.Next() {
+		row, err := pgx.RowToStructByName[RowTemperatureWetBulb](rows)
+		if err != nil {
+			return rowCount, err
+		}
+		err = rowWriter(row)
+		if err != nil {
+			return rowCount, err
+		}
+		rowCount++
+	}
+	if err := rows.Err(); err != nil {
+		return rowCount, fmt.Errorf(
+			"error in streaming the wet bulb temperatures from postgresql: %w",
+			err,
+		)
+	}
+
+	// return number of rows if all okay
+	return rowCount, closeWriter()
+}
+
+// CSV record of a row, nulls are empty fields
+func (row RowTemperatureWetBulb) recordCSV() []string {
+	return []string{
+		row.CalculationID,
+		row.CalculationMethod,
+		formatFloat(&row.CalculatedTemperatureDewPoint),
+		formatFloat(&row.CalculatedTemperatureWetBulb),
+		row.MeasurementID,
+		row.MeasurementRunID,
+		row.WeatherUnionStationCityName,
+		row.WeatherUnionStationLocalityName,
+		row.WeatherUnionStationLocalityID,
+		formatFloat(&row.WeatherUnionStationLongitude),
+		formatFloat(&row.WeatherUnionStationLatitude),
+		formatFloat(row.WeatherUnionStationTemperature),
+		formatFloat(row.WeatherUnionStationHumidity),
+		formatFloat(row.WeatherUnionStationWindSpeed),
+		formatFloat(row.WeatherUnionStationWindDirection),
+		formatFloat(row.WeatherUnionStationRainIntensity),
+		formatFloat(row.WeatherUnionStationRainAccumulation),
+		row.MeasurementTimeStamp.UTC().Format(time.RFC3339Nano),
+	}
+}
+
+// format a nullable float for CSV
+func formatFloat(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'f', -1, 64)
+}
@@ -0,0 +1,145 @@
+package datasets
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// wet bulb temperature rows of a station, one per hour
+func newRowsTemperatureWetBulb(count int) []RowTemperatureWetBulb {
+	var temperature float64 = 31.5
+	var sliceRows []RowTemperatureWetBulb
+	for i := range count {
+		sliceRows = append(sliceRows, RowTemperatureWetBulb{
+			CalculationID:                   "calculation",
+			CalculationMethod:               "stull",
+			CalculatedTemperatureDewPoint:   24.1,
+			CalculatedTemperatureWetBulb:    26.3,
+			MeasurementID:                   "measurement",
+			MeasurementRunID:                "run",
+			WeatherUnionStationCityName:     "Mumbai",
+			WeatherUnionStationLocalityName: "Andheri",
+			WeatherUnionStationLocalityID:   "ZWL001",
+			WeatherUnionStationLongitude:    72.85,
+			WeatherUnionStationLatitude:     19.12,
+			WeatherUnionStationTemperature:  &temperature,
+			MeasurementTimeStamp: time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC).
+				Add(time.Duration(i) * time.Hour),
+		})
+	}
+	return sliceRows
+}
+
+// whether the output is a complete file of the format with the number of
+// rows
+func isCompleteFile(t *testing.T, format string, data []byte, rowCount int) bool {
+	t.Helper()
+
+	switch format {
+	case FormatCSV:
+		sliceRecords, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+		return err == nil && len(sliceRecords) == rowCount+1
+	case FormatJSON:
+		var sliceRows []RowTemperatureWetBulb
+		err := json.Unmarshal(data, &sliceRows)
+		return err == nil && len(sliceRows) == rowCount
+	case FormatParquet:
+		sliceRows, err := parquet.Read[RowTemperatureWetBulb](
+			bytes.NewReader(data),
+			int64(len(data)),
+		)
+		return err == nil && len(sliceRows) == rowCount
+	default:
+		t.Fatalf("unknown format %s", format)
+		return false
+	}
+}
+
+// all rows are written as a complete file of each format
+func TestWriteRowsTemperatureWetBulb(t *testing.T) {
+	for _, format := range []string{FormatCSV, FormatJSON, FormatParquet} {
+		t.Run(format, func(t *testing.T) {
+			var buffer bytes.Buffer
+			rowCount, err := writeRowsTemperatureWetBulb(
+				&buffer,
+				&rowsFake[RowTemperatureWetBulb]{sliceRows: newRowsTemperatureWetBulb(3)},
+				format,
+			)
+			if err != nil {
+				t.Fatalf("error in writing the rows: %v", err)
+			}
+			if rowCount != 3 {
+				t.Errorf("rows written are %d, expected 3", rowCount)
+			}
+			if !isCompleteFile(t, format, buffer.Bytes(), 3) {
+				t.Errorf("output is not a complete %s file of 3 rows", format)
+			}
+		})
+	}
+}
+
+// a stream that fails partway through is not finished, so that it never
+// reads as a valid file
+// a row more than a parquet row group, so that something is written
+func TestWriteRowsTemperatureWetBulbTruncated(t *testing.T) {
+	var errStream error = errors.New("connection reset")
+	var sliceRows []RowTemperatureWetBulb = newRowsTemperatureWetBulb(
+		int(sizeParquetRowGroupStream) + 1,
+	)
+
+	for _, format := range []string{FormatJSON, FormatParquet} {
+		t.Run(format, func(t *testing.T) {
+			var buffer bytes.Buffer
+			_, err := writeRowsTemperatureWetBulb(
+				&buffer,
+				&rowsFake[RowTemperatureWetBulb]{sliceRows: sliceRows, err: errStream},
+				format,
+			)
+			if !errors.Is(err, errStream) {
+				t.Fatalf("error is %v, expected %v", err, errStream)
+			}
+			if buffer.Len() == 0 {
+				t.Fatal("nothing was written before the error")
+			}
+			if json.Valid(buffer.Bytes()) {
+				t.Error("truncated output is valid JSON")
+			}
+			_, err = parquet.Read[RowTemperatureWetBulb](
+				bytes.NewReader(buffer.Bytes()),
+				int64(buffer.Len()),
+			)
+			if err == nil {
+				t.Error("truncated output is a valid parquet file")
+			}
+		})
+	}
+}
+
+// an error before the first row is returned with nothing written, so that
+// it can still be sent as an error response
+func TestWriteRowsTemperatureWetBulbErrorFirst(t *testing.T) {
+	var errQuery error = errors.New("statement timeout")
+
+	for _, format := range []string{FormatCSV, FormatJSON, FormatParquet} {
+		t.Run(format, func(t *testing.T) {
+			var buffer bytes.Buffer
+			_, err := writeRowsTemperatureWetBulb(
+				&buffer,
+				&rowsFake[RowTemperatureWetBulb]{err: errQuery},
+				format,
+			)
+			if !errors.Is(err, errQuery) {
+				t.Fatalf("error is %v, expected %v", err, errQuery)
+			}
+			if buffer.Len() != 0 {
+				t.Errorf("%d bytes were written before the error", buffer.Len())
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+// Package fixtures records the responses of the weather APIs to files and
+// replays them, so that runs can be repeated offline and deterministically.
+package fixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// modes of the transport
+const (
+	// requests are sent to the APIs, nothing is recorded
+	ModeOff string = "off"
+	// requests are sent to the APIs and their responses are recorded
+	ModeRecord string = "record"
+	// responses are read from the recorded fixtures, nothing is sent
+	ModeReplay string = "replay"
+)
+
+// modes of the transport, for the config checks
+var SliceModes []string = []string{ModeOff, ModeRecord, ModeReplay}
+
+// a request has no recorded fixture in replay mode
+var ErrFixtureNotFound = errors.New("fixture not found")
+
+// query parameters and headers carrying the API keys, never recorded
+var (
+	sliceQueryParametersSecret []string = []string{"appid"}
+	sliceHeadersSecret         []string = []string{"X-Zomato-Api-Key"}
+)
+
+// text replacing the API keys in the recorded bodies
+const textRedacted string = "REDACTED"
+
+type contextKey string
+
+const contextKeyLocality contextKey = "locality"
+
+// a recorded response, stored as JSON
+type fixture struct {
+	StatusCode  int    `json:"status_code"`
+	ContentType string `json:"content_type,omitempty"`
+	// body of a JSON response, kept as is for readable files
+	Body json.RawMessage `json:"body,omitempty"`
+	// body of any other response
+	BodyText string `json:"body_text,omitempty"`
+}
+
+// http transport that records the responses of the weather APIs to files
+// or replays them
+// the fixtures are stored at <directory>/<provider>/<endpoint>/<locality>.json,
+// with the locality of the request context (see WithLocality). the API
+// keys are removed from the recorded responses and only the status, the
+// content type and the body are kept.
+type Transport struct {
+	// underlying transport, http.DefaultTransport if nil
+	Base http.RoundTripper
+	// one of ModeOff, ModeRecord or ModeReplay, ModeOff if empty
+	Mode string
+	// directory of the fixtures
+	Directory string
+	// provider of the API, a directory of its own
+	Provider string
+}
+
+// add the locality of the requests to a context, the key of their fixtures
+func WithLocality(ctx context.Context, localityID string) context.Context {
+	return context.WithValue(ctx, contextKeyLocality, localityID)
+}
+
+func (transport Transport) RoundTrip(request *http.Request) (*http.Response, error) {
+	var base http.RoundTripper = transport.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	switch transport.Mode {
+	case ModeRecord:
+		response, err := base.RoundTrip(request)
+		if err != nil {
+			return response, err
+		}
+		err = transport.record(request, response)
+		if err != nil {
+			response.Body.Close()
+			return nil, err
+		}
+		return response, nil
+	case ModeReplay:
+		return transport.replay(request)
+	default:
+		return base.RoundTrip(request)
+	}
+}
+
+// path to the fixture of a request
+func (transport Transport) pathToFixture(request *http.Request) string {
+	var endpoint string = path.Base(request.URL.Path)
+
+	// the locality of the context, otherwise the query parameters without
+	// the API keys (locality_id for weather union, lat and lon for open
+	// weather map)
+	localityID, _ := request.Context().Value(contextKeyLocality).(string)
+	var key string = localityID
+	if key == "" {
+		var query url.Values = request.URL.Query()
+		var sliceParts []string
+		for _, name := range []string{"locality_id", "lat", "lon"} {
+			if value := query.Get(name); value != "" {
+				sliceParts = append(sliceParts, value)
+			}
+		}
+		key = strings.Join(sliceParts, "_")
+	}
+
+	return filepath.Join(
+		transport.Directory,
+		sanitizeName(transport.Provider),
+		sanitizeName(endpoint),
+		sanitizeName(key)+".json",
+	)
+}
+
+// save the response of a request, the body of the response is read and
+// replaced so that the caller can still read it
+func (transport Transport) record(request *http.Request, response *http.Response) error {
+	body, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return fmt.Errorf("error in reading response to record: %w", err)
+	}
+	response.Body = io.NopCloser(bytes.NewReader(body))
+
+	// an error response may echo the API keys back
+	var bodyRedacted []byte = body
+	for _, secret := range secrets(request) {
+		bodyRedacted = bytes.ReplaceAll(bodyRedacted, []byte(secret), []byte(textRedacted))
+	}
+
+	var fixtureResponse fixture = fixture{
+		StatusCode:  response.StatusCode,
+		ContentType: response.Header.Get("Content-Type"),
+	}
+	if json.Valid(bodyRedacted) {
+		fixtureResponse.Body = json.RawMessage(bodyRedacted)
+	} else {
+		fixtureResponse.BodyText = string(bodyRedacted)
+	}
+
+	data, err := json.MarshalIndent(fixtureResponse, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error in encoding fixture: %w", err)
+	}
+
+	var pathToFixture string = transport.pathToFixture(request)
+	err = os.MkdirAll(filepath.Dir(pathToFixture), 0o755)
+	if err != nil {
+		return fmt.Errorf("error in creating fixture directory: %w", err)
+	}
+	err = os.WriteFile(pathToFixture, append(data, '\n'), 0o644)
+	if err != nil {
+		return fmt.Errorf("error in writing fixture: %w", err)
+	}
+
+	return nil
+}
+
+// build the response of a request from its fixture
+func (transport Transport) replay(request *http.Request) (*http.Response, error) {
+	var pathToFixture string = transport.pathToFixture(request)
+	data, err := os.ReadFile(pathToFixture)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrFixtureNotFound, pathToFixture)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error in reading fixture: %w", err)
+	}
+
+	var fixtureResponse fixture
+	err = json.Unmarshal(data, &fixtureResponse)
+	if err != nil {
+		return nil, fmt.Errorf("error in decoding fixture %s: %w", pathToFixture, err)
+	}
+
+	var body []byte = []byte(fixtureResponse.BodyText)
+	if len(fixtureResponse.Body) > 0 {
+		body = fixtureResponse.Body
+	}
+
+	var header http.Header = http.Header{}
+	if fixtureResponse.ContentType != "" {
+		header.Set("Content-Type", fixtureResponse.ContentType)
+	}
+
+	return &http.Response{
+		Status: fmt.Sprintf(
+			"%d %s",
+			fixtureResponse.StatusCode,
+			http.StatusText(fixtureResponse.StatusCode),
+		),
+		StatusCode:    fixtureResponse.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       request,
+	}, nil
+}
+
+// API keys sent with a request
+func secrets(request *http.Request) []string {
+	var sliceSecrets []string
+	var query url.Values = request.URL.Query()
+	for _, name := range sliceQueryParametersSecret {
+		if value := query.Get(name); value != "" {
+			sliceSecrets = append(sliceSecrets, value)
+		}
+	}
+	for _, name := range sliceHeadersSecret {
+		if value := request.Header.Get(name); value != "" {
+			sliceSecrets = append(sliceSecrets, value)
+		}
+	}
+	return sliceSecrets
+}
+
+// keep letters, digits, dots, dashes and underscores of a file name
+func sanitizeName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r == '.' || r == '-' || r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+	// no empty names and no parent directories
+	if strings.Trim(name, ".") == "" {
+		return "_"
+	}
+	return name
+}
@@ -0,0 +1,40 @@
+package geo
+
+import "encoding/json"
+
+// GeoJSON feature collection
+// see: https://datatracker.ietf.org/doc/html/rfc7946
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// GeoJSON feature
+// geometry is kept as raw JSON as it is usually built by PostGIS
+type Feature struct {
+	Type       string          `json:"type"`
+	Geometry   json.RawMessage `json:"geometry"`
+	Properties map[string]any  `json:"properties"`
+}
+
+// GeoJSON multi polygon geometry
+type MultiPolygon struct {
+	Type        string    `json:"type"`
+	Coordinates []Polygon `json:"coordinates"`
+}
+
+// create an empty feature collection
+func NewFeatureCollection() FeatureCollection {
+	return FeatureCollection{
+		Type:     "FeatureCollection",
+		Features: []Feature{},
+	}
+}
+
+// create a multi polygon geometry from polygons
+func NewMultiPolygon(polygons []Polygon) MultiPolygon {
+	return MultiPolygon{
+		Type:        "MultiPolygon",
+		Coordinates: polygons,
+	}
+}
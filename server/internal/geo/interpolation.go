@@ -0,0 +1,200 @@
+package geo
+
+import (
+	"context"
+	"errors"
+	"math"
+)
+
+// approximate length of one degree of latitude in kilometres
+const kilometresPerDegreeLatitude float64 = 111.32
+
+// maximum number of nodes allowed in a single interpolated grid, and of
+// distances from the nodes to the points computed for it
+// the grids are interpolated on requests, so these bound the work of one
+const (
+	maximumGridNodes     int = 250_000
+	maximumGridDistances int = 50_000_000
+)
+
+// returned when the grid would have more nodes, or take more distances to
+// interpolate, than allowed
+var ErrGridTooLarge = errors.New("grid is too large, increase the cell size")
+
+// a single value at a location
+type Point struct {
+	Longitude float64
+	Latitude  float64
+	Value     float64
+}
+
+// regular latitude/longitude grid of interpolated values
+// values are indexed as Values[row][column] where rows increase with
+// latitude and columns increase with longitude.
+// nodes without any data nearby hold NaN.
+type Grid struct {
+	MinLongitude  float64
+	MinLatitude   float64
+	StepLongitude float64
+	StepLatitude  float64
+	Values        [][]float64
+}
+
+// options for the inverse distance weighted interpolation
+type InterpolationOptions struct {
+	// size of a grid cell
+	CellSizeKilometres float64
+	// padding added around the bounding box of the points
+	MarginKilometres float64
+	// nodes further than this from every point are left empty (NaN)
+	MaxDistanceKilometres float64
+	// power parameter of the weights (usually 2)
+	Power float64
+}
+
+// number of rows in the grid
+func (grid Grid) Rows() int {
+	return len(grid.Values)
+}
+
+// number of columns in the grid
+func (grid Grid) Columns() int {
+	if len(grid.Values) == 0 {
+		return 0
+	}
+	return len(grid.Values[0])
+}
+
+// longitude and latitude of the node at (row, column)
+// rows and columns outside the grid are extrapolated
+func (grid Grid) Position(row int, column int) (float64, float64) {
+	var longitude float64 = grid.MinLongitude + float64(column)*grid.StepLongitude
+	var latitude float64 = grid.MinLatitude + float64(row)*grid.StepLatitude
+	return longitude, latitude
+}
+
+// interpolate the points onto a regular grid covering their bounding box
+// using inverse distance weighting
+// the interpolation stops with the error of the context once it is done
+func InterpolateInverseDistanceWeighted(
+	ctx context.Context,
+	points []Point,
+	options InterpolationOptions,
+) (Grid, error) {
+	if len(points) == 0 {
+		return Grid{}, errors.New("no points to interpolate")
+	}
+	if options.CellSizeKilometres <= 0 {
+		return Grid{}, errors.New("cell size must be positive")
+	}
+	if options.MaxDistanceKilometres <= 0 {
+		return Grid{}, errors.New("maximum distance must be positive")
+	}
+
+	// bounding box of all points
+	var minLongitude, maxLongitude float64 = points[0].Longitude, points[0].Longitude
+	var minLatitude, maxLatitude float64 = points[0].Latitude, points[0].Latitude
+	for _, point := range points[1:] {
+		minLongitude = math.Min(minLongitude, point.Longitude)
+		maxLongitude = math.Max(maxLongitude, point.Longitude)
+		minLatitude = math.Min(minLatitude, point.Latitude)
+		maxLatitude = math.Max(maxLatitude, point.Latitude)
+	}
+
+	// convert kilometres to degrees at the centre of the bounding box
+	var kilometresPerDegreeLongitude float64 = kilometresPerDegree(
+		(minLatitude + maxLatitude) / 2,
+	)
+	var stepLatitude float64 = options.CellSizeKilometres / kilometresPerDegreeLatitude
+	var stepLongitude float64 = options.CellSizeKilometres / kilometresPerDegreeLongitude
+	var marginLatitude float64 = options.MarginKilometres / kilometresPerDegreeLatitude
+	var marginLongitude float64 = options.MarginKilometres / kilometresPerDegreeLongitude
+
+	// pad the bounding box with the margin
+	minLongitude -= marginLongitude
+	maxLongitude += marginLongitude
+	minLatitude -= marginLatitude
+	maxLatitude += marginLatitude
+
+	// grid dimensions
+	var rows int = int(math.Ceil((maxLatitude-minLatitude)/stepLatitude)) + 1
+	var columns int = int(math.Ceil((maxLongitude-minLongitude)/stepLongitude)) + 1
+	if rows*columns > maximumGridNodes ||
+		rows*columns*len(points) > maximumGridDistances {
+		return Grid{}, ErrGridTooLarge
+	}
+
+	grid := Grid{
+		MinLongitude:  minLongitude,
+		MinLatitude:   minLatitude,
+		StepLongitude: stepLongitude,
+		StepLatitude:  stepLatitude,
+		Values:        make([][]float64, rows),
+	}
+
+	// interpolate the value at each node
+	for row := 0; row < rows; row++ {
+		// the request may have been abandoned
+		if err := ctx.Err(); err != nil {
+			return Grid{}, err
+		}
+		grid.Values[row] = make([]float64, columns)
+		for column := 0; column < columns; column++ {
+			longitude, latitude := grid.Position(row, column)
+
+			var sumWeights, sumWeightedValues float64
+			var isExact bool
+			for _, point := range points {
+				distance := DistanceKilometres(
+					longitude,
+					latitude,
+					point.Longitude,
+					point.Latitude,
+				)
+				if distance > options.MaxDistanceKilometres {
+					continue
+				}
+				// node sits on top of a point
+				if distance < 1e-9 {
+					grid.Values[row][column] = point.Value
+					isExact = true
+					break
+				}
+				weight := 1 / math.Pow(distance, options.Power)
+				sumWeights += weight
+				sumWeightedValues += weight * point.Value
+			}
+			if isExact {
+				continue
+			}
+
+			// no points in range of this node
+			if sumWeights == 0 {
+				grid.Values[row][column] = math.NaN()
+				continue
+			}
+			grid.Values[row][column] = sumWeightedValues / sumWeights
+		}
+	}
+
+	return grid, nil
+}
+
+// equirectangular approximation of the distance between two positions
+// accurate enough over the extent of a city
+func DistanceKilometres(
+	longitudeA float64,
+	latitudeA float64,
+	longitudeB float64,
+	latitudeB float64,
+) float64 {
+	var dx float64 = (longitudeB - longitudeA) *
+		kilometresPerDegree((latitudeA+latitudeB)/2)
+	var dy float64 = (latitudeB - latitudeA) * kilometresPerDegreeLatitude
+	return math.Hypot(dx, dy)
+}
+
+// length of one degree of longitude in kilometres at a latitude
+func kilometresPerDegree(latitude float64) float64 {
+	return kilometresPerDegreeLatitude * math.Cos(latitude*math.Pi/180)
+}
@@ -0,0 +1,147 @@
+package geo
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+)
+
+// options of the tests, with a cell of about a tenth of a degree
+var optionsTest InterpolationOptions = InterpolationOptions{
+	CellSizeKilometres:    kilometresPerDegreeLatitude / 10,
+	MarginKilometres:      0,
+	MaxDistanceKilometres: 20,
+	Power:                 2,
+}
+
+func TestInterpolateInverseDistanceWeighted(t *testing.T) {
+	// two points a degree of latitude apart on the equator, so that the
+	// grid is a single column of eleven nodes
+	var slicePoints []Point = []Point{
+		{Longitude: 0, Latitude: 0, Value: 10},
+		{Longitude: 0, Latitude: 1, Value: 20},
+	}
+
+	grid, err := InterpolateInverseDistanceWeighted(context.Background(), slicePoints, optionsTest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if grid.Rows() != 11 || grid.Columns() != 1 {
+		t.Fatalf("grid has %d rows and %d columns, expected 11 and 1", grid.Rows(), grid.Columns())
+	}
+
+	var sliceTests = []struct {
+		name string
+		row  int
+		// NaN for an empty node
+		value float64
+	}{
+		// nodes on top of the points take their values
+		{"first point", 0, 10},
+		{"second point", 10, 20},
+		// half way the weights are equal
+		{"half way", 5, 15},
+		// a fifth of the way the weights are 1/(0.2)² and 1/(0.8)²
+		{"a fifth of the way", 2, (10/0.04 + 20/0.64) / (1/0.04 + 1/0.64)},
+		// further than 20 km from the second point
+		{"out of range of one point", 1, 10},
+	}
+	for _, test := range sliceTests {
+		t.Run(test.name, func(t *testing.T) {
+			var value float64 = grid.Values[test.row][0]
+			if math.Abs(value-test.value) > 1e-6 {
+				t.Errorf("value is %g, expected %g", value, test.value)
+			}
+		})
+	}
+}
+
+// nodes out of range of every point are empty
+func TestInterpolateInverseDistanceWeightedEmptyNodes(t *testing.T) {
+	var slicePoints []Point = []Point{
+		{Longitude: 0, Latitude: 0, Value: 10},
+		{Longitude: 0, Latitude: 1, Value: 20},
+	}
+	var options InterpolationOptions = optionsTest
+	options.MaxDistanceKilometres = 5
+
+	grid, err := InterpolateInverseDistanceWeighted(context.Background(), slicePoints, options)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for row := 1; row < grid.Rows()-1; row++ {
+		if !math.IsNaN(grid.Values[row][0]) {
+			t.Errorf("node of row %d is %g, expected NaN", row, grid.Values[row][0])
+		}
+	}
+}
+
+func TestInterpolateInverseDistanceWeightedErrors(t *testing.T) {
+	var slicePoints []Point = []Point{
+		{Longitude: 0, Latitude: 0, Value: 10},
+		{Longitude: 1, Latitude: 1, Value: 20},
+	}
+	ctxCanceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var optionsCellZero InterpolationOptions = optionsTest
+	optionsCellZero.CellSizeKilometres = 0
+	var optionsDistanceZero InterpolationOptions = optionsTest
+	optionsDistanceZero.MaxDistanceKilometres = 0
+	// a metre over a degree, ten billion nodes
+	var optionsCellSmall InterpolationOptions = optionsTest
+	optionsCellSmall.CellSizeKilometres = 0.001
+
+	var sliceTests = []struct {
+		name    string
+		ctx     context.Context
+		points  []Point
+		options InterpolationOptions
+		// nil for any error
+		errIs error
+	}{
+		{"no points", context.Background(), nil, optionsTest, nil},
+		{"cell size of 0", context.Background(), slicePoints, optionsCellZero, nil},
+		{"maximum distance of 0", context.Background(), slicePoints, optionsDistanceZero, nil},
+		{"too many nodes", context.Background(), slicePoints, optionsCellSmall, ErrGridTooLarge},
+		{"canceled", ctxCanceled, slicePoints, optionsTest, context.Canceled},
+	}
+	for _, test := range sliceTests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := InterpolateInverseDistanceWeighted(test.ctx, test.points, test.options)
+			if err == nil {
+				t.Fatal("no error")
+			}
+			if test.errIs != nil && !errors.Is(err, test.errIs) {
+				t.Errorf("error is %v, expected %v", err, test.errIs)
+			}
+		})
+	}
+}
+
+// the distances to the points count against the grid as well as the nodes
+func TestInterpolateInverseDistanceWeightedTooManyDistances(t *testing.T) {
+	// 101 by 101 nodes, under the maximum of nodes
+	var slicePoints []Point = []Point{
+		{Longitude: 0, Latitude: 0, Value: 10},
+		{Longitude: 10, Latitude: 10, Value: 20},
+	}
+	var options InterpolationOptions = optionsTest
+	options.CellSizeKilometres = kilometresPerDegreeLatitude / 10
+
+	_, err := InterpolateInverseDistanceWeighted(context.Background(), slicePoints, options)
+	if err != nil {
+		t.Fatalf("unexpected error with two points: %v", err)
+	}
+
+	// as many points as it takes to go over the maximum of distances
+	var numberOfNodes int = 101 * 101
+	for len(slicePoints)*numberOfNodes <= maximumGridDistances {
+		slicePoints = append(slicePoints, Point{Longitude: 5, Latitude: 5, Value: 15})
+	}
+	_, err = InterpolateInverseDistanceWeighted(context.Background(), slicePoints, options)
+	if !errors.Is(err, ErrGridTooLarge) {
+		t.Errorf("error is %v, expected %v", err, ErrGridTooLarge)
+	}
+}
@@ -0,0 +1,253 @@
+package geo
+
+import (
+	"math"
+	"sort"
+)
+
+// closed ring of [longitude, latitude] positions
+// the first position is repeated at the end
+type Ring [][]float64
+
+// polygon made of an exterior ring followed by its holes
+// exterior rings are counter-clockwise and holes are clockwise
+// (right-hand rule of GeoJSON)
+type Polygon []Ring
+
+// edge of the grid between two neighbouring nodes
+// horizontal edges join (row, column) and (row, column+1)
+// vertical edges join (row, column) and (row+1, column)
+type edgeKey struct {
+	row        int
+	column     int
+	isVertical bool
+}
+
+// a contour crossing on one edge of a cell
+type edgeCrossing struct {
+	edge           edgeKey
+	isAboveToBelow bool
+}
+
+// trace the regions of the grid where the value is greater than or
+// equal to the level using marching squares.
+// nodes holding NaN and everything outside the grid count as below the
+// level, so every region is returned as a closed polygon.
+func (grid Grid) Contour(level float64) []Polygon {
+	var rows int = grid.Rows()
+	var columns int = grid.Columns()
+
+	// contour segments keyed by the edge they start on
+	// segments are oriented so that the region above the level is on
+	// their left
+	var segments map[edgeKey]edgeKey = make(map[edgeKey]edgeKey)
+
+	// iterate over all cells including a one cell border around the grid
+	for row := -1; row < rows; row++ {
+		for column := -1; column < columns; column++ {
+			// corners in counter-clockwise order
+			// bottom-left, bottom-right, top-right, top-left
+			var corners [4]float64 = [4]float64{
+				grid.valueAt(row, column),
+				grid.valueAt(row, column+1),
+				grid.valueAt(row+1, column+1),
+				grid.valueAt(row+1, column),
+			}
+			// edges leaving each corner in counter-clockwise order
+			// bottom, right, top, left
+			var edges [4]edgeKey = [4]edgeKey{
+				{row: row, column: column, isVertical: false},
+				{row: row, column: column + 1, isVertical: true},
+				{row: row + 1, column: column, isVertical: false},
+				{row: row, column: column, isVertical: true},
+			}
+
+			// collect the crossings walking around the cell
+			var crossings []edgeCrossing
+			for i := 0; i < 4; i++ {
+				var isAboveFrom bool = corners[i] >= level
+				var isAboveTo bool = corners[(i+1)%4] >= level
+				if isAboveFrom != isAboveTo {
+					crossings = append(crossings, edgeCrossing{
+						edge:           edges[i],
+						isAboveToBelow: isAboveFrom,
+					})
+				}
+			}
+			if len(crossings) == 0 {
+				continue
+			}
+
+			// saddle cells are resolved with the mean of the corners
+			// if the centre is above, the corners above are joined
+			var centre float64 = (corners[0] + corners[1] + corners[2] + corners[3]) / 4
+			var isCentreAbove bool = centre >= level
+
+			// join each above-to-below crossing with a below-to-above one
+			var count int = len(crossings)
+			for i, crossing := range crossings {
+				if !crossing.isAboveToBelow {
+					continue
+				}
+				var j int = (i - 1 + count) % count
+				if isCentreAbove {
+					j = (i + 1) % count
+				}
+				segments[crossing.edge] = crossings[j].edge
+			}
+		}
+	}
+
+	// sort the start edges so that the output is deterministic
+	var startEdges []edgeKey = make([]edgeKey, 0, len(segments))
+	for edge := range segments {
+		startEdges = append(startEdges, edge)
+	}
+	sort.Slice(startEdges, func(i, j int) bool {
+		if startEdges[i].row != startEdges[j].row {
+			return startEdges[i].row < startEdges[j].row
+		}
+		if startEdges[i].column != startEdges[j].column {
+			return startEdges[i].column < startEdges[j].column
+		}
+		return !startEdges[i].isVertical && startEdges[j].isVertical
+	})
+
+	// link the segments into closed rings
+	var exteriors []Ring
+	var holes []Ring
+	var visited map[edgeKey]bool = make(map[edgeKey]bool)
+	for _, start := range startEdges {
+		if visited[start] {
+			continue
+		}
+
+		var ring Ring
+		var edge edgeKey = start
+		for {
+			visited[edge] = true
+			position := grid.crossingPosition(edge, level)
+			// skip repeated positions from crossings on empty nodes
+			if len(ring) == 0 || !isSamePosition(ring[len(ring)-1], position) {
+				ring = append(ring, position)
+			}
+			next, ok := segments[edge]
+			if !ok || next == start {
+				break
+			}
+			edge = next
+		}
+		if len(ring) > 1 && isSamePosition(ring[0], ring[len(ring)-1]) {
+			ring = ring[:len(ring)-1]
+		}
+		// a ring needs at least three distinct positions
+		if len(ring) < 3 {
+			continue
+		}
+		// close the ring
+		ring = append(ring, []float64{ring[0][0], ring[0][1]})
+
+		var area float64 = ring.signedArea()
+		switch {
+		case area > 0:
+			exteriors = append(exteriors, ring)
+		case area < 0:
+			holes = append(holes, ring)
+		}
+	}
+
+	// start every polygon with its exterior ring
+	var polygons []Polygon = make([]Polygon, len(exteriors))
+	for i, exterior := range exteriors {
+		polygons[i] = Polygon{exterior}
+	}
+
+	// attach each hole to the smallest exterior ring containing it
+	for _, hole := range holes {
+		var index int = -1
+		var smallestArea float64 = math.Inf(1)
+		for i, exterior := range exteriors {
+			var area float64 = exterior.signedArea()
+			if area < smallestArea && exterior.contains(hole[0]) {
+				index = i
+				smallestArea = area
+			}
+		}
+		if index >= 0 {
+			polygons[index] = append(polygons[index], hole)
+		}
+	}
+
+	return polygons
+}
+
+// value at a node, NaN outside the grid
+func (grid Grid) valueAt(row int, column int) float64 {
+	if row < 0 || row >= grid.Rows() || column < 0 || column >= grid.Columns() {
+		return math.NaN()
+	}
+	return grid.Values[row][column]
+}
+
+// position where the contour crosses an edge
+// linear interpolation between the two nodes of the edge
+func (grid Grid) crossingPosition(edge edgeKey, level float64) []float64 {
+	var rowTo int = edge.row
+	var columnTo int = edge.column + 1
+	if edge.isVertical {
+		rowTo = edge.row + 1
+		columnTo = edge.column
+	}
+
+	var valueFrom float64 = grid.valueAt(edge.row, edge.column)
+	var valueTo float64 = grid.valueAt(rowTo, columnTo)
+
+	// place the crossing on the node with data if the other one is empty
+	var t float64
+	switch {
+	case math.IsNaN(valueFrom):
+		t = 1
+	case math.IsNaN(valueTo):
+		t = 0
+	default:
+		t = (level - valueFrom) / (valueTo - valueFrom)
+		t = math.Max(0, math.Min(1, t))
+	}
+
+	longitudeFrom, latitudeFrom := grid.Position(edge.row, edge.column)
+	longitudeTo, latitudeTo := grid.Position(rowTo, columnTo)
+
+	return []float64{
+		longitudeFrom + t*(longitudeTo-longitudeFrom),
+		latitudeFrom + t*(latitudeTo-latitudeFrom),
+	}
+}
+
+// signed area of a closed ring in square degrees (shoelace formula)
+// positive for counter-clockwise rings
+func (ring Ring) signedArea() float64 {
+	var area float64
+	for i := 0; i < len(ring)-1; i++ {
+		area += ring[i][0]*ring[i+1][1] - ring[i+1][0]*ring[i][1]
+	}
+	return area / 2
+}
+
+// check if a position lies inside a closed ring (ray casting)
+func (ring Ring) contains(position []float64) bool {
+	var isInside bool
+	for i, j := 0, len(ring)-2; i < len(ring)-1; j, i = i, i+1 {
+		var xi, yi float64 = ring[i][0], ring[i][1]
+		var xj, yj float64 = ring[j][0], ring[j][1]
+		if (yi > position[1]) != (yj > position[1]) &&
+			position[0] < (xj-xi)*(position[1]-yi)/(yj-yi)+xi {
+			isInside = !isInside
+		}
+	}
+	return isInside
+}
+
+// compare two positions
+func isSamePosition(a []float64, b []float64) bool {
+	return a[0] == b[0] && a[1] == b[1]
+}
@@ -0,0 +1,264 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+// grid of the values with its nodes on whole degrees, so that the node at
+// (row, column) is at longitude column and latitude row
+func newGridTest(values [][]float64) Grid {
+	return Grid{
+		MinLongitude:  0,
+		MinLatitude:   0,
+		StepLongitude: 1,
+		StepLatitude:  1,
+		Values:        values,
+	}
+}
+
+// values of a square grid by the Chebyshev distance of the nodes from its
+// centre
+func newGridRings(valuesByDistance ...float64) Grid {
+	var size int = 2*len(valuesByDistance) - 1
+	var centre int = len(valuesByDistance) - 1
+	var values [][]float64 = make([][]float64, size)
+	for row := range values {
+		values[row] = make([]float64, size)
+		for column := range values[row] {
+			var distance int = max(abs(row-centre), abs(column-centre))
+			values[row][column] = valuesByDistance[distance]
+		}
+	}
+	return newGridTest(values)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// every ring is closed, every exterior is counter-clockwise and every hole
+// clockwise and inside its exterior
+func checkPolygons(t *testing.T, polygons []Polygon) {
+	t.Helper()
+	for i, polygon := range polygons {
+		for j, ring := range polygon {
+			if len(ring) < 4 {
+				t.Errorf("ring %d of polygon %d has %d positions", j, i, len(ring))
+				continue
+			}
+			if !isSamePosition(ring[0], ring[len(ring)-1]) {
+				t.Errorf("ring %d of polygon %d is not closed", j, i)
+			}
+			var area float64 = ring.signedArea()
+			if j == 0 && area <= 0 {
+				t.Errorf("exterior of polygon %d is not counter-clockwise (area %g)", i, area)
+			}
+			if j > 0 && area >= 0 {
+				t.Errorf("hole %d of polygon %d is not clockwise (area %g)", j, i, area)
+			}
+			if j > 0 && !polygon[0].contains(ring[0]) {
+				t.Errorf("hole %d of polygon %d is outside its exterior", j, i)
+			}
+		}
+	}
+}
+
+func TestContour(t *testing.T) {
+	var NaN float64 = math.NaN()
+
+	var sliceTests = []struct {
+		name  string
+		grid  Grid
+		level float64
+		// number of rings of each polygon, in order
+		sliceRings []int
+		// area of the exterior of each polygon, in square degrees
+		sliceAreas []float64
+	}{
+		{
+			name:       "all below",
+			grid:       newGridTest([][]float64{{0, 0}, {0, 0}}),
+			level:      0.5,
+			sliceRings: []int{},
+		},
+		{
+			name:       "single node above",
+			grid:       newGridRings(1, 0),
+			level:      0.5,
+			sliceRings: []int{1},
+			// diamond half way to the neighbours
+			sliceAreas: []float64{0.5},
+		},
+		{
+			// the nodes outside the grid count as below, and the
+			// contour is placed on the nodes at the edge
+			name:       "all above closed at the border",
+			grid:       newGridTest([][]float64{{1, 1}, {1, 1}}),
+			level:      0.5,
+			sliceRings: []int{1},
+			sliceAreas: []float64{1},
+		},
+		{
+			name: "nan nodes at the border",
+			grid: newGridTest([][]float64{
+				{NaN, NaN, NaN},
+				{NaN, 1, 1},
+				{NaN, 1, 1},
+			}),
+			level:      0.5,
+			sliceRings: []int{1},
+			sliceAreas: []float64{1},
+		},
+		{
+			// the empty node counts as below and makes a hole
+			name:       "nan node inside",
+			grid:       newGridRings(NaN, 1),
+			level:      0.5,
+			sliceRings: []int{2},
+			sliceAreas: []float64{4},
+		},
+		{
+			// the mean of the corners (0.5) is above the level, so the
+			// corners above are joined
+			name:       "saddle joined",
+			grid:       newGridTest([][]float64{{1, 0}, {0, 1}}),
+			level:      0.4,
+			sliceRings: []int{1},
+		},
+		{
+			// the mean of the corners is below the level, so the corners
+			// above are apart
+			name:       "saddle apart",
+			grid:       newGridTest([][]float64{{1, 0}, {0, 1}}),
+			level:      0.6,
+			sliceRings: []int{1, 1},
+		},
+		{
+			// a ring of nodes above around a hole, which is kept
+			name:       "hole",
+			grid:       newGridRings(0, 1, 1),
+			level:      0.5,
+			sliceRings: []int{2},
+			sliceAreas: []float64{16},
+		},
+	}
+	for _, test := range sliceTests {
+		t.Run(test.name, func(t *testing.T) {
+			var polygons []Polygon = test.grid.Contour(test.level)
+			checkPolygons(t, polygons)
+
+			if len(polygons) != len(test.sliceRings) {
+				t.Fatalf("%d polygons, expected %d", len(polygons), len(test.sliceRings))
+			}
+			for i, polygon := range polygons {
+				if len(polygon) != test.sliceRings[i] {
+					t.Errorf("polygon %d has %d rings, expected %d", i, len(polygon), test.sliceRings[i])
+				}
+			}
+			for i, area := range test.sliceAreas {
+				if got := polygons[i][0].signedArea(); math.Abs(got-area) > 1e-9 {
+					t.Errorf("exterior of polygon %d has area %g, expected %g", i, got, area)
+				}
+			}
+		})
+	}
+}
+
+// the hole of the inner ring is attached to the inner exterior, which is
+// the smaller of the two exteriors containing it
+func TestContourHoleAttachment(t *testing.T) {
+	var polygons []Polygon = newGridRings(0, 1, 0, 1).Contour(0.5)
+	if len(polygons) != 2 || len(polygons[0]) != 2 || len(polygons[1]) != 2 {
+		t.Fatalf("expected two polygons with a hole each, got %v", polygons)
+	}
+
+	// the polygon with the smaller exterior has the smaller hole
+	var inner, outer Polygon = polygons[0], polygons[1]
+	if inner[0].signedArea() > outer[0].signedArea() {
+		inner, outer = outer, inner
+	}
+	if -inner[1].signedArea() >= -outer[1].signedArea() {
+		t.Errorf(
+			"hole of area %g attached to the inner exterior, hole of area %g to the outer one",
+			-inner[1].signedArea(),
+			-outer[1].signedArea(),
+		)
+	}
+	if !inner[0].contains(inner[1][0]) {
+		t.Error("hole of the inner exterior is outside of it")
+	}
+}
+
+func TestRingSignedArea(t *testing.T) {
+	var sliceTests = []struct {
+		name string
+		ring Ring
+		area float64
+	}{
+		{
+			name: "counter-clockwise square",
+			ring: Ring{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}},
+			area: 1,
+		},
+		{
+			name: "clockwise square",
+			ring: Ring{{0, 0}, {0, 1}, {1, 1}, {1, 0}, {0, 0}},
+			area: -1,
+		},
+		{
+			name: "triangle",
+			ring: Ring{{0, 0}, {4, 0}, {0, 3}, {0, 0}},
+			area: 6,
+		},
+		{
+			name: "concave",
+			ring: Ring{{0, 0}, {2, 0}, {2, 1}, {1, 1}, {1, 2}, {0, 2}, {0, 0}},
+			area: 3,
+		},
+		{
+			name: "degenerate",
+			ring: Ring{{0, 0}, {1, 1}, {2, 2}, {0, 0}},
+			area: 0,
+		},
+	}
+	for _, test := range sliceTests {
+		t.Run(test.name, func(t *testing.T) {
+			if area := test.ring.signedArea(); math.Abs(area-test.area) > 1e-12 {
+				t.Errorf("area is %g, expected %g", area, test.area)
+			}
+		})
+	}
+}
+
+func TestRingContains(t *testing.T) {
+	// L shape, the square (1, 1) to (2, 2) is outside
+	var ringConcave Ring = Ring{{0, 0}, {2, 0}, {2, 1}, {1, 1}, {1, 2}, {0, 2}, {0, 0}}
+	// square of side 2, clockwise
+	var ringClockwise Ring = Ring{{0, 0}, {0, 2}, {2, 2}, {2, 0}, {0, 0}}
+
+	var sliceTests = []struct {
+		name     string
+		ring     Ring
+		position []float64
+		isInside bool
+	}{
+		{"inside", ringConcave, []float64{0.5, 0.5}, true},
+		{"inside the arm", ringConcave, []float64{1.5, 0.5}, true},
+		{"in the notch", ringConcave, []float64{1.5, 1.5}, false},
+		{"outside", ringConcave, []float64{3, 0.5}, false},
+		{"below", ringConcave, []float64{0.5, -1}, false},
+		{"inside a clockwise ring", ringClockwise, []float64{1, 1}, true},
+		{"outside a clockwise ring", ringClockwise, []float64{-1, 1}, false},
+	}
+	for _, test := range sliceTests {
+		t.Run(test.name, func(t *testing.T) {
+			if isInside := test.ring.contains(test.position); isInside != test.isInside {
+				t.Errorf("contains is %v, expected %v", isInside, test.isInside)
+			}
+		})
+	}
+}
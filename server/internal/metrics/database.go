@@ -0,0 +1,207 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/cache"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// time the latest run is kept between scrapes, so that the scrapes do not
+// all query the database
+const durationRunLatestCache time.Duration = 30 * time.Second
+
+// key of the latest run in the cache of the collector
+const keyRunLatest string = "latest"
+
+// collector of the connection pool statistics and the age of the latest
+// run
+// the pool statistics are read on every scrape and the latest run at most
+// every durationRunLatestCache
+type collectorDatabase struct {
+	db *pgxpool.Pool
+	// time of the latest run with calculations
+	timeRunLatest func(ctx context.Context) (time.Time, error)
+	// cache of the time of the latest run
+	cacheRunLatest *cache.Cache[time.Time]
+
+	descriptionConnections          *prometheus.Desc
+	descriptionConnectionsMax       *prometheus.Desc
+	descriptionAcquires             *prometheus.Desc
+	descriptionAcquiresEmpty        *prometheus.Desc
+	descriptionAcquiresCanceled     *prometheus.Desc
+	descriptionAcquireDuration      *prometheus.Desc
+	descriptionConnectionsNew       *prometheus.Desc
+	descriptionConnectionsDestroyed *prometheus.Desc
+	descriptionRunLatestAge         *prometheus.Desc
+}
+
+// register the metrics of the postgresql connection pool (pgxpool.Stat)
+// and of the age of the latest run with calculations
+func (metrics *Metrics) RegisterDatabase(
+	DB *pgxpool.Pool,
+	timeRunLatest func(ctx context.Context) (time.Time, error),
+) {
+	var name = func(name string) string {
+		return prometheus.BuildFQName(namespace, "database_pool", name)
+	}
+
+	metrics.Registry.MustRegister(&collectorDatabase{
+		db:             DB,
+		timeRunLatest:  timeRunLatest,
+		cacheRunLatest: cache.New[time.Time](durationRunLatestCache, 1),
+		descriptionConnections: prometheus.NewDesc(
+			name("connections"),
+			"Connections of the pool by state.",
+			[]string{"state"},
+			nil,
+		),
+		descriptionConnectionsMax: prometheus.NewDesc(
+			name("connections_max"),
+			"Maximum size of the pool.",
+			nil,
+			nil,
+		),
+		descriptionAcquires: prometheus.NewDesc(
+			name("acquires_total"),
+			"Successful acquires of a connection from the pool.",
+			nil,
+			nil,
+		),
+		descriptionAcquiresEmpty: prometheus.NewDesc(
+			name("acquires_empty_total"),
+			"Acquires that had to wait for a connection.",
+			nil,
+			nil,
+		),
+		descriptionAcquiresCanceled: prometheus.NewDesc(
+			name("acquires_canceled_total"),
+			"Acquires canceled by their context.",
+			nil,
+			nil,
+		),
+		descriptionAcquireDuration: prometheus.NewDesc(
+			name("acquire_duration_seconds_total"),
+			"Total time spent acquiring connections.",
+			nil,
+			nil,
+		),
+		descriptionConnectionsNew: prometheus.NewDesc(
+			name("connections_new_total"),
+			"Connections opened by the pool.",
+			nil,
+			nil,
+		),
+		descriptionConnectionsDestroyed: prometheus.NewDesc(
+			name("connections_destroyed_total"),
+			"Connections closed by the pool by reason.",
+			[]string{"reason"},
+			nil,
+		),
+		descriptionRunLatestAge: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "run", "latest_age_seconds"),
+			"Age of the latest run with calculations.",
+			nil,
+			nil,
+		),
+	})
+}
+
+func (collector *collectorDatabase) Describe(channel chan<- *prometheus.Desc) {
+	channel <- collector.descriptionConnections
+	channel <- collector.descriptionConnectionsMax
+	channel <- collector.descriptionAcquires
+	channel <- collector.descriptionAcquiresEmpty
+	channel <- collector.descriptionAcquiresCanceled
+	channel <- collector.descriptionAcquireDuration
+	channel <- collector.descriptionConnectionsNew
+	channel <- collector.descriptionConnectionsDestroyed
+	channel <- collector.descriptionRunLatestAge
+}
+
+func (collector *collectorDatabase) Collect(channel chan<- prometheus.Metric) {
+	//
+	// pool statistics
+	//
+	var stat *pgxpool.Stat = collector.db.Stat()
+
+	for state, value := range map[string]int32{
+		"acquired":     stat.AcquiredConns(),
+		"idle":         stat.IdleConns(),
+		"constructing": stat.ConstructingConns(),
+	} {
+		channel <- prometheus.MustNewConstMetric(
+			collector.descriptionConnections,
+			prometheus.GaugeValue,
+			float64(value),
+			state,
+		)
+	}
+	channel <- prometheus.MustNewConstMetric(
+		collector.descriptionConnectionsMax,
+		prometheus.GaugeValue,
+		float64(stat.MaxConns()),
+	)
+	channel <- prometheus.MustNewConstMetric(
+		collector.descriptionAcquires,
+		prometheus.CounterValue,
+		float64(stat.AcquireCount()),
+	)
+	channel <- prometheus.MustNewConstMetric(
+		collector.descriptionAcquiresEmpty,
+		prometheus.CounterValue,
+		float64(stat.EmptyAcquireCount()),
+	)
+	channel <- prometheus.MustNewConstMetric(
+		collector.descriptionAcquiresCanceled,
+		prometheus.CounterValue,
+		float64(stat.CanceledAcquireCount()),
+	)
+	channel <- prometheus.MustNewConstMetric(
+		collector.descriptionAcquireDuration,
+		prometheus.CounterValue,
+		stat.AcquireDuration().Seconds(),
+	)
+	channel <- prometheus.MustNewConstMetric(
+		collector.descriptionConnectionsNew,
+		prometheus.CounterValue,
+		float64(stat.NewConnsCount()),
+	)
+	for reason, value := range map[string]int64{
+		"max_lifetime": stat.MaxLifetimeDestroyCount(),
+		"max_idle":     stat.MaxIdleDestroyCount(),
+	} {
+		channel <- prometheus.MustNewConstMetric(
+			collector.descriptionConnectionsDestroyed,
+			prometheus.CounterValue,
+			float64(value),
+			reason,
+		)
+	}
+
+	//
+	// latest run
+	//
+	// the metric is left out if there is no run or the query fails
+	timeRunLatest, err := collector.cacheRunLatest.Get(
+		keyRunLatest,
+		func() (time.Time, error) {
+			// create a 2 second timeout context, shorter than a scrape
+			ctxWT, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			// defer cancellation of the timeout
+			defer cancel()
+
+			return collector.timeRunLatest(ctxWT)
+		},
+	)
+	if err != nil {
+		return
+	}
+	channel <- prometheus.MustNewConstMetric(
+		collector.descriptionRunLatestAge,
+		prometheus.GaugeValue,
+		time.Since(timeRunLatest).Seconds(),
+	)
+}
@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// prefix of the names of all the metrics
+const namespace string = "weather_union"
+
+// names of the providers in the provider metrics
+const (
+	ProviderWeatherUnion   string = "weather_union"
+	ProviderOpenWeatherMap string = "open_weather_map"
+)
+
+// prometheus metrics of the web server and the cron
+// every binary has its own registry with the go runtime and process
+// metrics, the metrics it does not use are never exported
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	// http server, by route pattern, method and status
+	HTTPRequests        *prometheus.CounterVec
+	HTTPRequestDuration *prometheus.HistogramVec
+
+	// calls to the weather APIs, by provider
+	ProviderRequestDuration *prometheus.HistogramVec
+	ProviderRequestErrors   *prometheus.CounterVec
+	// stations with a measurement in the last run, by provider
+	RunStationsFetched *prometheus.GaugeVec
+
+	// wet bulb temperature calculations, by method
+	CalculationDuration *prometheus.HistogramVec
+	CalculationErrors   prometheus.Counter
+}
+
+// create the metrics on a new registry
+func New() *Metrics {
+	var metrics *Metrics = &Metrics{
+		Registry: prometheus.NewRegistry(),
+		HTTPRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "http_requests_total",
+				Help:      "HTTP requests by route pattern, method and status.",
+			},
+			[]string{"route", "method", "status"},
+		),
+		HTTPRequestDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      "http_request_duration_seconds",
+				Help:      "Latency of the HTTP requests by route pattern, method and status.",
+				Buckets:   prometheus.DefBuckets,
+			},
+			[]string{"route", "method", "status"},
+		),
+		ProviderRequestDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      "provider_request_duration_seconds",
+				Help:      "Latency of the calls to the weather APIs by provider.",
+				Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+			},
+			[]string{"provider"},
+		),
+		ProviderRequestErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "provider_request_errors_total",
+				Help:      "Failed calls to the weather APIs by provider.",
+			},
+			[]string{"provider"},
+		),
+		RunStationsFetched: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "run_stations_fetched",
+				Help:      "Stations with a measurement in the last run by provider.",
+			},
+			[]string{"provider"},
+		),
+		CalculationDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      "calculation_duration_seconds",
+				Help:      "Duration of a wet bulb temperature calculation by method.",
+				Buckets:   []float64{0.1, 0.25, 0.5, 1, 2, 4, 8, 16},
+			},
+			[]string{"method"},
+		),
+		CalculationErrors: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "calculation_errors_total",
+				Help:      "Failed wet bulb temperature calculations.",
+			},
+		),
+	}
+
+	metrics.Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		metrics.HTTPRequests,
+		metrics.HTTPRequestDuration,
+		metrics.ProviderRequestDuration,
+		metrics.ProviderRequestErrors,
+		metrics.RunStationsFetched,
+		metrics.CalculationDuration,
+		metrics.CalculationErrors,
+	)
+
+	return metrics
+}
+
+// handler of the /metrics endpoint
+func (metrics *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(
+		metrics.Registry,
+		promhttp.HandlerOpts{Registry: metrics.Registry},
+	)
+}
@@ -0,0 +1,329 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// the keys are the prefix followed by 32 random bytes in base64 (URL
+// encoding), the prefix and the first characters identify a key
+const (
+	prefixAPIKey        string = "wu_"
+	lengthAPIKeyPrefix  int    = len(prefixAPIKey) + 8
+	numberOfBytesAPIKey int    = 32
+)
+
+// returned when no key can be found for a prefix
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// model struct for the API keys of the public API
+type APIKeyModel struct {
+	DB *pgxpool.Pool
+}
+
+// an API key, without the key itself which is only stored as a hash
+type APIKey struct {
+	APIKeyID          uuid.UUID  `db:"api_key_id" json:"api_key_id"`
+	Name              string     `db:"name" json:"name"`
+	Prefix            string     `db:"prefix" json:"prefix"`
+	Scopes            []string   `db:"scopes" json:"scopes"`
+	RequestsPerSecond float64    `db:"requests_per_second" json:"requests_per_second"`
+	Burst             int        `db:"burst" json:"burst"`
+	QuotaDaily        int        `db:"quota_daily" json:"quota_daily"`
+	TimeStampCreated  time.Time  `db:"time_stamp_created" json:"time_stamp_created"`
+	TimeStampRevoked  *time.Time `db:"time_stamp_revoked" json:"time_stamp_revoked"`
+	TimeStampLastUsed *time.Time `db:"time_stamp_last_used" json:"time_stamp_last_used"`
+}
+
+// whether the key was issued with the scope
+func (key APIKey) HasScope(scope string) bool {
+	return slices.Contains(key.Scopes, scope)
+}
+
+// hash of a key as stored in the database
+// the keys are random, so a fast hash cannot be brute forced
+func HashAPIKey(key string) []byte {
+	var hash [sha256.Size]byte = sha256.Sum256([]byte(key))
+	return hash[:]
+}
+
+// create a new key and save its hash
+// the key is returned once and cannot be recovered afterwards
+func (model APIKeyModel) InsertAPIKey(
+	ctx context.Context,
+	key APIKey,
+) (string, APIKey, error) {
+	// random part of the key
+	var bytesRandom []byte = make([]byte, numberOfBytesAPIKey)
+	_, err := rand.Read(bytesRandom)
+	if err != nil {
+		return "", key, fmt.Errorf("error in generating api key: %w", err)
+	}
+	var keySecret string = prefixAPIKey +
+		base64.RawURLEncoding.EncodeToString(bytesRandom)
+
+	// postgresql query string
+	var queryString string = `
+	INSERT INTO api_keys (
+		name,
+		prefix,
+		hash_key,
+		scopes,
+		requests_per_second,
+		burst,
+		quota_daily
+	)
+	VALUES (
+		@name,
+		@prefix,
+		@hashKey,
+		@scopes,
+		@requestsPerSecond,
+		@burst,
+		@quotaDaily
+	)
+	RETURNING
+		api_key_id,
+		name,
+		prefix,
+		scopes,
+		requests_per_second,
+		burst,
+		quota_daily,
+		time_stamp_created,
+		time_stamp_revoked,
+		time_stamp_last_used;
+	`
+
+	// named arguments for building the query string
+	var queryArguments pgx.NamedArgs = pgx.NamedArgs{
+		"name":              key.Name,
+		"prefix":            keySecret[:lengthAPIKeyPrefix],
+		"hashKey":           HashAPIKey(keySecret),
+		"scopes":            key.Scopes,
+		"requestsPerSecond": key.RequestsPerSecond,
+		"burst":             key.Burst,
+		"quotaDaily":        key.QuotaDaily,
+	}
+
+	// create a 5 second timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, 5*time.Second)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// prepare the query
+	rows, err := model.DB.Query(ctxWT, queryString, queryArguments)
+	if err != nil {
+		return "", key, fmt.Errorf("error in inserting api key into postgresql: %w", err)
+	}
+
+	// run the query and collect the inserted key
+	keyInserted, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[APIKey])
+	if err != nil {
+		return "", key, fmt.Errorf("error in inserting api key into postgresql: %w", err)
+	}
+
+	return keySecret, keyInserted, nil
+}
+
+// get the key that is not revoked with the hash
+// returns pgx.ErrNoRows if there is no such key
+func (model APIKeyModel) GetAPIKeyByHash(
+	ctx context.Context,
+	hashKey []byte,
+) (APIKey, error) {
+	// postgresql query string
+	var queryString string = `
+	SELECT
+		api_key_id,
+		name,
+		prefix,
+		scopes,
+		requests_per_second,
+		burst,
+		quota_daily,
+		time_stamp_created,
+		time_stamp_revoked,
+		time_stamp_last_used
+	FROM api_keys
+	WHERE
+		hash_key = @hashKey AND
+		time_stamp_revoked IS NULL;
+	`
+
+	// named arguments for building the query string
+	var queryArguments pgx.NamedArgs = pgx.NamedArgs{
+		"hashKey": hashKey,
+	}
+
+	// create a 5 second timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, 5*time.Second)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// prepare the query
+	rows, err := model.DB.Query(ctxWT, queryString, queryArguments)
+	if err != nil {
+		return APIKey{}, err
+	}
+
+	// run the query and collect the key
+	return pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[APIKey])
+}
+
+// get all the keys, revoked ones included, newest first
+func (model APIKeyModel) GetAPIKeys(ctx context.Context) ([]APIKey, error) {
+	// postgresql query string
+	var queryString string = `
+	SELECT
+		api_key_id,
+		name,
+		prefix,
+		scopes,
+		requests_per_second,
+		burst,
+		quota_daily,
+		time_stamp_created,
+		time_stamp_revoked,
+		time_stamp_last_used
+	FROM api_keys
+	ORDER BY time_stamp_created DESC;
+	`
+
+	// create a 5 second timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, 5*time.Second)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// prepare the query
+	rows, err := model.DB.Query(ctxWT, queryString)
+	if err != nil {
+		return nil, fmt.Errorf("error in fetching api keys from postgresql: %w", err)
+	}
+
+	// run the query and collect rows
+	sliceKeys, err := pgx.CollectRows(rows, pgx.RowToStructByName[APIKey])
+	if err != nil {
+		return nil, fmt.Errorf("error in fetching api keys from postgresql: %w", err)
+	}
+
+	return sliceKeys, nil
+}
+
+// revoke the key with the prefix
+// returns ErrAPIKeyNotFound if there is no key left to revoke
+func (model APIKeyModel) RevokeAPIKey(ctx context.Context, prefix string) error {
+	// postgresql query string
+	// the servers are notified with the hex of the hash of the key, which
+	// they evict from their caches. the notification is only sent once the
+	// revocation is committed.
+	var queryString string = `
+	WITH revoked AS (
+		UPDATE api_keys
+		SET time_stamp_revoked = now()
+		WHERE
+			prefix = @prefix AND
+			time_stamp_revoked IS NULL
+		RETURNING hash_key
+	)
+	SELECT pg_notify(@channel, encode(hash_key, 'hex'))
+	FROM revoked;
+	`
+
+	// named arguments for building the query string
+	var queryArguments pgx.NamedArgs = pgx.NamedArgs{
+		"prefix":  prefix,
+		"channel": ChannelAPIKeysRevoked,
+	}
+
+	// create a 5 second timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, 5*time.Second)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// executing the query string with the named arguments
+	commandTag, err := model.DB.Exec(ctxWT, queryString, queryArguments)
+	if err != nil {
+		return fmt.Errorf("error in revoking api key in postgresql: %w", err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// add the requests of the keys to their usage of today (UTC) and set
+// their last use, in a single batch
+// returns the number of requests of each key today, by all the servers
+func (model APIKeyModel) AddAPIKeyUsage(
+	ctx context.Context,
+	mapNumberOfRequests map[uuid.UUID]int,
+) (map[uuid.UUID]int, error) {
+	// postgresql query string
+	// the usage of the day and the last use of the key in one statement
+	var queryString string = `
+	WITH key_used AS (
+		UPDATE api_keys
+		SET time_stamp_last_used = now()
+		WHERE api_key_id = @APIKeyID
+	)
+	INSERT INTO api_keys_usage_daily (api_key_id, day, number_of_requests)
+	VALUES (@APIKeyID, (now() AT TIME ZONE 'UTC')::DATE, @numberOfRequests)
+	ON CONFLICT (api_key_id, day)
+	DO UPDATE SET
+		number_of_requests = api_keys_usage_daily.number_of_requests +
+			EXCLUDED.number_of_requests
+	RETURNING number_of_requests;
+	`
+
+	// create batch queries for postgresql entry
+	// the keys in the order of the queries
+	var queryBatch *pgx.Batch = &pgx.Batch{}
+	var sliceAPIKeyIDs []uuid.UUID
+	for APIKeyID, numberOfRequests := range mapNumberOfRequests {
+		// named arguments for building the query string
+		var queryArguments pgx.NamedArgs = pgx.NamedArgs{
+			"APIKeyID":         APIKeyID,
+			"numberOfRequests": numberOfRequests,
+		}
+		queryBatch.Queue(queryString, queryArguments)
+		sliceAPIKeyIDs = append(sliceAPIKeyIDs, APIKeyID)
+	}
+
+	// create a 5 second timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, 5*time.Second)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// send the batch query via the connection pool
+	var batchResults pgx.BatchResults = model.DB.SendBatch(ctxWT, queryBatch)
+	defer batchResults.Close()
+
+	var mapNumberOfRequestsToday map[uuid.UUID]int = make(map[uuid.UUID]int)
+	for _, APIKeyID := range sliceAPIKeyIDs {
+		var numberOfRequests int
+		err := batchResults.QueryRow().Scan(&numberOfRequests)
+		if err != nil {
+			return nil, fmt.Errorf("error in counting api key usage in postgresql: %w", err)
+		}
+		mapNumberOfRequestsToday[APIKeyID] = numberOfRequests
+	}
+
+	err := batchResults.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error in counting api key usage in postgresql: %w", err)
+	}
+
+	return mapNumberOfRequestsToday, nil
+}
@@ -16,6 +16,8 @@ import (
 // model struct for calculations
 type CalculationModel struct {
 	DB *pgxpool.Pool
+	// path of the wet bulb temperature python script
+	PathToScriptWetBulbTemperature string
 }
 
 // this type contains all parameters that are needed in
@@ -27,6 +29,8 @@ type CalculationTemperature struct {
 	Method                      string
 	TemperatureDewPoint         float64
 	TemperatureWetBulb          float64
+	// time stamp of the run of the measurement
+	TimeStampRun time.Time
 }
 
 // struct holding dew point and wet bulb temperature calculations
@@ -38,6 +42,7 @@ type Temperature struct {
 // type to hold relevant data for display on front end
 type CalculationTemperatureWithStationDetails struct {
 	RunID                uuid.UUID `db:"run_id" json:"run_id"`
+	CityName             string    `db:"city_name" json:"city_name"`
 	LocalityID           string    `db:"locality_id" json:"locality_id"`
 	LocalityName         string    `db:"locality_name" json:"locality_name"`
 	Latitude             string    `db:"latitude" json:"latitude"`
@@ -76,7 +81,7 @@ func (model CalculationModel) CalculateTemperatureFromSingleMeasurement(
 	// fmt.Println(command.String())
 	command := exec.Command(
 		environmentBinary,
-		"../scripts/wet_bulb_temperature.py",
+		model.PathToScriptWetBulbTemperature,
 		CLATemperature,
 		CLAHumidity,
 		CLAPressure,
@@ -102,13 +107,15 @@ func (model CalculationModel) CalculateTemperatureFromSingleMeasurement(
 		Method:                      "metpy-with-open-weather-map",
 		TemperatureDewPoint:         temperature.DewPoint,
 		TemperatureWetBulb:          temperature.WetBulb,
+		TimeStampRun:                measurement.TimeStampRun,
 	}
 
 	return calculation, nil
 }
 
 // save the successful wet bulb and dew point temperature calculations
-// to the database
+// to the database, and move the watermarks of the calculation rollups
+// back to them if they are older
 func (model CalculationModel) SaveCalculationsTemperatures(
 	ctx context.Context,
 	sliceCalculationsSuccessful []CalculationTemperature,
@@ -129,6 +136,7 @@ func (model CalculationModel) SaveCalculationsTemperatures(
 			calculation.Method,
 			calculation.TemperatureDewPoint,
 			calculation.TemperatureWetBulb,
+			calculation.TimeStampRun,
 		}
 	}
 
@@ -137,8 +145,16 @@ func (model CalculationModel) SaveCalculationsTemperatures(
 	// defer cancellation of the timeout
 	defer cancel()
 
+	// begin transaction
+	tx, err := model.DB.Begin(ctxWT)
+	if err != nil {
+		return fmt.Errorf("error in beginning calculation transaction in postgresql: %w", err)
+	}
+	// rollback is a no-op after commit
+	defer tx.Rollback(ctxWT)
+
 	// create a bulk insert query
-	_, err := model.DB.CopyFrom(
+	_, err = tx.CopyFrom(
 		ctxWT,
 		pgx.Identifier{"calculations_temperature"},
 		[]string{
@@ -147,6 +163,7 @@ func (model CalculationModel) SaveCalculationsTemperatures(
 			"method",
 			"temperature_dew_point",
 			"temperature_wet_bulb",
+			"time_stamp_run",
 		},
 		pgx.CopyFromRows(sliceInsertValues),
 	)
@@ -157,6 +174,48 @@ func (model CalculationModel) SaveCalculationsTemperatures(
 		)
 	}
 
+	// the calculations of a run recalculated by an admin are older than
+	// the watermarks of the calculation rollups: the watermarks are moved
+	// back to the bucket of their oldest measurement so that the next
+	// rollup recomputes it. LEAST ignores the NULL of no measurement.
+	var sliceMeasurementIDs []uuid.UUID = make(
+		[]uuid.UUID,
+		len(sliceCalculationsSuccessful),
+	)
+	for i, calculation := range sliceCalculationsSuccessful {
+		sliceMeasurementIDs[i] = calculation.MeasurementIDWeatherUnion
+	}
+	for _, granularity := range rollupGranularities {
+		_, err = tx.Exec(
+			ctxWT,
+			fmt.Sprintf(`
+			UPDATE rollup_watermarks
+			SET time_stamp_until = LEAST(
+				time_stamp_until,
+				(
+					SELECT date_trunc('%s', MIN(time_stamp), 'UTC')
+					FROM measurements_weather_union
+					WHERE measurement_id = ANY(@arrayMeasurementIDs)
+				)
+			)
+			WHERE table_name = @tableName;
+			`, granularity.field),
+			pgx.NamedArgs{
+				"arrayMeasurementIDs": sliceMeasurementIDs,
+				"tableName":           "calculations_temperature_" + granularity.suffix,
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("error in moving back rollup watermark in postgresql: %w", err)
+		}
+	}
+
+	// commit transaction
+	err = tx.Commit(ctxWT)
+	if err != nil {
+		return fmt.Errorf("error in committing calculation transaction in postgresql: %w", err)
+	}
+
 	// return nil if all okay
 	return nil
 }
@@ -342,9 +401,10 @@ func (model CalculationModel) SetFlagsTemperature(
 }
 
 // get the temperature calculations for display
-// from a single run
-func (model CalculationModel) GetCalculationsTemperatureWithStationDetails(
+// from the run with the given run ID
+func (model CalculationModel) GetCalculationsTemperatureWithStationDetailsFromRun(
 	ctx context.Context,
+	runID uuid.UUID,
 ) (
 	[]CalculationTemperatureWithStationDetails,
 	error,
@@ -361,6 +421,7 @@ func (model CalculationModel) GetCalculationsTemperatureWithStationDetails(
 			AS temperature_dew_point,
 		ct.time_stamp AS time_stamp_calculation,
 		mwu.run_id,
+		wus.city_name,
 		wus.locality_id,
 		wus.locality_name,
 		ST_X(wus.location::geometry) AS longitude,
@@ -370,22 +431,22 @@ func (model CalculationModel) GetCalculationsTemperatureWithStationDetails(
 	ON ct.measurement_id_weather_union = mwu.measurement_id
 	JOIN weather_union_stations wus
 	ON mwu.weather_station_id = wus.weather_station_id
-	WHERE run_id = (
-		SELECT run_id
-		FROM measurement_runs
-		ORDER BY time_stamp DESC
-		LIMIT 1
-	)
+	WHERE run_id = @runID
 	ORDER BY temperature_wet_bulb DESC;
 	`
 
+	// named arguments for building the query string
+	var queryArguments pgx.NamedArgs = pgx.NamedArgs{
+		"runID": runID,
+	}
+
 	// create a 5 second timeout context
 	ctxWT, cancel := context.WithTimeout(ctx, 5*time.Second)
 	// defer cancellation of the timeout
 	defer cancel()
 
 	// prepare the query
-	rows, err := model.DB.Query(ctxWT, queryString)
+	rows, err := model.DB.Query(ctxWT, queryString, queryArguments)
 	if err != nil {
 		return nil, err
 	}
@@ -402,3 +463,129 @@ func (model CalculationModel) GetCalculationsTemperatureWithStationDetails(
 	// return slice of calculations for display
 	return sliceCalculations, nil
 }
+
+// queue the measurements of a run for calculation again and return their
+// number. the calculations of the run are deleted and the measurements
+// flagged as unprocessed, so that the next cron run calculates them, and
+// the watermarks of the calculation rollups are moved back to the run so
+// that the deleted calculations leave its buckets even if none are saved
+// again.
+// returns pgx.ErrNoRows if there is no such run
+func (model CalculationModel) ResetCalculationsRun(
+	ctx context.Context,
+	runID uuid.UUID,
+) (int64, error) {
+	// create a 5 second timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, 5*time.Second)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// begin transaction
+	tx, err := model.DB.Begin(ctxWT)
+	if err != nil {
+		return 0, fmt.Errorf("error in beginning recalculation transaction in postgresql: %w", err)
+	}
+	// rollback is a no-op after commit
+	defer tx.Rollback(ctxWT)
+
+	// named arguments for building the query strings
+	var queryArguments pgx.NamedArgs = pgx.NamedArgs{
+		"runID": runID,
+	}
+
+	// time of the oldest measurement of the run, nil if it has none left
+	var isRun bool
+	var timeMeasurementOldest *time.Time
+	err = tx.QueryRow(
+		ctxWT,
+		`
+		SELECT
+			EXISTS (SELECT 1 FROM measurement_runs WHERE run_id = @runID),
+			(
+				SELECT MIN(time_stamp)
+				FROM measurements_weather_union
+				WHERE run_id = @runID
+			);
+		`,
+		queryArguments,
+	).Scan(&isRun, &timeMeasurementOldest)
+	if err != nil {
+		return 0, fmt.Errorf("error in reading measurement run from postgresql: %w", err)
+	}
+	if !isRun {
+		return 0, pgx.ErrNoRows
+	}
+
+	// calculations first as they reference the weather union measurements
+	_, err = tx.Exec(
+		ctxWT,
+		`
+		DELETE FROM calculations_temperature ct
+		USING measurements_weather_union mwu
+		WHERE
+			ct.measurement_id_weather_union = mwu.measurement_id AND
+			mwu.run_id = @runID;
+		`,
+		queryArguments,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("error in deleting calculations of run in postgresql: %w", err)
+	}
+
+	// flag the measurements of both providers as unprocessed
+	var numberOfMeasurements int64
+	for _, table := range []string{
+		"measurements_weather_union",
+		"measurements_open_weather_map",
+	} {
+		commandTag, err := tx.Exec(
+			ctxWT,
+			fmt.Sprintf(`
+			UPDATE %s
+			SET
+				is_processed_for_calculation_temperature = FALSE,
+				is_successful_for_calculation_temperature = FALSE
+			WHERE run_id = @runID;
+			`, table),
+			queryArguments,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("error in resetting flags of run in postgresql: %w", err)
+		}
+		if table == "measurements_weather_union" {
+			numberOfMeasurements = commandTag.RowsAffected()
+		}
+	}
+
+	// roll up the buckets of the run again
+	if timeMeasurementOldest != nil {
+		for _, granularity := range rollupGranularities {
+			_, err = tx.Exec(
+				ctxWT,
+				fmt.Sprintf(`
+				UPDATE rollup_watermarks
+				SET time_stamp_until = LEAST(
+					time_stamp_until,
+					date_trunc('%s', @timeFrom::TIMESTAMPTZ, 'UTC')
+				)
+				WHERE table_name = @tableName;
+				`, granularity.field),
+				pgx.NamedArgs{
+					"timeFrom":  *timeMeasurementOldest,
+					"tableName": "calculations_temperature_" + granularity.suffix,
+				},
+			)
+			if err != nil {
+				return 0, fmt.Errorf("error in moving back rollup watermark in postgresql: %w", err)
+			}
+		}
+	}
+
+	// commit transaction
+	err = tx.Commit(ctxWT)
+	if err != nil {
+		return 0, fmt.Errorf("error in committing recalculation transaction in postgresql: %w", err)
+	}
+
+	return numberOfMeasurements, nil
+}
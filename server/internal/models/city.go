@@ -0,0 +1,237 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// model struct for the city statistics
+type CityModel struct {
+	DB *pgxpool.Pool
+}
+
+// aggregate wet bulb temperature statistics of a city in a run
+type CityStatistics struct {
+	CityName                         string                       `db:"city_name" json:"city_name"`
+	NumberOfStations                 int                          `db:"number_of_stations" json:"number_of_stations"`
+	TemperatureWetBulbMin            float64                      `db:"temperature_wet_bulb_min" json:"temperature_wet_bulb_min"`
+	TemperatureWetBulbMedian         float64                      `db:"temperature_wet_bulb_median" json:"temperature_wet_bulb_median"`
+	TemperatureWetBulbMax            float64                      `db:"temperature_wet_bulb_max" json:"temperature_wet_bulb_max"`
+	TemperatureWetBulbMedianPrevious *float64                     `db:"temperature_wet_bulb_median_previous" json:"temperature_wet_bulb_median_previous"`
+	TemperatureWetBulbMaxPrevious    *float64                     `db:"temperature_wet_bulb_max_previous" json:"temperature_wet_bulb_max_previous"`
+	NumbersAboveThresholds           []int                        `db:"numbers_above_thresholds" json:"-"`
+	StationsAboveThresholds          []CityStationsAboveThreshold `db:"-" json:"stations_above_thresholds"`
+}
+
+// number of stations of a city at or above a wet bulb temperature
+type CityStationsAboveThreshold struct {
+	Threshold        float64 `json:"threshold"`
+	NumberOfStations int     `json:"number_of_stations"`
+}
+
+// a locality of a city with its wet bulb temperature in a run
+type CityLocality struct {
+	CityName           string  `db:"city_name" json:"city_name"`
+	LocalityName       string  `db:"locality_name" json:"locality_name"`
+	LocalityID         string  `db:"locality_id" json:"locality_id"`
+	TemperatureWetBulb float64 `db:"temperature_wet_bulb" json:"temperature_wet_bulb"`
+}
+
+// get the wet bulb temperature statistics of every city in a run
+// the previous run (usually the same hour yesterday) is optional and
+// only used for the trend. if cityName is not nil only that city is
+// returned.
+func (model CityModel) GetCityStatistics(
+	ctx context.Context,
+	runID uuid.UUID,
+	runIDPrevious *uuid.UUID,
+	thresholds []float64,
+	cityName *string,
+) ([]CityStatistics, error) {
+	// postgresql query string
+	var queryString string = `
+	WITH current_run AS (
+		SELECT
+			wus.city_name,
+			ct.temperature_wet_bulb
+		FROM calculations_temperature ct
+		JOIN measurements_weather_union mwu
+		ON ct.measurement_id_weather_union = mwu.measurement_id
+		JOIN weather_union_stations wus
+		ON wus.weather_station_id = mwu.weather_station_id
+		WHERE
+			mwu.run_id = @runID AND
+			(@cityName::TEXT IS NULL OR wus.city_name = @cityName::TEXT)
+	),
+	previous_run AS (
+		SELECT
+			wus.city_name,
+			PERCENTILE_CONT(0.5) WITHIN GROUP (
+				ORDER BY ct.temperature_wet_bulb
+			) AS temperature_wet_bulb_median,
+			MAX(ct.temperature_wet_bulb) AS temperature_wet_bulb_max
+		FROM calculations_temperature ct
+		JOIN measurements_weather_union mwu
+		ON ct.measurement_id_weather_union = mwu.measurement_id
+		JOIN weather_union_stations wus
+		ON wus.weather_station_id = mwu.weather_station_id
+		WHERE
+			mwu.run_id = @runIDPrevious::UUID AND
+			(@cityName::TEXT IS NULL OR wus.city_name = @cityName::TEXT)
+		GROUP BY wus.city_name
+	)
+	SELECT
+		cr.city_name,
+		COUNT(*)::INTEGER AS number_of_stations,
+		ROUND(MIN(cr.temperature_wet_bulb)::NUMERIC, 3)::FLOAT
+			AS temperature_wet_bulb_min,
+		ROUND(
+			PERCENTILE_CONT(0.5) WITHIN GROUP (
+				ORDER BY cr.temperature_wet_bulb
+			)::NUMERIC,
+			3
+		)::FLOAT AS temperature_wet_bulb_median,
+		ROUND(MAX(cr.temperature_wet_bulb)::NUMERIC, 3)::FLOAT
+			AS temperature_wet_bulb_max,
+		ROUND(MAX(pr.temperature_wet_bulb_median)::NUMERIC, 3)::FLOAT
+			AS temperature_wet_bulb_median_previous,
+		ROUND(MAX(pr.temperature_wet_bulb_max)::NUMERIC, 3)::FLOAT
+			AS temperature_wet_bulb_max_previous,
+		ARRAY(
+			SELECT (
+				SELECT COUNT(*)::INTEGER
+				FROM current_run c
+				WHERE
+					c.city_name = cr.city_name AND
+					c.temperature_wet_bulb >= t.threshold
+			)
+			FROM UNNEST(@thresholds::FLOAT[]) WITH ORDINALITY
+				AS t(threshold, ordinal)
+			ORDER BY t.ordinal
+		) AS numbers_above_thresholds
+	FROM current_run cr
+	LEFT JOIN previous_run pr
+	ON pr.city_name = cr.city_name
+	GROUP BY cr.city_name
+	ORDER BY cr.city_name;
+	`
+
+	// named arguments for building the query string
+	var queryArguments pgx.NamedArgs = pgx.NamedArgs{
+		"runID":         runID,
+		"runIDPrevious": runIDPrevious,
+		"thresholds":    thresholds,
+		"cityName":      cityName,
+	}
+
+	// create a 5 second timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, 5*time.Second)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// prepare the query
+	rows, err := model.DB.Query(ctxWT, queryString, queryArguments)
+	if err != nil {
+		return nil, fmt.Errorf("error in querying city statistics from postgresql: %w", err)
+	}
+
+	// run the query and collect rows
+	sliceStatistics, err := pgx.CollectRows(
+		rows,
+		pgx.RowToStructByName[CityStatistics],
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error in collecting city statistics from postgresql: %w", err)
+	}
+
+	// pair the counts with their thresholds
+	for i := range sliceStatistics {
+		sliceStatistics[i].StationsAboveThresholds = make(
+			[]CityStationsAboveThreshold,
+			len(thresholds),
+		)
+		for j, threshold := range thresholds {
+			sliceStatistics[i].StationsAboveThresholds[j] = CityStationsAboveThreshold{
+				Threshold:        threshold,
+				NumberOfStations: sliceStatistics[i].NumbersAboveThresholds[j],
+			}
+		}
+	}
+
+	return sliceStatistics, nil
+}
+
+// get the hottest localities of every city in a run, at most
+// limitPerCity per city and hottest first. if cityName is not nil only
+// the localities of that city are returned.
+func (model CityModel) GetCityLocalitiesHottest(
+	ctx context.Context,
+	runID uuid.UUID,
+	limitPerCity int,
+	cityName *string,
+) ([]CityLocality, error) {
+	// postgresql query string
+	var queryString string = `
+	SELECT
+		city_name,
+		locality_name,
+		locality_id,
+		temperature_wet_bulb
+	FROM (
+		SELECT
+			wus.city_name,
+			wus.locality_name,
+			wus.locality_id,
+			ROUND(ct.temperature_wet_bulb::NUMERIC, 3)::FLOAT
+				AS temperature_wet_bulb,
+			ROW_NUMBER() OVER (
+				PARTITION BY wus.city_name
+				ORDER BY ct.temperature_wet_bulb DESC
+			) AS rank_in_city
+		FROM calculations_temperature ct
+		JOIN measurements_weather_union mwu
+		ON ct.measurement_id_weather_union = mwu.measurement_id
+		JOIN weather_union_stations wus
+		ON wus.weather_station_id = mwu.weather_station_id
+		WHERE
+			mwu.run_id = @runID AND
+			(@cityName::TEXT IS NULL OR wus.city_name = @cityName::TEXT)
+	) ranked
+	WHERE rank_in_city <= @limitPerCity
+	ORDER BY city_name, rank_in_city;
+	`
+
+	// named arguments for building the query string
+	var queryArguments pgx.NamedArgs = pgx.NamedArgs{
+		"runID":        runID,
+		"limitPerCity": limitPerCity,
+		"cityName":     cityName,
+	}
+
+	// create a 5 second timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, 5*time.Second)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// prepare the query
+	rows, err := model.DB.Query(ctxWT, queryString, queryArguments)
+	if err != nil {
+		return nil, fmt.Errorf("error in querying hottest localities from postgresql: %w", err)
+	}
+
+	// run the query and collect rows
+	sliceLocalities, err := pgx.CollectRows(
+		rows,
+		pgx.RowToStructByName[CityLocality],
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error in collecting hottest localities from postgresql: %w", err)
+	}
+
+	return sliceLocalities, nil
+}
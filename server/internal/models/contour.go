@@ -0,0 +1,226 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/geo"
+)
+
+// model struct for contours
+type ContourModel struct {
+	DB *pgxpool.Pool
+}
+
+// options for generating the wet bulb temperature contours
+type ContourOptions struct {
+	// wet bulb temperature levels in celsius
+	Levels []float64
+	// size of a cell of the interpolated grid
+	CellSizeKilometres float64
+	// grid nodes further than this from every station are left empty
+	MaxDistanceKilometres float64
+}
+
+// a contour (zone above a level) clipped to the boundary of a city
+type ContourWetBulbCity struct {
+	CityName             string
+	Level                float64
+	Geometry             json.RawMessage
+	AreaSquareKilometres float64
+	IsClipped            bool
+}
+
+// interpolate the calculations of a run city by city, trace the zones
+// above each wet bulb level with marching squares and clip them to the
+// city boundaries. the result is a GeoJSON feature collection with one
+// multi polygon feature per city and level.
+func (model ContourModel) CreateContoursWetBulb(
+	ctx context.Context,
+	sliceCalculations []CalculationTemperatureWithStationDetails,
+	options ContourOptions,
+) (geo.FeatureCollection, error) {
+	var featureCollection geo.FeatureCollection = geo.NewFeatureCollection()
+	if len(sliceCalculations) == 0 {
+		return featureCollection, nil
+	}
+
+	// group the calculations by city so that grids never span
+	// across cities
+	var mapPointsCity map[string][]geo.Point = make(map[string][]geo.Point)
+	for _, calculation := range sliceCalculations {
+		longitude, err := strconv.ParseFloat(calculation.Longitude, 64)
+		if err != nil {
+			return featureCollection, err
+		}
+		latitude, err := strconv.ParseFloat(calculation.Latitude, 64)
+		if err != nil {
+			return featureCollection, err
+		}
+		mapPointsCity[calculation.CityName] = append(
+			mapPointsCity[calculation.CityName],
+			geo.Point{
+				Longitude: longitude,
+				Latitude:  latitude,
+				Value:     calculation.TemperatureWetBulb,
+			},
+		)
+	}
+
+	// sorted city names for a stable output
+	var sliceCityNames []string = make([]string, 0, len(mapPointsCity))
+	for cityName := range mapPointsCity {
+		sliceCityNames = append(sliceCityNames, cityName)
+	}
+	sort.Strings(sliceCityNames)
+
+	// postgresql query string
+	// clip the contour to the city boundary if there is one
+	var queryString string = `
+	WITH contour AS (
+		SELECT ST_MakeValid(
+			ST_SetSRID(ST_GeomFromGeoJSON(@geometry), 4326)
+		) AS geometry
+	), clipped AS (
+		SELECT
+			CASE
+				WHEN cb.boundary IS NULL THEN contour.geometry
+				ELSE ST_CollectionExtract(
+					ST_Intersection(contour.geometry, cb.boundary::geometry),
+					3
+				)
+			END AS geometry,
+			cb.boundary IS NOT NULL AS is_clipped
+		FROM contour
+		LEFT JOIN city_boundaries cb
+		ON cb.city_name = @cityName
+	)
+	SELECT
+		ST_AsGeoJSON(ST_Multi(geometry), 6) AS geometry,
+		ST_Area(geometry::geography) / 1000000 AS area_square_kilometres,
+		is_clipped
+	FROM clipped
+	WHERE NOT ST_IsEmpty(geometry);
+	`
+
+	// create batch queries for clipping
+	var queryBatch *pgx.Batch = &pgx.Batch{}
+	// contours in the order of the queued queries
+	var sliceContours []ContourWetBulbCity
+
+	for _, cityName := range sliceCityNames {
+		// interpolate the stations of the city onto a grid
+		grid, err := geo.InterpolateInverseDistanceWeighted(
+			ctx,
+			mapPointsCity[cityName],
+			geo.InterpolationOptions{
+				CellSizeKilometres:    options.CellSizeKilometres,
+				MarginKilometres:      options.MaxDistanceKilometres,
+				MaxDistanceKilometres: options.MaxDistanceKilometres,
+				Power:                 2,
+			},
+		)
+		if err != nil {
+			return featureCollection, fmt.Errorf(
+				"error in interpolating the grid for %s: %w",
+				cityName,
+				err,
+			)
+		}
+
+		for _, level := range options.Levels {
+			// the request may have been abandoned
+			if err := ctx.Err(); err != nil {
+				return featureCollection, err
+			}
+
+			// trace the zones above the level
+			slicePolygons := grid.Contour(level)
+			if len(slicePolygons) == 0 {
+				continue
+			}
+
+			geometryJSONBytes, err := json.Marshal(
+				geo.NewMultiPolygon(slicePolygons),
+			)
+			if err != nil {
+				return featureCollection, err
+			}
+
+			// named arguments for building the query string
+			var queryArguments pgx.NamedArgs = pgx.NamedArgs{
+				"geometry": string(geometryJSONBytes),
+				"cityName": cityName,
+			}
+			// append to pg query batch
+			queryBatch.Queue(queryString, queryArguments)
+			sliceContours = append(sliceContours, ContourWetBulbCity{
+				CityName: cityName,
+				Level:    level,
+			})
+		}
+	}
+
+	if len(sliceContours) == 0 {
+		return featureCollection, nil
+	}
+
+	// create a 5 second timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, 5*time.Second)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// send the batch query via the connection pool
+	var batchResults pgx.BatchResults = model.DB.SendBatch(
+		ctxWT,
+		queryBatch,
+	)
+	defer batchResults.Close()
+
+	// read the clipped contours in the order they were queued
+	var runID string = sliceCalculations[0].RunID.String()
+	for _, contour := range sliceContours {
+		var geometry string
+		err := batchResults.QueryRow().Scan(
+			&geometry,
+			&contour.AreaSquareKilometres,
+			&contour.IsClipped,
+		)
+		// nothing left of the contour inside the city boundary
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return featureCollection, fmt.Errorf(
+				"error in clipping contours in postgresql: %w",
+				err,
+			)
+		}
+		contour.Geometry = json.RawMessage(geometry)
+
+		featureCollection.Features = append(
+			featureCollection.Features,
+			geo.Feature{
+				Type:     "Feature",
+				Geometry: contour.Geometry,
+				Properties: map[string]any{
+					"run_id":                 runID,
+					"city_name":              contour.CityName,
+					"level":                  contour.Level,
+					"area_square_kilometres": contour.AreaSquareKilometres,
+					"is_clipped":             contour.IsClipped,
+				},
+			},
+		)
+	}
+
+	// return the feature collection if all okay
+	return featureCollection, nil
+}
@@ -24,17 +24,22 @@ type MeasurementTemperature struct {
 	Temperature                 float64   `json:"temperature"`
 	Humidity                    float64   `json:"humidity"`
 	Pressure                    float64   `json:"pressure"`
+	// time stamp of the run, the partition key of the calculations
+	TimeStampRun time.Time `db:"time_stamp_run" json:"time_stamp_run"`
 }
 
 // function to save the measurement run ID
+// returns the time stamp of the run, which the measurements of the run
+// are saved with
 func (model MeasurementModel) SaveMeasurementRun(
 	ctx context.Context,
 	runID uuid.UUID,
-) error {
+) (time.Time, error) {
 	// postgresql query string
 	var queryString string = `
 	INSERT INTO measurement_runs(run_id)
-	VALUES (@runID);
+	VALUES (@runID)
+	RETURNING time_stamp;
 	`
 
 	// named arguments for building the query string
@@ -48,15 +53,16 @@ func (model MeasurementModel) SaveMeasurementRun(
 	defer cancel()
 
 	// executing the query string with the named arguments
-	_, err := model.DB.Exec(ctxWT, queryString, queryArguments)
+	var timeStamp time.Time
+	err := model.DB.QueryRow(ctxWT, queryString, queryArguments).Scan(&timeStamp)
 	if err != nil {
-		return fmt.Errorf(
+		return time.Time{}, fmt.Errorf(
 			"error in inserting measurement run data into postgresql: %w",
 			err,
 		)
 	}
 
-	return nil
+	return timeStamp, nil
 }
 
 // get all the unprocessed measurement values
@@ -73,7 +79,8 @@ func (model MeasurementModel) GetUnprocessedDataForCalculationsTemperature(
 		mwu.temperature,
 		mwu.humidity,
 		mowm.measurement_id AS measurement_id_open_weather_map,
-		mowm.pressure
+		mowm.pressure,
+		mwu.time_stamp_run
 	FROM measurements_weather_union mwu
 	JOIN measurements_open_weather_map mowm
 	ON
@@ -110,3 +117,282 @@ func (model MeasurementModel) GetUnprocessedDataForCalculationsTemperature(
 	// return slice of unprocessed data for wet bulb calculations
 	return unprocessedSlice, nil
 }
+
+// a measurement run with a summary of its calculations
+type MeasurementRun struct {
+	RunID                 uuid.UUID `db:"run_id" json:"run_id"`
+	TimeStamp             time.Time `db:"time_stamp" json:"time_stamp"`
+	NumberOfCalculations  int       `db:"number_of_calculations" json:"number_of_calculations"`
+	TemperatureWetBulbMax float64   `db:"temperature_wet_bulb_max" json:"temperature_wet_bulb_max"`
+}
+
+// get the runs with calculations in a time range, oldest first
+func (model MeasurementModel) GetMeasurementRuns(
+	ctx context.Context,
+	timeFrom time.Time,
+	timeTo time.Time,
+) ([]MeasurementRun, error) {
+	// postgresql query string
+	var queryString string = `
+	SELECT
+		mr.run_id,
+		mr.time_stamp,
+		COUNT(ct.calculation_id)::INTEGER AS number_of_calculations,
+		ROUND(MAX(ct.temperature_wet_bulb)::NUMERIC, 3)::FLOAT
+			AS temperature_wet_bulb_max
+	FROM measurement_runs mr
+	JOIN measurements_weather_union mwu
+	ON mwu.run_id = mr.run_id
+	JOIN calculations_temperature ct
+	ON ct.measurement_id_weather_union = mwu.measurement_id
+	WHERE
+		mr.time_stamp >= @timeFrom AND
+		mr.time_stamp < @timeTo
+	GROUP BY mr.run_id, mr.time_stamp
+	ORDER BY mr.time_stamp;
+	`
+
+	// named arguments for building the query string
+	var queryArguments pgx.NamedArgs = pgx.NamedArgs{
+		"timeFrom": timeFrom,
+		"timeTo":   timeTo,
+	}
+
+	// create a 5 second timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, 5*time.Second)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// prepare the query
+	rows, err := model.DB.Query(ctxWT, queryString, queryArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	// run the query and collect rows
+	sliceRuns, err := pgx.CollectRows(
+		rows,
+		pgx.RowToStructByName[MeasurementRun],
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// return slice of runs
+	return sliceRuns, nil
+}
+
+// get the latest run with calculations at or before a time
+// returns pgx.ErrNoRows if there is no such run
+func (model MeasurementModel) GetMeasurementRunAtTime(
+	ctx context.Context,
+	timeAt time.Time,
+) (MeasurementRun, error) {
+	// postgresql query string
+	var queryString string = `
+	SELECT
+		mr.run_id,
+		mr.time_stamp,
+		COUNT(ct.calculation_id)::INTEGER AS number_of_calculations,
+		ROUND(MAX(ct.temperature_wet_bulb)::NUMERIC, 3)::FLOAT
+			AS temperature_wet_bulb_max
+	FROM measurement_runs mr
+	JOIN measurements_weather_union mwu
+	ON mwu.run_id = mr.run_id
+	JOIN calculations_temperature ct
+	ON ct.measurement_id_weather_union = mwu.measurement_id
+	WHERE mr.time_stamp <= @timeAt
+	GROUP BY mr.run_id, mr.time_stamp
+	ORDER BY mr.time_stamp DESC
+	LIMIT 1;
+	`
+
+	// named arguments for building the query string
+	var queryArguments pgx.NamedArgs = pgx.NamedArgs{
+		"timeAt": timeAt,
+	}
+
+	// create a 5 second timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, 5*time.Second)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// prepare the query
+	rows, err := model.DB.Query(ctxWT, queryString, queryArguments)
+	if err != nil {
+		return MeasurementRun{}, err
+	}
+
+	// run the query and collect the single row
+	run, err := pgx.CollectOneRow(
+		rows,
+		pgx.RowToStructByName[MeasurementRun],
+	)
+	if err != nil {
+		return MeasurementRun{}, err
+	}
+
+	return run, nil
+}
+
+// get the latest run with calculations
+// returns pgx.ErrNoRows if there are no runs yet
+func (model MeasurementModel) GetMeasurementRunLatest(
+	ctx context.Context,
+) (MeasurementRun, error) {
+	return model.GetMeasurementRunAtTime(ctx, time.Now())
+}
+
+// get a run by its ID
+// returns pgx.ErrNoRows if the run has no calculations
+func (model MeasurementModel) GetMeasurementRun(
+	ctx context.Context,
+	runID uuid.UUID,
+) (MeasurementRun, error) {
+	// postgresql query string
+	var queryString string = `
+	SELECT
+		mr.run_id,
+		mr.time_stamp,
+		COUNT(ct.calculation_id)::INTEGER AS number_of_calculations,
+		ROUND(MAX(ct.temperature_wet_bulb)::NUMERIC, 3)::FLOAT
+			AS temperature_wet_bulb_max
+	FROM measurement_runs mr
+	JOIN measurements_weather_union mwu
+	ON mwu.run_id = mr.run_id
+	JOIN calculations_temperature ct
+	ON ct.measurement_id_weather_union = mwu.measurement_id
+	WHERE mr.run_id = @runID
+	GROUP BY mr.run_id, mr.time_stamp;
+	`
+
+	// named arguments for building the query string
+	var queryArguments pgx.NamedArgs = pgx.NamedArgs{
+		"runID": runID,
+	}
+
+	// create a 5 second timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, 5*time.Second)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// prepare the query
+	rows, err := model.DB.Query(ctxWT, queryString, queryArguments)
+	if err != nil {
+		return MeasurementRun{}, err
+	}
+
+	// run the query and collect the single row
+	run, err := pgx.CollectOneRow(
+		rows,
+		pgx.RowToStructByName[MeasurementRun],
+	)
+	if err != nil {
+		return MeasurementRun{}, err
+	}
+
+	return run, nil
+}
+
+// outcome of a measurement run: the measurements saved from each provider,
+// the successful ones (with the values the calculations need) and the
+// calculations
+type MeasurementRunSummary struct {
+	RunID                              uuid.UUID `db:"run_id" json:"run_id"`
+	TimeStamp                          time.Time `db:"time_stamp" json:"time_stamp"`
+	NumberOfMeasurementsWeatherUnion   int       `db:"number_of_measurements_weather_union" json:"number_of_measurements_weather_union"`
+	NumberOfSuccessesWeatherUnion      int       `db:"number_of_successes_weather_union" json:"number_of_successes_weather_union"`
+	NumberOfMeasurementsOpenWeatherMap int       `db:"number_of_measurements_open_weather_map" json:"number_of_measurements_open_weather_map"`
+	NumberOfSuccessesOpenWeatherMap    int       `db:"number_of_successes_open_weather_map" json:"number_of_successes_open_weather_map"`
+	NumberOfCalculations               int       `db:"number_of_calculations" json:"number_of_calculations"`
+	// successful measurements the cron has not calculated yet
+	NumberOfMeasurementsPending int `db:"number_of_measurements_pending" json:"number_of_measurements_pending"`
+}
+
+// get a page of the runs with their outcomes, newest first
+// runs without calculations are included
+func (model MeasurementModel) GetMeasurementRunSummaries(
+	ctx context.Context,
+	limit int,
+	offset int,
+) ([]MeasurementRunSummary, error) {
+	// postgresql query string
+	// a weather union measurement is a success with a temperature and a
+	// humidity, an open weather map measurement with a pressure
+	var queryString string = `
+	SELECT
+		mr.run_id,
+		mr.time_stamp,
+		COALESCE(mwu.number_of_measurements, 0)
+			AS number_of_measurements_weather_union,
+		COALESCE(mwu.number_of_successes, 0)
+			AS number_of_successes_weather_union,
+		COALESCE(mowm.number_of_measurements, 0)
+			AS number_of_measurements_open_weather_map,
+		COALESCE(mowm.number_of_successes, 0)
+			AS number_of_successes_open_weather_map,
+		COALESCE(mwu.number_of_calculations, 0)
+			AS number_of_calculations,
+		COALESCE(mwu.number_of_pending, 0)
+			AS number_of_measurements_pending
+	FROM (
+		SELECT run_id, time_stamp
+		FROM measurement_runs
+		ORDER BY time_stamp DESC
+		LIMIT @limit
+		OFFSET @offset
+	) mr
+	LEFT JOIN LATERAL (
+		SELECT
+			COUNT(*)::INTEGER AS number_of_measurements,
+			(COUNT(*) FILTER (
+				WHERE temperature IS NOT NULL AND humidity IS NOT NULL
+			))::INTEGER AS number_of_successes,
+			(COUNT(*) FILTER (
+				WHERE
+					is_processed_for_calculation_temperature = FALSE AND
+					temperature IS NOT NULL AND
+					humidity IS NOT NULL
+			))::INTEGER AS number_of_pending,
+			(
+				SELECT COUNT(*)::INTEGER
+				FROM calculations_temperature ct
+				JOIN measurements_weather_union mwuc
+				ON ct.measurement_id_weather_union = mwuc.measurement_id
+				WHERE mwuc.run_id = mr.run_id
+			) AS number_of_calculations
+		FROM measurements_weather_union
+		WHERE run_id = mr.run_id
+	) mwu ON TRUE
+	LEFT JOIN LATERAL (
+		SELECT
+			COUNT(*)::INTEGER AS number_of_measurements,
+			(COUNT(*) FILTER (
+				WHERE pressure IS NOT NULL
+			))::INTEGER AS number_of_successes
+		FROM measurements_open_weather_map
+		WHERE run_id = mr.run_id
+	) mowm ON TRUE
+	ORDER BY mr.time_stamp DESC;
+	`
+
+	// named arguments for building the query string
+	var queryArguments pgx.NamedArgs = pgx.NamedArgs{
+		"limit":  limit,
+		"offset": offset,
+	}
+
+	// create a 5 second timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, 5*time.Second)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// prepare the query
+	rows, err := model.DB.Query(ctxWT, queryString, queryArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	// run the query and collect rows
+	return pgx.CollectRows(rows, pgx.RowToStructByName[MeasurementRunSummary])
+}
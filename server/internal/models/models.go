@@ -1,8 +1,26 @@
 package models
 
+import "net/http"
+
 type Models struct {
 	WeatherUnion   *WeatherUnionModel
 	OpenWeatherMap *OpenWeatherMapModel
 	Measurement    *MeasurementModel
 	Calculation    *CalculationModel
+	Contour        *ContourModel
+	Station        *StationModel
+	City           *CityModel
+	Rollup         *RollupModel
+	Partition      *PartitionModel
+	Notification   *NotificationModel
+	APIKey         *APIKeyModel
+}
+
+// http client of the calls to the weather APIs
+// http.DefaultClient if none is configured
+func httpClient(client *http.Client) *http.Client {
+	if client == nil {
+		return http.DefaultClient
+	}
+	return client
 }
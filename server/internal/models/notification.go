@@ -0,0 +1,136 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// postgresql channel notified with the run ID once the calculations of a
+// run are saved
+const ChannelMeasurementRuns string = "measurement_runs"
+
+// postgresql channel notified with the hex of the hash of a key once it is
+// revoked
+const ChannelAPIKeysRevoked string = "api_keys_revoked"
+
+// model struct for the postgresql notifications
+type NotificationModel struct {
+	DB *pgxpool.Pool
+}
+
+// notify the listeners that the calculations of a run are saved
+func (model NotificationModel) NotifyMeasurementRun(
+	ctx context.Context,
+	runID uuid.UUID,
+) error {
+	// postgresql query string
+	// pg_notify instead of NOTIFY, which does not take parameters
+	var queryString string = `
+	SELECT pg_notify(@channel, @runID);
+	`
+
+	// named arguments for building the query string
+	var queryArguments pgx.NamedArgs = pgx.NamedArgs{
+		"channel": ChannelMeasurementRuns,
+		"runID":   runID.String(),
+	}
+
+	// create a 5 second timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, 5*time.Second)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// executing the query string with the named arguments
+	_, err := model.DB.Exec(ctxWT, queryString, queryArguments)
+	if err != nil {
+		return fmt.Errorf(
+			"error in notifying measurement run in postgresql: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+// listen to the notifications of the runs on a connection of its own,
+// calling onNotification with the run ID of each
+// onListen is called once listening has started, from when no
+// notification is missed. blocks until the context is done (nil is
+// returned) or the connection fails.
+func (model NotificationModel) ListenMeasurementRuns(
+	ctx context.Context,
+	onListen func(),
+	onNotification func(runID string),
+) error {
+	return model.listen(ctx, ChannelMeasurementRuns, onListen, onNotification)
+}
+
+// listen to the revocations of the keys on a connection of its own,
+// calling onNotification with the hex of the hash of each key
+// onListen is called once listening has started, from when no
+// notification is missed. blocks until the context is done (nil is
+// returned) or the connection fails.
+func (model NotificationModel) ListenAPIKeysRevoked(
+	ctx context.Context,
+	onListen func(),
+	onNotification func(hashKeyHex string),
+) error {
+	return model.listen(ctx, ChannelAPIKeysRevoked, onListen, onNotification)
+}
+
+// listen to the notifications of the channel on a connection of its own,
+// calling onNotification with the payload of each
+func (model NotificationModel) listen(
+	ctx context.Context,
+	channel string,
+	onListen func(),
+	onNotification func(payload string),
+) error {
+	// a connection of the pool is held while listening
+	connection, err := model.DB.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("error in acquiring postgresql connection: %w", err)
+	}
+	// the connection goes back to the pool without listening, so that the
+	// notifications are not queued on it for its next user, or is closed
+	// if it cannot stop. the context of the listener is done by then.
+	defer func() {
+		ctxWT, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err := connection.Exec(ctxWT, "UNLISTEN *")
+		if err != nil {
+			connection.Conn().Close(ctxWT)
+		}
+		connection.Release()
+	}()
+
+	_, err = connection.Exec(
+		ctx,
+		"LISTEN "+pgx.Identifier{channel}.Sanitize(),
+	)
+	if err != nil {
+		return fmt.Errorf("error in listening in postgresql: %w", err)
+	}
+	onListen()
+
+	for {
+		notification, err := connection.Conn().WaitForNotification(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			// the connection is in an unknown state, close it
+			connection.Conn().Close(context.Background())
+			return fmt.Errorf(
+				"error in waiting for postgresql notification: %w",
+				err,
+			)
+		}
+		onNotification(notification.Payload)
+	}
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/kelaaditya/zomato-weather-union/server/internal/testdb"
+)
+
+// the connection of a listener goes back to the pool without listening
+func TestListenReleasesConnection(t *testing.T) {
+	var DB *pgxpool.Pool = testdb.New(t)
+	var ctx context.Context = context.Background()
+
+	// a pool of a single connection, which the listener then gives back
+	var configPool *pgxpool.Config = DB.Config()
+	configPool.MaxConns = 1
+	poolSingle, err := pgxpool.NewWithConfig(ctx, configPool)
+	if err != nil {
+		t.Fatalf("error in opening the pool: %v", err)
+	}
+	defer poolSingle.Close()
+
+	var model NotificationModel = NotificationModel{DB: poolSingle}
+	var chanListening chan struct{} = make(chan struct{})
+	ctxListen, cancel := context.WithCancel(ctx)
+	var chanDone chan error = make(chan error, 1)
+	go func() {
+		chanDone <- model.ListenMeasurementRuns(
+			ctxListen,
+			func() { close(chanListening) },
+			func(string) {},
+		)
+	}()
+	select {
+	case <-chanListening:
+	case err := <-chanDone:
+		t.Fatalf("error in listening to the runs: %v", err)
+	}
+	cancel()
+	select {
+	case err := <-chanDone:
+		if err != nil {
+			t.Fatalf("error of the stopped listener: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("listener does not stop")
+	}
+
+	var numberOfChannels int
+	err = poolSingle.QueryRow(ctx, "SELECT COUNT(*) FROM pg_listening_channels();").Scan(&numberOfChannels)
+	if err != nil {
+		t.Fatalf("error in reading the channels: %v", err)
+	}
+	if numberOfChannels != 0 {
+		t.Errorf("connection of the pool listens to %d channels, expected none", numberOfChannels)
+	}
+}
@@ -3,8 +3,10 @@ package models
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,15 +18,19 @@ import (
 // model struct for open weather map
 type OpenWeatherMapModel struct {
 	DB *pgxpool.Pool
+	// http client of the API calls, http.DefaultClient if nil
+	Client *http.Client
 }
 
 // type to hold a "measurement" from open weather map
 // see the schema structure for the table "measurements_open_weather_map"
 // in the PostgreSQL migration files.
 type OpenWeatherMapMeasurement struct {
-	MeasurementID            uuid.UUID `json:"measurement_id"`
-	WeatherStationID         uuid.UUID `json:"weather_station_id"`
-	RunID                    uuid.UUID `json:"run_id"`
+	MeasurementID    uuid.UUID `json:"measurement_id"`
+	WeatherStationID uuid.UUID `json:"weather_station_id"`
+	RunID            uuid.UUID `json:"run_id"`
+	// time stamp of the run, the partition key of the measurements
+	TimeStampRun             time.Time `json:"time_stamp_run"`
 	OpenWeatherMapAPIReponse           // embedded struct
 }
 
@@ -67,6 +73,7 @@ type OpenWeatherMapObjectSubWeather struct {
 
 // function for calling the Open Weather API
 func (model OpenWeatherMapModel) CallAPIOpenWeatherMap(
+	ctx context.Context,
 	APIBaseURL string,
 	APIKey string,
 	station *WeatherUnionStation,
@@ -101,10 +108,27 @@ func (model OpenWeatherMapModel) CallAPIOpenWeatherMap(
 	//
 	// carry out GET request
 	//
-	response, err := http.Get(URLString)
+	request, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		URLString,
+		nil,
+	)
 	if err != nil {
 		return OpenWeatherMapMeasurement{}, err
 	}
+	response, err := httpClient(model.Client).Do(request)
+	if err != nil {
+		// the URL holds the API key, keep it out of the logs and spans
+		var errURL *url.Error
+		if errors.As(err, &errURL) {
+			err = fmt.Errorf(
+				"error in calling open weather map api: %w",
+				errURL.Err,
+			)
+		}
+		return OpenWeatherMapMeasurement{}, err
+	}
 	defer response.Body.Close()
 
 	// check the http status codes
@@ -155,10 +179,16 @@ func (model OpenWeatherMapModel) SaveMeasurementOpenWeatherMap(
 
 	// build a slice of values for bulk insert
 	for i, measurement := range sliceMeasurementsOpenWeatherMap {
+		// the first weather object, the response may have none
+		var weatherObject OpenWeatherMapObjectSubWeather
+		if len(measurement.Current.WeatherObject) > 0 {
+			weatherObject = measurement.Current.WeatherObject[0]
+		}
 		sliceInsertValues[i] = []any{
 			measurement.MeasurementID,
 			measurement.WeatherStationID,
 			measurement.RunID,
+			measurement.TimeStampRun,
 			utilities.DereferenceOrNil(measurement.TimeZone),
 			utilities.DereferenceOrNil(measurement.TimeZoneOffset),
 			utilities.DereferenceOrNil(measurement.Current.TimeCurrent),
@@ -175,10 +205,10 @@ func (model OpenWeatherMapModel) SaveMeasurementOpenWeatherMap(
 			utilities.DereferenceOrNil(measurement.Current.WindSpeed),
 			utilities.DereferenceOrNil(measurement.Current.WindDirection),
 			utilities.DereferenceOrNil(measurement.Current.WindGust),
-			utilities.DereferenceOrNil(measurement.Current.WeatherObject[0].ID),
-			utilities.DereferenceOrNil(measurement.Current.WeatherObject[0].Main),
-			utilities.DereferenceOrNil(measurement.Current.WeatherObject[0].Description),
-			utilities.DereferenceOrNil(measurement.Current.WeatherObject[0].Icon),
+			utilities.DereferenceOrNil(weatherObject.ID),
+			utilities.DereferenceOrNil(weatherObject.Main),
+			utilities.DereferenceOrNil(weatherObject.Description),
+			utilities.DereferenceOrNil(weatherObject.Icon),
 		}
 	}
 
@@ -195,6 +225,7 @@ func (model OpenWeatherMapModel) SaveMeasurementOpenWeatherMap(
 			"measurement_id",
 			"weather_station_id",
 			"run_id",
+			"time_stamp_run",
 			"time_zone",
 			"time_zone_offset",
 			"time_current",
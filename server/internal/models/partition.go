@@ -0,0 +1,230 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// tables partitioned by month on the time stamp of the run of their rows,
+// time_stamp_run
+var tablesPartitioned []string = []string{
+	"measurements_weather_union",
+	"measurements_open_weather_map",
+	"calculations_temperature",
+}
+
+// schema the detached partitions are moved to
+const schemaArchive string = "archive"
+
+// model struct for the maintenance of the monthly partitions
+type PartitionModel struct {
+	DB *pgxpool.Pool
+}
+
+// layout of the month in the partition names, <table>_yYYYYmMM
+const layoutPartitionMonth string = "y2006m01"
+
+// name of the partition of a table for the month (UTC) of a time
+func partitionName(table string, timeMonth time.Time) string {
+	return table + "_" + timeMonth.UTC().Format(layoutPartitionMonth)
+}
+
+// start of the month (UTC) of a time
+func startOfMonth(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// create the partitions of every partitioned table for the current
+// month and the following months
+// the rows of a new partition's month are moved out of the default
+// partition (see create_partition_month in the migrations).
+func (model PartitionModel) CreatePartitionsFuture(
+	ctx context.Context,
+	numberOfMonthsAhead int,
+) error {
+	// create a 5 second timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, 5*time.Second)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// create batch queries for postgresql entry
+	var queryBatch *pgx.Batch = &pgx.Batch{}
+
+	var timeMonthCurrent time.Time = startOfMonth(time.Now())
+	for _, table := range tablesPartitioned {
+		for i := 0; i <= numberOfMonthsAhead; i++ {
+			var timeFrom time.Time = timeMonthCurrent.AddDate(0, i, 0)
+			var timeTo time.Time = timeFrom.AddDate(0, 1, 0)
+			queryBatch.Queue(
+				"SELECT create_partition_month(@table, @partition, @timeFrom, @timeTo);",
+				pgx.NamedArgs{
+					"table":     table,
+					"partition": partitionName(table, timeFrom),
+					"timeFrom":  timeFrom,
+					"timeTo":    timeTo,
+				},
+			)
+		}
+	}
+
+	// send the batch query via the connection pool
+	err := model.DB.SendBatch(ctxWT, queryBatch).Close()
+	if err != nil {
+		return fmt.Errorf("error in creating partitions in postgresql: %w", err)
+	}
+
+	// return nil if all okay
+	return nil
+}
+
+// detach the partitions of every partitioned table that end before the
+// months to keep and move them to the archive schema, from where they
+// can be dumped and dropped. returns the names of the archived partitions.
+// the partitions of a month are archived together in a transaction, oldest
+// month first. the calculations have the time stamp of the run of their
+// measurement, so they are archived with their measurements.
+func (model PartitionModel) ArchivePartitionsOld(
+	ctx context.Context,
+	numberOfMonthsToKeep int,
+) ([]string, error) {
+	// partitions of months before this time are archived
+	var timeBefore time.Time = startOfMonth(time.Now()).AddDate(0, -numberOfMonthsToKeep, 0)
+
+	// postgresql query string for the attached partitions of the tables
+	var queryString string = `
+	SELECT parent.relname, child.relname
+	FROM pg_inherits
+	JOIN pg_class parent
+	ON parent.oid = pg_inherits.inhparent
+	JOIN pg_class child
+	ON child.oid = pg_inherits.inhrelid
+	JOIN pg_namespace
+	ON pg_namespace.oid = child.relnamespace
+	WHERE
+		parent.relname = ANY(@tableNames) AND
+		pg_namespace.nspname = current_schema();
+	`
+
+	// named arguments for building the query string
+	var queryArguments pgx.NamedArgs = pgx.NamedArgs{
+		"tableNames": tablesPartitioned,
+	}
+
+	// create a timeout context, detaching waits for running queries
+	ctxWT, cancel := context.WithTimeout(ctx, time.Minute)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// prepare the query
+	rows, err := model.DB.Query(ctxWT, queryString, queryArguments)
+	if err != nil {
+		return nil, fmt.Errorf("error in querying partitions from postgresql: %w", err)
+	}
+
+	// partitions to archive by month, and by table within a month
+	var mapMonths map[time.Time]map[string]string = make(map[time.Time]map[string]string)
+	var table, partition string
+	_, err = pgx.ForEachRow(rows, []any{&table, &partition}, func() error {
+		// the month of the partition from its name, partitions not
+		// named by the maintenance are left alone
+		timeMonth, err := time.Parse(
+			layoutPartitionMonth,
+			strings.TrimPrefix(partition, table+"_"),
+		)
+		if err != nil {
+			return nil
+		}
+		if timeMonth.Before(timeBefore) {
+			if mapMonths[timeMonth] == nil {
+				mapMonths[timeMonth] = make(map[string]string)
+			}
+			mapMonths[timeMonth][table] = partition
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error in collecting partitions from postgresql: %w", err)
+	}
+
+	// oldest month first
+	var sliceMonths []time.Time = make([]time.Time, 0, len(mapMonths))
+	for timeMonth := range mapMonths {
+		sliceMonths = append(sliceMonths, timeMonth)
+	}
+	slices.SortFunc(sliceMonths, time.Time.Compare)
+
+	_, err = model.DB.Exec(
+		ctxWT,
+		"CREATE SCHEMA IF NOT EXISTS "+pgx.Identifier{schemaArchive}.Sanitize()+";",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error in creating archive schema in postgresql: %w", err)
+	}
+
+	// archive the partitions of each month in their own transaction
+	var sliceArchived []string
+	for _, timeMonth := range sliceMonths {
+		sliceArchivedMonth, err := model.archiveMonth(ctxWT, mapMonths[timeMonth])
+		if err != nil {
+			return sliceArchived, fmt.Errorf(
+				"error in archiving partitions of %s in postgresql: %w",
+				timeMonth.Format("2006-01"),
+				err,
+			)
+		}
+		sliceArchived = append(sliceArchived, sliceArchivedMonth...)
+	}
+
+	return sliceArchived, nil
+}
+
+// detach the partitions of a month (by table) and move them to the archive
+// schema in a transaction. returns the names of the archived partitions.
+func (model PartitionModel) archiveMonth(
+	ctx context.Context,
+	mapPartitions map[string]string,
+) ([]string, error) {
+	// partitions of the month in the order of the tables
+	var slicePartitions []string
+	var sliceTables []string
+	for _, table := range tablesPartitioned {
+		if partition, ok := mapPartitions[table]; ok {
+			slicePartitions = append(slicePartitions, partition)
+			sliceTables = append(sliceTables, table)
+		}
+	}
+
+	err := pgx.BeginFunc(ctx, model.DB, func(tx pgx.Tx) error {
+		for i, partition := range slicePartitions {
+			_, err := tx.Exec(ctx, fmt.Sprintf(
+				"ALTER TABLE %s DETACH PARTITION %s;",
+				pgx.Identifier{sliceTables[i]}.Sanitize(),
+				pgx.Identifier{partition}.Sanitize(),
+			))
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(ctx, fmt.Sprintf(
+				"ALTER TABLE %s SET SCHEMA %s;",
+				pgx.Identifier{partition}.Sanitize(),
+				pgx.Identifier{schemaArchive}.Sanitize(),
+			))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return slicePartitions, nil
+}
@@ -0,0 +1,215 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/kelaaditya/zomato-weather-union/server/internal/testdb"
+)
+
+// save a weather union station for the test and return its ID
+func saveStationTest(t *testing.T, DB *pgxpool.Pool) uuid.UUID {
+	t.Helper()
+
+	var stationID uuid.UUID = uuid.New()
+	_, err := DB.Exec(
+		context.Background(),
+		`
+		INSERT INTO weather_union_stations (
+			weather_station_id,
+			city_name,
+			locality_name,
+			locality_id,
+			location,
+			device_type,
+			device_type_integer,
+			is_active
+		)
+		VALUES (
+			@stationID,
+			'Delhi NCR',
+			'Locality',
+			'ZWL000001',
+			ST_SetSRID(ST_MakePoint(77.2, 28.6), 4326)::geography,
+			'Weather Station',
+			1,
+			TRUE
+		);
+		`,
+		pgx.NamedArgs{"stationID": stationID},
+	)
+	if err != nil {
+		t.Fatalf("error in saving station: %v", err)
+	}
+	return stationID
+}
+
+// the partitions of a month are archived together, with the calculations
+// of the runs of the month however late they were calculated
+func TestArchivePartitionsOld(t *testing.T) {
+	var DB *pgxpool.Pool = testdb.New(t)
+	var ctx context.Context = context.Background()
+
+	// two months long before the partitions of the migrations
+	var timeJanuary time.Time = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	var timeFebruary time.Time = timeJanuary.AddDate(0, 1, 0)
+	var slicePartitions []string
+	for _, timeMonth := range []time.Time{timeJanuary, timeFebruary} {
+		for _, table := range tablesPartitioned {
+			var partition string = partitionName(table, timeMonth)
+			slicePartitions = append(slicePartitions, partition)
+			_, err := DB.Exec(
+				ctx,
+				"SELECT create_partition_month(@table, @partition, @timeFrom, @timeTo);",
+				pgx.NamedArgs{
+					"table":     table,
+					"partition": partition,
+					"timeFrom":  timeMonth,
+					"timeTo":    timeMonth.AddDate(0, 1, 0),
+				},
+			)
+			if err != nil {
+				t.Fatalf("error in creating partition %s: %v", partition, err)
+			}
+		}
+	}
+	t.Cleanup(func() {
+		for _, partition := range slicePartitions {
+			for _, schema := range []string{schemaArchive, "public"} {
+				DB.Exec(ctx, "DROP TABLE IF EXISTS "+pgx.Identifier{schema, partition}.Sanitize()+";")
+			}
+		}
+	})
+
+	var stationID uuid.UUID = saveStationTest(t, DB)
+
+	// a run at the end of january with calculations on both sides of the
+	// month, and a run in february calculated in march
+	var sliceRuns = []struct {
+		timeRun           time.Time
+		sliceCalculations []time.Time
+	}{
+		{
+			timeRun: timeFebruary.Add(-time.Hour),
+			sliceCalculations: []time.Time{
+				timeFebruary.Add(-time.Minute),
+				timeFebruary.Add(time.Minute),
+			},
+		},
+		{
+			timeRun:           timeFebruary.AddDate(0, 0, 27),
+			sliceCalculations: []time.Time{timeFebruary.AddDate(0, 1, 0).Add(time.Minute)},
+		},
+	}
+	for _, run := range sliceRuns {
+		var runID uuid.UUID = uuid.New()
+		var measurementID uuid.UUID = uuid.New()
+		var queryBatch *pgx.Batch = &pgx.Batch{}
+		var queryArguments pgx.NamedArgs = pgx.NamedArgs{
+			"runID":         runID,
+			"measurementID": measurementID,
+			"stationID":     stationID,
+			"timeRun":       run.timeRun,
+		}
+		queryBatch.Queue(
+			"INSERT INTO measurement_runs (run_id, time_stamp) VALUES (@runID, @timeRun);",
+			queryArguments,
+		)
+		queryBatch.Queue(
+			`INSERT INTO measurements_weather_union (measurement_id, weather_station_id, run_id, time_stamp_run)
+			VALUES (@measurementID, @stationID, @runID, @timeRun);`,
+			queryArguments,
+		)
+		queryBatch.Queue(
+			`INSERT INTO measurements_open_weather_map (measurement_id, weather_station_id, run_id, time_stamp_run)
+			VALUES (gen_random_uuid(), @stationID, @runID, @timeRun);`,
+			queryArguments,
+		)
+		for _, timeCalculation := range run.sliceCalculations {
+			queryBatch.Queue(
+				`INSERT INTO calculations_temperature (
+					calculation_id,
+					measurement_id_weather_union,
+					method,
+					temperature_dew_point,
+					temperature_wet_bulb,
+					time_stamp,
+					time_stamp_run
+				)
+				VALUES (
+					gen_random_uuid(),
+					@measurementID,
+					'metpy-with-open-weather-map',
+					20,
+					25,
+					@timeCalculation,
+					@timeRun
+				);`,
+				pgx.NamedArgs{
+					"measurementID":   measurementID,
+					"timeCalculation": timeCalculation,
+					"timeRun":         run.timeRun,
+				},
+			)
+		}
+		err := DB.SendBatch(ctx, queryBatch).Close()
+		if err != nil {
+			t.Fatalf("error in saving run: %v", err)
+		}
+	}
+
+	// keep every month from march 2000
+	var timeNow time.Time = time.Now().UTC()
+	var numberOfMonthsToKeep int = (timeNow.Year()-2000)*12 + int(timeNow.Month()) - 3
+	var model PartitionModel = PartitionModel{DB: DB}
+	sliceArchived, err := model.ArchivePartitionsOld(ctx, numberOfMonthsToKeep)
+	if err != nil {
+		t.Fatalf("error in archiving partitions: %v", err)
+	}
+	if len(sliceArchived) != len(slicePartitions) {
+		t.Errorf("archived %v, expected %v", sliceArchived, slicePartitions)
+	}
+
+	// no attached calculation is left without its measurement
+	var numberOfOrphans int
+	err = DB.QueryRow(
+		ctx,
+		`SELECT COUNT(*)
+		FROM calculations_temperature ct
+		WHERE NOT EXISTS (
+			SELECT 1
+			FROM measurements_weather_union mwu
+			WHERE mwu.measurement_id = ct.measurement_id_weather_union
+		);`,
+	).Scan(&numberOfOrphans)
+	if err != nil {
+		t.Fatalf("error in counting calculations: %v", err)
+	}
+	if numberOfOrphans != 0 {
+		t.Errorf("%d attached calculations of archived measurements", numberOfOrphans)
+	}
+
+	// the calculations are archived with the month of their run
+	for timeMonth, numberOfCalculations := range map[time.Time]int{
+		timeJanuary:  2,
+		timeFebruary: 1,
+	} {
+		var partition string = partitionName("calculations_temperature", timeMonth)
+		var count int
+		err = DB.QueryRow(
+			ctx,
+			"SELECT COUNT(*) FROM "+pgx.Identifier{schemaArchive, partition}.Sanitize()+";",
+		).Scan(&count)
+		if err != nil {
+			t.Fatalf("error in counting archived calculations of %s: %v", partition, err)
+		}
+		if count != numberOfCalculations {
+			t.Errorf("%s has %d calculations, expected %d", partition, count, numberOfCalculations)
+		}
+	}
+}
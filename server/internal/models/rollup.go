@@ -0,0 +1,366 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// the first rollup goes over the whole history and takes much longer
+// than the usual 5 seconds
+const timeoutRollup time.Duration = 5 * time.Minute
+
+// buckets within this time of now are rolled up again on the next run,
+// as measurements of a run are saved a few minutes after their time stamp
+const lagRollup time.Duration = time.Hour
+
+// model struct for the hourly and daily rollups
+type RollupModel struct {
+	DB *pgxpool.Pool
+}
+
+// a raw table that is rolled up per station
+type rollupSource struct {
+	// name of the raw table, the rollup tables add _hourly and _daily
+	table string
+	// column with the number of rows in a bucket
+	columnCount string
+	// columns aggregated to their minimum, maximum and mean
+	columns []string
+	// FROM clause giving weather_station_id, time_stamp and the columns
+	from string
+}
+
+// a granularity of the rollups
+type rollupGranularity struct {
+	// suffix of the rollup tables
+	suffix string
+	// date_trunc field of the buckets
+	field string
+}
+
+// raw tables that are rolled up
+var rollupSources []rollupSource = []rollupSource{
+	{
+		table:       "measurements_weather_union",
+		columnCount: "number_of_measurements",
+		columns:     []string{"temperature", "humidity", "wind_speed", "rain_intensity"},
+		from:        "measurements_weather_union source",
+	},
+	{
+		table:       "measurements_open_weather_map",
+		columnCount: "number_of_measurements",
+		columns:     []string{"temperature", "humidity", "pressure", "wind_speed"},
+		from:        "measurements_open_weather_map source",
+	},
+	{
+		// calculations are bucketed by the time of their measurement
+		table:       "calculations_temperature",
+		columnCount: "number_of_calculations",
+		columns:     []string{"temperature_wet_bulb", "temperature_dew_point"},
+		from: `(
+			SELECT
+				mwu.weather_station_id,
+				mwu.time_stamp,
+				ct.temperature_wet_bulb,
+				ct.temperature_dew_point
+			FROM calculations_temperature ct
+			JOIN measurements_weather_union mwu
+			ON ct.measurement_id_weather_union = mwu.measurement_id
+		) source`,
+	},
+}
+
+// granularities of the rollups
+var rollupGranularities []rollupGranularity = []rollupGranularity{
+	{suffix: "hourly", field: "hour"},
+	{suffix: "daily", field: "day"},
+}
+
+// names of all rollup tables
+func rollupTables() []string {
+	var tables []string
+	for _, source := range rollupSources {
+		for _, granularity := range rollupGranularities {
+			tables = append(tables, source.table+"_"+granularity.suffix)
+		}
+	}
+	return tables
+}
+
+// bring all rollup tables up to date
+//
+// every rollup table has a watermark in rollup_watermarks. the buckets
+// from the watermark onwards are recomputed from the raw rows and
+// upserted, so running the rollups again is harmless. saving or deleting
+// calculations of an older run moves the watermarks of the calculation
+// rollups back to its buckets.
+func (model RollupModel) UpdateRollups(ctx context.Context) error {
+	for _, source := range rollupSources {
+		for _, granularity := range rollupGranularities {
+			err := model.updateRollup(ctx, source, granularity)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// return nil if all okay
+	return nil
+}
+
+// recompute the buckets of a single rollup table from its watermark
+func (model RollupModel) updateRollup(
+	ctx context.Context,
+	source rollupSource,
+	granularity rollupGranularity,
+) error {
+	var tableRollup string = source.table + "_" + granularity.suffix
+
+	// aggregate and update expressions of the columns
+	var columnsInsert []string = []string{"weather_station_id", "time_stamp", source.columnCount}
+	var expressionsSelect []string
+	var expressionsUpdate []string = []string{
+		fmt.Sprintf("%[1]s = EXCLUDED.%[1]s", source.columnCount),
+	}
+	for _, column := range source.columns {
+		columnsInsert = append(
+			columnsInsert,
+			column+"_min",
+			column+"_max",
+			column+"_mean",
+		)
+		expressionsSelect = append(
+			expressionsSelect,
+			fmt.Sprintf("MIN(source.%s)", column),
+			fmt.Sprintf("MAX(source.%s)", column),
+			fmt.Sprintf("AVG(source.%s)", column),
+		)
+		expressionsUpdate = append(
+			expressionsUpdate,
+			fmt.Sprintf("%[1]s_min = EXCLUDED.%[1]s_min", column),
+			fmt.Sprintf("%[1]s_max = EXCLUDED.%[1]s_max", column),
+			fmt.Sprintf("%[1]s_mean = EXCLUDED.%[1]s_mean", column),
+		)
+	}
+
+	// postgresql query string for the upsert of the buckets
+	var queryStringUpsert string = fmt.Sprintf(`
+	INSERT INTO %s (%s)
+	SELECT
+		source.weather_station_id,
+		date_trunc('%s', source.time_stamp, 'UTC') AS bucket,
+		COUNT(*)::INTEGER,
+		%s
+	FROM %s
+	WHERE source.time_stamp >= @timeFrom
+	GROUP BY source.weather_station_id, bucket
+	ON CONFLICT (weather_station_id, time_stamp) DO UPDATE SET
+		%s;
+	`,
+		tableRollup,
+		strings.Join(columnsInsert, ", "),
+		granularity.field,
+		strings.Join(expressionsSelect, ",\n\t\t"),
+		source.from,
+		strings.Join(expressionsUpdate, ",\n\t\t"),
+	)
+
+	// postgresql query string for the deletion of the buckets without rows
+	var queryStringDelete string = fmt.Sprintf(`
+	DELETE FROM %s bucket
+	WHERE
+		bucket.time_stamp >= @timeFrom AND
+		NOT EXISTS (
+			SELECT 1
+			FROM %s
+			WHERE
+				source.weather_station_id = bucket.weather_station_id AND
+				source.time_stamp >= bucket.time_stamp AND
+				source.time_stamp < bucket.time_stamp + INTERVAL '1 %s'
+		);
+	`,
+		tableRollup,
+		source.from,
+		granularity.field,
+	)
+
+	// create a timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, timeoutRollup)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// begin transaction
+	tx, err := model.DB.Begin(ctxWT)
+	if err != nil {
+		return fmt.Errorf("error in beginning rollup transaction in postgresql: %w", err)
+	}
+	// rollback is a no-op after commit
+	defer tx.Rollback(ctxWT)
+
+	// watermark of the rollup table, the start of the first bucket that
+	// is recomputed. rolls up the whole history if there is none.
+	var timeWatermark *time.Time
+	err = tx.QueryRow(
+		ctxWT,
+		`
+		SELECT time_stamp_until
+		FROM rollup_watermarks
+		WHERE table_name = @tableName
+		FOR UPDATE;
+		`,
+		pgx.NamedArgs{"tableName": tableRollup},
+	).Scan(&timeWatermark)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("error in reading rollup watermark of %s from postgresql: %w", tableRollup, err)
+	}
+	var timeFrom time.Time
+	if timeWatermark != nil {
+		timeFrom = *timeWatermark
+	}
+
+	// recompute the buckets
+	_, err = tx.Exec(ctxWT, queryStringUpsert, pgx.NamedArgs{"timeFrom": timeFrom})
+	if err != nil {
+		return fmt.Errorf("error in updating rollup %s in postgresql: %w", tableRollup, err)
+	}
+
+	// delete the buckets whose rows are all gone, as the calculations of
+	// a run recalculated by an admin
+	_, err = tx.Exec(ctxWT, queryStringDelete, pgx.NamedArgs{"timeFrom": timeFrom})
+	if err != nil {
+		return fmt.Errorf("error in deleting empty buckets of rollup %s in postgresql: %w", tableRollup, err)
+	}
+
+	// move the watermark to the start of the bucket that may still
+	// receive rows
+	_, err = tx.Exec(
+		ctxWT,
+		fmt.Sprintf(`
+		INSERT INTO rollup_watermarks (table_name, time_stamp_until, time_stamp)
+		VALUES (
+			@tableName,
+			GREATEST(
+				date_trunc('%s', NOW() - @lag::INTERVAL, 'UTC'),
+				@timeFrom
+			),
+			NOW()
+		)
+		ON CONFLICT (table_name) DO UPDATE SET
+			time_stamp_until = EXCLUDED.time_stamp_until,
+			time_stamp = EXCLUDED.time_stamp;
+		`, granularity.field),
+		pgx.NamedArgs{
+			"tableName": tableRollup,
+			"lag":       lagRollup,
+			"timeFrom":  timeFrom,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("error in saving rollup watermark of %s in postgresql: %w", tableRollup, err)
+	}
+
+	// commit transaction
+	err = tx.Commit(ctxWT)
+	if err != nil {
+		return fmt.Errorf("error in committing rollup transaction in postgresql: %w", err)
+	}
+
+	// return nil if all okay
+	return nil
+}
+
+// delete the raw measurements and calculations older than a number of
+// days, but only those that are already covered by every rollup table.
+// returns the number of deleted rows.
+func (model RollupModel) DeleteRawRowsRolledUp(
+	ctx context.Context,
+	numberOfDays int,
+) (int64, error) {
+	// create a timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, timeoutRollup)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// begin transaction
+	tx, err := model.DB.Begin(ctxWT)
+	if err != nil {
+		return 0, fmt.Errorf("error in beginning retention transaction in postgresql: %w", err)
+	}
+	// rollback is a no-op after commit
+	defer tx.Rollback(ctxWT)
+
+	// the oldest watermark of all rollup tables
+	var tables []string = rollupTables()
+	var numberOfWatermarks int
+	var timeWatermark *time.Time
+	err = tx.QueryRow(
+		ctxWT,
+		`
+		SELECT COUNT(*)::INTEGER, MIN(time_stamp_until)
+		FROM rollup_watermarks
+		WHERE table_name = ANY(@tableNames);
+		`,
+		pgx.NamedArgs{"tableNames": tables},
+	).Scan(&numberOfWatermarks, &timeWatermark)
+	if err != nil {
+		return 0, fmt.Errorf("error in reading rollup watermarks from postgresql: %w", err)
+	}
+	// nothing to delete until every table has been rolled up
+	if numberOfWatermarks < len(tables) || timeWatermark == nil {
+		return 0, nil
+	}
+
+	// delete rows before the retention period and the oldest watermark
+	var timeBefore time.Time = time.Now().AddDate(0, 0, -numberOfDays)
+	if timeWatermark.Before(timeBefore) {
+		timeBefore = *timeWatermark
+	}
+	// at the start of a day, so that the buckets of a run recalculated
+	// later are rolled up again from all their rows
+	timeBefore = timeBefore.UTC().Truncate(24 * time.Hour)
+
+	// calculations first as they reference the weather union measurements
+	var queryStrings []string = []string{
+		`
+		DELETE FROM calculations_temperature ct
+		USING measurements_weather_union mwu
+		WHERE
+			ct.measurement_id_weather_union = mwu.measurement_id AND
+			mwu.time_stamp < @timeBefore;
+		`,
+		`
+		DELETE FROM measurements_weather_union
+		WHERE time_stamp < @timeBefore;
+		`,
+		`
+		DELETE FROM measurements_open_weather_map
+		WHERE time_stamp < @timeBefore;
+		`,
+	}
+	var numberOfRowsDeleted int64
+	for _, queryString := range queryStrings {
+		commandTag, err := tx.Exec(
+			ctxWT,
+			queryString,
+			pgx.NamedArgs{"timeBefore": timeBefore},
+		)
+		if err != nil {
+			return 0, fmt.Errorf("error in deleting rolled up raw rows in postgresql: %w", err)
+		}
+		numberOfRowsDeleted += commandTag.RowsAffected()
+	}
+
+	// commit transaction
+	err = tx.Commit(ctxWT)
+	if err != nil {
+		return 0, fmt.Errorf("error in committing retention transaction in postgresql: %w", err)
+	}
+
+	return numberOfRowsDeleted, nil
+}
@@ -0,0 +1,143 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/kelaaditya/zomato-weather-union/server/internal/testdb"
+)
+
+// the buckets of a run recalculated after its rollup are rolled up again,
+// without its deleted calculations and with its saved ones
+func TestUpdateRollupsRecalculation(t *testing.T) {
+	var DB *pgxpool.Pool = testdb.New(t)
+	var ctx context.Context = context.Background()
+	var modelRollup RollupModel = RollupModel{DB: DB}
+	var modelCalculation CalculationModel = CalculationModel{DB: DB}
+
+	// a run a few days old, long behind the watermarks
+	var stationID uuid.UUID = saveStationTest(t, DB)
+	var runID uuid.UUID = uuid.New()
+	var measurementID uuid.UUID = uuid.New()
+	var timeRun time.Time = time.Now().UTC().AddDate(0, 0, -3).Truncate(time.Hour).Add(10 * time.Minute)
+	var queryBatch *pgx.Batch = &pgx.Batch{}
+	var queryArguments pgx.NamedArgs = pgx.NamedArgs{
+		"runID":         runID,
+		"measurementID": measurementID,
+		"stationID":     stationID,
+		"timeRun":       timeRun,
+	}
+	queryBatch.Queue(
+		"INSERT INTO measurement_runs (run_id, time_stamp) VALUES (@runID, @timeRun);",
+		queryArguments,
+	)
+	queryBatch.Queue(
+		`INSERT INTO measurements_weather_union (measurement_id, weather_station_id, run_id, time_stamp, time_stamp_run)
+		VALUES (@measurementID, @stationID, @runID, @timeRun, @timeRun);`,
+		queryArguments,
+	)
+	queryBatch.Queue(
+		`INSERT INTO measurements_open_weather_map (measurement_id, weather_station_id, run_id, time_stamp, time_stamp_run)
+		VALUES (gen_random_uuid(), @stationID, @runID, @timeRun, @timeRun);`,
+		queryArguments,
+	)
+	err := DB.SendBatch(ctx, queryBatch).Close()
+	if err != nil {
+		t.Fatalf("error in saving run: %v", err)
+	}
+
+	// save a calculation of the measurement with the wet bulb temperature
+	// and roll up
+	var saveAndRollUp = func(temperatureWetBulb float64) {
+		t.Helper()
+		err := modelCalculation.SaveCalculationsTemperatures(
+			ctx,
+			[]CalculationTemperature{{
+				CalculationID:             uuid.New(),
+				MeasurementIDWeatherUnion: measurementID,
+				Method:                    "metpy-with-open-weather-map",
+				TemperatureDewPoint:       20,
+				TemperatureWetBulb:        temperatureWetBulb,
+			}},
+		)
+		if err != nil {
+			t.Fatalf("error in saving calculation: %v", err)
+		}
+		err = modelRollup.UpdateRollups(ctx)
+		if err != nil {
+			t.Fatalf("error in updating rollups: %v", err)
+		}
+	}
+
+	// wet bulb temperature mean of the bucket of the run, per rollup
+	// table that has the bucket
+	var readBuckets = func() map[string]float64 {
+		t.Helper()
+		var mapMeans map[string]float64 = map[string]float64{}
+		for _, granularity := range rollupGranularities {
+			var table string = "calculations_temperature_" + granularity.suffix
+			var mean float64
+			err := DB.QueryRow(
+				ctx,
+				`SELECT temperature_wet_bulb_mean
+				FROM `+pgx.Identifier{table}.Sanitize()+`
+				WHERE
+					weather_station_id = @stationID AND
+					time_stamp = date_trunc(@field, @timeRun::TIMESTAMPTZ, 'UTC');`,
+				pgx.NamedArgs{
+					"stationID": stationID,
+					"field":     granularity.field,
+					"timeRun":   timeRun,
+				},
+			).Scan(&mean)
+			if errors.Is(err, pgx.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				t.Fatalf("error in reading bucket of %s: %v", table, err)
+			}
+			mapMeans[table] = mean
+		}
+		return mapMeans
+	}
+
+	saveAndRollUp(25)
+	var mapMeans map[string]float64 = readBuckets()
+	for _, granularity := range rollupGranularities {
+		var table string = "calculations_temperature_" + granularity.suffix
+		if mean, isBucket := mapMeans[table]; !isBucket || mean != 25 {
+			t.Fatalf("%s has mean %v (bucket %t), expected 25", table, mean, isBucket)
+		}
+	}
+
+	// the recalculation deletes the calculations of the run, and a rollup
+	// before they are saved again removes its buckets
+	_, err = modelCalculation.ResetCalculationsRun(ctx, runID)
+	if err != nil {
+		t.Fatalf("error in resetting calculations of run: %v", err)
+	}
+	err = modelRollup.UpdateRollups(ctx)
+	if err != nil {
+		t.Fatalf("error in updating rollups: %v", err)
+	}
+	for table, mean := range readBuckets() {
+		t.Errorf("%s has mean %v after the reset, expected no bucket", table, mean)
+	}
+
+	// the calculations saved again are rolled up although the watermarks
+	// have moved past the run
+	saveAndRollUp(30)
+	mapMeans = readBuckets()
+	for _, granularity := range rollupGranularities {
+		var table string = "calculations_temperature_" + granularity.suffix
+		if mean, isBucket := mapMeans[table]; !isBucket || mean != 30 {
+			t.Errorf("%s has mean %v (bucket %t) after the recalculation, expected 30", table, mean, isBucket)
+		}
+	}
+}
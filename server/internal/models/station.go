@@ -0,0 +1,581 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// statuses of the health of a station
+const (
+	StationStatusHealthy  string = "healthy"
+	StationStatusDegraded string = "degraded"
+	StationStatusOffline  string = "offline"
+	StationStatusInactive string = "inactive"
+)
+
+// model struct for the station details
+type StationModel struct {
+	DB *pgxpool.Pool
+}
+
+// metadata of a weather station
+type StationDetails struct {
+	WeatherStationID  uuid.UUID `db:"weather_station_id" json:"weather_station_id"`
+	CityName          string    `db:"city_name" json:"city_name"`
+	LocalityName      string    `db:"locality_name" json:"locality_name"`
+	LocalityID        string    `db:"locality_id" json:"locality_id"`
+	Longitude         float64   `db:"longitude" json:"longitude"`
+	Latitude          float64   `db:"latitude" json:"latitude"`
+	Elevation         *float64  `db:"elevation" json:"elevation"`
+	DeviceType        string    `db:"device_type" json:"device_type"`
+	DeviceTypeInteger int       `db:"device_type_integer" json:"device_type_integer"`
+	IsActive          bool      `db:"is_active" json:"is_active"`
+}
+
+// latest readings of a station from both providers and the calculation
+// of the same run. values missing from a provider are nil.
+type StationReadingLatest struct {
+	RunID                            uuid.UUID `db:"run_id" json:"run_id"`
+	TimeStamp                        time.Time `db:"time_stamp" json:"time_stamp"`
+	WeatherUnionTemperature          *float64  `db:"weather_union_temperature" json:"weather_union_temperature"`
+	WeatherUnionHumidity             *float64  `db:"weather_union_humidity" json:"weather_union_humidity"`
+	WeatherUnionWindSpeed            *float64  `db:"weather_union_wind_speed" json:"weather_union_wind_speed"`
+	WeatherUnionWindDirection        *float64  `db:"weather_union_wind_direction" json:"weather_union_wind_direction"`
+	WeatherUnionRainIntensity        *float64  `db:"weather_union_rain_intensity" json:"weather_union_rain_intensity"`
+	WeatherUnionRainAccumulation     *float64  `db:"weather_union_rain_accumulation" json:"weather_union_rain_accumulation"`
+	OpenWeatherMapTemperature        *float64  `db:"open_weather_map_temperature" json:"open_weather_map_temperature"`
+	OpenWeatherMapFeelsLike          *float64  `db:"open_weather_map_feels_like" json:"open_weather_map_feels_like"`
+	OpenWeatherMapHumidity           *float64  `db:"open_weather_map_humidity" json:"open_weather_map_humidity"`
+	OpenWeatherMapPressure           *float64  `db:"open_weather_map_pressure" json:"open_weather_map_pressure"`
+	OpenWeatherMapDewPoint           *float64  `db:"open_weather_map_dew_point" json:"open_weather_map_dew_point"`
+	OpenWeatherMapUVIndex            *float64  `db:"open_weather_map_uv_index" json:"open_weather_map_uv_index"`
+	OpenWeatherMapClouds             *float64  `db:"open_weather_map_clouds" json:"open_weather_map_clouds"`
+	OpenWeatherMapWindSpeed          *float64  `db:"open_weather_map_wind_speed" json:"open_weather_map_wind_speed"`
+	OpenWeatherMapWeatherDescription *string   `db:"open_weather_map_weather_description" json:"open_weather_map_weather_description"`
+	TemperatureWetBulb               *float64  `db:"temperature_wet_bulb" json:"temperature_wet_bulb"`
+	TemperatureDewPoint              *float64  `db:"temperature_dew_point" json:"temperature_dew_point"`
+}
+
+// hourly aggregate of the readings of a station
+type StationSeriesPoint struct {
+	Hour                   time.Time `db:"hour" json:"hour"`
+	TemperatureWetBulbMean *float64  `db:"temperature_wet_bulb_mean" json:"temperature_wet_bulb_mean"`
+	TemperatureWetBulbMax  *float64  `db:"temperature_wet_bulb_max" json:"temperature_wet_bulb_max"`
+	TemperatureMean        *float64  `db:"temperature_mean" json:"temperature_mean"`
+	HumidityMean           *float64  `db:"humidity_mean" json:"humidity_mean"`
+}
+
+// quality control summary of a station over the last 24 hours
+type StationHealth struct {
+	NumberOfRuns                 int        `db:"number_of_runs" json:"number_of_runs"`
+	NumberOfMeasurements         int        `db:"number_of_measurements" json:"number_of_measurements"`
+	NumberOfMeasurementsComplete int        `db:"number_of_measurements_complete" json:"number_of_measurements_complete"`
+	NumberOfCalculations         int        `db:"number_of_calculations" json:"number_of_calculations"`
+	TimeLastMeasurement          *time.Time `db:"time_last_measurement" json:"time_last_measurement"`
+	Status                       string     `db:"-" json:"status"`
+}
+
+// a station near another station
+type StationNearby struct {
+	LocalityID         string   `db:"locality_id" json:"locality_id"`
+	LocalityName       string   `db:"locality_name" json:"locality_name"`
+	DistanceKilometres float64  `db:"distance_kilometres" json:"distance_kilometres"`
+	TemperatureWetBulb *float64 `db:"temperature_wet_bulb" json:"temperature_wet_bulb"`
+}
+
+// get the metadata of a station by its locality ID
+// returns pgx.ErrNoRows if there is no such station
+func (model StationModel) GetStationDetails(
+	ctx context.Context,
+	localityID string,
+) (StationDetails, error) {
+	// postgresql query string
+	var queryString string = `
+	SELECT
+		weather_station_id,
+		city_name,
+		locality_name,
+		locality_id,
+		ST_X(location::geometry) AS longitude,
+		ST_Y(location::geometry) AS latitude,
+		elevation,
+		device_type,
+		device_type_integer,
+		is_active
+	FROM weather_union_stations
+	WHERE locality_id = @localityID
+	LIMIT 1;
+	`
+
+	// named arguments for building the query string
+	var queryArguments pgx.NamedArgs = pgx.NamedArgs{
+		"localityID": localityID,
+	}
+
+	// create a 5 second timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, 5*time.Second)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// prepare the query
+	rows, err := model.DB.Query(ctxWT, queryString, queryArguments)
+	if err != nil {
+		return StationDetails{}, err
+	}
+
+	// run the query and collect the single row
+	return pgx.CollectOneRow(rows, pgx.RowToStructByName[StationDetails])
+}
+
+// get the latest readings of a station from both providers
+// returns pgx.ErrNoRows if the station has no measurements
+func (model StationModel) GetStationReadingLatest(
+	ctx context.Context,
+	weatherStationID uuid.UUID,
+) (StationReadingLatest, error) {
+	// postgresql query string
+	var queryString string = `
+	SELECT
+		mwu.run_id,
+		mwu.time_stamp,
+		mwu.temperature                 AS weather_union_temperature,
+		mwu.humidity                    AS weather_union_humidity,
+		mwu.wind_speed                  AS weather_union_wind_speed,
+		mwu.wind_direction              AS weather_union_wind_direction,
+		mwu.rain_intensity              AS weather_union_rain_intensity,
+		mwu.rain_accumulation           AS weather_union_rain_accumulation,
+		mowm.temperature                AS open_weather_map_temperature,
+		mowm.feels_like                 AS open_weather_map_feels_like,
+		mowm.humidity                   AS open_weather_map_humidity,
+		mowm.pressure                   AS open_weather_map_pressure,
+		mowm.dew_point                  AS open_weather_map_dew_point,
+		mowm.uv_index                   AS open_weather_map_uv_index,
+		mowm.clouds                     AS open_weather_map_clouds,
+		mowm.wind_speed                 AS open_weather_map_wind_speed,
+		mowm.weather_object_description AS open_weather_map_weather_description,
+		ROUND(ct.temperature_wet_bulb::NUMERIC, 3)::FLOAT
+			AS temperature_wet_bulb,
+		ROUND(ct.temperature_dew_point::NUMERIC, 3)::FLOAT
+			AS temperature_dew_point
+	FROM measurements_weather_union mwu
+	LEFT JOIN measurements_open_weather_map mowm
+	ON
+		mowm.weather_station_id = mwu.weather_station_id AND
+		mowm.run_id = mwu.run_id
+	LEFT JOIN calculations_temperature ct
+	ON ct.measurement_id_weather_union = mwu.measurement_id
+	WHERE mwu.weather_station_id = @weatherStationID
+	ORDER BY mwu.time_stamp DESC
+	LIMIT 1;
+	`
+
+	// named arguments for building the query string
+	var queryArguments pgx.NamedArgs = pgx.NamedArgs{
+		"weatherStationID": weatherStationID,
+	}
+
+	// create a 5 second timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, 5*time.Second)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// prepare the query
+	rows, err := model.DB.Query(ctxWT, queryString, queryArguments)
+	if err != nil {
+		return StationReadingLatest{}, err
+	}
+
+	// run the query and collect the single row
+	return pgx.CollectOneRow(rows, pgx.RowToStructByName[StationReadingLatest])
+}
+
+// get the hourly aggregates of the readings of a station since a time
+// hours before the watermark of the hourly rollups are read from the
+// rollup tables, as their raw rows may have been deleted
+func (model StationModel) GetStationSeriesHourly(
+	ctx context.Context,
+	weatherStationID uuid.UUID,
+	timeFrom time.Time,
+) ([]StationSeriesPoint, error) {
+	// postgresql query string
+	var queryString string = `
+	WITH watermark AS (
+		SELECT COALESCE(MIN(time_stamp_until), '-infinity'::TIMESTAMPTZ)
+			AS time_stamp_until
+		FROM rollup_watermarks
+		WHERE table_name IN (
+			'measurements_weather_union_hourly',
+			'calculations_temperature_hourly'
+		)
+	)
+	SELECT
+		mwuh.time_stamp AS hour,
+		cth.temperature_wet_bulb_mean,
+		cth.temperature_wet_bulb_max,
+		mwuh.temperature_mean,
+		mwuh.humidity_mean
+	FROM measurements_weather_union_hourly mwuh
+	LEFT JOIN calculations_temperature_hourly cth
+	ON
+		cth.weather_station_id = mwuh.weather_station_id AND
+		cth.time_stamp = mwuh.time_stamp
+	WHERE
+		mwuh.weather_station_id = @weatherStationID AND
+		mwuh.time_stamp >= date_trunc('hour', @timeFrom::TIMESTAMPTZ, 'UTC') AND
+		mwuh.time_stamp < (SELECT time_stamp_until FROM watermark)
+	UNION ALL
+	SELECT
+		date_trunc('hour', mwu.time_stamp, 'UTC') AS hour,
+		AVG(ct.temperature_wet_bulb) AS temperature_wet_bulb_mean,
+		MAX(ct.temperature_wet_bulb) AS temperature_wet_bulb_max,
+		AVG(mwu.temperature) AS temperature_mean,
+		AVG(mwu.humidity) AS humidity_mean
+	FROM measurements_weather_union mwu
+	LEFT JOIN calculations_temperature ct
+	ON ct.measurement_id_weather_union = mwu.measurement_id
+	WHERE
+		mwu.weather_station_id = @weatherStationID AND
+		mwu.time_stamp >= @timeFrom AND
+		mwu.time_stamp >= (SELECT time_stamp_until FROM watermark)
+	GROUP BY hour
+	ORDER BY hour;
+	`
+
+	// named arguments for building the query string
+	var queryArguments pgx.NamedArgs = pgx.NamedArgs{
+		"weatherStationID": weatherStationID,
+		"timeFrom":         timeFrom,
+	}
+
+	// create a 5 second timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, 5*time.Second)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// prepare the query
+	rows, err := model.DB.Query(ctxWT, queryString, queryArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	// run the query and collect rows
+	return pgx.CollectRows(rows, pgx.RowToStructByName[StationSeriesPoint])
+}
+
+// get the quality control summary of a station over the last 24 hours
+func (model StationModel) GetStationHealth(
+	ctx context.Context,
+	station StationDetails,
+) (StationHealth, error) {
+	// postgresql query string
+	var queryString string = `
+	SELECT
+		(
+			SELECT COUNT(*)
+			FROM measurement_runs
+			WHERE time_stamp >= NOW() - INTERVAL '24 hours'
+		)::INTEGER AS number_of_runs,
+		COUNT(mwu.measurement_id)::INTEGER AS number_of_measurements,
+		COUNT(mwu.measurement_id) FILTER (
+			WHERE mwu.temperature IS NOT NULL AND mwu.humidity IS NOT NULL
+		)::INTEGER AS number_of_measurements_complete,
+		COUNT(mwu.measurement_id) FILTER (
+			WHERE mwu.is_successful_for_calculation_temperature
+		)::INTEGER AS number_of_calculations,
+		MAX(mwu.time_stamp) FILTER (
+			WHERE mwu.temperature IS NOT NULL AND mwu.humidity IS NOT NULL
+		) AS time_last_measurement
+	FROM measurements_weather_union mwu
+	WHERE
+		mwu.weather_station_id = @weatherStationID AND
+		mwu.time_stamp >= NOW() - INTERVAL '24 hours';
+	`
+
+	// named arguments for building the query string
+	var queryArguments pgx.NamedArgs = pgx.NamedArgs{
+		"weatherStationID": station.WeatherStationID,
+	}
+
+	// create a 5 second timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, 5*time.Second)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// prepare the query
+	rows, err := model.DB.Query(ctxWT, queryString, queryArguments)
+	if err != nil {
+		return StationHealth{}, err
+	}
+
+	// run the query and collect the single row
+	health, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[StationHealth])
+	if err != nil {
+		return StationHealth{}, err
+	}
+
+	// status from the share of complete measurements over the runs
+	// and the time since the last complete measurement
+	var ratioComplete float64
+	if health.NumberOfRuns > 0 {
+		ratioComplete = float64(health.NumberOfMeasurementsComplete) /
+			float64(health.NumberOfRuns)
+	}
+	switch {
+	case !station.IsActive:
+		health.Status = StationStatusInactive
+	case health.TimeLastMeasurement == nil ||
+		time.Since(*health.TimeLastMeasurement) > 2*time.Hour:
+		health.Status = StationStatusOffline
+	case ratioComplete >= 0.9:
+		health.Status = StationStatusHealthy
+	default:
+		health.Status = StationStatusDegraded
+	}
+
+	return health, nil
+}
+
+// get the nearest active stations within a radius of a station with
+// their latest wet bulb temperature
+func (model StationModel) GetStationsNearby(
+	ctx context.Context,
+	weatherStationID uuid.UUID,
+	radiusKilometres float64,
+	limit int,
+) ([]StationNearby, error) {
+	// postgresql query string
+	var queryString string = `
+	SELECT
+		wus.locality_id,
+		wus.locality_name,
+		ST_Distance(wus.location, origin.location) / 1000
+			AS distance_kilometres,
+		(
+			SELECT ROUND(ct.temperature_wet_bulb::NUMERIC, 3)::FLOAT
+			FROM measurements_weather_union mwu
+			JOIN calculations_temperature ct
+			ON ct.measurement_id_weather_union = mwu.measurement_id
+			WHERE mwu.weather_station_id = wus.weather_station_id
+			ORDER BY mwu.time_stamp DESC
+			LIMIT 1
+		) AS temperature_wet_bulb
+	FROM weather_union_stations wus
+	CROSS JOIN (
+		SELECT location
+		FROM weather_union_stations
+		WHERE weather_station_id = @weatherStationID
+	) origin
+	WHERE
+		wus.weather_station_id <> @weatherStationID AND
+		wus.is_active = TRUE AND
+		ST_DWithin(wus.location, origin.location, @radiusMetres)
+	ORDER BY wus.location <-> origin.location
+	LIMIT @limit;
+	`
+
+	// named arguments for building the query string
+	var queryArguments pgx.NamedArgs = pgx.NamedArgs{
+		"weatherStationID": weatherStationID,
+		"radiusMetres":     radiusKilometres * 1000,
+		"limit":            limit,
+	}
+
+	// create a 5 second timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, 5*time.Second)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// prepare the query
+	rows, err := model.DB.Query(ctxWT, queryString, queryArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	// run the query and collect rows
+	return pgx.CollectRows(rows, pgx.RowToStructByName[StationNearby])
+}
+
+// filters of the station list of the admin pages
+type StationFilter struct {
+	// part of the city name, locality name or locality ID, any if empty
+	Search string
+	// active or inactive stations only, any if nil
+	IsActive *bool
+}
+
+// get a page of the stations matching the filter, by city and locality,
+// with the number of matching stations
+func (model StationModel) GetStations(
+	ctx context.Context,
+	filter StationFilter,
+	limit int,
+	offset int,
+) ([]StationDetails, int, error) {
+	// postgresql query strings of the page and the count
+	// the search is a case insensitive substring match
+	var queryStringWhere string = `
+	WHERE
+		(
+			@search = '' OR
+			city_name ILIKE '%' || @search || '%' OR
+			locality_name ILIKE '%' || @search || '%' OR
+			locality_id ILIKE '%' || @search || '%'
+		) AND
+		(@isActive::BOOLEAN IS NULL OR is_active = @isActive)
+	`
+	var queryString string = `
+	SELECT
+		weather_station_id,
+		city_name,
+		locality_name,
+		locality_id,
+		ST_X(location::geometry) AS longitude,
+		ST_Y(location::geometry) AS latitude,
+		elevation,
+		device_type,
+		device_type_integer,
+		is_active
+	FROM weather_union_stations
+	` + queryStringWhere + `
+	ORDER BY city_name, locality_name, locality_id
+	LIMIT @limit
+	OFFSET @offset;
+	`
+	var queryStringCount string = `
+	SELECT COUNT(*)::INTEGER
+	FROM weather_union_stations
+	` + queryStringWhere + `;`
+
+	// named arguments for building the query strings
+	// the wildcards of LIKE in the search are matched literally
+	var queryArguments pgx.NamedArgs = pgx.NamedArgs{
+		"search": strings.NewReplacer(
+			`\`, `\\`,
+			`%`, `\%`,
+			`_`, `\_`,
+		).Replace(filter.Search),
+		"isActive": filter.IsActive,
+		"limit":    limit,
+		"offset":   offset,
+	}
+
+	// create a 5 second timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, 5*time.Second)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// number of matching stations
+	var numberOfStations int
+	err := model.DB.QueryRow(ctxWT, queryStringCount, queryArguments).Scan(&numberOfStations)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error in counting stations in postgresql: %w", err)
+	}
+
+	// prepare the query
+	rows, err := model.DB.Query(ctxWT, queryString, queryArguments)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// run the query and collect rows
+	sliceStations, err := pgx.CollectRows(rows, pgx.RowToStructByName[StationDetails])
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return sliceStations, numberOfStations, nil
+}
+
+// update the editable metadata of a station, found by its locality ID:
+// city and locality names, location, elevation and whether it is active
+// returns pgx.ErrNoRows if there is no such station
+func (model StationModel) UpdateStation(
+	ctx context.Context,
+	station StationDetails,
+) error {
+	// postgresql query string
+	var queryString string = `
+	UPDATE weather_union_stations
+	SET
+		city_name = @cityName,
+		locality_name = @localityName,
+		location = ST_SetSRID(
+			ST_MakePoint(@longitude, @latitude),
+			4326
+		)::geography,
+		elevation = @elevation,
+		is_active = @isActive
+	WHERE locality_id = @localityID;
+	`
+
+	// named arguments for building the query string
+	var queryArguments pgx.NamedArgs = pgx.NamedArgs{
+		"cityName":     station.CityName,
+		"localityName": station.LocalityName,
+		"longitude":    station.Longitude,
+		"latitude":     station.Latitude,
+		"elevation":    station.Elevation,
+		"isActive":     station.IsActive,
+		"localityID":   station.LocalityID,
+	}
+
+	// create a 5 second timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, 5*time.Second)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// executing the query string with the named arguments
+	commandTag, err := model.DB.Exec(ctxWT, queryString, queryArguments)
+	if err != nil {
+		return fmt.Errorf("error in updating station in postgresql: %w", err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// activate or deactivate a station, found by its locality ID
+// only active stations are fetched by the cron
+// returns pgx.ErrNoRows if there is no such station
+func (model StationModel) SetStationActive(
+	ctx context.Context,
+	localityID string,
+	isActive bool,
+) error {
+	// postgresql query string
+	var queryString string = `
+	UPDATE weather_union_stations
+	SET is_active = @isActive
+	WHERE locality_id = @localityID;
+	`
+
+	// named arguments for building the query string
+	var queryArguments pgx.NamedArgs = pgx.NamedArgs{
+		"isActive":   isActive,
+		"localityID": localityID,
+	}
+
+	// create a 5 second timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, 5*time.Second)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// executing the query string with the named arguments
+	commandTag, err := model.DB.Exec(ctxWT, queryString, queryArguments)
+	if err != nil {
+		return fmt.Errorf("error in updating station activity in postgresql: %w", err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
@@ -16,15 +16,19 @@ import (
 // model struct for weather union
 type WeatherUnionModel struct {
 	DB *pgxpool.Pool
+	// http client of the API calls, http.DefaultClient if nil
+	Client *http.Client
 }
 
 // type to hold a "measurement" from weather union
 // see the schema structure for the table "measurement_weather_union"
 // in the PostgreSQL migration files.
 type WeatherUnionMeasurement struct {
-	MeasurementID                  uuid.UUID `json:"measurement_id"`
-	WeatherStationID               uuid.UUID `json:"weather_station_id"`
-	RunID                          uuid.UUID `json:"run_id"`
+	MeasurementID    uuid.UUID `json:"measurement_id"`
+	WeatherStationID uuid.UUID `json:"weather_station_id"`
+	RunID            uuid.UUID `json:"run_id"`
+	// time stamp of the run, the partition key of the measurements
+	TimeStampRun                   time.Time `json:"time_stamp_run"`
 	WeatherUnionAPIReponseLocality           // embedded struct
 }
 
@@ -60,6 +64,7 @@ type WeatherUnionStation struct {
 
 // get weather data from locality (weather union)
 func (model WeatherUnionModel) CallAPIWeatherUnionLocality(
+	ctx context.Context,
 	APIBaseURL string,
 	APIKey string,
 	station *WeatherUnionStation,
@@ -92,7 +97,12 @@ func (model WeatherUnionModel) CallAPIWeatherUnionLocality(
 	// initialize new GET request
 	//
 	// new request
-	request, err := http.NewRequest(http.MethodGet, URLString, nil)
+	request, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		URLString,
+		nil,
+	)
 	if err != nil {
 		return WeatherUnionMeasurement{}, err
 	}
@@ -101,10 +111,8 @@ func (model WeatherUnionModel) CallAPIWeatherUnionLocality(
 		"X-Zomato-Api-Key",
 		APIKey,
 	)
-	// initialize new http client
-	var client http.Client = http.Client{}
 	// carry out get request
-	response, err := client.Do(request)
+	response, err := httpClient(model.Client).Do(request)
 	if err != nil {
 		return WeatherUnionMeasurement{}, err
 	}
@@ -162,6 +170,7 @@ func (model WeatherUnionModel) SaveMeasurementsWeatherUnion(
 			measurement.MeasurementID,
 			measurement.WeatherStationID,
 			measurement.RunID,
+			measurement.TimeStampRun,
 			utilities.DereferenceOrNil(measurement.Message),
 			utilities.DereferenceOrNil(measurement.DeviceType),
 			utilities.DereferenceOrNil(measurement.LocalityWeatherData.Temperature),
@@ -186,6 +195,7 @@ func (model WeatherUnionModel) SaveMeasurementsWeatherUnion(
 			"measurement_id",
 			"weather_station_id",
 			"run_id",
+			"time_stamp_run",
 			"message",
 			"device_type",
 			"temperature",
@@ -0,0 +1,275 @@
+package schema
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kelaaditya/zomato-weather-union/server/migrations"
+)
+
+// usage of the migrate subcommand
+const usage string = `usage: migrate <command>
+
+commands:
+  up [N]       apply all pending migrations, or the next N
+  down [N]     revert the last migration, or the last N
+  status       list the migrations and whether they are applied
+  version      print the current schema version
+  force V      set the schema version to V without migrating (clears dirty)`
+
+// create a migrate instance over the embedded migration files
+func newMigrate(databaseURL string) (*migrate.Migrate, error) {
+	// embedded migration files
+	sourceDriver, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return nil, fmt.Errorf("error in reading embedded migrations: %w", err)
+	}
+
+	// the pgx/v5 database driver is registered under the pgx5 scheme
+	var databaseURLMigrate string = databaseURL
+	for _, scheme := range []string{"postgres://", "postgresql://"} {
+		if strings.HasPrefix(databaseURL, scheme) {
+			databaseURLMigrate = "pgx5://" + strings.TrimPrefix(databaseURL, scheme)
+		}
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", sourceDriver, databaseURLMigrate)
+	if err != nil {
+		return nil, fmt.Errorf("error in connecting to postgresql for migrations: %w", err)
+	}
+
+	return m, nil
+}
+
+// versions and names of the embedded migrations in order
+func migrationsEmbedded() ([]uint, map[uint]string, error) {
+	sourceDriver, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return nil, nil, fmt.Errorf("error in reading embedded migrations: %w", err)
+	}
+	defer sourceDriver.Close()
+
+	var versions []uint
+	var names map[uint]string = make(map[uint]string)
+
+	version, err := sourceDriver.First()
+	for err == nil {
+		versions = append(versions, version)
+		// name of the migration from its up file
+		reader, identifier, errRead := sourceDriver.ReadUp(version)
+		if errRead == nil {
+			reader.Close()
+			names[version] = identifier
+		}
+		version, err = sourceDriver.Next(version)
+	}
+	// the source reports the end of the migrations as not existing
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, fmt.Errorf("error in listing embedded migrations: %w", err)
+	}
+
+	return versions, names, nil
+}
+
+// version of the latest embedded migration, the version the binary expects
+func VersionLatest() (uint, error) {
+	versions, _, err := migrationsEmbedded()
+	if err != nil {
+		return 0, err
+	}
+	if len(versions) == 0 {
+		return 0, errors.New("no embedded migrations")
+	}
+	return versions[len(versions)-1], nil
+}
+
+// command of the migrate subcommand and its optional count or version,
+// checked before connecting to the database
+func parseArguments(args []string) (string, int, error) {
+	if len(args) == 0 {
+		return "", 0, errors.New(usage)
+	}
+
+	// commands and whether they take a number, which force requires
+	var isNumberTaken bool
+	switch args[0] {
+	case "up", "down", "force":
+		isNumberTaken = true
+	case "status", "version":
+	default:
+		return "", 0, fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+	if len(args) > 2 || (len(args) > 1 && !isNumberTaken) {
+		return "", 0, fmt.Errorf("too many arguments\n%s", usage)
+	}
+	if args[0] == "force" && len(args) < 2 {
+		return "", 0, fmt.Errorf("missing version\n%s", usage)
+	}
+
+	// optional count or version argument
+	var number int
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 0 {
+			return "", 0, fmt.Errorf("invalid argument %q\n%s", args[1], usage)
+		}
+		number = n
+	}
+
+	return args[0], number, nil
+}
+
+// run the migrate subcommand with its arguments, writing its output to w
+func Run(args []string, databaseURL string, w io.Writer) error {
+	command, number, err := parseArguments(args)
+	if err != nil {
+		return err
+	}
+
+	m, err := newMigrate(databaseURL)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	switch command {
+	case "up":
+		if number > 0 {
+			err = m.Steps(number)
+		} else {
+			err = m.Up()
+		}
+		if errors.Is(err, migrate.ErrNoChange) {
+			fmt.Fprintln(w, "no pending migrations")
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error in applying migrations: %w", err)
+		}
+		return printVersion(m, w)
+	case "down":
+		if number == 0 {
+			number = 1
+		}
+		err = m.Steps(-number)
+		if err != nil {
+			return fmt.Errorf("error in reverting migrations: %w", err)
+		}
+		return printVersion(m, w)
+	case "status":
+		return printStatus(m, w)
+	case "version":
+		return printVersion(m, w)
+	case "force":
+		err = m.Force(number)
+		if err != nil {
+			return fmt.Errorf("error in forcing the schema version: %w", err)
+		}
+		return printVersion(m, w)
+	}
+	return nil
+}
+
+// print the current schema version
+func printVersion(m *migrate.Migrate, w io.Writer) error {
+	version, isDirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		fmt.Fprintln(w, "version: none (no migrations applied)")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error in reading the schema version: %w", err)
+	}
+	if isDirty {
+		fmt.Fprintf(w, "version: %d (dirty)\n", version)
+		return nil
+	}
+	fmt.Fprintf(w, "version: %d\n", version)
+	return nil
+}
+
+// print every embedded migration and whether it is applied
+func printStatus(m *migrate.Migrate, w io.Writer) error {
+	versions, names, err := migrationsEmbedded()
+	if err != nil {
+		return err
+	}
+
+	versionCurrent, isDirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return fmt.Errorf("error in reading the schema version: %w", err)
+	}
+	var isNone bool = errors.Is(err, migrate.ErrNilVersion)
+
+	for _, version := range versions {
+		var state string
+		switch {
+		case isNone || version > versionCurrent:
+			state = "pending"
+		case version == versionCurrent && isDirty:
+			state = "dirty"
+		default:
+			state = "applied"
+		}
+		fmt.Fprintf(w, "%06d  %-8s  %s\n", version, state, names[version])
+	}
+	return nil
+}
+
+// check that the database schema is at the version of the latest
+// embedded migration and not dirty
+func CheckVersion(ctx context.Context, db *pgxpool.Pool) error {
+	versionLatest, err := VersionLatest()
+	if err != nil {
+		return err
+	}
+
+	// create a 5 second timeout context
+	ctxWT, cancel := context.WithTimeout(ctx, 5*time.Second)
+	// defer cancellation of the timeout
+	defer cancel()
+
+	// golang-migrate keeps the version in a single row
+	var version int64
+	var isDirty bool
+	err = db.QueryRow(
+		ctxWT,
+		"SELECT version, dirty FROM schema_migrations LIMIT 1;",
+	).Scan(&version, &isDirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("schema has no migrations applied, expected version %d", versionLatest)
+	}
+	if err != nil {
+		return fmt.Errorf("error in reading the schema version from postgresql: %w", err)
+	}
+
+	return compareVersion(version, isDirty, versionLatest)
+}
+
+// check the version of the schema against the version of the latest
+// embedded migration
+func compareVersion(version int64, isDirty bool, versionLatest uint) error {
+	if isDirty {
+		return fmt.Errorf("schema version %d is dirty, fix it and run migrate force", version)
+	}
+	if uint(version) != versionLatest {
+		return fmt.Errorf(
+			"schema version %d does not match the expected version %d, run migrate up",
+			version,
+			versionLatest,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+// the arguments of the migrate subcommand are checked before connecting to
+// the database
+func TestParseArguments(t *testing.T) {
+	var sliceTests = []struct {
+		name    string
+		args    []string
+		command string
+		number  int
+		// part of the error, none if empty
+		errorPart string
+	}{
+		{"up", []string{"up"}, "up", 0, ""},
+		{"up N", []string{"up", "2"}, "up", 2, ""},
+		{"down", []string{"down"}, "down", 0, ""},
+		{"down N", []string{"down", "3"}, "down", 3, ""},
+		{"force V", []string{"force", "12"}, "force", 12, ""},
+		{"force 0", []string{"force", "0"}, "force", 0, ""},
+		{"status", []string{"status"}, "status", 0, ""},
+		{"version", []string{"version"}, "version", 0, ""},
+		{"no command", nil, "", 0, "usage: migrate"},
+		{"unknown command", []string{"sideways"}, "", 0, `unknown command "sideways"`},
+		{"force without V", []string{"force"}, "", 0, "missing version"},
+		{"N not a number", []string{"up", "all"}, "", 0, `invalid argument "all"`},
+		{"N negative", []string{"down", "-1"}, "", 0, `invalid argument "-1"`},
+		{"V not a number", []string{"force", "v12"}, "", 0, `invalid argument "v12"`},
+		{"N of status", []string{"status", "1"}, "", 0, "too many arguments"},
+		{"two numbers", []string{"up", "1", "2"}, "", 0, "too many arguments"},
+	}
+	for _, test := range sliceTests {
+		t.Run(test.name, func(t *testing.T) {
+			command, number, err := parseArguments(test.args)
+			if test.errorPart != "" {
+				if err == nil || !strings.Contains(err.Error(), test.errorPart) {
+					t.Fatalf("error is %v, expected one with %q", err, test.errorPart)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error in parsing %v: %v", test.args, err)
+			}
+			if command != test.command || number != test.number {
+				t.Errorf("parsed %q %d, expected %q %d", command, number, test.command, test.number)
+			}
+		})
+	}
+}
+
+// the schema must be at the latest embedded version and not dirty
+func TestCompareVersion(t *testing.T) {
+	versionLatest, err := VersionLatest()
+	if err != nil {
+		t.Fatalf("error in reading the embedded migrations: %v", err)
+	}
+
+	var sliceTests = []struct {
+		name    string
+		version int64
+		isDirty bool
+		// part of the error, none if empty
+		errorPart string
+	}{
+		{"latest", int64(versionLatest), false, ""},
+		{"dirty", int64(versionLatest), true, "is dirty"},
+		{"dirty and behind", int64(versionLatest) - 1, true, "is dirty"},
+		{"behind", int64(versionLatest) - 1, false, "does not match"},
+		{"ahead", int64(versionLatest) + 1, false, "does not match"},
+	}
+	for _, test := range sliceTests {
+		t.Run(test.name, func(t *testing.T) {
+			err := compareVersion(test.version, test.isDirty, versionLatest)
+			if test.errorPart == "" && err != nil {
+				t.Errorf("error of the latest version: %v", err)
+			}
+			if test.errorPart != "" && (err == nil || !strings.Contains(err.Error(), test.errorPart)) {
+				t.Errorf("error is %v, expected one with %q", err, test.errorPart)
+			}
+		})
+	}
+}
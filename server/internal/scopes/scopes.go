@@ -0,0 +1,16 @@
+package scopes
+
+// scopes of the API keys
+const (
+	// runs, calculations, cities and contours
+	Read string = "read"
+	// on-demand exports of the datasets
+	Export string = "export"
+)
+
+// all the scopes a key can be issued with
+var SliceAll []string = []string{Read, Export}
+
+// the scopes the requests without a key can be given
+// exports are heavy, so they always need a key
+var SliceAnonymous []string = []string{Read}
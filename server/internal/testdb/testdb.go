@@ -0,0 +1,85 @@
+// Package testdb opens the postgresql database of the integration tests,
+// given by TEST_DATABASE_URL. The tests are skipped without it.
+package testdb
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kelaaditya/zomato-weather-union/server/internal/schema"
+)
+
+// key of the advisory lock held by a test for its use of the database, so
+// that the tests of the packages (run in parallel) take turns
+const keyLock int64 = 7_230_114
+
+// tables emptied before every test, the rollup watermarks included
+const queryTruncate string = `
+TRUNCATE
+	api_keys_usage_daily,
+	api_keys,
+	calculations_temperature_hourly,
+	calculations_temperature_daily,
+	calculations_temperature,
+	measurements_weather_union_hourly,
+	measurements_weather_union_daily,
+	measurements_weather_union,
+	measurements_open_weather_map_hourly,
+	measurements_open_weather_map_daily,
+	measurements_open_weather_map,
+	measurement_runs,
+	rollup_watermarks,
+	city_boundaries,
+	weather_union_stations
+CASCADE;
+`
+
+// connection pool to the migrated and emptied test database
+// the test is skipped if TEST_DATABASE_URL is not set. the database is
+// locked for the test until it ends, when the pool is closed.
+func New(t testing.TB) *pgxpool.Pool {
+	t.Helper()
+
+	var databaseURL string = os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	var ctx context.Context = context.Background()
+
+	// lock the database for the test, on a connection of its own
+	connectionLock, err := pgx.Connect(ctx, databaseURL)
+	if err != nil {
+		t.Fatalf("error in connecting to the test database: %v", err)
+	}
+	_, err = connectionLock.Exec(ctx, "SELECT pg_advisory_lock($1);", keyLock)
+	if err != nil {
+		connectionLock.Close(ctx)
+		t.Fatalf("error in locking the test database: %v", err)
+	}
+	t.Cleanup(func() {
+		// the lock is released with the connection
+		connectionLock.Close(ctx)
+	})
+
+	err = schema.Run([]string{"up"}, databaseURL, io.Discard)
+	if err != nil {
+		t.Fatalf("error in migrating the test database: %v", err)
+	}
+
+	DB, err := pgxpool.New(ctx, databaseURL)
+	if err != nil {
+		t.Fatalf("error in connecting to the test database: %v", err)
+	}
+	t.Cleanup(DB.Close)
+
+	_, err = DB.Exec(ctx, queryTruncate)
+	if err != nil {
+		t.Fatalf("error in emptying the test database: %v", err)
+	}
+
+	return DB
+}
@@ -0,0 +1,123 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// name of the instrumentation scope of the spans created here
+const nameTracer string = "github.com/kelaaditya/zomato-weather-union/server"
+
+// exporters of the spans
+const (
+	// no tracing
+	ExporterNone string = "none"
+	// OTLP over HTTP, to the endpoint in OTEL_EXPORTER_OTLP_ENDPOINT or
+	// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT (default localhost:4318)
+	ExporterOTLP string = "otlp"
+	// pretty printed JSON on stdout, for development
+	ExporterStdout string = "stdout"
+)
+
+// attribute keys of the spans
+const (
+	AttributeRunID      attribute.Key = "weather_union.run.id"
+	AttributeLocalityID attribute.Key = "weather_union.station.locality_id"
+	AttributeProvider   attribute.Key = "weather_union.provider"
+	AttributeMethod     attribute.Key = "weather_union.calculation.method"
+)
+
+// set up the global tracer provider with the exporter and the sampling
+// ratio (0 to 1, of the new traces)
+// the returned function flushes the spans left and stops the provider.
+// with ExporterNone the global provider stays a no-op.
+func Initialize(
+	ctx context.Context,
+	exporterName string,
+	serviceName string,
+	samplingRatio float64,
+) (func(ctx context.Context) error, error) {
+	var shutdownNone = func(ctx context.Context) error { return nil }
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch exporterName {
+	case ExporterNone, "":
+		return shutdownNone, nil
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(
+			stdouttrace.WithWriter(os.Stdout),
+			stdouttrace.WithPrettyPrint(),
+		)
+	default:
+		return shutdownNone, fmt.Errorf("unknown tracing exporter %q", exporterName)
+	}
+	if err != nil {
+		return shutdownNone, fmt.Errorf("error in creating the tracing exporter: %w", err)
+	}
+
+	// name of the service, the binary
+	resourceService, err := resource.Merge(
+		resource.Default(),
+		resource.NewSchemaless(
+			attribute.String("service.name", serviceName),
+		),
+	)
+	if err != nil {
+		return shutdownNone, fmt.Errorf("error in creating the tracing resource: %w", err)
+	}
+
+	var provider *sdktrace.TracerProvider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resourceService),
+		// children follow the decision of their parent
+		sdktrace.WithSampler(
+			sdktrace.ParentBased(sdktrace.TraceIDRatioBased(samplingRatio)),
+		),
+	)
+	otel.SetTracerProvider(provider)
+	// W3C trace context, for traces that start at a proxy
+	otel.SetTextMapPropagator(
+		propagation.NewCompositeTextMapPropagator(
+			propagation.TraceContext{},
+			propagation.Baggage{},
+		),
+	)
+
+	return provider.Shutdown, nil
+}
+
+// start a span of the application, a child of the span in the context
+func Start(
+	ctx context.Context,
+	name string,
+	attributes ...attribute.KeyValue,
+) (context.Context, trace.Span) {
+	return otel.Tracer(nameTracer).Start(
+		ctx,
+		name,
+		trace.WithAttributes(attributes...),
+	)
+}
+
+// record an error on a span and mark the span as failed
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
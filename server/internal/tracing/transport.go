@@ -0,0 +1,55 @@
+package tracing
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// http transport that creates a client span for every request
+// only the method, host, path and status are recorded: the query strings
+// of the weather APIs carry the API keys, so the URL is never recorded
+// whole. the trace context is not sent to the APIs.
+type Transport struct {
+	// underlying transport, http.DefaultTransport if nil
+	Base http.RoundTripper
+	// provider of the API, added to the spans
+	Provider string
+}
+
+func (transport Transport) RoundTrip(request *http.Request) (*http.Response, error) {
+	var base http.RoundTripper = transport.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	ctx, span := otel.Tracer(nameTracer).Start(
+		request.Context(),
+		"HTTP "+request.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			AttributeProvider.String(transport.Provider),
+			attribute.String("http.request.method", request.Method),
+			attribute.String("server.address", request.URL.Host),
+			attribute.String("url.path", request.URL.Path),
+		),
+	)
+	defer span.End()
+
+	response, err := base.RoundTrip(request.WithContext(ctx))
+	if err != nil {
+		RecordError(span, err)
+		return response, err
+	}
+
+	span.SetAttributes(
+		attribute.Int("http.response.status_code", response.StatusCode),
+	)
+	if response.StatusCode >= 400 {
+		span.SetStatus(codes.Error, http.StatusText(response.StatusCode))
+	}
+	return response, nil
+}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS city_boundaries;
\ No newline at end of file
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS city_boundaries(
+    city_name TEXT PRIMARY KEY NOT NULL,
+    boundary geography(MULTIPOLYGON, 4326) NOT NULL,
+    source TEXT NOT NULL,
+    time_stamp TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
\ No newline at end of file
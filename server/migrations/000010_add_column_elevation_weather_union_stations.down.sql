@@ -0,0 +1 @@
+ALTER TABLE weather_union_stations DROP COLUMN IF EXISTS elevation;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE weather_union_stations ADD COLUMN IF NOT EXISTS elevation FLOAT;
\ No newline at end of file
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS rollup_watermarks;
+DROP TABLE IF EXISTS measurements_weather_union_hourly;
+DROP TABLE IF EXISTS measurements_weather_union_daily;
+DROP TABLE IF EXISTS measurements_open_weather_map_hourly;
+DROP TABLE IF EXISTS measurements_open_weather_map_daily;
+DROP TABLE IF EXISTS calculations_temperature_hourly;
+DROP TABLE IF EXISTS calculations_temperature_daily;
\ No newline at end of file
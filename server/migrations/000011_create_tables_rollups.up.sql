@@ -0,0 +1,107 @@
+CREATE TABLE IF NOT EXISTS measurements_weather_union_hourly(
+    weather_station_id UUID NOT NULL REFERENCES weather_union_stations(weather_station_id),
+    time_stamp TIMESTAMPTZ NOT NULL,
+    number_of_measurements INTEGER NOT NULL,
+    temperature_min FLOAT,
+    temperature_max FLOAT,
+    temperature_mean FLOAT,
+    humidity_min FLOAT,
+    humidity_max FLOAT,
+    humidity_mean FLOAT,
+    wind_speed_min FLOAT,
+    wind_speed_max FLOAT,
+    wind_speed_mean FLOAT,
+    rain_intensity_min FLOAT,
+    rain_intensity_max FLOAT,
+    rain_intensity_mean FLOAT,
+    PRIMARY KEY (weather_station_id, time_stamp)
+);
+
+CREATE TABLE IF NOT EXISTS measurements_weather_union_daily(
+    weather_station_id UUID NOT NULL REFERENCES weather_union_stations(weather_station_id),
+    time_stamp TIMESTAMPTZ NOT NULL,
+    number_of_measurements INTEGER NOT NULL,
+    temperature_min FLOAT,
+    temperature_max FLOAT,
+    temperature_mean FLOAT,
+    humidity_min FLOAT,
+    humidity_max FLOAT,
+    humidity_mean FLOAT,
+    wind_speed_min FLOAT,
+    wind_speed_max FLOAT,
+    wind_speed_mean FLOAT,
+    rain_intensity_min FLOAT,
+    rain_intensity_max FLOAT,
+    rain_intensity_mean FLOAT,
+    PRIMARY KEY (weather_station_id, time_stamp)
+);
+
+CREATE TABLE IF NOT EXISTS measurements_open_weather_map_hourly(
+    weather_station_id UUID NOT NULL REFERENCES weather_union_stations(weather_station_id),
+    time_stamp TIMESTAMPTZ NOT NULL,
+    number_of_measurements INTEGER NOT NULL,
+    temperature_min FLOAT,
+    temperature_max FLOAT,
+    temperature_mean FLOAT,
+    humidity_min FLOAT,
+    humidity_max FLOAT,
+    humidity_mean FLOAT,
+    pressure_min FLOAT,
+    pressure_max FLOAT,
+    pressure_mean FLOAT,
+    wind_speed_min FLOAT,
+    wind_speed_max FLOAT,
+    wind_speed_mean FLOAT,
+    PRIMARY KEY (weather_station_id, time_stamp)
+);
+
+CREATE TABLE IF NOT EXISTS measurements_open_weather_map_daily(
+    weather_station_id UUID NOT NULL REFERENCES weather_union_stations(weather_station_id),
+    time_stamp TIMESTAMPTZ NOT NULL,
+    number_of_measurements INTEGER NOT NULL,
+    temperature_min FLOAT,
+    temperature_max FLOAT,
+    temperature_mean FLOAT,
+    humidity_min FLOAT,
+    humidity_max FLOAT,
+    humidity_mean FLOAT,
+    pressure_min FLOAT,
+    pressure_max FLOAT,
+    pressure_mean FLOAT,
+    wind_speed_min FLOAT,
+    wind_speed_max FLOAT,
+    wind_speed_mean FLOAT,
+    PRIMARY KEY (weather_station_id, time_stamp)
+);
+
+CREATE TABLE IF NOT EXISTS calculations_temperature_hourly(
+    weather_station_id UUID NOT NULL REFERENCES weather_union_stations(weather_station_id),
+    time_stamp TIMESTAMPTZ NOT NULL,
+    number_of_calculations INTEGER NOT NULL,
+    temperature_wet_bulb_min FLOAT,
+    temperature_wet_bulb_max FLOAT,
+    temperature_wet_bulb_mean FLOAT,
+    temperature_dew_point_min FLOAT,
+    temperature_dew_point_max FLOAT,
+    temperature_dew_point_mean FLOAT,
+    PRIMARY KEY (weather_station_id, time_stamp)
+);
+
+CREATE TABLE IF NOT EXISTS calculations_temperature_daily(
+    weather_station_id UUID NOT NULL REFERENCES weather_union_stations(weather_station_id),
+    time_stamp TIMESTAMPTZ NOT NULL,
+    number_of_calculations INTEGER NOT NULL,
+    temperature_wet_bulb_min FLOAT,
+    temperature_wet_bulb_max FLOAT,
+    temperature_wet_bulb_mean FLOAT,
+    temperature_dew_point_min FLOAT,
+    temperature_dew_point_max FLOAT,
+    temperature_dew_point_mean FLOAT,
+    PRIMARY KEY (weather_station_id, time_stamp)
+);
+
+CREATE TABLE IF NOT EXISTS rollup_watermarks(
+    table_name TEXT PRIMARY KEY NOT NULL,
+    time_stamp_until TIMESTAMPTZ NOT NULL,
+    time_stamp TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
\ No newline at end of file
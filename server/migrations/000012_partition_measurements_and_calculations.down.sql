@@ -0,0 +1,145 @@
+DROP FUNCTION IF EXISTS create_partition_month(TEXT, TEXT, TIMESTAMPTZ, TIMESTAMPTZ);
+
+DROP INDEX IF EXISTS measurement_runs_time_stamp_idx;
+
+-- move the partitioned tables (with their partitions) out of the way
+CREATE SCHEMA partitioned;
+ALTER TABLE measurements_weather_union SET SCHEMA partitioned;
+ALTER TABLE measurements_open_weather_map SET SCHEMA partitioned;
+ALTER TABLE calculations_temperature SET SCHEMA partitioned;
+
+CREATE TABLE measurements_weather_union(
+    measurement_id UUID PRIMARY KEY NOT NULL,
+    weather_station_id UUID NOT NULL REFERENCES weather_union_stations(weather_station_id),
+    run_id UUID NOT NULL REFERENCES measurement_runs(run_id),
+    message TEXT,
+    device_type INTEGER,
+    temperature FLOAT,
+    humidity FLOAT,
+    wind_speed FLOAT,
+    wind_direction FLOAT,
+    rain_intensity FLOAT,
+    rain_accumulation FLOAT,
+    is_processed_for_calculation_temperature BOOLEAN NOT NULL DEFAULT FALSE,
+    is_successful_for_calculation_temperature BOOLEAN NOT NULL DEFAULT FALSE,
+    time_stamp TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (measurement_id, weather_station_id),
+    UNIQUE (weather_station_id, run_id)
+);
+
+CREATE TABLE measurements_open_weather_map(
+    measurement_id UUID PRIMARY KEY NOT NULL,
+    weather_station_id UUID NOT NULL REFERENCES weather_union_stations(weather_station_id),
+    run_id UUID NOT NULL REFERENCES measurement_runs(run_id),
+    time_zone TEXT,
+    time_zone_offset INTEGER,
+    time_current BIGINT,
+    time_sunrise BIGINT,
+    time_sunset BIGINT,
+    temperature FLOAT,
+    feels_like FLOAT,
+    pressure FLOAT,
+    humidity FLOAT,
+    dew_point FLOAT,
+    uv_index FLOAT,
+    clouds FLOAT,
+    visibility BIGINT,
+    wind_speed FLOAT,
+    wind_direction FLOAT,
+    wind_gust FLOAT,
+    weather_object_id INTEGER,
+    weather_object_main TEXT,
+    weather_object_description TEXT,
+    weather_object_icon TEXT,
+    is_processed_for_calculation_temperature BOOLEAN NOT NULL DEFAULT FALSE,
+    is_successful_for_calculation_temperature BOOLEAN NOT NULL DEFAULT FALSE,
+    time_stamp TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (weather_station_id, run_id)
+);
+
+CREATE TABLE calculations_temperature(
+    calculation_id UUID PRIMARY KEY NOT NULL,
+    measurement_id_weather_union UUID NOT NULL REFERENCES measurements_weather_union(measurement_id),
+    method TEXT NOT NULL CHECK (method IN (
+        'metpy-with-open-weather-map'
+    )),
+    temperature_dew_point FLOAT NOT NULL,
+    temperature_wet_bulb FLOAT NOT NULL,
+    time_stamp TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- copy the rows back, those of the attached partitions (the default
+-- partitions included) and those of the partitions archived by the cron,
+-- which are dropped once copied. the calculations of an archived
+-- measurement are archived with it, so the measurements of both are copied
+-- before the calculations for their foreign key. the columns of the
+-- unpartitioned tables are copied, time_stamp_run is left behind.
+DO $$
+DECLARE
+    name_table TEXT;
+    name_archived TEXT;
+    columns TEXT;
+BEGIN
+    FOREACH name_table IN ARRAY ARRAY[
+        'measurements_weather_union',
+        'measurements_open_weather_map',
+        'calculations_temperature'
+    ] LOOP
+        SELECT string_agg(quote_ident(attname), ', ' ORDER BY attnum)
+        FROM pg_attribute
+        WHERE
+            attrelid = quote_ident(name_table)::regclass AND
+            attnum > 0 AND
+            NOT attisdropped
+        INTO columns;
+
+        EXECUTE format(
+            'INSERT INTO %I (%s) SELECT %s FROM partitioned.%I',
+            name_table,
+            columns,
+            columns,
+            name_table
+        );
+
+        FOR name_archived IN
+            SELECT c.relname
+            FROM pg_class c
+            JOIN pg_namespace n
+            ON n.oid = c.relnamespace
+            WHERE
+                n.nspname = 'archive' AND
+                c.relkind = 'r' AND
+                c.relname ~ ('^' || name_table || '_y[0-9]{4}m[0-9]{2}$')
+            ORDER BY c.relname
+        LOOP
+            EXECUTE format(
+                'INSERT INTO %I (%s) SELECT %s FROM archive.%I',
+                name_table,
+                columns,
+                columns,
+                name_archived
+            );
+            EXECUTE format('DROP TABLE archive.%I', name_archived);
+        END LOOP;
+    END LOOP;
+END $$;
+
+DROP SCHEMA partitioned CASCADE;
+-- the archive schema of the cron, if nothing else is left in it
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1
+        FROM pg_class c
+        JOIN pg_namespace n
+        ON n.oid = c.relnamespace
+        WHERE n.nspname = 'archive'
+    ) THEN
+        DROP SCHEMA IF EXISTS archive;
+    END IF;
+END $$;
+
+-- the triggers were dropped with the partitioned tables
+DROP FUNCTION IF EXISTS check_measurement_calculations();
+DROP FUNCTION IF EXISTS check_calculation_measurement();
+DROP FUNCTION IF EXISTS check_measurement_time_stamp_run();
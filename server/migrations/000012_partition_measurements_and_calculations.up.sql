@@ -0,0 +1,280 @@
+-- a foreign key on measurement_id alone cannot reference a partitioned table,
+-- whose unique constraints have to include the partition key
+ALTER TABLE calculations_temperature
+DROP CONSTRAINT IF EXISTS calculations_temperature_measurement_id_weather_union_fkey;
+
+-- move the existing tables (with their indexes) out of the way
+CREATE SCHEMA unpartitioned;
+ALTER TABLE measurements_weather_union SET SCHEMA unpartitioned;
+ALTER TABLE measurements_open_weather_map SET SCHEMA unpartitioned;
+ALTER TABLE calculations_temperature SET SCHEMA unpartitioned;
+
+-- the tables are partitioned by the time stamp of the run of their rows,
+-- time_stamp_run: the measurements of a run and their calculations are in
+-- the partitions of the same month, whenever they were saved. time_stamp
+-- keeps the time each row was saved.
+CREATE TABLE measurements_weather_union(
+    measurement_id UUID NOT NULL,
+    weather_station_id UUID NOT NULL REFERENCES weather_union_stations(weather_station_id),
+    run_id UUID NOT NULL REFERENCES measurement_runs(run_id),
+    message TEXT,
+    device_type INTEGER,
+    temperature FLOAT,
+    humidity FLOAT,
+    wind_speed FLOAT,
+    wind_direction FLOAT,
+    rain_intensity FLOAT,
+    rain_accumulation FLOAT,
+    is_processed_for_calculation_temperature BOOLEAN NOT NULL DEFAULT FALSE,
+    is_successful_for_calculation_temperature BOOLEAN NOT NULL DEFAULT FALSE,
+    time_stamp TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    time_stamp_run TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (measurement_id, time_stamp_run),
+    UNIQUE (weather_station_id, run_id, time_stamp_run)
+) PARTITION BY RANGE (time_stamp_run);
+
+CREATE TABLE measurements_open_weather_map(
+    measurement_id UUID NOT NULL,
+    weather_station_id UUID NOT NULL REFERENCES weather_union_stations(weather_station_id),
+    run_id UUID NOT NULL REFERENCES measurement_runs(run_id),
+    time_zone TEXT,
+    time_zone_offset INTEGER,
+    time_current BIGINT,
+    time_sunrise BIGINT,
+    time_sunset BIGINT,
+    temperature FLOAT,
+    feels_like FLOAT,
+    pressure FLOAT,
+    humidity FLOAT,
+    dew_point FLOAT,
+    uv_index FLOAT,
+    clouds FLOAT,
+    visibility BIGINT,
+    wind_speed FLOAT,
+    wind_direction FLOAT,
+    wind_gust FLOAT,
+    weather_object_id INTEGER,
+    weather_object_main TEXT,
+    weather_object_description TEXT,
+    weather_object_icon TEXT,
+    is_processed_for_calculation_temperature BOOLEAN NOT NULL DEFAULT FALSE,
+    is_successful_for_calculation_temperature BOOLEAN NOT NULL DEFAULT FALSE,
+    time_stamp TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    time_stamp_run TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (measurement_id, time_stamp_run),
+    UNIQUE (weather_station_id, run_id, time_stamp_run)
+) PARTITION BY RANGE (time_stamp_run);
+
+CREATE TABLE calculations_temperature(
+    calculation_id UUID NOT NULL,
+    measurement_id_weather_union UUID NOT NULL,
+    method TEXT NOT NULL CHECK (method IN (
+        'metpy-with-open-weather-map'
+    )),
+    temperature_dew_point FLOAT NOT NULL,
+    temperature_wet_bulb FLOAT NOT NULL,
+    time_stamp TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    -- time stamp of the run of the measurement
+    time_stamp_run TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (calculation_id, time_stamp_run)
+) PARTITION BY RANGE (time_stamp_run);
+
+-- monthly partitions (UTC) from the oldest run until three months ahead,
+-- named <table>_yYYYYmMM. later partitions are created by the cron.
+DO $$
+DECLARE
+    name_table TEXT;
+    time_oldest TIMESTAMP;
+    time_month TIMESTAMP;
+BEGIN
+    SELECT MIN(time_stamp) AT TIME ZONE 'UTC'
+    FROM measurement_runs
+    INTO time_oldest;
+
+    FOREACH name_table IN ARRAY ARRAY[
+        'measurements_weather_union',
+        'measurements_open_weather_map',
+        'calculations_temperature'
+    ] LOOP
+        FOR time_month IN
+            SELECT generate_series(
+                date_trunc('month', COALESCE(time_oldest, NOW() AT TIME ZONE 'UTC')),
+                date_trunc('month', NOW() AT TIME ZONE 'UTC') + INTERVAL '3 months',
+                INTERVAL '1 month'
+            )
+        LOOP
+            EXECUTE format(
+                'CREATE TABLE %I PARTITION OF %I FOR VALUES FROM (%L) TO (%L)',
+                name_table || '_' || to_char(time_month, '"y"YYYY"m"MM'),
+                name_table,
+                to_char(time_month, 'YYYY-MM-DD') || ' 00:00:00+00',
+                to_char(time_month + INTERVAL '1 month', 'YYYY-MM-DD') || ' 00:00:00+00'
+            );
+        END LOOP;
+    END LOOP;
+END $$;
+
+-- default partitions, so that rows outside of the monthly partitions are
+-- kept instead of failing the insert. create_partition_month moves them
+-- into the monthly partition once it is created.
+CREATE TABLE measurements_weather_union_default
+PARTITION OF measurements_weather_union DEFAULT;
+CREATE TABLE measurements_open_weather_map_default
+PARTITION OF measurements_open_weather_map DEFAULT;
+CREATE TABLE calculations_temperature_default
+PARTITION OF calculations_temperature DEFAULT;
+
+-- copy the rows into the partitions, with the time stamp of their run
+INSERT INTO measurements_weather_union
+SELECT mwu.*, mr.time_stamp
+FROM unpartitioned.measurements_weather_union mwu
+JOIN measurement_runs mr
+ON mr.run_id = mwu.run_id;
+INSERT INTO measurements_open_weather_map
+SELECT mowm.*, mr.time_stamp
+FROM unpartitioned.measurements_open_weather_map mowm
+JOIN measurement_runs mr
+ON mr.run_id = mowm.run_id;
+INSERT INTO calculations_temperature
+SELECT ct.*, mr.time_stamp
+FROM unpartitioned.calculations_temperature ct
+JOIN unpartitioned.measurements_weather_union mwu
+ON mwu.measurement_id = ct.measurement_id_weather_union
+JOIN measurement_runs mr
+ON mr.run_id = mwu.run_id;
+
+DROP SCHEMA unpartitioned CASCADE;
+
+-- indexes for the time series, latest run and unprocessed queries
+CREATE INDEX measurements_weather_union_weather_station_id_time_stamp_idx
+ON measurements_weather_union(weather_station_id, time_stamp DESC);
+CREATE INDEX measurements_weather_union_run_id_idx
+ON measurements_weather_union(run_id);
+CREATE INDEX measurements_weather_union_unprocessed_idx
+ON measurements_weather_union(time_stamp)
+WHERE is_processed_for_calculation_temperature = FALSE;
+CREATE INDEX measurements_open_weather_map_run_id_idx
+ON measurements_open_weather_map(run_id);
+CREATE INDEX calculations_temperature_measurement_id_weather_union_idx
+ON calculations_temperature(measurement_id_weather_union);
+CREATE INDEX measurement_runs_time_stamp_idx
+ON measurement_runs(time_stamp DESC);
+
+-- refuse measurements not stamped with the time of their run
+CREATE FUNCTION check_measurement_time_stamp_run() RETURNS TRIGGER AS $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1
+        FROM measurement_runs
+        WHERE
+            run_id = NEW.run_id AND
+            time_stamp = NEW.time_stamp_run
+    ) THEN
+        RAISE EXCEPTION 'time_stamp_run % of % is not the time of run %',
+            NEW.time_stamp_run, TG_TABLE_NAME, NEW.run_id
+        USING ERRCODE = 'check_violation';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER measurements_weather_union_time_stamp_run_check
+BEFORE INSERT OR UPDATE OF run_id, time_stamp_run ON measurements_weather_union
+FOR EACH ROW EXECUTE FUNCTION check_measurement_time_stamp_run();
+CREATE TRIGGER measurements_open_weather_map_time_stamp_run_check
+BEFORE INSERT OR UPDATE OF run_id, time_stamp_run ON measurements_open_weather_map
+FOR EACH ROW EXECUTE FUNCTION check_measurement_time_stamp_run();
+
+-- the foreign key of the calculations to the measurements of weather union,
+-- which cannot reference a partitioned table on measurement_id alone. the
+-- calculation has the time stamp of the run of its measurement, so that
+-- both are in the partitions of the same month.
+CREATE FUNCTION check_calculation_measurement() RETURNS TRIGGER AS $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1
+        FROM measurements_weather_union
+        WHERE
+            measurement_id = NEW.measurement_id_weather_union AND
+            time_stamp_run = NEW.time_stamp_run
+    ) THEN
+        RAISE EXCEPTION 'measurement % of calculation % does not exist at %',
+            NEW.measurement_id_weather_union, NEW.calculation_id, NEW.time_stamp_run
+        USING ERRCODE = 'foreign_key_violation';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER calculations_temperature_measurement_check
+BEFORE INSERT OR UPDATE OF measurement_id_weather_union, time_stamp_run ON calculations_temperature
+FOR EACH ROW EXECUTE FUNCTION check_calculation_measurement();
+
+-- the calculations of a measurement are deleted before the measurement,
+-- except for the rows moved between partitions by create_partition_month
+CREATE FUNCTION check_measurement_calculations() RETURNS TRIGGER AS $$
+BEGIN
+    IF current_setting('weather_union.is_moving_partition', TRUE) = 'on' THEN
+        RETURN OLD;
+    END IF;
+    IF EXISTS (
+        SELECT 1
+        FROM calculations_temperature
+        WHERE
+            measurement_id_weather_union = OLD.measurement_id AND
+            time_stamp_run = OLD.time_stamp_run
+    ) THEN
+        RAISE EXCEPTION 'measurement % still has calculations',
+            OLD.measurement_id
+        USING ERRCODE = 'foreign_key_violation';
+    END IF;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER measurements_weather_union_calculations_check
+BEFORE DELETE ON measurements_weather_union
+FOR EACH ROW EXECUTE FUNCTION check_measurement_calculations();
+
+-- create the monthly partition of a table, moving the rows of the month
+-- out of the default partition. nothing is done if the partition exists.
+CREATE FUNCTION create_partition_month(
+    name_table TEXT,
+    name_partition TEXT,
+    time_from TIMESTAMPTZ,
+    time_to TIMESTAMPTZ
+) RETURNS VOID AS $$
+BEGIN
+    IF to_regclass(quote_ident(name_partition)) IS NOT NULL THEN
+        RETURN;
+    END IF;
+
+    -- the partition is filled before it is attached, so that the rows of
+    -- the month are not checked again on their way in. the rows leaving the
+    -- default partition keep their calculations.
+    PERFORM set_config('weather_union.is_moving_partition', 'on', TRUE);
+    EXECUTE format(
+        'CREATE TABLE %I (LIKE %I INCLUDING DEFAULTS INCLUDING CONSTRAINTS)',
+        name_partition,
+        name_table
+    );
+    EXECUTE format(
+        'WITH moved AS ('
+            'DELETE FROM ONLY %I WHERE time_stamp_run >= %L AND time_stamp_run < %L '
+            'RETURNING *'
+        ') INSERT INTO %I SELECT * FROM moved',
+        name_table || '_default',
+        time_from,
+        time_to,
+        name_partition
+    );
+    EXECUTE format(
+        'ALTER TABLE %I ATTACH PARTITION %I FOR VALUES FROM (%L) TO (%L)',
+        name_table,
+        name_partition,
+        time_from,
+        time_to
+    );
+    PERFORM set_config('weather_union.is_moving_partition', 'off', TRUE);
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS api_keys_usage_daily;
+DROP TABLE IF EXISTS api_keys;
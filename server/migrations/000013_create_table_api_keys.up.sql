@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS api_keys(
+    api_key_id UUID PRIMARY KEY NOT NULL DEFAULT uuid_generate_v4(),
+    name TEXT NOT NULL,
+    -- first characters of the key, shown to tell the keys apart
+    prefix TEXT NOT NULL UNIQUE,
+    -- sha-256 of the key, the key itself is never stored
+    hash_key BYTEA NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    -- token bucket of the key
+    requests_per_second FLOAT NOT NULL,
+    burst INTEGER NOT NULL,
+    -- requests per day (UTC), 0 for no quota
+    quota_daily INTEGER NOT NULL DEFAULT 0,
+    time_stamp_created TIMESTAMPTZ NOT NULL DEFAULT now(),
+    time_stamp_revoked TIMESTAMPTZ,
+    time_stamp_last_used TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS api_keys_usage_daily(
+    api_key_id UUID NOT NULL REFERENCES api_keys(api_key_id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    number_of_requests INTEGER NOT NULL,
+    PRIMARY KEY (api_key_id, day)
+);
@@ -0,0 +1,10 @@
+// Package migrations embeds the SQL migration files of the database so the
+// binaries can migrate the schema without the golang-migrate CLI.
+package migrations
+
+import "embed"
+
+// up and down migration files, 0000NN_name.up.sql and 0000NN_name.down.sql
+//
+//go:embed *.sql
+var FS embed.FS
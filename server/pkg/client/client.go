@@ -0,0 +1,256 @@
+// Package client is a typed client of the JSON API of the web server, as
+// described by the OpenAPI document in api/openapi.json.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// formats of the export
+const (
+	FormatCSV     string = "csv"
+	FormatJSON    string = "json"
+	FormatParquet string = "parquet"
+)
+
+// longest error message read from a response
+const sizeErrorMessageMax int64 = 4096
+
+// client of the API of a web server
+type Client struct {
+	// URL of the web server, without the /api path
+	BaseURL string
+	// API key sent as a bearer token, the requests are anonymous if empty
+	APIKey string
+	// http.DefaultClient if nil
+	HTTPClient *http.Client
+}
+
+// create a client of the web server at the base URL
+func New(baseURL string, APIKey string) *Client {
+	return &Client{
+		BaseURL: baseURL,
+		APIKey:  APIKey,
+	}
+}
+
+// selection of a run: by ID, or the latest run at or before a time, or
+// the latest run if both are zero
+type RunQuery struct {
+	RunID uuid.UUID
+	Time  time.Time
+}
+
+// options of the contours
+// the levels default to the risk thresholds of the server and the cell
+// size to 0.5 km if zero
+type ContourQuery struct {
+	RunQuery
+	Levels             []float64
+	CellSizeKilometres float64
+}
+
+// filters and format of an export
+// all cities and the whole time range if zero, in CSV if no format
+type ExportQuery struct {
+	CityName string
+	TimeFrom time.Time
+	TimeTo   time.Time
+	Format   string
+}
+
+// error response of the API
+type Error struct {
+	StatusCode int
+	Message    string
+	// time to wait before the next request, on a 429
+	RetryAfter time.Duration
+}
+
+func (err *Error) Error() string {
+	return fmt.Sprintf("api error %d: %s", err.StatusCode, err.Message)
+}
+
+// runs with calculations in a time range of at most 31 days, oldest first
+func (client *Client) Runs(
+	ctx context.Context,
+	timeFrom time.Time,
+	timeTo time.Time,
+) ([]Run, error) {
+	var values url.Values = url.Values{}
+	values.Set("from", timeFrom.Format(time.RFC3339))
+	values.Set("to", timeTo.Format(time.RFC3339))
+
+	var sliceRuns []Run
+	err := client.getJSON(ctx, "/api/v1/runs", values, &sliceRuns)
+	return sliceRuns, err
+}
+
+// calculations with station details of a run
+func (client *Client) Calculations(
+	ctx context.Context,
+	query RunQuery,
+) (Calculations, error) {
+	var calculations Calculations
+	err := client.getJSON(ctx, "/api/v1/calculations", query.values(), &calculations)
+	return calculations, err
+}
+
+// statistics of all cities in a run
+func (client *Client) Cities(ctx context.Context, query RunQuery) (Cities, error) {
+	var cities Cities
+	err := client.getJSON(ctx, "/api/v1/cities", query.values(), &cities)
+	return cities, err
+}
+
+// statistics of a single city in a run, the only element of Cities
+func (client *Client) City(
+	ctx context.Context,
+	cityName string,
+	query RunQuery,
+) (Cities, error) {
+	var cities Cities
+	err := client.getJSON(
+		ctx,
+		"/api/v1/cities/"+url.PathEscape(cityName),
+		query.values(),
+		&cities,
+	)
+	return cities, err
+}
+
+// wet bulb temperature contours of a run
+func (client *Client) Contours(
+	ctx context.Context,
+	query ContourQuery,
+) (FeatureCollection, error) {
+	var values url.Values = query.RunQuery.values()
+	if len(query.Levels) > 0 {
+		var parts []string
+		for _, level := range query.Levels {
+			parts = append(parts, strconv.FormatFloat(level, 'f', -1, 64))
+		}
+		values.Set("levels", strings.Join(parts, ","))
+	}
+	if query.CellSizeKilometres != 0 {
+		values.Set("cell_size_km", strconv.FormatFloat(query.CellSizeKilometres, 'f', -1, 64))
+	}
+
+	var featureCollection FeatureCollection
+	err := client.getJSON(ctx, "/api/v1/contours", values, &featureCollection)
+	return featureCollection, err
+}
+
+// stream of an export of the wet bulb temperature dataset
+// the caller closes the stream, which is read as it is exported
+func (client *Client) Export(
+	ctx context.Context,
+	query ExportQuery,
+) (io.ReadCloser, error) {
+	var values url.Values = url.Values{}
+	if query.CityName != "" {
+		values.Set("city", query.CityName)
+	}
+	if !query.TimeFrom.IsZero() {
+		values.Set("from", query.TimeFrom.Format(time.RFC3339))
+	}
+	if !query.TimeTo.IsZero() {
+		values.Set("to", query.TimeTo.Format(time.RFC3339))
+	}
+	if query.Format != "" {
+		values.Set("format", query.Format)
+	}
+
+	response, err := client.get(ctx, "/api/v1/export", values)
+	if err != nil {
+		return nil, err
+	}
+	return response.Body, nil
+}
+
+// query parameters of the run selection
+func (query RunQuery) values() url.Values {
+	var values url.Values = url.Values{}
+	switch {
+	case query.RunID != uuid.Nil:
+		values.Set("run_id", query.RunID.String())
+	case !query.Time.IsZero():
+		values.Set("time", query.Time.Format(time.RFC3339))
+	}
+	return values
+}
+
+// send a GET request and decode the JSON response into target
+func (client *Client) getJSON(
+	ctx context.Context,
+	path string,
+	values url.Values,
+	target any,
+) error {
+	response, err := client.get(ctx, path, values)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	err = json.NewDecoder(response.Body).Decode(target)
+	if err != nil {
+		return fmt.Errorf("error in decoding the response of %s: %w", path, err)
+	}
+	return nil
+}
+
+// send a GET request, a response that is not a 200 is returned as an
+// *Error
+func (client *Client) get(
+	ctx context.Context,
+	path string,
+	values url.Values,
+) (*http.Response, error) {
+	var URL string = strings.TrimSuffix(client.BaseURL, "/") + path
+	if len(values) > 0 {
+		URL += "?" + values.Encode()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error in creating the request of %s: %w", path, err)
+	}
+	if client.APIKey != "" {
+		request.Header.Set("Authorization", "Bearer "+client.APIKey)
+	}
+
+	var HTTPClient *http.Client = client.HTTPClient
+	if HTTPClient == nil {
+		HTTPClient = http.DefaultClient
+	}
+	response, err := HTTPClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("error in requesting %s: %w", path, err)
+	}
+	if response.StatusCode == http.StatusOK {
+		return response, nil
+	}
+	defer response.Body.Close()
+
+	// the errors are plain text
+	message, _ := io.ReadAll(io.LimitReader(response.Body, sizeErrorMessageMax))
+	var errorResponse *Error = &Error{
+		StatusCode: response.StatusCode,
+		Message:    strings.TrimSpace(string(message)),
+	}
+	if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil {
+		errorResponse.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return nil, errorResponse
+}
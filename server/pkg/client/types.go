@@ -0,0 +1,102 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// the types follow the schemas of the OpenAPI document (api/openapi.json)
+
+// a measurement run with calculations
+type Run struct {
+	RunID                 uuid.UUID `json:"run_id"`
+	TimeStamp             time.Time `json:"time_stamp"`
+	NumberOfCalculations  int       `json:"number_of_calculations"`
+	TemperatureWetBulbMax float64   `json:"temperature_wet_bulb_max"`
+}
+
+// wet bulb temperature of a station in a run
+// the coordinates are sent as text, as they are stored
+type CalculationTemperatureWithStationDetails struct {
+	RunID                uuid.UUID `json:"run_id"`
+	CityName             string    `json:"city_name"`
+	LocalityID           string    `json:"locality_id"`
+	LocalityName         string    `json:"locality_name"`
+	Latitude             string    `json:"latitude"`
+	Longitude            string    `json:"longitude"`
+	TemperatureDewPoint  float64   `json:"temperature_dew_point"`
+	TemperatureWetBulb   float64   `json:"temperature_wet_bulb"`
+	CalculationTimeStamp time.Time `json:"time_stamp_calculation"`
+}
+
+// a run with its calculations
+type Calculations struct {
+	Run          Run                                        `json:"run"`
+	Calculations []CalculationTemperatureWithStationDetails `json:"calculations"`
+}
+
+// statistics of the cities in a run
+type Cities struct {
+	Run Run `json:"run"`
+	// run of about the same time the day before, for the trend
+	RunPrevious *Run `json:"run_previous"`
+	// wet bulb temperatures of the risk levels
+	Thresholds []float64 `json:"thresholds"`
+	Cities     []City    `json:"cities"`
+}
+
+// wet bulb temperature statistics of a city with its hottest localities
+type City struct {
+	CityName                         string                       `json:"city_name"`
+	NumberOfStations                 int                          `json:"number_of_stations"`
+	TemperatureWetBulbMin            float64                      `json:"temperature_wet_bulb_min"`
+	TemperatureWetBulbMedian         float64                      `json:"temperature_wet_bulb_median"`
+	TemperatureWetBulbMax            float64                      `json:"temperature_wet_bulb_max"`
+	TemperatureWetBulbMedianPrevious *float64                     `json:"temperature_wet_bulb_median_previous"`
+	TemperatureWetBulbMaxPrevious    *float64                     `json:"temperature_wet_bulb_max_previous"`
+	StationsAboveThresholds          []CityStationsAboveThreshold `json:"stations_above_thresholds"`
+	// change of the median since the previous run
+	TrendMedian       *float64       `json:"trend_median"`
+	LocalitiesHottest []CityLocality `json:"localities_hottest"`
+}
+
+// number of stations of a city at or above a wet bulb temperature
+type CityStationsAboveThreshold struct {
+	Threshold        float64 `json:"threshold"`
+	NumberOfStations int     `json:"number_of_stations"`
+}
+
+// a locality of a city with its wet bulb temperature in a run
+type CityLocality struct {
+	CityName           string  `json:"city_name"`
+	LocalityName       string  `json:"locality_name"`
+	LocalityID         string  `json:"locality_id"`
+	TemperatureWetBulb float64 `json:"temperature_wet_bulb"`
+}
+
+// GeoJSON feature collection of the wet bulb temperature contours
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// GeoJSON feature of a zone above a level in a city
+// the geometry (a MultiPolygon) is left as raw JSON for a GeoJSON library
+type Feature struct {
+	Type       string            `json:"type"`
+	Geometry   json.RawMessage   `json:"geometry"`
+	Properties ContourProperties `json:"properties"`
+}
+
+// properties of a contour feature
+type ContourProperties struct {
+	RunID    uuid.UUID `json:"run_id"`
+	CityName string    `json:"city_name"`
+	// wet bulb temperature of the zone in celsius
+	Level                float64 `json:"level"`
+	AreaSquareKilometres float64 `json:"area_square_kilometres"`
+	// whether the zone was clipped to the city boundary
+	IsClipped bool `json:"is_clipped"`
+}
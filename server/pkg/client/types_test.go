@@ -0,0 +1,172 @@
+package client
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kelaaditya/zomato-weather-union/server/api"
+)
+
+// types of the schemas of the OpenAPI document
+var mapSchemaTypes map[string]reflect.Type = map[string]reflect.Type{
+	"Run": reflect.TypeFor[Run](),
+	"CalculationTemperatureWithStationDetails": reflect.TypeFor[CalculationTemperatureWithStationDetails](),
+	"Calculations":               reflect.TypeFor[Calculations](),
+	"Cities":                     reflect.TypeFor[Cities](),
+	"City":                       reflect.TypeFor[City](),
+	"CityStationsAboveThreshold": reflect.TypeFor[CityStationsAboveThreshold](),
+	"CityLocality":               reflect.TypeFor[CityLocality](),
+	"FeatureCollection":          reflect.TypeFor[FeatureCollection](),
+	"Feature":                    reflect.TypeFor[Feature](),
+	"ContourProperties":          reflect.TypeFor[ContourProperties](),
+}
+
+// schemas without a type, the export is returned to the caller as is
+var sliceSchemasUntyped []string = []string{"Error", "RowTemperatureWetBulb"}
+
+// the types follow the schemas of the document: every schema has a type
+// (or is listed as untyped), and the types have the properties of their
+// schema with matching Go types
+func TestTypesMatchOpenAPI(t *testing.T) {
+	var document struct {
+		Components struct {
+			Schemas map[string]map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	err := json.Unmarshal(api.OpenAPI, &document)
+	if err != nil {
+		t.Fatalf("error in decoding the openapi document: %v", err)
+	}
+
+	for name, schema := range document.Components.Schemas {
+		if slices.Contains(sliceSchemasUntyped, name) {
+			continue
+		}
+		typeSchema, ok := mapSchemaTypes[name]
+		if !ok {
+			t.Errorf("schema %s has no type in pkg/client", name)
+			continue
+		}
+		checkStruct(t, name, schema, typeSchema)
+	}
+	for name := range mapSchemaTypes {
+		if _, ok := document.Components.Schemas[name]; !ok {
+			t.Errorf("type of schema %s but the schema is not in the document", name)
+		}
+	}
+}
+
+// check the fields of a struct against the properties of an object schema
+func checkStruct(t *testing.T, name string, schema map[string]any, typeStruct reflect.Type) {
+	t.Helper()
+
+	properties, _ := schema["properties"].(map[string]any)
+
+	// fields by their JSON name
+	var mapFields map[string]reflect.StructField = make(map[string]reflect.StructField)
+	for index := range typeStruct.NumField() {
+		var field reflect.StructField = typeStruct.Field(index)
+		nameJSON, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if nameJSON == "" || nameJSON == "-" {
+			continue
+		}
+		mapFields[nameJSON] = field
+	}
+
+	for property, schemaProperty := range properties {
+		field, ok := mapFields[property]
+		if !ok {
+			t.Errorf("%s: property %s has no field in %s", name, property, typeStruct.Name())
+			continue
+		}
+		checkType(t, name+"."+property, schemaProperty.(map[string]any), field.Type)
+	}
+	for nameJSON := range mapFields {
+		if _, ok := properties[nameJSON]; !ok {
+			t.Errorf("%s: field %s of %s is not in the schema", name, nameJSON, typeStruct.Name())
+		}
+	}
+}
+
+// check a Go type against the schema of a property
+func checkType(t *testing.T, path string, schema map[string]any, typeField reflect.Type) {
+	t.Helper()
+
+	// a nullable property is a pointer, as {"oneOf": [{"$ref"}, {"type":
+	// "null"}]} or {"type": [T, "null"]}
+	var schemaValue map[string]any = schema
+	var isNullable bool
+	if sliceOneOf, ok := schema["oneOf"].([]any); ok {
+		for _, schemaOne := range sliceOneOf {
+			if schemaOne.(map[string]any)["type"] == "null" {
+				isNullable = true
+			} else {
+				schemaValue = schemaOne.(map[string]any)
+			}
+		}
+	}
+	var typeSchema any = schemaValue["type"]
+	if sliceTypes, ok := typeSchema.([]any); ok {
+		for _, typeOne := range sliceTypes {
+			if typeOne == "null" {
+				isNullable = true
+			} else {
+				typeSchema = typeOne
+			}
+		}
+	}
+	if isNullable {
+		if typeField.Kind() != reflect.Pointer {
+			t.Errorf("%s: nullable but %s is not a pointer", path, typeField)
+			return
+		}
+		typeField = typeField.Elem()
+	}
+
+	if ref, ok := schemaValue["$ref"].(string); ok {
+		var nameRef string = strings.TrimPrefix(ref, "#/components/schemas/")
+		if mapSchemaTypes[nameRef] != typeField {
+			t.Errorf("%s: %s is not the type of schema %s", path, typeField, nameRef)
+		}
+		return
+	}
+	if _, ok := schemaValue["const"].(string); ok {
+		typeSchema = "string"
+	}
+
+	var isMatching bool
+	switch typeSchema {
+	case "string":
+		switch schemaValue["format"] {
+		case "uuid":
+			isMatching = typeField == reflect.TypeFor[uuid.UUID]()
+		case "date-time":
+			isMatching = typeField == reflect.TypeFor[time.Time]()
+		default:
+			isMatching = typeField.Kind() == reflect.String
+		}
+	case "number":
+		isMatching = typeField.Kind() == reflect.Float64
+	case "integer":
+		isMatching = typeField.Kind() == reflect.Int
+	case "boolean":
+		isMatching = typeField.Kind() == reflect.Bool
+	case "object":
+		// an object without properties is left as raw JSON
+		isMatching = typeField == reflect.TypeFor[json.RawMessage]()
+	case "array":
+		if typeField.Kind() != reflect.Slice {
+			break
+		}
+		checkType(t, path+"[]", schemaValue["items"].(map[string]any), typeField.Elem())
+		return
+	}
+	if !isMatching {
+		t.Errorf("%s: %s does not match the schema %v", path, typeField, schema)
+	}
+}
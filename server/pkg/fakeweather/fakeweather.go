@@ -0,0 +1,327 @@
+// Package fakeweather is a local stand-in for the Weather Union and
+// OpenWeatherMap APIs, for development and integration tests.
+// It serves get_locality_weather_data and onecall with values of a seeded
+// generator or of a scenario, and injects errors, 429s, latency and null
+// fields on demand.
+package fakeweather
+
+import (
+	"encoding/json"
+	"hash"
+	"hash/fnv"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"sync"
+	"time"
+)
+
+// endpoints of the APIs, served under any base path
+const (
+	EndpointWeatherUnion   string = "get_locality_weather_data"
+	EndpointOpenWeatherMap string = "onecall"
+)
+
+// faults injected into the responses, as shares of the requests from 0
+// to 1
+type Faults struct {
+	// answered with a 500
+	RateErrors float64
+	// answered with a 429
+	RateTooManyRequests float64
+	// answered with null measurements
+	RateNullFields float64
+	// delay of every response
+	Latency time.Duration
+	// random delay of up to this added to the latency
+	LatencyJitter time.Duration
+}
+
+// settings of the fake APIs
+type Options struct {
+	// key expected in the X-Zomato-Api-Key header, any key but an empty
+	// one is accepted if empty
+	APIKeyWeatherUnion string
+	// key expected in the appid query parameter, any key but an empty one
+	// is accepted if empty
+	APIKeyOpenWeatherMap string
+	// seed of the generator, the same seed gives the same responses to the
+	// same sequence of requests
+	Seed uint64
+	// responses of given stations, generated if nil
+	Scenario *Scenario
+	Faults   Faults
+	// clock of the time stamps, time.Now if nil
+	Now func() time.Time
+}
+
+// fake APIs, an http.Handler
+type Server struct {
+	mutex   sync.Mutex
+	options Options
+	// number of requests by endpoint and station, to vary the values of
+	// successive requests
+	mapCounts map[string]uint64
+	// number of requests by endpoint
+	mapRequests map[string]int
+}
+
+// create the fake APIs
+func New(options Options) *Server {
+	if options.Now == nil {
+		options.Now = time.Now
+	}
+	return &Server{
+		options:     options,
+		mapCounts:   make(map[string]uint64),
+		mapRequests: make(map[string]int),
+	}
+}
+
+// start the fake APIs on a local port, for tests
+// both URL bases of the cron are the URL of the httptest server, which the
+// caller closes.
+func NewTestServer(options Options) (*Server, *httptest.Server) {
+	var server *Server = New(options)
+	return server, httptest.NewServer(server)
+}
+
+// change the faults of the next requests
+func (server *Server) SetFaults(faults Faults) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.options.Faults = faults
+}
+
+// change the scenario of the next requests, nil for generated values
+func (server *Server) SetScenario(scenario *Scenario) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.options.Scenario = scenario
+}
+
+// number of requests received by an endpoint, rejected ones included
+func (server *Server) NumberOfRequests(endpoint string) int {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	return server.mapRequests[endpoint]
+}
+
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var endpoint string = path.Base(r.URL.Path)
+	if endpoint != EndpointWeatherUnion && endpoint != EndpointOpenWeatherMap {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(
+			w,
+			http.StatusText(http.StatusMethodNotAllowed),
+			http.StatusMethodNotAllowed,
+		)
+		return
+	}
+
+	server.mutex.Lock()
+	server.mapRequests[endpoint]++
+	var options Options = server.options
+	server.mutex.Unlock()
+
+	switch endpoint {
+	case EndpointWeatherUnion:
+		server.serveWeatherUnion(w, r, options)
+	case EndpointOpenWeatherMap:
+		server.serveOpenWeatherMap(w, r, options)
+	}
+}
+
+// respond as get_locality_weather_data of weather union
+func (server *Server) serveWeatherUnion(w http.ResponseWriter, r *http.Request, options Options) {
+	var key string = r.Header.Get("X-Zomato-Api-Key")
+	if key == "" || (options.APIKeyWeatherUnion != "" && key != options.APIKeyWeatherUnion) {
+		writeJSON(w, http.StatusForbidden, map[string]any{
+			"status":  "403",
+			"message": "Invalid API Key",
+		})
+		return
+	}
+	var localityID string = r.URL.Query().Get("locality_id")
+	if localityID == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"status":  "400",
+			"message": "locality_id is required",
+		})
+		return
+	}
+
+	var response *Response
+	if options.Scenario != nil {
+		response = options.Scenario.weatherUnion(localityID)
+	}
+	random, base := server.random(EndpointWeatherUnion, localityID, options.Seed)
+	statusCode, isNull := server.fault(r, random, options.Faults, response)
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		writeJSON(w, statusCode, map[string]any{
+			"status":  "429",
+			"message": "Rate limit exceeded",
+		})
+		return
+	case statusCode != http.StatusOK:
+		writeJSON(w, statusCode, map[string]any{
+			"status":  "500",
+			"message": "Internal Server Error",
+		})
+		return
+	}
+
+	var data map[string]any = generateWeatherUnion(random, base)
+	if response != nil {
+		response.apply(data)
+	}
+	if isNull {
+		for _, field := range fieldsWeatherUnion {
+			data[field] = nil
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status":                "200",
+		"message":               "",
+		"device_type":           1,
+		"locality_weather_data": data,
+	})
+}
+
+// respond as onecall of open weather map
+func (server *Server) serveOpenWeatherMap(w http.ResponseWriter, r *http.Request, options Options) {
+	var query url.Values = r.URL.Query()
+	var key string = query.Get("appid")
+	if key == "" || (options.APIKeyOpenWeatherMap != "" && key != options.APIKeyOpenWeatherMap) {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{
+			"cod": 401,
+			"message": "Invalid API key. Please see " +
+				"https://openweathermap.org/faq#error401 for more info.",
+		})
+		return
+	}
+	latitude, errLatitude := parseCoordinate(query.Get("lat"), 90)
+	longitude, errLongitude := parseCoordinate(query.Get("lon"), 180)
+	if errLatitude != nil || errLongitude != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"cod":     "400",
+			"message": "wrong latitude or longitude",
+		})
+		return
+	}
+
+	var response *Response
+	if options.Scenario != nil {
+		response = options.Scenario.openWeatherMap(query.Get("lat"), query.Get("lon"))
+	}
+	random, base := server.random(
+		EndpointOpenWeatherMap,
+		query.Get("lat")+","+query.Get("lon"),
+		options.Seed,
+	)
+	statusCode, isNull := server.fault(r, random, options.Faults, response)
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		writeJSON(w, statusCode, map[string]any{
+			"cod": 429,
+			"message": "Your account is temporary blocked due to exceeding of " +
+				"requests limitation of your subscription type.",
+		})
+		return
+	case statusCode != http.StatusOK:
+		writeJSON(w, statusCode, map[string]any{
+			"cod":     "500",
+			"message": "Internal error",
+		})
+		return
+	}
+
+	var current map[string]any = generateOpenWeatherMap(random, base, options.Now())
+	if response != nil {
+		response.apply(current)
+	}
+	if isNull {
+		for _, field := range fieldsOpenWeatherMap {
+			current[field] = nil
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"lat":             latitude,
+		"lon":             longitude,
+		"timezone":        "Asia/Kolkata",
+		"timezone_offset": 19800,
+		"current":         current,
+	})
+}
+
+// generators of a request: one for the values of this request and one for
+// the values of the station, which are the same for every request
+func (server *Server) random(endpoint string, key string, seed uint64) (*rand.Rand, *rand.Rand) {
+	var hasher hash.Hash64 = fnv.New64a()
+	hasher.Write([]byte(endpoint + "/" + key))
+	var sum uint64 = hasher.Sum64()
+
+	server.mutex.Lock()
+	var count uint64 = server.mapCounts[endpoint+"/"+key]
+	server.mapCounts[endpoint+"/"+key]++
+	server.mutex.Unlock()
+
+	return rand.New(rand.NewPCG(seed, sum+count+1)), rand.New(rand.NewPCG(seed, sum))
+}
+
+// wait for the latency and pick the status of a request and whether its
+// measurements are null, the scenario of the station before the faults
+func (server *Server) fault(
+	r *http.Request,
+	random *rand.Rand,
+	faults Faults,
+	response *Response,
+) (int, bool) {
+	var latency time.Duration = faults.Latency
+	if faults.LatencyJitter > 0 {
+		latency += time.Duration(random.Int64N(int64(faults.LatencyJitter)))
+	}
+	if response != nil && response.LatencyMilliseconds > 0 {
+		latency = time.Duration(response.LatencyMilliseconds) * time.Millisecond
+	}
+	if latency > 0 {
+		var timer *time.Timer = time.NewTimer(latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+		}
+	}
+
+	// both draws are made every time, so that a scenario does not change
+	// the values of the other requests
+	var drawStatus float64 = random.Float64()
+	var drawNull float64 = random.Float64()
+
+	if response != nil && response.StatusCode != 0 {
+		return response.StatusCode, false
+	}
+	switch {
+	case drawStatus < faults.RateTooManyRequests:
+		return http.StatusTooManyRequests, false
+	case drawStatus < faults.RateTooManyRequests+faults.RateErrors:
+		return http.StatusInternalServerError, false
+	}
+	return http.StatusOK, drawNull < faults.RateNullFields
+}
+
+// write a JSON response
+func writeJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,118 @@
+package fakeweather
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"time"
+)
+
+// measurements of the responses, sent as null with the null fields fault
+var (
+	fieldsWeatherUnion []string = []string{
+		"temperature",
+		"humidity",
+		"wind_speed",
+		"wind_direction",
+		"rain_intensity",
+		"rain_accumulation",
+	}
+	fieldsOpenWeatherMap []string = []string{
+		"temp",
+		"feels_like",
+		"pressure",
+		"humidity",
+		"dew_point",
+	}
+)
+
+// temperature of a station in celsius and relative humidity in percent,
+// about the summer weather of the indian cities
+// the station generator gives the climate of the station, the request
+// generator the change of this request
+func generateClimate(random *rand.Rand, base *rand.Rand) (float64, float64) {
+	var temperature float64 = 27 + 9*base.Float64() + 3*(random.Float64()-0.5)
+	var humidity float64 = 35 + 50*base.Float64() + 10*(random.Float64()-0.5)
+	return temperature, math.Min(math.Max(humidity, 5), 100)
+}
+
+// locality_weather_data of weather union
+func generateWeatherUnion(random *rand.Rand, base *rand.Rand) map[string]any {
+	temperature, humidity := generateClimate(random, base)
+
+	// mostly dry, with light rain now and then
+	var rainIntensity float64
+	if random.Float64() < 0.1 {
+		rainIntensity = 10 * random.Float64()
+	}
+
+	return map[string]any{
+		"temperature":       round(temperature, 2),
+		"humidity":          round(humidity, 2),
+		"wind_speed":        round(6*random.Float64(), 2),
+		"wind_direction":    round(360*random.Float64(), 0),
+		"rain_intensity":    round(rainIntensity, 2),
+		"rain_accumulation": round(rainIntensity*random.Float64(), 2),
+	}
+}
+
+// current weather of open weather map, in its standard units (kelvin)
+func generateOpenWeatherMap(random *rand.Rand, base *rand.Rand, now time.Time) map[string]any {
+	temperature, humidity := generateClimate(random, base)
+
+	// dew point by the magnus formula
+	var gamma float64 = math.Log(humidity/100) + 17.62*temperature/(243.12+temperature)
+	var temperatureDewPoint float64 = 243.12 * gamma / (17.62 - gamma)
+
+	// sunrise and sunset of india, about 06:00 and 18:30 IST
+	var day time.Time = now.UTC().Truncate(24 * time.Hour)
+	var timeSunrise time.Time = day.Add(30 * time.Minute)
+	var timeSunset time.Time = day.Add(13 * time.Hour)
+
+	var windSpeed float64 = 6 * random.Float64()
+
+	return map[string]any{
+		"dt":         now.Unix(),
+		"sunrise":    timeSunrise.Unix(),
+		"sunset":     timeSunset.Unix(),
+		"temp":       round(temperature+273.15, 2),
+		"feels_like": round(temperature+273.15+4*humidity/100, 2),
+		"pressure":   round(1000+15*base.Float64()+2*(random.Float64()-0.5), 0),
+		"humidity":   round(humidity, 0),
+		"dew_point":  round(temperatureDewPoint+273.15, 2),
+		"uvi":        round(11*random.Float64(), 2),
+		"clouds":     round(100*random.Float64(), 0),
+		"visibility": 10000,
+		"wind_speed": round(windSpeed, 2),
+		"wind_deg":   round(360*random.Float64(), 0),
+		"wind_gust":  round(windSpeed*(1+random.Float64()), 2),
+		"weather": []map[string]any{
+			{
+				"id":          800,
+				"main":        "Clear",
+				"description": "clear sky",
+				"icon":        "01d",
+			},
+		},
+	}
+}
+
+// round a value to a number of decimals
+func round(value float64, decimals int) float64 {
+	var scale float64 = math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
+
+// parse a latitude or longitude of the query, up to a bound in degrees
+// open weather map sends them back rounded to 4 decimals
+func parseCoordinate(text string, bound float64) (float64, error) {
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, err
+	}
+	if !(value >= -bound && value <= bound) {
+		return 0, errors.New("coordinate out of range")
+	}
+	return round(value, 4), nil
+}
@@ -0,0 +1,102 @@
+package fakeweather
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// fixed responses of given stations, read from a JSON file:
+//
+//	{
+//	  "stations": [
+//	    {
+//	      "locality_id": "ZWL005764",
+//	      "latitude": "28.5355",
+//	      "longitude": "77.3910",
+//	      "weather_union": {"values": {"temperature": 34.5, "humidity": 70}},
+//	      "open_weather_map": {"status_code": 429}
+//	    }
+//	  ]
+//	}
+//
+// the other stations get generated values.
+type Scenario struct {
+	Stations []ScenarioStation `json:"stations"`
+}
+
+// responses of a station, weather union matches the locality ID and open
+// weather map the coordinates as sent by the cron
+type ScenarioStation struct {
+	LocalityID     string    `json:"locality_id"`
+	Latitude       string    `json:"latitude"`
+	Longitude      string    `json:"longitude"`
+	WeatherUnion   *Response `json:"weather_union"`
+	OpenWeatherMap *Response `json:"open_weather_map"`
+}
+
+// response of a station to an API
+type Response struct {
+	// status of the response, 200 if 0. other statuses are sent as the
+	// errors of the API.
+	StatusCode int `json:"status_code"`
+	// delay of the response instead of the latency of the faults
+	LatencyMilliseconds int `json:"latency_ms"`
+	// measurements by their field in the API, in its units, replacing the
+	// generated ones
+	Values map[string]float64 `json:"values"`
+	// measurements sent as null
+	NullFields []string `json:"null_fields"`
+}
+
+// read a scenario file, unknown keys are an error
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error in reading scenario file: %w", err)
+	}
+
+	var scenario Scenario
+	var decoder *json.Decoder = json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&scenario)
+	if err != nil {
+		return nil, fmt.Errorf("error in decoding scenario file %s: %w", path, err)
+	}
+
+	return &scenario, nil
+}
+
+// response of weather union for a locality, nil if not in the scenario
+func (scenario *Scenario) weatherUnion(localityID string) *Response {
+	for _, station := range scenario.Stations {
+		if station.LocalityID == localityID && station.WeatherUnion != nil {
+			return station.WeatherUnion
+		}
+	}
+	return nil
+}
+
+// response of open weather map for coordinates, nil if not in the scenario
+func (scenario *Scenario) openWeatherMap(latitude string, longitude string) *Response {
+	for _, station := range scenario.Stations {
+		if station.Latitude == latitude &&
+			station.Longitude == longitude &&
+			station.OpenWeatherMap != nil {
+			return station.OpenWeatherMap
+		}
+	}
+	return nil
+}
+
+// set the values and null fields of a response on the generated
+// measurements
+func (response *Response) apply(data map[string]any) {
+	for field, value := range response.Values {
+		data[field] = value
+	}
+	for _, field := range response.NullFields {
+		data[field] = nil
+	}
+}
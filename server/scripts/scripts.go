@@ -0,0 +1,36 @@
+// Package scripts embeds the Python scripts run by the calculations, so the
+// binaries do not depend on the directory they are started from.
+package scripts
+
+import (
+	"os"
+	"path/filepath"
+
+	_ "embed"
+)
+
+// script calculating the dew point and wet bulb temperature with MetPy
+//
+//go:embed wet_bulb_temperature.py
+var fileWetBulbTemperature []byte
+
+// write the wet bulb temperature script to a new temporary directory
+// returns the path of the script and a function removing the directory
+func WriteWetBulbTemperature() (string, func() error, error) {
+	directory, err := os.MkdirTemp("", "zomato-weather-union-scripts-")
+	if err != nil {
+		return "", nil, err
+	}
+	var remove = func() error {
+		return os.RemoveAll(directory)
+	}
+
+	var path string = filepath.Join(directory, "wet_bulb_temperature.py")
+	err = os.WriteFile(path, fileWetBulbTemperature, 0o600)
+	if err != nil {
+		remove()
+		return "", nil, err
+	}
+
+	return path, remove, nil
+}
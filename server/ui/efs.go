@@ -0,0 +1,8 @@
+package ui
+
+import "embed"
+
+// HTML templates and static files, embedded into the web binary
+//
+//go:embed "html" "static"
+var Files embed.FS
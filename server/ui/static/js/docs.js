@@ -0,0 +1,12 @@
+//
+// api reference
+//
+// rendered from the OpenAPI document served next to the API
+SwaggerUIBundle({
+    url: "/api/openapi.json",
+    dom_id: "#docs",
+    deepLinking: true,
+    // "try it out" sends the requests from the browser, without a key
+    // unless one is entered with the authorize button
+    tryItOutEnabled: false,
+});
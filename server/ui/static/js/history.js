@@ -0,0 +1,160 @@
+//
+// elements
+//
+const inputFrom = document.getElementById("history-from");
+const inputTo = document.getElementById("history-to");
+const buttonLoad = document.getElementById("history-load");
+const buttonPrevious = document.getElementById("history-previous");
+const buttonPlay = document.getElementById("history-play");
+const buttonNext = document.getElementById("history-next");
+const slider = document.getElementById("history-slider");
+const information = document.getElementById("information");
+
+//
+// state
+//
+// runs of the selected date range (oldest first)
+let runs = [];
+// calculations of the runs already fetched, keyed by run ID
+const cacheCalculations = new Map();
+// interval of the animation
+let timerPlay = null;
+
+//
+// colour bar
+//
+fillColourBar(document.getElementById("colour-bar"));
+
+//
+// map
+//
+const map = createMap("map");
+// circles of the displayed run
+const layerCircles = L.layerGroup().addTo(map);
+
+//
+// date range
+//
+// default to today (IST)
+const today = new Date().toLocaleDateString("en-CA", { "timeZone": "Asia/Kolkata" });
+inputFrom.value = today;
+inputTo.value = today;
+
+//
+// events
+//
+buttonLoad.addEventListener("click", loadRuns);
+slider.addEventListener("input", () => showRun(Number(slider.value)));
+buttonPrevious.addEventListener("click", () => step(-1));
+buttonNext.addEventListener("click", () => step(1));
+buttonPlay.addEventListener("click", togglePlay);
+
+loadRuns();
+
+// function to fetch the runs of the selected date range (IST days)
+async function loadRuns() {
+    stopPlay();
+
+    // the end date is inclusive, so the range ends at the next midnight
+    const from = `${inputFrom.value}T00:00:00+05:30`;
+    const dateTo = new Date(`${inputTo.value}T00:00:00+05:30`);
+    dateTo.setDate(dateTo.getDate() + 1);
+    const to = dateTo.toISOString();
+
+    const response = await fetch(
+        `/api/v1/runs?from=${encodeURIComponent(from)}&to=${encodeURIComponent(to)}`
+    );
+    if (!response.ok) {
+        information.textContent = `Could not load runs: ${await response.text()}`;
+        return;
+    }
+    runs = await response.json();
+
+    if (runs.length === 0) {
+        slider.disabled = true;
+        layerCircles.clearLayers();
+        information.textContent = "No runs in the selected date range.";
+        return;
+    }
+
+    slider.disabled = false;
+    slider.min = 0;
+    slider.max = runs.length - 1;
+    slider.value = runs.length - 1;
+    showRun(runs.length - 1);
+};
+
+// function to draw the calculations of a run on the map
+async function showRun(index) {
+    const run = runs[index];
+    if (run === undefined) {
+        return;
+    }
+
+    // fetch the calculations once per run
+    if (!cacheCalculations.has(run.run_id)) {
+        const response = await fetch(`/api/v1/calculations?run_id=${run.run_id}`);
+        if (!response.ok) {
+            information.textContent = `Could not load run: ${await response.text()}`;
+            return;
+        }
+        const body = await response.json();
+        cacheCalculations.set(run.run_id, processCalculations(body.calculations));
+    }
+
+    // the slider may have moved while fetching
+    if (Number(slider.value) !== index) {
+        return;
+    }
+
+    layerCircles.clearLayers();
+    displayDataAsCircles(layerCircles, cacheCalculations.get(run.run_id));
+
+    information.textContent = `
+        Run ${index + 1} of ${runs.length} at ${formatTimeIST(run.time_stamp)} IST (DD-MM-YYYY).
+        ${run.number_of_calculations} measurements, highest wet bulb temperature ${run.temperature_wet_bulb_max}°C.
+    `;
+};
+
+// function to move the slider by a number of runs
+function step(delta) {
+    const index = Number(slider.value) + delta;
+    if (index < 0 || index >= runs.length) {
+        return;
+    }
+    slider.value = index;
+    showRun(index);
+};
+
+// function to start or stop the animation through the runs
+function togglePlay() {
+    if (timerPlay !== null) {
+        stopPlay();
+        return;
+    }
+    if (runs.length === 0) {
+        return;
+    }
+    // restart from the first run if at the end
+    if (Number(slider.value) >= runs.length - 1) {
+        slider.value = 0;
+        showRun(0);
+    }
+    buttonPlay.textContent = "Pause";
+    timerPlay = setInterval(() => {
+        if (Number(slider.value) >= runs.length - 1) {
+            stopPlay();
+            return;
+        }
+        step(1);
+    }, 1000);
+};
+
+// function to stop the animation
+function stopPlay() {
+    if (timerPlay !== null) {
+        clearInterval(timerPlay);
+        timerPlay = null;
+    }
+    buttonPlay.textContent = "Play";
+};
@@ -0,0 +1,128 @@
+//
+// shared functions for the leaflet (open street maps) pages
+//
+
+// function to create a map centered on India with the Open Street Map tiles
+function createMap(elementID) {
+    // map centered on India
+    const map = L.map(elementID).setView([20, 78], 5);
+
+    // add a title layer with the Open Street Map information
+    L.tileLayer('https://tile.openstreetmap.org/{z}/{x}/{y}.png', {
+        maxZoom: 19,
+        attribution: '&copy; <a href="http://www.openstreetmap.org/copyright">OpenStreetMap</a>'
+    }).addTo(map);
+
+    return map;
+};
+
+// function to convert the timestamps of the calculations from the server
+// to locale strings (IST)
+function processCalculations(data) {
+    return data.map((element) => {
+        // append new time string to data element
+        element.time_string = formatTimeIST(element.time_stamp_calculation);
+        return element;
+    });
+};
+
+// function to format a timestamp as a locale string (IST)
+function formatTimeIST(timeStamp) {
+    return new Date(timeStamp).toLocaleString(
+        "en-IN",
+        {
+            "timeZone": "Asia/Kolkata",
+            "hour12": false
+        }
+    );
+};
+
+// function to fill the colour bar with gradients
+function fillColourBar(colourBar) {
+    for (let i = 20; i <= 40; i++) {
+        const listItem = document.createElement('li');
+        listItem.innerHTML = `
+            <div class="colour-box"></div>
+            <div class="colour-box-item-value">${
+                (i == 20) ? "≤" + i + "°C" : (i == 40) ? "≥" + i + "°C": i + "°C"
+            }</div>
+        `;
+        colourBar.appendChild(listItem);
+    }
+
+    // set colour here as inline styles are not supported by the CSP
+    // set by the server
+    // <div class="colour-box" style="background-color: ${color};"></div>
+    for (let i = 0; i <= 20; i++) {
+        const colour = getGradientColor(i + 20);
+        colourBar.children[i].children[0].style.backgroundColor = colour;
+    }
+};
+
+// function to set up measurements as circles via Leaflet
+// the layer can be the map or a layer group
+function displayDataAsCircles(layer, dataArray) {
+    for (let i = 0; i < dataArray.length; i++) {
+        const {
+            locality_id,
+            locality_name,
+            longitude,
+            latitude,
+            temperature_wet_bulb,
+            time_string
+        } = dataArray[i];
+
+        // colour based in temperature
+        const color = getGradientColor(temperature_wet_bulb);
+
+        const circleMarker = L.circleMarker([latitude, longitude], {
+            color: color,
+            fillColor: color,
+            fillOpacity: 0.6,
+            radius: 9, // constant radius
+        }).addTo(layer);
+
+        // add popup with the information
+        circleMarker.on("click", function () {
+            // create the popup content
+            const popupContent = `
+            <div>
+                <strong>Locality Name:</strong> ${locality_name}<br/>
+                <strong>Locality ID:</strong> ${locality_id}<br/>
+                <strong>Wet Bulb Temperature:</strong> ${temperature_wet_bulb}°C<br/>
+                <strong>Time:</strong> ${time_string}<br/>
+                <a href="/stations/${encodeURIComponent(locality_id)}">Station details</a>
+            </div>`;
+
+            // bind popup to the marker and open it
+            circleMarker.bindPopup(popupContent).openPopup();
+        });
+    }
+};
+
+// function to get colour values (rgb) for temperature values
+// 20 is green, 30 is yellow and 40 is red.
+// with a gradient in the middle
+function getGradientColor(value) {
+    // normalize to 0-1 range
+    const normalizedValue = (value - 20) / 20;
+    let r, g, b;
+    if (normalizedValue < 0.33) {
+        // blue to green
+        r = 0;
+        g = Math.round((normalizedValue / 0.33) * 255);
+        b = Math.round((1 - normalizedValue / 0.33) * 255);
+    } else if (normalizedValue < 0.67) {
+        // green to yellow
+        r = Math.round(((normalizedValue - 0.33) / 0.34) * 255);
+        g = 255;
+        b = 0;
+    } else {
+        // yellow to red
+        r = 255;
+        g = Math.round((1 - (normalizedValue - 0.67) / 0.33) * 255);
+        b = 0;
+    }
+
+    return `rgb(${r}, ${g}, ${b})`;
+};
@@ -1,9 +1,41 @@
 package ui
 
-import "html/template"
+import (
+	"html/template"
+	"io/fs"
+	"strconv"
+)
 
-// cache of HTML template files
-func CreateHTMLTemplateCache() (map[string]*template.Template, error) {
+// functions available in the HTML templates
+var functions template.FuncMap = template.FuncMap{
+	"formatNumber":       formatNumber,
+	"formatNumberSigned": formatNumberSigned,
+}
+
+// format an optional number with a fixed number of decimals
+// missing numbers are shown as a dash
+func formatNumber(value *float64, decimals int) string {
+	if value == nil {
+		return "–"
+	}
+	return strconv.FormatFloat(*value, 'f', decimals, 64)
+}
+
+// format an optional number with its sign, for differences
+func formatNumberSigned(value *float64, decimals int) string {
+	if value == nil {
+		return "–"
+	}
+	var formatted string = strconv.FormatFloat(*value, 'f', decimals, 64)
+	if *value >= 0 {
+		return "+" + formatted
+	}
+	return formatted
+}
+
+// cache of HTML template files, read from the html directory of fsys
+// (the embedded Files, or the ui directory on disk in development)
+func CreateHTMLTemplateCache(fsys fs.FS) (map[string]*template.Template, error) {
 	// initialize cache (map)
 	cache := make(map[string]*template.Template)
 
@@ -12,17 +44,173 @@ func CreateHTMLTemplateCache() (map[string]*template.Template, error) {
 	//
 	// list of all HTML template files involved for the home page
 	var templateFilesHome []string = []string{
-		"./ui/html/base.tmpl.html",
-		"./ui/html/components/navbar.tmpl.html",
-		"./ui/html/pages/home.tmpl.html",
+		"html/base.tmpl.html",
+		"html/components/navbar.tmpl.html",
+		"html/pages/home.tmpl.html",
 	}
 	// parse the HTML template files for home
-	parsedTemplateHome, err := template.ParseFiles(templateFilesHome...)
+	parsedTemplateHome, err := template.New("base").Funcs(functions).ParseFS(fsys, templateFilesHome...)
 	if err != nil {
 		return nil, err
 	}
 	// add to the cache
 	cache["home"] = parsedTemplateHome
 
+	//
+	// page - downloads
+	//
+	// list of all HTML template files involved for the downloads page
+	var templateFilesDownloads []string = []string{
+		"html/base.tmpl.html",
+		"html/components/navbar.tmpl.html",
+		"html/pages/downloads.tmpl.html",
+	}
+	// parse the HTML template files for downloads
+	parsedTemplateDownloads, err := template.New("base").Funcs(functions).ParseFS(fsys, templateFilesDownloads...)
+	if err != nil {
+		return nil, err
+	}
+	// add to the cache
+	cache["downloads"] = parsedTemplateDownloads
+
+	//
+	// page - history
+	//
+	// list of all HTML template files involved for the history page
+	var templateFilesHistory []string = []string{
+		"html/base.tmpl.html",
+		"html/components/navbar.tmpl.html",
+		"html/pages/history.tmpl.html",
+	}
+	// parse the HTML template files for history
+	parsedTemplateHistory, err := template.New("base").Funcs(functions).ParseFS(fsys, templateFilesHistory...)
+	if err != nil {
+		return nil, err
+	}
+	// add to the cache
+	cache["history"] = parsedTemplateHistory
+
+	//
+	// page - station
+	//
+	// list of all HTML template files involved for the station page
+	var templateFilesStation []string = []string{
+		"html/base.tmpl.html",
+		"html/components/navbar.tmpl.html",
+		"html/pages/station.tmpl.html",
+	}
+	// parse the HTML template files for station
+	parsedTemplateStation, err := template.New("base").Funcs(functions).ParseFS(fsys, templateFilesStation...)
+	if err != nil {
+		return nil, err
+	}
+	// add to the cache
+	cache["station"] = parsedTemplateStation
+
+	//
+	// page - cities
+	//
+	// list of all HTML template files involved for the cities page
+	var templateFilesCities []string = []string{
+		"html/base.tmpl.html",
+		"html/components/navbar.tmpl.html",
+		"html/pages/cities.tmpl.html",
+	}
+	// parse the HTML template files for cities
+	parsedTemplateCities, err := template.New("base").Funcs(functions).ParseFS(fsys, templateFilesCities...)
+	if err != nil {
+		return nil, err
+	}
+	// add to the cache
+	cache["cities"] = parsedTemplateCities
+
+	//
+	// page - city
+	//
+	// list of all HTML template files involved for the city page
+	var templateFilesCity []string = []string{
+		"html/base.tmpl.html",
+		"html/components/navbar.tmpl.html",
+		"html/pages/city.tmpl.html",
+	}
+	// parse the HTML template files for city
+	parsedTemplateCity, err := template.New("base").Funcs(functions).ParseFS(fsys, templateFilesCity...)
+	if err != nil {
+		return nil, err
+	}
+	// add to the cache
+	cache["city"] = parsedTemplateCity
+
+	//
+	// page - docs
+	//
+	// list of all HTML template files involved for the api docs page
+	var templateFilesDocs []string = []string{
+		"html/base.tmpl.html",
+		"html/components/navbar.tmpl.html",
+		"html/pages/docs.tmpl.html",
+	}
+	// parse the HTML template files for docs
+	parsedTemplateDocs, err := template.New("base").Funcs(functions).ParseFS(fsys, templateFilesDocs...)
+	if err != nil {
+		return nil, err
+	}
+	// add to the cache
+	cache["docs"] = parsedTemplateDocs
+
+	//
+	// page - admin stations
+	//
+	// list of all HTML template files involved for the admin stations page
+	var templateFilesAdminStations []string = []string{
+		"html/base.tmpl.html",
+		"html/components/navbar.tmpl.html",
+		"html/components/adminNavbar.tmpl.html",
+		"html/pages/adminStations.tmpl.html",
+	}
+	// parse the HTML template files for admin stations
+	parsedTemplateAdminStations, err := template.New("base").Funcs(functions).ParseFS(fsys, templateFilesAdminStations...)
+	if err != nil {
+		return nil, err
+	}
+	// add to the cache
+	cache["adminStations"] = parsedTemplateAdminStations
+
+	//
+	// page - admin station
+	//
+	// list of all HTML template files involved for the admin station page
+	var templateFilesAdminStation []string = []string{
+		"html/base.tmpl.html",
+		"html/components/navbar.tmpl.html",
+		"html/components/adminNavbar.tmpl.html",
+		"html/pages/adminStation.tmpl.html",
+	}
+	// parse the HTML template files for admin station
+	parsedTemplateAdminStation, err := template.New("base").Funcs(functions).ParseFS(fsys, templateFilesAdminStation...)
+	if err != nil {
+		return nil, err
+	}
+	// add to the cache
+	cache["adminStation"] = parsedTemplateAdminStation
+
+	//
+	// page - admin runs
+	//
+	// list of all HTML template files involved for the admin runs page
+	var templateFilesAdminRuns []string = []string{
+		"html/base.tmpl.html",
+		"html/components/navbar.tmpl.html",
+		"html/components/adminNavbar.tmpl.html",
+		"html/pages/adminRuns.tmpl.html",
+	}
+	// parse the HTML template files for admin runs
+	parsedTemplateAdminRuns, err := template.New("base").Funcs(functions).ParseFS(fsys, templateFilesAdminRuns...)
+	if err != nil {
+		return nil, err
+	}
+	// add to the cache
+	cache["adminRuns"] = parsedTemplateAdminRuns
+
 	return cache, nil
 }